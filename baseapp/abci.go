@@ -18,9 +18,11 @@ import (
 
 	"github.com/cosmos/cosmos-sdk/codec"
 	snapshottypes "github.com/cosmos/cosmos-sdk/snapshots/types"
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
 	"github.com/cosmos/cosmos-sdk/telemetry"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
 )
 
 // InitChain implements the ABCI interface. It runs the initialization logic
@@ -257,7 +259,12 @@ func (app *BaseApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 	ctx := app.getContextForTx(mode, req.Tx)
 	res, err := app.txHandler.CheckTx(ctx, tx, req)
 	if err != nil {
-		return sdkerrors.ResponseCheckTx(err, uint64(res.GasUsed), uint64(res.GasWanted), app.trace)
+		errRes := sdkerrors.ResponseCheckTx(err, uint64(res.GasUsed), uint64(res.GasWanted), app.trace)
+		// Keep the events returned alongside the error, e.g. by the error
+		// middleware.
+		errRes.Events = res.Events
+
+		return errRes
 	}
 
 	return res
@@ -288,7 +295,12 @@ func (app *BaseApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDeliverTx
 	ctx := app.getContextForTx(runTxModeDeliver, req.Tx)
 	res, err = app.txHandler.DeliverTx(ctx, tx, req)
 	if err != nil {
-		res = sdkerrors.ResponseDeliverTx(err, uint64(res.GasUsed), uint64(res.GasWanted), app.trace)
+		errRes := sdkerrors.ResponseDeliverTx(err, uint64(res.GasUsed), uint64(res.GasWanted), app.trace)
+		// Keep the events returned alongside the error, e.g. by the gas
+		// middleware.
+		errRes.Events = res.Events
+		res = errRes
+
 		return res
 	}
 
@@ -659,6 +671,55 @@ func (app *BaseApp) createQueryContext(height int64, prove bool) (sdk.Context, e
 	return ctx, nil
 }
 
+// SimulateAtHeight executes a tx in simulate mode against the committed state
+// at the given height, as if it was included in the next block, to get result
+// and gas info. A zero height simulates the tx like Simulate does, against the
+// latest state. It returns an error if the state at the given height is not
+// available, e.g. because it has been pruned.
+func (app *BaseApp) SimulateAtHeight(txBytes []byte, height int64) (sdk.GasInfo, *sdk.Result, error) {
+	if height == 0 {
+		return app.Simulate(txBytes)
+	}
+
+	sdkTx, err := app.txDecoder(txBytes)
+	if err != nil {
+		return sdk.GasInfo{}, nil, err
+	}
+
+	if height > app.LastBlockHeight() {
+		return sdk.GasInfo{}, nil, sdkerrors.Wrapf(
+			sdkerrors.ErrInvalidRequest,
+			"cannot simulate at height %d; latest height: %d", height, app.LastBlockHeight(),
+		)
+	}
+
+	// pruned heights would otherwise be loaded as empty stores
+	if rms, ok := app.cms.(*rootmulti.Store); ok && !rms.VersionExists(height) {
+		return sdk.GasInfo{}, nil, sdkerrors.Wrapf(
+			sdkerrors.ErrInvalidRequest,
+			"cannot simulate at height %d; state is not available, it may have been pruned", height,
+		)
+	}
+
+	ctx, err := app.createQueryContext(height, false)
+	if err != nil {
+		return sdk.GasInfo{}, nil, err
+	}
+
+	ctx = ctx.
+		WithBlockHeight(height + 1).
+		WithTxBytes(txBytes).
+		WithVoteInfos(app.voteInfos)
+	ctx = ctx.WithConsensusParams(app.GetConsensusParams(ctx))
+
+	res, err := app.txHandler.SimulateTx(sdk.WrapSDKContext(ctx), sdkTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	if err != nil {
+		return res.GasInfo, nil, err
+	}
+
+	return res.GasInfo, res.Result, nil
+}
+
 // GetBlockRetentionHeight returns the height for which all blocks below this height
 // are pruned from Tendermint. Given a commitment height and a non-zero local
 // minRetainBlocks configuration, the retentionHeight is the smallest height that
@@ -151,6 +151,9 @@ func NewBaseApp(
 		fauxMerkleMode:  false,
 	}
 
+	// Expose the middleware stack of the tx handler, once it is set.
+	tx.RegisterMiddlewareServiceServer(app.grpcQueryRouter, middlewareServiceServer{app: app})
+
 	for _, option := range options {
 		option(app)
 	}
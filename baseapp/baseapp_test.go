@@ -2,6 +2,7 @@ package baseapp_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -22,6 +23,7 @@ import (
 	dbm "github.com/tendermint/tm-db"
 
 	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/snapshots"
 	snapshottypes "github.com/cosmos/cosmos-sdk/snapshots/types"
@@ -33,6 +35,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
 	"github.com/cosmos/cosmos-sdk/x/auth/migrations/legacytx"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 )
 
 var (
@@ -1057,6 +1060,81 @@ func TestDeliverTx(t *testing.T) {
 	}
 }
 
+// The events to index are marked in the DeliverTx response itself, so that
+// the flags are part of the response serialized to an out-of-process
+// Tendermint right away, and seen by the ABCI listeners.
+func TestDeliverTxIndexEvents(t *testing.T) {
+	txHandlerOpt := func(bapp *baseapp.BaseApp) {
+		legacyRouter := middleware.NewLegacyRouter()
+		r := sdk.NewRoute(routeMsgCounter, handlerMsgCounter(t, capKey1, []byte("deliver-key")))
+		legacyRouter.AddRoute(r)
+		txHandler := testTxHandler(
+			middleware.TxHandlerOptions{
+				LegacyRouter:     legacyRouter,
+				MsgServiceRouter: middleware.NewMsgServiceRouter(interfaceRegistry),
+				IndexEvents:      map[string]struct{}{"post_handlers.update_counter": {}},
+			},
+			customHandlerTxTest(t, capKey1, []byte("ante-key")),
+		)
+		bapp.SetTxHandler(txHandler)
+	}
+	app := setupBaseApp(t, txHandlerOpt)
+	app.InitChain(abci.RequestInitChain{})
+
+	codec := codec.NewLegacyAmino()
+	registerTestCodec(codec)
+	txBytes, err := codec.Marshal(newTxCounter(0, 0))
+	require.NoError(t, err)
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: tmproto.Header{Height: 1}})
+	res := app.DeliverTx(abci.RequestDeliverTx{Tx: txBytes})
+	require.True(t, res.IsOK(), fmt.Sprintf("%v", res))
+	resBytes, err := res.Marshal()
+	require.NoError(t, err)
+	app.EndBlock(abci.RequestEndBlock{})
+
+	var serialized abci.ResponseDeliverTx
+	require.NoError(t, serialized.Unmarshal(resBytes))
+	require.Len(t, serialized.Events, 3)
+	require.Equal(t, "post_handlers", serialized.Events[0].Type)
+	require.True(t, serialized.Events[0].Attributes[0].Index)
+	require.Equal(t, sdk.EventTypeMessage, serialized.Events[2].Type)
+	require.False(t, serialized.Events[2].Attributes[0].Index)
+}
+
+// The events returned alongside a DeliverTx error are kept in the response.
+func TestDeliverTxFailureEvents(t *testing.T) {
+	txHandlerOpt := func(bapp *baseapp.BaseApp) {
+		legacyRouter := middleware.NewLegacyRouter()
+		r := sdk.NewRoute(routeMsgCounter, handlerMsgCounter(t, capKey1, []byte("deliver-key")))
+		legacyRouter.AddRoute(r)
+		txHandler := middleware.ComposeMiddlewares(
+			middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(interfaceRegistry), legacyRouter),
+			middleware.NewGasTxMiddlewareWithOptions(middleware.GasTxOptions{RecordAnteGas: true}),
+			middleware.RecoveryTxMiddleware,
+			middleware.ValidateBasicMiddleware,
+		)
+		bapp.SetTxHandler(txHandler)
+	}
+	app := setupBaseApp(t, txHandlerOpt)
+	app.InitChain(abci.RequestInitChain{})
+
+	codec := codec.NewLegacyAmino()
+	registerTestCodec(codec)
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: tmproto.Header{Height: 1}})
+	tx := newTxCounter(0, 0)
+	tx.setFailOnHandler(true)
+	txBytes, err := codec.Marshal(tx)
+	require.NoError(t, err)
+
+	res := app.DeliverTx(abci.RequestDeliverTx{Tx: txBytes})
+	require.False(t, res.IsOK(), fmt.Sprintf("%v", res))
+	require.Len(t, res.Events, 1)
+	require.Equal(t, sdk.EventTypeTx, res.Events[0].Type)
+	require.Equal(t, sdk.AttributeKeyAnteGasUsed, string(res.Events[0].Attributes[0].Key))
+}
+
 // Number of messages doesn't matter to CheckTx.
 func TestMultiMsgCheckTx(t *testing.T) {
 	// TODO: ensure we get the same results
@@ -1217,6 +1295,75 @@ func TestSimulateTx(t *testing.T) {
 	}
 }
 
+func TestSimulateTxAtHeight(t *testing.T) {
+	gasConsumed := uint64(5)
+
+	txHandlerOpt := func(bapp *baseapp.BaseApp) {
+		legacyRouter := middleware.NewLegacyRouter()
+		r := sdk.NewRoute(routeMsgCounter, func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+			ctx.GasMeter().ConsumeGas(gasConsumed, "test")
+			return &sdk.Result{}, nil
+		})
+		legacyRouter.AddRoute(r)
+		txHandler := testTxHandler(
+			middleware.TxHandlerOptions{
+				LegacyRouter:     legacyRouter,
+				MsgServiceRouter: middleware.NewMsgServiceRouter(interfaceRegistry),
+			},
+			func(ctx sdk.Context, tx sdk.Tx, simulate bool) (sdk.Context, error) { return ctx, nil },
+		)
+		bapp.SetTxHandler(txHandler)
+	}
+	// write to the store at every height, so that each one has its own version
+	beginBlockerOpt := func(bapp *baseapp.BaseApp) {
+		bapp.SetBeginBlocker(func(ctx sdk.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+			ctx.KVStore(capKey1).Set([]byte("height"), sdk.Uint64ToBigEndian(uint64(req.Header.Height)))
+			return abci.ResponseBeginBlock{}
+		})
+	}
+	// only keep the latest committed height and the one before it
+	pruningOpt := baseapp.SetPruning(storetypes.NewPruningOptions(1, 0, 1))
+	app := setupBaseApp(t, txHandlerOpt, beginBlockerOpt, pruningOpt)
+	authtx.RegisterTxService(app.GRPCQueryRouter(), client.Context{}, app.SimulateAtHeight, interfaceRegistry)
+
+	app.InitChain(abci.RequestInitChain{})
+
+	nBlocks := int64(5)
+	for height := int64(1); height <= nBlocks; height++ {
+		app.BeginBlock(abci.RequestBeginBlock{Header: tmproto.Header{Height: height}})
+		app.EndBlock(abci.RequestEndBlock{})
+		app.Commit()
+	}
+
+	// Create same codec used in txDecoder
+	cdc := codec.NewLegacyAmino()
+	registerTestCodec(cdc)
+
+	counterTx := newTxCounter(1, 1)
+	counterTx.GasLimit = gasConsumed
+	txBytes, err := cdc.Marshal(counterTx)
+	require.NoError(t, err)
+
+	helper := &baseapp.QueryServiceTestHelper{
+		GRPCQueryRouter: app.GRPCQueryRouter(),
+		Ctx:             app.NewContext(true, tmproto.Header{}),
+	}
+	txClient := tx.NewServiceClient(helper)
+
+	// the latest and the kept heights can be simulated against
+	for _, height := range []int64{0, nBlocks, nBlocks - 1} {
+		res, err := txClient.Simulate(context.Background(), &tx.SimulateRequest{TxBytes: txBytes, Height: height})
+		require.NoError(t, err, "height %d", height)
+		require.Equal(t, gasConsumed, res.GasInfo.GasUsed)
+	}
+
+	// the pruned heights and the future ones can't
+	for _, height := range []int64{1, nBlocks - 2, nBlocks + 1} {
+		_, err := txClient.Simulate(context.Background(), &tx.SimulateRequest{TxBytes: txBytes, Height: height})
+		require.ErrorIs(t, err, sdkerrors.ErrInvalidRequest, "height %d", height)
+	}
+}
+
 func TestRunInvalidTransaction(t *testing.T) {
 	txHandlerOpt := func(bapp *baseapp.BaseApp) {
 		legacyRouter := middleware.NewLegacyRouter()
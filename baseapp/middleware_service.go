@@ -0,0 +1,33 @@
+package baseapp
+
+import (
+	gocontext "context"
+
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// middlewareServiceServer implements the tx.MiddlewareServiceServer interface,
+// describing the tx handler of the BaseApp.
+type middlewareServiceServer struct {
+	app *BaseApp
+}
+
+var _ tx.MiddlewareServiceServer = middlewareServiceServer{}
+
+// DescribeMiddlewares implements the MiddlewareService.DescribeMiddlewares
+// RPC method. It returns an empty list if the tx handler can't be described.
+func (s middlewareServiceServer) DescribeMiddlewares(_ gocontext.Context, _ *tx.DescribeMiddlewaresRequest) (*tx.DescribeMiddlewaresResponse, error) {
+	describer, ok := s.app.txHandler.(tx.MiddlewareDescriber)
+	if !ok {
+		return &tx.DescribeMiddlewaresResponse{}, nil
+	}
+
+	infos := describer.DescribeMiddlewares()
+	middlewares := make([]*tx.MiddlewareInfo, len(infos))
+	for i, info := range infos {
+		info := info
+		middlewares[i] = &info
+	}
+
+	return &tx.DescribeMiddlewaresResponse{Middlewares: middlewares}, nil
+}
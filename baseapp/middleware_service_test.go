@@ -0,0 +1,41 @@
+package baseapp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/baseapp"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func TestDescribeMiddlewaresQuery(t *testing.T) {
+	app := newBaseApp(t.Name())
+	helper := &baseapp.QueryServiceTestHelper{
+		GRPCQueryRouter: app.GRPCQueryRouter(),
+		Ctx:             sdk.Context{}.WithContext(context.Background()),
+	}
+	client := tx.NewMiddlewareServiceClient(helper)
+
+	// no tx handler set yet
+	res, err := client.DescribeMiddlewares(context.Background(), &tx.DescribeMiddlewaresRequest{})
+	require.NoError(t, err)
+	require.Empty(t, res.Middlewares)
+
+	app.SetTxHandler(middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(nil), nil),
+		middleware.GasTxMiddleware,
+		middleware.RecoveryTxMiddleware,
+	))
+
+	res, err = client.DescribeMiddlewares(context.Background(), &tx.DescribeMiddlewaresRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []*tx.MiddlewareInfo{
+		{Name: "middleware.gasTxHandler"},
+		{Name: "middleware.recoveryTxHandler"},
+		{Name: "run_msgs"},
+	}, res.Middlewares)
+}
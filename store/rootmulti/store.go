@@ -480,6 +480,24 @@ func (rs *Store) CacheMultiStore() types.CacheMultiStore {
 	return cachemulti.NewStore(rs.db, stores, rs.keysByName, rs.traceWriter, rs.traceContext, rs.listeners)
 }
 
+// VersionExists returns whether the given version (height) is stored by any of
+// the mounted IAVL stores, i.e. whether it was committed and not pruned yet.
+// Loading a missing version with CacheMultiStoreWithVersion doesn't fail, it
+// returns empty IAVL stores instead.
+func (rs *Store) VersionExists(version int64) bool {
+	for key, store := range rs.stores {
+		if store.GetStoreType() != types.StoreTypeIAVL {
+			continue
+		}
+
+		if rs.GetCommitKVStore(key).(*iavl.Store).VersionExists(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // CacheMultiStoreWithVersion is analogous to CacheMultiStore except that it
 // attempts to load stores at a given version (height). An error is returned if
 // any store cannot be loaded. This should only be used for querying and
@@ -507,12 +507,16 @@ func TestMultiStore_Pruning(t *testing.T) {
 			for _, v := range tc.saved {
 				_, err := ms.CacheMultiStoreWithVersion(v)
 				require.NoError(t, err, "expected error when loading height: %d", v)
+				require.True(t, ms.VersionExists(v), "expected height %d to exist", v)
 			}
 
 			for _, v := range tc.deleted {
 				_, err := ms.CacheMultiStoreWithVersion(v)
 				require.NoError(t, err, "expected error when loading height: %d", v)
+				require.False(t, ms.VersionExists(v), "expected height %d to be pruned", v)
 			}
+
+			require.False(t, ms.VersionExists(tc.numVersions+1))
 		})
 	}
 }
@@ -147,6 +147,22 @@ var (
 
 	// ErrAppConfig defines an error occurred if min-gas-prices field in BaseConfig is empty.
 	ErrAppConfig = Register(RootCodespace, 40, "error in app.toml")
+
+	// ErrTooManyRequests defines an error returned when a client exceeds the
+	// allowed rate of requests, e.g. txs submitted to the mempool.
+	ErrTooManyRequests = Register(RootCodespace, 41, "too many requests")
+
+	// ErrServiceUnavailable defines an error returned when the node
+	// temporarily doesn't accept requests, e.g. during a maintenance window.
+	ErrServiceUnavailable = Register(RootCodespace, 42, "service unavailable")
+
+	// ErrInvalidGasLimit defines an error returned when a tx declares a gas
+	// limit above the maximum accepted by the node.
+	ErrInvalidGasLimit = Register(RootCodespace, 43, "invalid gas limit")
+
+	// ErrInvalidFee defines an error returned when a tx pays a fee different
+	// from the one required, e.g. on chains charging a fixed fee.
+	ErrInvalidFee = Register(RootCodespace, 44, "invalid fee")
 )
 
 // Register returns an error instance that should be used as the base for
@@ -218,6 +218,11 @@ var (
 	AttributeKeySignature       = "signature"
 	AttributeKeyFee             = "fee"
 
+	AttributeKeyFeeConversionRate = "fee_conversion_rate"
+	AttributeKeyFeeRefund         = "fee_refund"
+	AttributeKeyAnteGasUsed       = "ante_gas_used"
+	AttributeKeyMsgMeterGasUsed   = "msg_meter_gas_used"
+
 	EventTypeMessage = "message"
 
 	AttributeKeyAction = "action"
@@ -297,7 +302,21 @@ func StringifyEvents(events []abci.Event) StringEvents {
 // MarkEventsToIndex returns the set of ABCI events, where each event's attribute
 // has it's index value marked based on the provided set of events to index.
 func MarkEventsToIndex(events []abci.Event, indexSet map[string]struct{}) []abci.Event {
-	indexAll := len(indexSet) == 0
+	return MarkEventsToIndexWithPredicates(events, indexSet, nil)
+}
+
+// EventValuePredicate reports whether an event attribute with the given value
+// should be indexed.
+type EventValuePredicate func(value string) bool
+
+// MarkEventsToIndexWithPredicates is the same as MarkEventsToIndex, but also
+// accepts value predicates keyed by {eventType}.{attributeKey}. An attribute
+// with a predicate is only indexed if the predicate matches its value, while
+// the other attributes are marked based on the set of events to index. If both
+// the set of events to index and the predicates are empty, all events are
+// indexed.
+func MarkEventsToIndexWithPredicates(events []abci.Event, indexSet map[string]struct{}, valuePredicates map[string]EventValuePredicate) []abci.Event {
+	indexAll := len(indexSet) == 0 && len(valuePredicates) == 0
 	updatedEvents := make([]abci.Event, len(events))
 
 	for i, e := range events {
@@ -307,7 +326,12 @@ func MarkEventsToIndex(events []abci.Event, indexSet map[string]struct{}) []abci
 		}
 
 		for j, attr := range e.Attributes {
-			_, index := indexSet[fmt.Sprintf("%s.%s", e.Type, attr.Key)]
+			key := fmt.Sprintf("%s.%s", e.Type, attr.Key)
+			_, index := indexSet[key]
+			if predicate, ok := valuePredicates[key]; ok {
+				index = predicate(attr.Value)
+			}
+
 			updatedAttr := abci.EventAttribute{
 				Key:   attr.Key,
 				Value: attr.Value,
@@ -219,3 +219,80 @@ func (s *eventsTestSuite) TestMarkEventsToIndex() {
 		})
 	}
 }
+
+func (s *eventsTestSuite) TestMarkEventsToIndexWithPredicates() {
+	events := []abci.Event{
+		{
+			Type: "transfer",
+			Attributes: []abci.EventAttribute{
+				{Key: "recipient", Value: "hot-wallet"},
+				{Key: "sender", Value: "foo"},
+			},
+		},
+		{
+			Type: "transfer",
+			Attributes: []abci.EventAttribute{
+				{Key: "recipient", Value: "bar"},
+				{Key: "sender", Value: "hot-wallet"},
+			},
+		},
+	}
+	isHotWallet := func(value string) bool { return value == "hot-wallet" }
+
+	testCases := map[string]struct {
+		indexSet   map[string]struct{}
+		predicates map[string]sdk.EventValuePredicate
+		expected   []abci.Event
+	}{
+		"only matching values are indexed": {
+			predicates: map[string]sdk.EventValuePredicate{"transfer.recipient": isHotWallet},
+			expected: []abci.Event{
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "recipient", Value: "hot-wallet", Index: true},
+						{Key: "sender", Value: "foo"},
+					},
+				},
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "recipient", Value: "bar"},
+						{Key: "sender", Value: "hot-wallet"},
+					},
+				},
+			},
+		},
+		"predicates combined with the index set": {
+			indexSet:   map[string]struct{}{"transfer.sender": {}},
+			predicates: map[string]sdk.EventValuePredicate{"transfer.recipient": isHotWallet},
+			expected: []abci.Event{
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "recipient", Value: "hot-wallet", Index: true},
+						{Key: "sender", Value: "foo", Index: true},
+					},
+				},
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "recipient", Value: "bar"},
+						{Key: "sender", Value: "hot-wallet", Index: true},
+					},
+				},
+			},
+		},
+		"no predicates behaves as MarkEventsToIndex": {
+			indexSet: map[string]struct{}{"transfer.sender": {}},
+			expected: sdk.MarkEventsToIndex(events, map[string]struct{}{"transfer.sender": {}}),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		s.T().Run(name, func(_ *testing.T) {
+			s.Require().Equal(tc.expected, sdk.MarkEventsToIndexWithPredicates(events, tc.indexSet, tc.predicates))
+		})
+	}
+}
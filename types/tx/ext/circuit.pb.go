@@ -0,0 +1,631 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cosmos/tx/ext/v1/circuit.proto
+
+package ext
+
+import (
+	context "context"
+	fmt "fmt"
+	grpc1 "github.com/gogo/protobuf/grpc"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// MsgSetCircuitBreaker is the Msg/SetCircuitBreaker request type. It can only
+// be executed by the circuit breaker authority, e.g. the gov module account.
+type MsgSetCircuitBreaker struct {
+	// authority is the address of the circuit breaker authority.
+	Authority string `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	// msg_type_urls are the type URLs of the msgs to ban, or whose ban to lift.
+	MsgTypeUrls []string `protobuf:"bytes,2,rep,name=msg_type_urls,json=msgTypeUrls,proto3" json:"msg_type_urls,omitempty"`
+	// banned defines whether the msg types are banned, or their ban is lifted.
+	Banned bool `protobuf:"varint,3,opt,name=banned,proto3" json:"banned,omitempty"`
+}
+
+func (m *MsgSetCircuitBreaker) Reset()         { *m = MsgSetCircuitBreaker{} }
+func (m *MsgSetCircuitBreaker) String() string { return proto.CompactTextString(m) }
+func (*MsgSetCircuitBreaker) ProtoMessage()    {}
+func (*MsgSetCircuitBreaker) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fd197b22569cb775, []int{0}
+}
+func (m *MsgSetCircuitBreaker) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgSetCircuitBreaker) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgSetCircuitBreaker.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgSetCircuitBreaker) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgSetCircuitBreaker.Merge(m, src)
+}
+func (m *MsgSetCircuitBreaker) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgSetCircuitBreaker) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgSetCircuitBreaker.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgSetCircuitBreaker proto.InternalMessageInfo
+
+func (m *MsgSetCircuitBreaker) GetAuthority() string {
+	if m != nil {
+		return m.Authority
+	}
+	return ""
+}
+
+func (m *MsgSetCircuitBreaker) GetMsgTypeUrls() []string {
+	if m != nil {
+		return m.MsgTypeUrls
+	}
+	return nil
+}
+
+func (m *MsgSetCircuitBreaker) GetBanned() bool {
+	if m != nil {
+		return m.Banned
+	}
+	return false
+}
+
+// MsgSetCircuitBreakerResponse is the Msg/SetCircuitBreaker response type.
+type MsgSetCircuitBreakerResponse struct {
+}
+
+func (m *MsgSetCircuitBreakerResponse) Reset()         { *m = MsgSetCircuitBreakerResponse{} }
+func (m *MsgSetCircuitBreakerResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgSetCircuitBreakerResponse) ProtoMessage()    {}
+func (*MsgSetCircuitBreakerResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_fd197b22569cb775, []int{1}
+}
+func (m *MsgSetCircuitBreakerResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgSetCircuitBreakerResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgSetCircuitBreakerResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgSetCircuitBreakerResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgSetCircuitBreakerResponse.Merge(m, src)
+}
+func (m *MsgSetCircuitBreakerResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgSetCircuitBreakerResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgSetCircuitBreakerResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgSetCircuitBreakerResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*MsgSetCircuitBreaker)(nil), "cosmos.tx.ext.v1.MsgSetCircuitBreaker")
+	proto.RegisterType((*MsgSetCircuitBreakerResponse)(nil), "cosmos.tx.ext.v1.MsgSetCircuitBreakerResponse")
+}
+
+func init() { proto.RegisterFile("cosmos/tx/ext/v1/circuit.proto", fileDescriptor_fd197b22569cb775) }
+
+var fileDescriptor_fd197b22569cb775 = []byte{
+	// 265 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0x4b, 0xce, 0x2f, 0xce,
+	0xcd, 0x2f, 0xd6, 0x2f, 0xa9, 0xd0, 0x4f, 0xad, 0x28, 0xd1, 0x2f, 0x33, 0xd4, 0x4f, 0xce, 0x2c,
+	0x4a, 0x2e, 0xcd, 0x2c, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0x80, 0xc8, 0xeb, 0x95,
+	0x54, 0xe8, 0xa5, 0x56, 0x94, 0xe8, 0x95, 0x19, 0x2a, 0x15, 0x70, 0x89, 0xf8, 0x16, 0xa7, 0x07,
+	0xa7, 0x96, 0x38, 0x43, 0x14, 0x3a, 0x15, 0xa5, 0x26, 0x66, 0xa7, 0x16, 0x09, 0xc9, 0x70, 0x71,
+	0x26, 0x96, 0x96, 0x64, 0xe4, 0x17, 0x65, 0x96, 0x54, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06,
+	0x21, 0x04, 0x84, 0x94, 0xb8, 0x78, 0x73, 0x8b, 0xd3, 0xe3, 0x4b, 0x2a, 0x0b, 0x52, 0xe3, 0x4b,
+	0x8b, 0x72, 0x8a, 0x25, 0x98, 0x14, 0x98, 0x35, 0x38, 0x83, 0xb8, 0x73, 0x8b, 0xd3, 0x43, 0x2a,
+	0x0b, 0x52, 0x43, 0x8b, 0x72, 0x8a, 0x85, 0xc4, 0xb8, 0xd8, 0x92, 0x12, 0xf3, 0xf2, 0x52, 0x53,
+	0x24, 0x98, 0x15, 0x18, 0x35, 0x38, 0x82, 0xa0, 0x3c, 0x25, 0x39, 0x2e, 0x19, 0x6c, 0x36, 0x06,
+	0xa5, 0x16, 0x17, 0xe4, 0xe7, 0x15, 0xa7, 0x1a, 0x15, 0x71, 0x31, 0xfb, 0x16, 0xa7, 0x0b, 0x65,
+	0x73, 0x09, 0x62, 0xba, 0x4a, 0x4d, 0x0f, 0xdd, 0x03, 0x7a, 0xd8, 0xcc, 0x92, 0xd2, 0x23, 0x4e,
+	0x1d, 0xcc, 0x4e, 0x27, 0xe7, 0x13, 0x8f, 0xe4, 0x18, 0x2f, 0x3c, 0x92, 0x63, 0x7c, 0xf0, 0x48,
+	0x8e, 0x71, 0xc2, 0x63, 0x39, 0x86, 0x0b, 0x8f, 0xe5, 0x18, 0x6e, 0x3c, 0x96, 0x63, 0x88, 0xd2,
+	0x4c, 0xcf, 0x2c, 0xc9, 0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x87, 0x06, 0x2e, 0x84, 0xd2,
+	0x2d, 0x4e, 0xc9, 0xd6, 0x07, 0x05, 0x00, 0x2c, 0xb4, 0x93, 0xd8, 0xc0, 0x61, 0x6c, 0x0c, 0x18,
+	0x00, 0x19, 0xd8, 0x6b, 0xbd, 0x85, 0x01, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MsgClient is the client API for Msg service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MsgClient interface {
+	// SetCircuitBreaker bans, or lifts the ban of, msg types in the circuit
+	// breaker middleware.
+	SetCircuitBreaker(ctx context.Context, in *MsgSetCircuitBreaker, opts ...grpc.CallOption) (*MsgSetCircuitBreakerResponse, error)
+}
+
+type msgClient struct {
+	cc grpc1.ClientConn
+}
+
+func NewMsgClient(cc grpc1.ClientConn) MsgClient {
+	return &msgClient{cc}
+}
+
+func (c *msgClient) SetCircuitBreaker(ctx context.Context, in *MsgSetCircuitBreaker, opts ...grpc.CallOption) (*MsgSetCircuitBreakerResponse, error) {
+	out := new(MsgSetCircuitBreakerResponse)
+	err := c.cc.Invoke(ctx, "/cosmos.tx.ext.v1.Msg/SetCircuitBreaker", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MsgServer is the server API for Msg service.
+type MsgServer interface {
+	// SetCircuitBreaker bans, or lifts the ban of, msg types in the circuit
+	// breaker middleware.
+	SetCircuitBreaker(context.Context, *MsgSetCircuitBreaker) (*MsgSetCircuitBreakerResponse, error)
+}
+
+// UnimplementedMsgServer can be embedded to have forward compatible implementations.
+type UnimplementedMsgServer struct {
+}
+
+func (*UnimplementedMsgServer) SetCircuitBreaker(ctx context.Context, req *MsgSetCircuitBreaker) (*MsgSetCircuitBreakerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCircuitBreaker not implemented")
+}
+
+func RegisterMsgServer(s grpc1.Server, srv MsgServer) {
+	s.RegisterService(&_Msg_serviceDesc, srv)
+}
+
+func _Msg_SetCircuitBreaker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgSetCircuitBreaker)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).SetCircuitBreaker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cosmos.tx.ext.v1.Msg/SetCircuitBreaker",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).SetCircuitBreaker(ctx, req.(*MsgSetCircuitBreaker))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Msg_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cosmos.tx.ext.v1.Msg",
+	HandlerType: (*MsgServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetCircuitBreaker",
+			Handler:    _Msg_SetCircuitBreaker_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cosmos/tx/ext/v1/circuit.proto",
+}
+
+func (m *MsgSetCircuitBreaker) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgSetCircuitBreaker) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgSetCircuitBreaker) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Banned {
+		i--
+		if m.Banned {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.MsgTypeUrls) > 0 {
+		for iNdEx := len(m.MsgTypeUrls) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.MsgTypeUrls[iNdEx])
+			copy(dAtA[i:], m.MsgTypeUrls[iNdEx])
+			i = encodeVarintCircuit(dAtA, i, uint64(len(m.MsgTypeUrls[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Authority) > 0 {
+		i -= len(m.Authority)
+		copy(dAtA[i:], m.Authority)
+		i = encodeVarintCircuit(dAtA, i, uint64(len(m.Authority)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgSetCircuitBreakerResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgSetCircuitBreakerResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgSetCircuitBreakerResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintCircuit(dAtA []byte, offset int, v uint64) int {
+	offset -= sovCircuit(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *MsgSetCircuitBreaker) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Authority)
+	if l > 0 {
+		n += 1 + l + sovCircuit(uint64(l))
+	}
+	if len(m.MsgTypeUrls) > 0 {
+		for _, s := range m.MsgTypeUrls {
+			l = len(s)
+			n += 1 + l + sovCircuit(uint64(l))
+		}
+	}
+	if m.Banned {
+		n += 2
+	}
+	return n
+}
+
+func (m *MsgSetCircuitBreakerResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func sovCircuit(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozCircuit(x uint64) (n int) {
+	return sovCircuit(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *MsgSetCircuitBreaker) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCircuit
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgSetCircuitBreaker: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgSetCircuitBreaker: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Authority", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCircuit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Authority = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgTypeUrls", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCircuit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MsgTypeUrls = append(m.MsgTypeUrls, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Banned", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCircuit
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Banned = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCircuit(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgSetCircuitBreakerResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCircuit
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgSetCircuitBreakerResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgSetCircuitBreakerResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCircuit(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthCircuit
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipCircuit(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowCircuit
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCircuit
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCircuit
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthCircuit
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupCircuit
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthCircuit
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthCircuit        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowCircuit          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupCircuit = fmt.Errorf("proto: unexpected end of group")
+)
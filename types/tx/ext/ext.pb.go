@@ -0,0 +1,941 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cosmos/tx/ext/v1/ext.proto
+
+package ext
+
+import (
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_types "github.com/gogo/protobuf/types"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+	time "time"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+var _ = time.Kitchen
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// IdempotencyKey is the tx extension option holding the idempotency key of a
+// tx, so that the middleware executes at most once the txs of a fee payer
+// carrying the same key.
+type IdempotencyKey struct {
+	// key identifies the tx among the txs paid by its fee payer.
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *IdempotencyKey) Reset()         { *m = IdempotencyKey{} }
+func (m *IdempotencyKey) String() string { return proto.CompactTextString(m) }
+func (*IdempotencyKey) ProtoMessage()    {}
+func (*IdempotencyKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a823bf9d4ec6ad2f, []int{0}
+}
+func (m *IdempotencyKey) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IdempotencyKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IdempotencyKey.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IdempotencyKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IdempotencyKey.Merge(m, src)
+}
+func (m *IdempotencyKey) XXX_Size() int {
+	return m.Size()
+}
+func (m *IdempotencyKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_IdempotencyKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IdempotencyKey proto.InternalMessageInfo
+
+func (m *IdempotencyKey) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+// IdempotencyRecord is the result of a tx processed with an idempotency key, as
+// saved by the idempotency middleware.
+type IdempotencyRecord struct {
+	// data is the data of the tx response.
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// log is the log of the tx response.
+	Log string `protobuf:"bytes,2,opt,name=log,proto3" json:"log,omitempty"`
+	// height is the block height the tx was executed at.
+	Height int64 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (m *IdempotencyRecord) Reset()         { *m = IdempotencyRecord{} }
+func (m *IdempotencyRecord) String() string { return proto.CompactTextString(m) }
+func (*IdempotencyRecord) ProtoMessage()    {}
+func (*IdempotencyRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a823bf9d4ec6ad2f, []int{1}
+}
+func (m *IdempotencyRecord) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *IdempotencyRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IdempotencyRecord.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *IdempotencyRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IdempotencyRecord.Merge(m, src)
+}
+func (m *IdempotencyRecord) XXX_Size() int {
+	return m.Size()
+}
+func (m *IdempotencyRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_IdempotencyRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IdempotencyRecord proto.InternalMessageInfo
+
+func (m *IdempotencyRecord) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *IdempotencyRecord) GetLog() string {
+	if m != nil {
+		return m.Log
+	}
+	return ""
+}
+
+func (m *IdempotencyRecord) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+// ChainID is the tx extension option holding the chain-id a tx is meant for.
+type ChainID struct {
+	// chain_id is the chain-id of the chain the tx is meant for.
+	ChainId string `protobuf:"bytes,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+}
+
+func (m *ChainID) Reset()         { *m = ChainID{} }
+func (m *ChainID) String() string { return proto.CompactTextString(m) }
+func (*ChainID) ProtoMessage()    {}
+func (*ChainID) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a823bf9d4ec6ad2f, []int{2}
+}
+func (m *ChainID) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ChainID) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ChainID.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ChainID) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChainID.Merge(m, src)
+}
+func (m *ChainID) XXX_Size() int {
+	return m.Size()
+}
+func (m *ChainID) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChainID.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChainID proto.InternalMessageInfo
+
+func (m *ChainID) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+// NotBefore is the tx extension option holding the time before which a tx
+// can't be executed.
+type NotBefore struct {
+	// time is the earliest block time the tx can be executed at.
+	Time time.Time `protobuf:"bytes,1,opt,name=time,proto3,stdtime" json:"time"`
+}
+
+func (m *NotBefore) Reset()         { *m = NotBefore{} }
+func (m *NotBefore) String() string { return proto.CompactTextString(m) }
+func (*NotBefore) ProtoMessage()    {}
+func (*NotBefore) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a823bf9d4ec6ad2f, []int{3}
+}
+func (m *NotBefore) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NotBefore) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NotBefore.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *NotBefore) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NotBefore.Merge(m, src)
+}
+func (m *NotBefore) XXX_Size() int {
+	return m.Size()
+}
+func (m *NotBefore) XXX_DiscardUnknown() {
+	xxx_messageInfo_NotBefore.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NotBefore proto.InternalMessageInfo
+
+func (m *NotBefore) GetTime() time.Time {
+	if m != nil {
+		return m.Time
+	}
+	return time.Time{}
+}
+
+func init() {
+	proto.RegisterType((*IdempotencyKey)(nil), "cosmos.tx.ext.v1.IdempotencyKey")
+	proto.RegisterType((*IdempotencyRecord)(nil), "cosmos.tx.ext.v1.IdempotencyRecord")
+	proto.RegisterType((*ChainID)(nil), "cosmos.tx.ext.v1.ChainID")
+	proto.RegisterType((*NotBefore)(nil), "cosmos.tx.ext.v1.NotBefore")
+}
+
+func init() { proto.RegisterFile("cosmos/tx/ext/v1/ext.proto", fileDescriptor_a823bf9d4ec6ad2f) }
+
+var fileDescriptor_a823bf9d4ec6ad2f = []byte{
+	// 316 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xcf, 0x4e, 0xc2, 0x40,
+	0x10, 0xc6, 0xbb, 0x42, 0xf8, 0xb3, 0x1a, 0x83, 0x1b, 0x63, 0xb0, 0x87, 0x42, 0x1a, 0x0f, 0x78,
+	0x70, 0x37, 0xe8, 0xc5, 0x33, 0xe8, 0x81, 0x98, 0x98, 0xd8, 0x78, 0xf2, 0x62, 0x4a, 0xbb, 0x6c,
+	0x1b, 0x28, 0xd3, 0xd0, 0x81, 0xd0, 0xb7, 0xe0, 0xb1, 0x38, 0x72, 0xf4, 0xa4, 0x06, 0x5e, 0xc4,
+	0xec, 0x96, 0x26, 0x9e, 0xe6, 0x9b, 0x9d, 0xdf, 0x97, 0xd9, 0x6f, 0xa8, 0x1d, 0x40, 0x96, 0x40,
+	0x26, 0x70, 0x2d, 0xe4, 0x1a, 0xc5, 0xaa, 0xaf, 0x0b, 0x4f, 0x17, 0x80, 0xc0, 0x5a, 0xc5, 0x8c,
+	0xe3, 0x9a, 0xeb, 0xc7, 0x55, 0xdf, 0xbe, 0x54, 0xa0, 0xc0, 0x0c, 0x85, 0x56, 0x05, 0x67, 0x77,
+	0x14, 0x80, 0x9a, 0x49, 0x61, 0xba, 0xf1, 0x72, 0x22, 0x30, 0x4e, 0x64, 0x86, 0x7e, 0x92, 0x16,
+	0x80, 0xeb, 0xd2, 0xf3, 0x51, 0x28, 0x93, 0x14, 0x50, 0xce, 0x83, 0xfc, 0x45, 0xe6, 0xac, 0x45,
+	0x2b, 0x53, 0x99, 0xb7, 0x49, 0x97, 0xf4, 0x9a, 0x9e, 0x96, 0xee, 0x1b, 0xbd, 0xf8, 0xc7, 0x78,
+	0x32, 0x80, 0x45, 0xc8, 0x18, 0xad, 0x86, 0x3e, 0xfa, 0x86, 0x3b, 0xf3, 0x8c, 0xd6, 0xd6, 0x19,
+	0xa8, 0xf6, 0x49, 0x61, 0x9d, 0x81, 0x62, 0x57, 0xb4, 0x16, 0xc9, 0x58, 0x45, 0xd8, 0xae, 0x74,
+	0x49, 0xaf, 0xe2, 0x1d, 0x3b, 0xf7, 0x86, 0xd6, 0x87, 0x91, 0x1f, 0xcf, 0x47, 0x4f, 0xec, 0x9a,
+	0x36, 0x02, 0x2d, 0x3f, 0xe3, 0xf0, 0xb8, 0xb4, 0x6e, 0xfa, 0x51, 0xe8, 0x3e, 0xd3, 0xe6, 0x2b,
+	0xe0, 0x40, 0x4e, 0x60, 0x21, 0xd9, 0x23, 0xad, 0xea, 0xcf, 0x1b, 0xe6, 0xf4, 0xde, 0xe6, 0x45,
+	0x32, 0x5e, 0x26, 0xe3, 0xef, 0x65, 0xb2, 0x41, 0x63, 0xfb, 0xdd, 0xb1, 0x36, 0x3f, 0x1d, 0xe2,
+	0x19, 0xc7, 0x60, 0xb8, 0xdd, 0x3b, 0x64, 0xb7, 0x77, 0xc8, 0xef, 0xde, 0x21, 0x9b, 0x83, 0x63,
+	0xed, 0x0e, 0x8e, 0xf5, 0x75, 0x70, 0xac, 0x8f, 0x5b, 0x15, 0x63, 0xb4, 0x1c, 0xf3, 0x00, 0x12,
+	0x71, 0xbc, 0x76, 0x51, 0xee, 0xb2, 0x70, 0x2a, 0x30, 0x4f, 0x65, 0x79, 0xfe, 0x71, 0xcd, 0x2c,
+	0x7a, 0xf8, 0x1b, 0x00, 0xa6, 0xe2, 0x4c, 0x40, 0x96, 0x01, 0x00, 0x00,
+}
+
+func (m *IdempotencyKey) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IdempotencyKey) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IdempotencyKey) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintExt(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *IdempotencyRecord) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IdempotencyRecord) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IdempotencyRecord) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Height != 0 {
+		i = encodeVarintExt(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Log) > 0 {
+		i -= len(m.Log)
+		copy(dAtA[i:], m.Log)
+		i = encodeVarintExt(dAtA, i, uint64(len(m.Log)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintExt(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ChainID) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ChainID) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ChainID) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.ChainId) > 0 {
+		i -= len(m.ChainId)
+		copy(dAtA[i:], m.ChainId)
+		i = encodeVarintExt(dAtA, i, uint64(len(m.ChainId)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *NotBefore) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NotBefore) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *NotBefore) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	n1, err1 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
+	if err1 != nil {
+		return 0, err1
+	}
+	i -= n1
+	i = encodeVarintExt(dAtA, i, uint64(n1))
+	i--
+	dAtA[i] = 0xa
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintExt(dAtA []byte, offset int, v uint64) int {
+	offset -= sovExt(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *IdempotencyKey) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovExt(uint64(l))
+	}
+	return n
+}
+
+func (m *IdempotencyRecord) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovExt(uint64(l))
+	}
+	l = len(m.Log)
+	if l > 0 {
+		n += 1 + l + sovExt(uint64(l))
+	}
+	if m.Height != 0 {
+		n += 1 + sovExt(uint64(m.Height))
+	}
+	return n
+}
+
+func (m *ChainID) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.ChainId)
+	if l > 0 {
+		n += 1 + l + sovExt(uint64(l))
+	}
+	return n
+}
+
+func (m *NotBefore) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.Time)
+	n += 1 + l + sovExt(uint64(l))
+	return n
+}
+
+func sovExt(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozExt(x uint64) (n int) {
+	return sovExt(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *IdempotencyKey) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExt
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IdempotencyKey: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IdempotencyKey: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthExt
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExt
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExt(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthExt
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IdempotencyRecord) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExt
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IdempotencyRecord: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IdempotencyRecord: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthExt
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExt
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Log", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthExt
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExt
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Log = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExt(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthExt
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ChainID) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExt
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ChainID: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ChainID: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChainId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthExt
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExt
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ChainId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExt(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthExt
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *NotBefore) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExt
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: NotBefore: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NotBefore: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthExt
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthExt
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.Time, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExt(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthExt
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipExt(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowExt
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowExt
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthExt
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupExt
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthExt
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthExt        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowExt          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupExt = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,49 @@
+package ext
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+var (
+	_ sdk.Msg = &MsgSetCircuitBreaker{}
+	_ sdk.Msg = &MsgSetGlobalPause{}
+)
+
+// ValidateBasic implements the Msg.ValidateBasic method.
+func (m MsgSetCircuitBreaker) ValidateBasic() error {
+	if _, err := sdk.AccAddressFromBech32(m.Authority); err != nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidAddress, "invalid authority address (%s)", m.Authority)
+	}
+	if len(m.MsgTypeUrls) == 0 {
+		return sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "no msg type URL")
+	}
+	for _, typeURL := range m.MsgTypeUrls {
+		if typeURL == "" {
+			return sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "empty msg type URL")
+		}
+	}
+
+	return nil
+}
+
+// GetSigners implements the Msg.GetSigners method.
+func (m MsgSetCircuitBreaker) GetSigners() []sdk.AccAddress {
+	authority, _ := sdk.AccAddressFromBech32(m.Authority)
+	return []sdk.AccAddress{authority}
+}
+
+// ValidateBasic implements the Msg.ValidateBasic method.
+func (m MsgSetGlobalPause) ValidateBasic() error {
+	if _, err := sdk.AccAddressFromBech32(m.Authority); err != nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidAddress, "invalid authority address (%s)", m.Authority)
+	}
+
+	return nil
+}
+
+// GetSigners implements the Msg.GetSigners method.
+func (m MsgSetGlobalPause) GetSigners() []sdk.AccAddress {
+	authority, _ := sdk.AccAddressFromBech32(m.Authority)
+	return []sdk.AccAddress{authority}
+}
@@ -0,0 +1,571 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cosmos/tx/ext/v1/pause.proto
+
+package ext
+
+import (
+	context "context"
+	fmt "fmt"
+	grpc1 "github.com/gogo/protobuf/grpc"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// MsgSetGlobalPause is the PauseMsg/SetGlobalPause request type. It can only
+// be executed by the global pause authority, e.g. the gov module account.
+type MsgSetGlobalPause struct {
+	// authority is the address of the global pause authority.
+	Authority string `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	// paused defines whether the txs are paused, or resumed.
+	Paused bool `protobuf:"varint,2,opt,name=paused,proto3" json:"paused,omitempty"`
+}
+
+func (m *MsgSetGlobalPause) Reset()         { *m = MsgSetGlobalPause{} }
+func (m *MsgSetGlobalPause) String() string { return proto.CompactTextString(m) }
+func (*MsgSetGlobalPause) ProtoMessage()    {}
+func (*MsgSetGlobalPause) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cb727e4080c61f0, []int{0}
+}
+func (m *MsgSetGlobalPause) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgSetGlobalPause) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgSetGlobalPause.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgSetGlobalPause) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgSetGlobalPause.Merge(m, src)
+}
+func (m *MsgSetGlobalPause) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgSetGlobalPause) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgSetGlobalPause.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgSetGlobalPause proto.InternalMessageInfo
+
+func (m *MsgSetGlobalPause) GetAuthority() string {
+	if m != nil {
+		return m.Authority
+	}
+	return ""
+}
+
+func (m *MsgSetGlobalPause) GetPaused() bool {
+	if m != nil {
+		return m.Paused
+	}
+	return false
+}
+
+// MsgSetGlobalPauseResponse is the PauseMsg/SetGlobalPause response type.
+type MsgSetGlobalPauseResponse struct {
+}
+
+func (m *MsgSetGlobalPauseResponse) Reset()         { *m = MsgSetGlobalPauseResponse{} }
+func (m *MsgSetGlobalPauseResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgSetGlobalPauseResponse) ProtoMessage()    {}
+func (*MsgSetGlobalPauseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1cb727e4080c61f0, []int{1}
+}
+func (m *MsgSetGlobalPauseResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgSetGlobalPauseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgSetGlobalPauseResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgSetGlobalPauseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgSetGlobalPauseResponse.Merge(m, src)
+}
+func (m *MsgSetGlobalPauseResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgSetGlobalPauseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgSetGlobalPauseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgSetGlobalPauseResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*MsgSetGlobalPause)(nil), "cosmos.tx.ext.v1.MsgSetGlobalPause")
+	proto.RegisterType((*MsgSetGlobalPauseResponse)(nil), "cosmos.tx.ext.v1.MsgSetGlobalPauseResponse")
+}
+
+func init() { proto.RegisterFile("cosmos/tx/ext/v1/pause.proto", fileDescriptor_1cb727e4080c61f0) }
+
+var fileDescriptor_1cb727e4080c61f0 = []byte{
+	// 226 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0x49, 0xce, 0x2f, 0xce,
+	0xcd, 0x2f, 0xd6, 0x2f, 0xa9, 0xd0, 0x4f, 0xad, 0x28, 0xd1, 0x2f, 0x33, 0xd4, 0x2f, 0x48, 0x2c,
+	0x2d, 0x4e, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0x80, 0xc8, 0xea, 0x95, 0x54, 0xe8,
+	0xa5, 0x56, 0x94, 0xe8, 0x95, 0x19, 0x2a, 0x79, 0x72, 0x09, 0xfa, 0x16, 0xa7, 0x07, 0xa7, 0x96,
+	0xb8, 0xe7, 0xe4, 0x27, 0x25, 0xe6, 0x04, 0x80, 0x14, 0x0b, 0xc9, 0x70, 0x71, 0x26, 0x96, 0x96,
+	0x64, 0xe4, 0x17, 0x65, 0x96, 0x54, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x21, 0x04, 0x84,
+	0xc4, 0xb8, 0xd8, 0xc0, 0x66, 0xa6, 0x48, 0x30, 0x29, 0x30, 0x6a, 0x70, 0x04, 0x41, 0x79, 0x4a,
+	0xd2, 0x5c, 0x92, 0x18, 0x46, 0x05, 0xa5, 0x16, 0x17, 0xe4, 0xe7, 0x15, 0xa7, 0x1a, 0xe5, 0x71,
+	0x71, 0x80, 0x05, 0x7c, 0x8b, 0xd3, 0x85, 0x92, 0xb8, 0xf8, 0xd0, 0x2c, 0x54, 0xd6, 0x43, 0x77,
+	0x98, 0x1e, 0x86, 0x51, 0x52, 0xda, 0x44, 0x28, 0x82, 0xd9, 0xe7, 0xe4, 0x7c, 0xe2, 0x91, 0x1c,
+	0xe3, 0x85, 0x47, 0x72, 0x8c, 0x0f, 0x1e, 0xc9, 0x31, 0x4e, 0x78, 0x2c, 0xc7, 0x70, 0xe1, 0xb1,
+	0x1c, 0xc3, 0x8d, 0xc7, 0x72, 0x0c, 0x51, 0x9a, 0xe9, 0x99, 0x25, 0x19, 0xa5, 0x49, 0x7a, 0xc9,
+	0xf9, 0xb9, 0xfa, 0xd0, 0xc0, 0x82, 0x50, 0xba, 0xc5, 0x29, 0xd9, 0xfa, 0x25, 0x95, 0x05, 0xa9,
+	0xb0, 0xd0, 0x4b, 0x62, 0x03, 0x87, 0x9a, 0x31, 0x60, 0x00, 0x32, 0x44, 0xbe, 0xac, 0x55, 0x01,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// PauseMsgClient is the client API for PauseMsg service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PauseMsgClient interface {
+	// SetGlobalPause pauses, or resumes, the txs.
+	SetGlobalPause(ctx context.Context, in *MsgSetGlobalPause, opts ...grpc.CallOption) (*MsgSetGlobalPauseResponse, error)
+}
+
+type pauseMsgClient struct {
+	cc grpc1.ClientConn
+}
+
+func NewPauseMsgClient(cc grpc1.ClientConn) PauseMsgClient {
+	return &pauseMsgClient{cc}
+}
+
+func (c *pauseMsgClient) SetGlobalPause(ctx context.Context, in *MsgSetGlobalPause, opts ...grpc.CallOption) (*MsgSetGlobalPauseResponse, error) {
+	out := new(MsgSetGlobalPauseResponse)
+	err := c.cc.Invoke(ctx, "/cosmos.tx.ext.v1.PauseMsg/SetGlobalPause", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PauseMsgServer is the server API for PauseMsg service.
+type PauseMsgServer interface {
+	// SetGlobalPause pauses, or resumes, the txs.
+	SetGlobalPause(context.Context, *MsgSetGlobalPause) (*MsgSetGlobalPauseResponse, error)
+}
+
+// UnimplementedPauseMsgServer can be embedded to have forward compatible implementations.
+type UnimplementedPauseMsgServer struct {
+}
+
+func (*UnimplementedPauseMsgServer) SetGlobalPause(ctx context.Context, req *MsgSetGlobalPause) (*MsgSetGlobalPauseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetGlobalPause not implemented")
+}
+
+func RegisterPauseMsgServer(s grpc1.Server, srv PauseMsgServer) {
+	s.RegisterService(&_PauseMsg_serviceDesc, srv)
+}
+
+func _PauseMsg_SetGlobalPause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgSetGlobalPause)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PauseMsgServer).SetGlobalPause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cosmos.tx.ext.v1.PauseMsg/SetGlobalPause",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PauseMsgServer).SetGlobalPause(ctx, req.(*MsgSetGlobalPause))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _PauseMsg_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cosmos.tx.ext.v1.PauseMsg",
+	HandlerType: (*PauseMsgServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetGlobalPause",
+			Handler:    _PauseMsg_SetGlobalPause_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cosmos/tx/ext/v1/pause.proto",
+}
+
+func (m *MsgSetGlobalPause) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgSetGlobalPause) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgSetGlobalPause) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Paused {
+		i--
+		if m.Paused {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Authority) > 0 {
+		i -= len(m.Authority)
+		copy(dAtA[i:], m.Authority)
+		i = encodeVarintPause(dAtA, i, uint64(len(m.Authority)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgSetGlobalPauseResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgSetGlobalPauseResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgSetGlobalPauseResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintPause(dAtA []byte, offset int, v uint64) int {
+	offset -= sovPause(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *MsgSetGlobalPause) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Authority)
+	if l > 0 {
+		n += 1 + l + sovPause(uint64(l))
+	}
+	if m.Paused {
+		n += 2
+	}
+	return n
+}
+
+func (m *MsgSetGlobalPauseResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func sovPause(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozPause(x uint64) (n int) {
+	return sovPause(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *MsgSetGlobalPause) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPause
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgSetGlobalPause: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgSetGlobalPause: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Authority", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPause
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPause
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPause
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Authority = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Paused", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPause
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Paused = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPause(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthPause
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgSetGlobalPauseResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPause
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgSetGlobalPauseResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgSetGlobalPauseResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPause(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthPause
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipPause(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowPause
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowPause
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowPause
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthPause
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupPause
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthPause
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthPause        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowPause          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupPause = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,719 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: cosmos/tx/v1beta1/introspection.proto
+
+package tx
+
+import (
+	context "context"
+	fmt "fmt"
+	grpc1 "github.com/gogo/protobuf/grpc"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// DescribeMiddlewaresRequest is the request type for the
+// MiddlewareService.DescribeMiddlewares RPC method.
+type DescribeMiddlewaresRequest struct {
+}
+
+func (m *DescribeMiddlewaresRequest) Reset()         { *m = DescribeMiddlewaresRequest{} }
+func (m *DescribeMiddlewaresRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeMiddlewaresRequest) ProtoMessage()    {}
+func (*DescribeMiddlewaresRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_abdcf86310c5e041, []int{0}
+}
+func (m *DescribeMiddlewaresRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DescribeMiddlewaresRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DescribeMiddlewaresRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DescribeMiddlewaresRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeMiddlewaresRequest.Merge(m, src)
+}
+func (m *DescribeMiddlewaresRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *DescribeMiddlewaresRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeMiddlewaresRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeMiddlewaresRequest proto.InternalMessageInfo
+
+// DescribeMiddlewaresResponse is the response type for the
+// MiddlewareService.DescribeMiddlewares RPC method.
+type DescribeMiddlewaresResponse struct {
+	// middlewares are the middlewares of the tx handler, from outer to inner.
+	Middlewares []*MiddlewareInfo `protobuf:"bytes,1,rep,name=middlewares,proto3" json:"middlewares,omitempty"`
+}
+
+func (m *DescribeMiddlewaresResponse) Reset()         { *m = DescribeMiddlewaresResponse{} }
+func (m *DescribeMiddlewaresResponse) String() string { return proto.CompactTextString(m) }
+func (*DescribeMiddlewaresResponse) ProtoMessage()    {}
+func (*DescribeMiddlewaresResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_abdcf86310c5e041, []int{1}
+}
+func (m *DescribeMiddlewaresResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DescribeMiddlewaresResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DescribeMiddlewaresResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DescribeMiddlewaresResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeMiddlewaresResponse.Merge(m, src)
+}
+func (m *DescribeMiddlewaresResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *DescribeMiddlewaresResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeMiddlewaresResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeMiddlewaresResponse proto.InternalMessageInfo
+
+func (m *DescribeMiddlewaresResponse) GetMiddlewares() []*MiddlewareInfo {
+	if m != nil {
+		return m.Middlewares
+	}
+	return nil
+}
+
+// MiddlewareInfo describes a middleware of the tx handler.
+type MiddlewareInfo struct {
+	// name is the name of the middleware.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *MiddlewareInfo) Reset()         { *m = MiddlewareInfo{} }
+func (m *MiddlewareInfo) String() string { return proto.CompactTextString(m) }
+func (*MiddlewareInfo) ProtoMessage()    {}
+func (*MiddlewareInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_abdcf86310c5e041, []int{2}
+}
+func (m *MiddlewareInfo) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MiddlewareInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MiddlewareInfo.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MiddlewareInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MiddlewareInfo.Merge(m, src)
+}
+func (m *MiddlewareInfo) XXX_Size() int {
+	return m.Size()
+}
+func (m *MiddlewareInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_MiddlewareInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MiddlewareInfo proto.InternalMessageInfo
+
+func (m *MiddlewareInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*DescribeMiddlewaresRequest)(nil), "cosmos.tx.v1beta1.DescribeMiddlewaresRequest")
+	proto.RegisterType((*DescribeMiddlewaresResponse)(nil), "cosmos.tx.v1beta1.DescribeMiddlewaresResponse")
+	proto.RegisterType((*MiddlewareInfo)(nil), "cosmos.tx.v1beta1.MiddlewareInfo")
+}
+
+func init() {
+	proto.RegisterFile("cosmos/tx/v1beta1/introspection.proto", fileDescriptor_abdcf86310c5e041)
+}
+
+var fileDescriptor_abdcf86310c5e041 = []byte{
+	// 258 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x4d, 0xce, 0x2f, 0xce,
+	0xcd, 0x2f, 0xd6, 0x2f, 0xa9, 0xd0, 0x2f, 0x33, 0x4c, 0x4a, 0x2d, 0x49, 0x34, 0xd4, 0xcf, 0xcc,
+	0x2b, 0x29, 0xca, 0x2f, 0x2e, 0x48, 0x4d, 0x2e, 0xc9, 0xcc, 0xcf, 0xd3, 0x2b, 0x28, 0xca, 0x2f,
+	0xc9, 0x17, 0x12, 0x84, 0x28, 0xd3, 0x2b, 0xa9, 0xd0, 0x83, 0x2a, 0x53, 0x92, 0xe1, 0x92, 0x72,
+	0x49, 0x2d, 0x4e, 0x2e, 0xca, 0x4c, 0x4a, 0xf5, 0xcd, 0x4c, 0x49, 0xc9, 0x49, 0x2d, 0x4f, 0x2c,
+	0x4a, 0x2d, 0x0e, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x51, 0x4a, 0xe2, 0x92, 0xc6, 0x2a, 0x5b,
+	0x5c, 0x90, 0x9f, 0x57, 0x9c, 0x2a, 0xe4, 0xcc, 0xc5, 0x9d, 0x8b, 0x10, 0x96, 0x60, 0x54, 0x60,
+	0xd6, 0xe0, 0x36, 0x52, 0xd4, 0xc3, 0xb0, 0x45, 0x0f, 0xa1, 0xd9, 0x33, 0x2f, 0x2d, 0x3f, 0x08,
+	0x59, 0x97, 0x92, 0x0a, 0x17, 0x1f, 0xaa, 0xb4, 0x90, 0x10, 0x17, 0x4b, 0x5e, 0x62, 0x6e, 0xaa,
+	0x04, 0xa3, 0x02, 0xa3, 0x06, 0x67, 0x10, 0x98, 0x6d, 0xd4, 0xc9, 0xc8, 0x25, 0x88, 0x50, 0x16,
+	0x9c, 0x5a, 0x54, 0x96, 0x99, 0x9c, 0x2a, 0x54, 0xc2, 0x25, 0x8c, 0xc5, 0x7d, 0x42, 0xba, 0x58,
+	0x9c, 0x80, 0xdb, 0x97, 0x52, 0x7a, 0xc4, 0x2a, 0x87, 0x78, 0xdb, 0xc9, 0xfe, 0xc4, 0x23, 0x39,
+	0xc6, 0x0b, 0x8f, 0xe4, 0x18, 0x1f, 0x3c, 0x92, 0x63, 0x9c, 0xf0, 0x58, 0x8e, 0xe1, 0xc2, 0x63,
+	0x39, 0x86, 0x1b, 0x8f, 0xe5, 0x18, 0xa2, 0x54, 0xd3, 0x33, 0x4b, 0x32, 0x4a, 0x93, 0xf4, 0x92,
+	0xf3, 0x73, 0xf5, 0xa1, 0x51, 0x02, 0xa1, 0x74, 0x8b, 0x53, 0xb2, 0xf5, 0x4b, 0x2a, 0x0b, 0x52,
+	0x41, 0x71, 0x94, 0xc4, 0x06, 0x8e, 0x0e, 0x63, 0xc0, 0x00, 0xb2, 0xd1, 0x99, 0xfd, 0xb7, 0x01,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MiddlewareServiceClient is the client API for MiddlewareService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MiddlewareServiceClient interface {
+	// DescribeMiddlewares lists the middlewares of the app's tx handler, from
+	// outer to inner.
+	DescribeMiddlewares(ctx context.Context, in *DescribeMiddlewaresRequest, opts ...grpc.CallOption) (*DescribeMiddlewaresResponse, error)
+}
+
+type middlewareServiceClient struct {
+	cc grpc1.ClientConn
+}
+
+func NewMiddlewareServiceClient(cc grpc1.ClientConn) MiddlewareServiceClient {
+	return &middlewareServiceClient{cc}
+}
+
+func (c *middlewareServiceClient) DescribeMiddlewares(ctx context.Context, in *DescribeMiddlewaresRequest, opts ...grpc.CallOption) (*DescribeMiddlewaresResponse, error) {
+	out := new(DescribeMiddlewaresResponse)
+	err := c.cc.Invoke(ctx, "/cosmos.tx.v1beta1.MiddlewareService/DescribeMiddlewares", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MiddlewareServiceServer is the server API for MiddlewareService service.
+type MiddlewareServiceServer interface {
+	// DescribeMiddlewares lists the middlewares of the app's tx handler, from
+	// outer to inner.
+	DescribeMiddlewares(context.Context, *DescribeMiddlewaresRequest) (*DescribeMiddlewaresResponse, error)
+}
+
+// UnimplementedMiddlewareServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedMiddlewareServiceServer struct {
+}
+
+func (*UnimplementedMiddlewareServiceServer) DescribeMiddlewares(ctx context.Context, req *DescribeMiddlewaresRequest) (*DescribeMiddlewaresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeMiddlewares not implemented")
+}
+
+func RegisterMiddlewareServiceServer(s grpc1.Server, srv MiddlewareServiceServer) {
+	s.RegisterService(&_MiddlewareService_serviceDesc, srv)
+}
+
+func _MiddlewareService_DescribeMiddlewares_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeMiddlewaresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MiddlewareServiceServer).DescribeMiddlewares(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cosmos.tx.v1beta1.MiddlewareService/DescribeMiddlewares",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MiddlewareServiceServer).DescribeMiddlewares(ctx, req.(*DescribeMiddlewaresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _MiddlewareService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cosmos.tx.v1beta1.MiddlewareService",
+	HandlerType: (*MiddlewareServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DescribeMiddlewares",
+			Handler:    _MiddlewareService_DescribeMiddlewares_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cosmos/tx/v1beta1/introspection.proto",
+}
+
+func (m *DescribeMiddlewaresRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DescribeMiddlewaresRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DescribeMiddlewaresRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
+func (m *DescribeMiddlewaresResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DescribeMiddlewaresResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DescribeMiddlewaresResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Middlewares) > 0 {
+		for iNdEx := len(m.Middlewares) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Middlewares[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIntrospection(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MiddlewareInfo) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MiddlewareInfo) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MiddlewareInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintIntrospection(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintIntrospection(dAtA []byte, offset int, v uint64) int {
+	offset -= sovIntrospection(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *DescribeMiddlewaresRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
+func (m *DescribeMiddlewaresResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Middlewares) > 0 {
+		for _, e := range m.Middlewares {
+			l = e.Size()
+			n += 1 + l + sovIntrospection(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *MiddlewareInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovIntrospection(uint64(l))
+	}
+	return n
+}
+
+func sovIntrospection(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozIntrospection(x uint64) (n int) {
+	return sovIntrospection(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *DescribeMiddlewaresRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIntrospection
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: DescribeMiddlewaresRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: DescribeMiddlewaresRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIntrospection(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DescribeMiddlewaresResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIntrospection
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: DescribeMiddlewaresResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: DescribeMiddlewaresResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Middlewares", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIntrospection
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Middlewares = append(m.Middlewares, &MiddlewareInfo{})
+			if err := m.Middlewares[len(m.Middlewares)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIntrospection(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MiddlewareInfo) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIntrospection
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MiddlewareInfo: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MiddlewareInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIntrospection
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIntrospection(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIntrospection
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipIntrospection(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowIntrospection
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowIntrospection
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowIntrospection
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthIntrospection
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupIntrospection
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthIntrospection
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthIntrospection        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowIntrospection          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupIntrospection = fmt.Errorf("proto: unexpected end of group")
+)
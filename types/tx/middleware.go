@@ -11,7 +11,42 @@ import (
 // RequestSimulateTx is the request type for the tx.Handler.RequestSimulateTx
 // method.
 type RequestSimulateTx struct {
-	TxBytes []byte
+	TxBytes         []byte
+	SimulateOptions SimulateOptions
+	// GasAdjustment is the safety multiplier applied to the simulated gas used
+	// to compute ResponseSimulateTx.RecommendedGasLimit. If not positive, it
+	// defaults to 1.0.
+	GasAdjustment float64
+}
+
+// SimulateOptions defines optional flags altering the behavior of the
+// tx.Handler.SimulateTx method.
+type SimulateOptions struct {
+	// StopAfterAnte skips the execution of the tx's msgs, so that the
+	// simulation only reports the gas consumed by the middlewares, e.g.
+	// signature verification and fee deduction.
+	StopAfterAnte bool
+	// GroupEvents populates ResponseSimulateTx.GroupedEvents with the events
+	// emitted by each msg. The flattened Result.Events are still populated.
+	GroupEvents bool
+	// SkipSequenceCheck makes the signature verification middleware accept
+	// signatures whose sequence doesn't match the signer's account sequence,
+	// e.g. to simulate a tx queued after other txs of the same signer that
+	// are not committed yet. It is only needed on the nodes checking the
+	// sequences in simulations, see the SigVerification middleware
+	// SimulateSequenceCheck option. The sequence doesn't alter the gas
+	// consumed by the tx, so the gas estimate of such a simulation remains
+	// valid. It has no effect on CheckTx and DeliverTx.
+	SkipSequenceCheck bool
+	// ReturnStateDiff populates ResponseSimulateTx.StateDiff with the store
+	// entries changed by the simulated tx. It is only honored by the
+	// StateDiff middleware.
+	ReturnStateDiff bool
+	// EnforceGasLimit meters the simulation with the tx's gas limit, like
+	// CheckTx and DeliverTx, instead of an infinite gas meter, so that the
+	// simulation of a tx declaring a low gas limit runs out of gas. It is
+	// only honored by the Gas middleware.
+	EnforceGasLimit bool
 }
 
 // ResponseSimulateTx is the response type for the tx.Handler.RequestSimulateTx
@@ -19,6 +54,81 @@ type RequestSimulateTx struct {
 type ResponseSimulateTx struct {
 	GasInfo sdk.GasInfo
 	Result  *sdk.Result
+	// GroupedEvents holds the events emitted by each msg, indexed like the
+	// tx's msgs. It is only populated if SimulateOptions.GroupEvents is set.
+	GroupedEvents [][]abci.Event
+	// EstimatedFee holds the fee required by the node's min gas prices for the
+	// simulated gas, with one coin per min gas price denom. It is empty if the
+	// node has no min gas prices.
+	EstimatedFee sdk.Coins
+	// RecommendedGasLimit holds the simulated gas used multiplied by the
+	// request GasAdjustment, rounded up and capped to the block gas limit.
+	RecommendedGasLimit uint64
+	// MsgResponses holds the execution result of each msg, indexed like the
+	// tx's msgs. It is only populated by the MsgResults middleware.
+	MsgResponses []MsgResult
+	// StateDiff holds the store entries changed by the simulated tx, sorted
+	// by store key name and then by key. It is only populated if
+	// SimulateOptions.ReturnStateDiff is set. StateDiffTruncated reports
+	// whether some changes were left out to bound the response size.
+	StateDiff          []StateChange
+	StateDiffTruncated bool
+}
+
+// StateChange is the change of a store entry by a simulated tx.
+type StateChange struct {
+	StoreKey string
+	Key      []byte
+	// OldValue is nil if the entry is created.
+	OldValue []byte
+	// NewValue is nil if the entry is deleted.
+	NewValue []byte
+}
+
+// MsgStatus is the execution status of a msg.
+type MsgStatus int
+
+const (
+	// MsgStatusSucceeded is the status of the msgs executed successfully.
+	MsgStatusSucceeded MsgStatus = iota
+	// MsgStatusFailed is the status of the msgs whose execution failed.
+	MsgStatusFailed
+	// MsgStatusSkipped is the status of the msgs which were not executed,
+	// because a previous msg of an atomic tx failed.
+	MsgStatusSkipped
+)
+
+// String implements fmt.Stringer.
+func (s MsgStatus) String() string {
+	switch s {
+	case MsgStatusSucceeded:
+		return "succeeded"
+	case MsgStatusFailed:
+		return "failed"
+	case MsgStatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// MsgResult holds the execution result of a msg of a tx.
+type MsgResult struct {
+	MsgType string
+	Status  MsgStatus
+	// Log holds the msg log, or the error of a failed msg.
+	Log string
+}
+
+// Response is a common view over the responses of the tx.Handler methods,
+// used by middlewares which need to inspect a response independently of the
+// tx.Handler method which produced it.
+type Response struct {
+	GasWanted uint64
+	GasUsed   uint64
+	Data      []byte
+	Log       string
+	Events    []abci.Event
 }
 
 // TxHandler defines the baseapp's CheckTx, DeliverTx and Simulate respective
@@ -31,3 +141,11 @@ type Handler interface {
 
 // TxMiddleware defines one layer of the TxHandler middleware stack.
 type Middleware func(Handler) Handler
+
+// MiddlewareDescriber is implemented by tx.Handlers which can describe the
+// middleware stack they are made of.
+type MiddlewareDescriber interface {
+	// DescribeMiddlewares lists the middlewares of the stack, from outer to
+	// inner.
+	DescribeMiddlewares() []MiddlewareInfo
+}
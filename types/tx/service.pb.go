@@ -345,6 +345,10 @@ type SimulateRequest struct {
 	//
 	// Since: cosmos-sdk 0.43
 	TxBytes []byte `protobuf:"bytes,2,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	// height is the height of the committed state to simulate the transaction
+	// against, as if it was included in the next block. Zero simulates the
+	// transaction against the latest state, like before it was introduced.
+	Height int64 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
 }
 
 func (m *SimulateRequest) Reset()         { *m = SimulateRequest{} }
@@ -395,6 +399,13 @@ func (m *SimulateRequest) GetTxBytes() []byte {
 	return nil
 }
 
+func (m *SimulateRequest) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 // SimulateResponse is the response type for the
 // Service.SimulateRPC method.
 type SimulateResponse struct {
@@ -582,59 +593,60 @@ func init() {
 }
 
 var fileDescriptor_e0b00a618705eca7 = []byte{
-	// 831 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0x41, 0x8f, 0xdb, 0x44,
-	0x14, 0x5e, 0x3b, 0x65, 0x93, 0xbe, 0x64, 0x4b, 0x3a, 0xbb, 0x14, 0x93, 0x82, 0x37, 0x75, 0xc9,
-	0x36, 0x44, 0xc2, 0x56, 0x03, 0x48, 0x08, 0x71, 0x89, 0x93, 0x74, 0x59, 0x41, 0x9b, 0x6a, 0xb2,
-	0x08, 0x15, 0x21, 0x45, 0x4e, 0x32, 0xf5, 0x5a, 0x6c, 0x3c, 0x59, 0xcf, 0x64, 0xe5, 0xa8, 0xad,
-	0x90, 0x38, 0x72, 0x42, 0xe2, 0x67, 0xf0, 0x27, 0x38, 0x72, 0x5c, 0x89, 0x0b, 0x47, 0xb4, 0xe1,
-	0x47, 0x70, 0x44, 0x1e, 0x4f, 0x12, 0x27, 0xeb, 0x74, 0x11, 0xa7, 0xbc, 0xc9, 0x7c, 0xef, 0x7b,
-	0xdf, 0xfb, 0xe6, 0xcd, 0x18, 0xf6, 0x07, 0x94, 0x8d, 0x28, 0xb3, 0x78, 0x68, 0x9d, 0x3f, 0xec,
-	0x13, 0xee, 0x3c, 0xb4, 0x18, 0x09, 0xce, 0xbd, 0x01, 0x31, 0xc7, 0x01, 0xe5, 0x14, 0xdd, 0x8e,
-	0x01, 0x26, 0x0f, 0x4d, 0x09, 0x28, 0xbd, 0xeb, 0x52, 0xea, 0x9e, 0x12, 0xcb, 0x19, 0x7b, 0x96,
-	0xe3, 0xfb, 0x94, 0x3b, 0xdc, 0xa3, 0x3e, 0x8b, 0x13, 0x4a, 0xf7, 0x25, 0x63, 0xdf, 0x61, 0xc4,
-	0x72, 0xfa, 0x03, 0x6f, 0x41, 0x1c, 0x2d, 0x24, 0xa8, 0x74, 0xb5, 0x2c, 0x0f, 0xe5, 0xde, 0x9e,
-	0x4b, 0x5d, 0x2a, 0x42, 0x2b, 0x8a, 0xe4, 0xbf, 0xb5, 0x24, 0xed, 0xd9, 0x84, 0x04, 0xd3, 0x45,
-	0xe6, 0xd8, 0x71, 0x3d, 0x5f, 0x68, 0x88, 0xb1, 0xc6, 0xaf, 0x0a, 0xa0, 0x43, 0xc2, 0x8f, 0x43,
-	0xd6, 0x3e, 0x27, 0x3e, 0xc7, 0xe4, 0x6c, 0x42, 0x18, 0x47, 0x77, 0x60, 0x9b, 0x44, 0x6b, 0xa6,
-	0x29, 0xe5, 0x4c, 0xf5, 0x26, 0x96, 0x2b, 0xf4, 0x08, 0x60, 0x49, 0xa1, 0xa9, 0x65, 0xa5, 0x9a,
-	0xaf, 0x1f, 0x98, 0xb2, 0xef, 0xa8, 0x9e, 0x29, 0xea, 0xcd, 0xfb, 0x37, 0x9f, 0x3a, 0x2e, 0x91,
-	0x9c, 0x38, 0x91, 0x89, 0x3e, 0x81, 0x1c, 0x0d, 0x86, 0x24, 0xe8, 0xf5, 0xa7, 0x5a, 0xa6, 0xac,
-	0x54, 0x6f, 0xd5, 0x4b, 0xe6, 0x15, 0xf7, 0xcc, 0x4e, 0x04, 0xb1, 0xa7, 0x38, 0x4b, 0xe3, 0xc0,
-	0xb8, 0x50, 0x60, 0x77, 0x45, 0x2d, 0x1b, 0x53, 0x9f, 0x11, 0xf4, 0x00, 0x32, 0x3c, 0x8c, 0xb5,
-	0xe6, 0xeb, 0x6f, 0xa5, 0x30, 0x1d, 0x87, 0x38, 0x42, 0xa0, 0x43, 0x28, 0xf0, 0xb0, 0x17, 0xc8,
-	0x3c, 0xa6, 0xa9, 0x22, 0xe3, 0xfd, 0x95, 0x0e, 0x84, 0xf7, 0x89, 0x44, 0x09, 0xc6, 0x79, 0xbe,
-	0x88, 0x23, 0xa2, 0xa4, 0x11, 0x19, 0x61, 0xc4, 0x83, 0x6b, 0x8d, 0x90, 0x4c, 0x89, 0x54, 0x83,
-	0x00, 0xb2, 0x03, 0xea, 0x0c, 0x07, 0x0e, 0xe3, 0x51, 0xb1, 0xd8, 0xff, 0x77, 0x20, 0xc7, 0xc3,
-	0x5e, 0x7f, 0xca, 0x49, 0xd4, 0x95, 0x52, 0x2d, 0xe0, 0x2c, 0x0f, 0xed, 0x68, 0x89, 0x3e, 0x86,
-	0x1b, 0x23, 0x3a, 0x24, 0xc2, 0xfc, 0x5b, 0xf5, 0x72, 0x4a, 0xb3, 0x0b, 0xbe, 0xc7, 0x74, 0x48,
-	0xb0, 0x40, 0x1b, 0xdf, 0xc1, 0xee, 0x4a, 0x19, 0x69, 0x5c, 0x1b, 0xf2, 0x09, 0x3f, 0x44, 0xa9,
-	0xff, 0x6a, 0x07, 0x2c, 0xed, 0x30, 0xbe, 0x81, 0x37, 0xbb, 0xde, 0x68, 0x72, 0xea, 0xf0, 0xf9,
-	0x69, 0xa3, 0x0f, 0x40, 0xe5, 0xa1, 0x24, 0x4c, 0x3f, 0x11, 0x5b, 0xd5, 0x14, 0xac, 0xf2, 0x70,
-	0xa5, 0x59, 0x75, 0xa5, 0x59, 0xe3, 0x27, 0x05, 0x8a, 0x4b, 0x66, 0x29, 0xfa, 0x73, 0xc8, 0xb9,
-	0x0e, 0xeb, 0x79, 0xfe, 0x73, 0x2a, 0x0b, 0xdc, 0xdb, 0xac, 0xf8, 0xd0, 0x61, 0x47, 0xfe, 0x73,
-	0x8a, 0xb3, 0x6e, 0x1c, 0xa0, 0x4f, 0x61, 0x3b, 0x20, 0x6c, 0x72, 0xca, 0xe5, 0xf8, 0x96, 0x37,
-	0xe7, 0x62, 0x81, 0xc3, 0x12, 0x6f, 0x18, 0x50, 0x10, 0xc3, 0x37, 0x6f, 0x11, 0xc1, 0x8d, 0x13,
-	0x87, 0x9d, 0x08, 0x0d, 0x37, 0xb1, 0x88, 0x8d, 0x57, 0xb0, 0x23, 0x31, 0x52, 0x6c, 0xe5, 0x5a,
-	0x1f, 0x84, 0x07, 0x6b, 0x07, 0xa1, 0xfe, 0xbf, 0x83, 0xa8, 0x7d, 0x01, 0x59, 0x79, 0x69, 0x90,
-	0x06, 0x7b, 0x1d, 0xdc, 0x6a, 0xe3, 0x9e, 0xfd, 0xac, 0xf7, 0xf5, 0x93, 0xee, 0xd3, 0x76, 0xf3,
-	0xe8, 0xd1, 0x51, 0xbb, 0x55, 0xdc, 0x42, 0x45, 0x28, 0x2c, 0x76, 0x1a, 0xdd, 0x66, 0x51, 0x41,
-	0xb7, 0x61, 0x67, 0xf1, 0x4f, 0xab, 0xdd, 0x6d, 0x16, 0xd5, 0xda, 0x4b, 0xd8, 0x59, 0x99, 0x23,
-	0xa4, 0x43, 0xc9, 0xc6, 0x9d, 0x46, 0xab, 0xd9, 0xe8, 0x1e, 0xf7, 0x1e, 0x77, 0x5a, 0xed, 0x35,
-	0x56, 0x0d, 0xf6, 0xd6, 0xf6, 0xed, 0xaf, 0x3a, 0xcd, 0x2f, 0x8b, 0x0a, 0x7a, 0x1b, 0x76, 0xd7,
-	0x76, 0xba, 0xcf, 0x9e, 0x34, 0x8b, 0x6a, 0x4a, 0x4a, 0x43, 0xec, 0x64, 0xea, 0xff, 0x64, 0x20,
-	0xdb, 0x8d, 0x1f, 0x57, 0xf4, 0x02, 0x72, 0xf3, 0x11, 0x40, 0x46, 0x8a, 0x83, 0x6b, 0x93, 0x57,
-	0xba, 0xff, 0x5a, 0x8c, 0x9c, 0xd8, 0x83, 0x1f, 0xff, 0xf8, 0xfb, 0x17, 0xb5, 0x6c, 0xdc, 0xb5,
-	0x52, 0x5e, 0x75, 0x09, 0xfe, 0x4c, 0xa9, 0xa1, 0x33, 0x78, 0x43, 0x9c, 0x27, 0xda, 0x4f, 0x61,
-	0x4d, 0x4e, 0x43, 0xa9, 0xbc, 0x19, 0x20, 0x6b, 0x56, 0x44, 0xcd, 0x7d, 0xf4, 0x9e, 0x95, 0xf6,
-	0xa4, 0x33, 0xeb, 0x45, 0x34, 0x41, 0xaf, 0xd0, 0x0f, 0x90, 0x4f, 0x5c, 0x55, 0x54, 0x79, 0xdd,
-	0x0d, 0x5f, 0x96, 0x3f, 0xb8, 0x0e, 0x26, 0x45, 0xdc, 0x13, 0x22, 0xee, 0x1a, 0x77, 0xd2, 0x45,
-	0x44, 0x3d, 0xbf, 0x84, 0x7c, 0xe2, 0x91, 0x4d, 0x15, 0x70, 0xf5, 0x93, 0x91, 0x2a, 0x20, 0xe5,
-	0xad, 0x36, 0x74, 0x21, 0x40, 0x43, 0x1b, 0x04, 0xd8, 0xcd, 0xdf, 0x2f, 0x75, 0xe5, 0xe2, 0x52,
-	0x57, 0xfe, 0xba, 0xd4, 0x95, 0x9f, 0x67, 0xfa, 0xd6, 0x6f, 0x33, 0x5d, 0xb9, 0x98, 0xe9, 0x5b,
-	0x7f, 0xce, 0xf4, 0xad, 0x6f, 0x2b, 0xae, 0xc7, 0x4f, 0x26, 0x7d, 0x73, 0x40, 0x47, 0xf3, 0xfc,
-	0xf8, 0xe7, 0x43, 0x36, 0xfc, 0xde, 0xe2, 0xd3, 0x31, 0x89, 0x08, 0xfb, 0xdb, 0xe2, 0xeb, 0xf6,
-	0xd1, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xe4, 0x86, 0xcd, 0x5c, 0xb4, 0x07, 0x00, 0x00,
+	// 840 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0x41, 0x6f, 0xe3, 0x44,
+	0x14, 0xae, 0x9d, 0xa5, 0xe9, 0xbe, 0xb4, 0x4b, 0x76, 0x5a, 0x8a, 0xf1, 0x82, 0x9b, 0xf5, 0xd2,
+	0x6e, 0xa9, 0x84, 0xad, 0x0d, 0x20, 0x21, 0xc4, 0xa5, 0x4e, 0xb2, 0xa5, 0x82, 0xdd, 0xac, 0x26,
+	0xe1, 0xb0, 0x08, 0x29, 0x72, 0x92, 0x59, 0xc7, 0xa2, 0xf1, 0xa4, 0x9e, 0x49, 0xe5, 0x68, 0x77,
+	0x85, 0xc4, 0x91, 0x13, 0x12, 0x3f, 0x83, 0x3f, 0xc1, 0x91, 0x63, 0x25, 0x2e, 0x1c, 0x51, 0xc3,
+	0x8f, 0xe0, 0x88, 0x3c, 0x9e, 0x24, 0x4e, 0xea, 0x34, 0x68, 0x4f, 0x79, 0x93, 0xf9, 0xde, 0xf7,
+	0xbe, 0xf7, 0xcd, 0x9b, 0x31, 0xec, 0x75, 0x28, 0xeb, 0x53, 0x66, 0xf3, 0xc8, 0xbe, 0x78, 0xd4,
+	0x26, 0xdc, 0x7d, 0x64, 0x33, 0x12, 0x5e, 0xf8, 0x1d, 0x62, 0x0d, 0x42, 0xca, 0x29, 0xba, 0x9b,
+	0x00, 0x2c, 0x1e, 0x59, 0x12, 0xa0, 0xbf, 0xef, 0x51, 0xea, 0x9d, 0x11, 0xdb, 0x1d, 0xf8, 0xb6,
+	0x1b, 0x04, 0x94, 0xbb, 0xdc, 0xa7, 0x01, 0x4b, 0x12, 0xf4, 0x07, 0x92, 0xb1, 0xed, 0x32, 0x62,
+	0xbb, 0xed, 0x8e, 0x3f, 0x25, 0x8e, 0x17, 0x12, 0xa4, 0x5f, 0x2f, 0xcb, 0x23, 0xb9, 0xb7, 0xe3,
+	0x51, 0x8f, 0x8a, 0xd0, 0x8e, 0x23, 0xf9, 0xef, 0x51, 0x9a, 0xf6, 0x7c, 0x48, 0xc2, 0xd1, 0x34,
+	0x73, 0xe0, 0x7a, 0x7e, 0x20, 0x34, 0x24, 0x58, 0xf3, 0x37, 0x05, 0xd0, 0x09, 0xe1, 0xcd, 0x88,
+	0xd5, 0x2e, 0x48, 0xc0, 0x31, 0x39, 0x1f, 0x12, 0xc6, 0xd1, 0x2e, 0xac, 0x93, 0x78, 0xcd, 0x34,
+	0xa5, 0x94, 0x3b, 0xbc, 0x8d, 0xe5, 0x0a, 0x3d, 0x06, 0x98, 0x51, 0x68, 0x6a, 0x49, 0x39, 0x2c,
+	0x94, 0x0f, 0x2c, 0xd9, 0x77, 0x5c, 0xcf, 0x12, 0xf5, 0x26, 0xfd, 0x5b, 0xcf, 0x5c, 0x8f, 0x48,
+	0x4e, 0x9c, 0xca, 0x44, 0x9f, 0xc1, 0x06, 0x0d, 0xbb, 0x24, 0x6c, 0xb5, 0x47, 0x5a, 0xae, 0xa4,
+	0x1c, 0xde, 0x29, 0xeb, 0xd6, 0x35, 0xf7, 0xac, 0x7a, 0x0c, 0x71, 0x46, 0x38, 0x4f, 0x93, 0xc0,
+	0xbc, 0x54, 0x60, 0x7b, 0x4e, 0x2d, 0x1b, 0xd0, 0x80, 0x11, 0xf4, 0x10, 0x72, 0x3c, 0x4a, 0xb4,
+	0x16, 0xca, 0xef, 0x64, 0x30, 0x35, 0x23, 0x1c, 0x23, 0xd0, 0x09, 0x6c, 0xf2, 0xa8, 0x15, 0xca,
+	0x3c, 0xa6, 0xa9, 0x22, 0xe3, 0xc3, 0xb9, 0x0e, 0x84, 0xf7, 0xa9, 0x44, 0x09, 0xc6, 0x05, 0x3e,
+	0x8d, 0x63, 0xa2, 0xb4, 0x11, 0x39, 0x61, 0xc4, 0xc3, 0x95, 0x46, 0x48, 0xa6, 0x54, 0xaa, 0x49,
+	0x00, 0x39, 0x21, 0x75, 0xbb, 0x1d, 0x97, 0xf1, 0x66, 0x24, 0xbd, 0x42, 0xef, 0xc1, 0x06, 0x8f,
+	0x5a, 0xed, 0x11, 0x27, 0x71, 0x57, 0xca, 0xe1, 0x26, 0xce, 0xf3, 0xc8, 0x89, 0x97, 0xe8, 0x53,
+	0xb8, 0xd5, 0xa7, 0x5d, 0x22, 0xcc, 0xbf, 0x53, 0x2e, 0x65, 0x34, 0x3b, 0xe5, 0x7b, 0x42, 0xbb,
+	0x04, 0x0b, 0xb4, 0xf9, 0x3d, 0x6c, 0xcf, 0x95, 0x91, 0xc6, 0xd5, 0xa0, 0x90, 0xf2, 0x43, 0x94,
+	0xfa, 0xbf, 0x76, 0xc0, 0xcc, 0x0e, 0x93, 0xc2, 0xdb, 0x0d, 0xbf, 0x3f, 0x3c, 0x73, 0xf9, 0xe4,
+	0xb4, 0xd1, 0x47, 0xa0, 0xf2, 0x48, 0x12, 0x66, 0x9f, 0x88, 0xa3, 0x6a, 0x0a, 0x56, 0x79, 0x34,
+	0xd7, 0xac, 0x3a, 0xdf, 0xec, 0x2e, 0xac, 0xf7, 0x88, 0xef, 0xf5, 0xb8, 0xb0, 0x38, 0x87, 0xe5,
+	0xca, 0xfc, 0x59, 0x81, 0xe2, 0xac, 0xa2, 0x6c, 0xe6, 0x4b, 0xd8, 0xf0, 0x5c, 0xd6, 0xf2, 0x83,
+	0x17, 0x54, 0x16, 0xbe, 0xbf, 0xbc, 0x93, 0x13, 0x97, 0x9d, 0x06, 0x2f, 0x28, 0xce, 0x7b, 0x49,
+	0x80, 0x3e, 0x87, 0xf5, 0x90, 0xb0, 0xe1, 0x19, 0x97, 0x63, 0x5d, 0x5a, 0x9e, 0x8b, 0x05, 0x0e,
+	0x4b, 0xbc, 0x69, 0xc2, 0xa6, 0x18, 0xca, 0x49, 0xeb, 0x08, 0x6e, 0xf5, 0x5c, 0xd6, 0x13, 0x1a,
+	0x6e, 0x63, 0x11, 0x9b, 0xaf, 0x61, 0x4b, 0x62, 0xa4, 0xd8, 0xfd, 0x95, 0xfe, 0x08, 0x6f, 0x16,
+	0x0e, 0x48, 0x7d, 0xb3, 0x03, 0x3a, 0xfa, 0x0a, 0xf2, 0xf2, 0x32, 0x21, 0x0d, 0x76, 0xea, 0xb8,
+	0x5a, 0xc3, 0x2d, 0xe7, 0x79, 0xeb, 0xdb, 0xa7, 0x8d, 0x67, 0xb5, 0xca, 0xe9, 0xe3, 0xd3, 0x5a,
+	0xb5, 0xb8, 0x86, 0x8a, 0xb0, 0x39, 0xdd, 0x39, 0x6e, 0x54, 0x8a, 0x0a, 0xba, 0x0b, 0x5b, 0xd3,
+	0x7f, 0xaa, 0xb5, 0x46, 0xa5, 0xa8, 0x1e, 0xbd, 0x82, 0xad, 0xb9, 0xf9, 0x42, 0x06, 0xe8, 0x0e,
+	0xae, 0x1f, 0x57, 0x2b, 0xc7, 0x8d, 0x66, 0xeb, 0x49, 0xbd, 0x5a, 0x5b, 0x60, 0xd5, 0x60, 0x67,
+	0x61, 0xdf, 0xf9, 0xa6, 0x5e, 0xf9, 0xba, 0xa8, 0xa0, 0x77, 0x61, 0x7b, 0x61, 0xa7, 0xf1, 0xfc,
+	0x69, 0xa5, 0xa8, 0x66, 0xa4, 0x1c, 0x8b, 0x9d, 0x5c, 0xf9, 0xdf, 0x1c, 0xe4, 0x1b, 0xc9, 0xa3,
+	0x8b, 0x5e, 0xc2, 0xc6, 0x64, 0x04, 0x90, 0x99, 0xe1, 0xe0, 0xc2, 0x44, 0xea, 0x0f, 0x6e, 0xc4,
+	0xc8, 0x49, 0x3e, 0xf8, 0xe9, 0xcf, 0x7f, 0x7e, 0x55, 0x4b, 0xe6, 0x3d, 0x3b, 0xe3, 0xb5, 0x97,
+	0xe0, 0x2f, 0x94, 0x23, 0x74, 0x0e, 0x6f, 0x89, 0xf3, 0x44, 0x7b, 0x19, 0xac, 0xe9, 0x69, 0xd0,
+	0x4b, 0xcb, 0x01, 0xb2, 0xe6, 0xbe, 0xa8, 0xb9, 0x87, 0x3e, 0xb0, 0xb3, 0x9e, 0x7a, 0x66, 0xbf,
+	0x8c, 0x27, 0xe8, 0x35, 0xfa, 0x11, 0x0a, 0xa9, 0x2b, 0x8c, 0xf6, 0x6f, 0xba, 0xf9, 0xb3, 0xf2,
+	0x07, 0xab, 0x60, 0x52, 0xc4, 0x7d, 0x21, 0xe2, 0x9e, 0xb9, 0x9b, 0x2d, 0x22, 0xee, 0xf9, 0x15,
+	0x14, 0x52, 0x8f, 0x6f, 0xa6, 0x80, 0xeb, 0x9f, 0x12, 0xfd, 0x60, 0x15, 0x4c, 0x0a, 0x30, 0x84,
+	0x00, 0x0d, 0x2d, 0x11, 0xe0, 0x54, 0xfe, 0xb8, 0x32, 0x94, 0xcb, 0x2b, 0x43, 0xf9, 0xfb, 0xca,
+	0x50, 0x7e, 0x19, 0x1b, 0x6b, 0xbf, 0x8f, 0x0d, 0xe5, 0x72, 0x6c, 0xac, 0xfd, 0x35, 0x36, 0xd6,
+	0xbe, 0xdb, 0xf7, 0x7c, 0xde, 0x1b, 0xb6, 0xad, 0x0e, 0xed, 0x4f, 0xf2, 0x93, 0x9f, 0x8f, 0x59,
+	0xf7, 0x07, 0x9b, 0x8f, 0x06, 0x24, 0x26, 0x6c, 0xaf, 0x8b, 0xaf, 0xde, 0x27, 0xff, 0x0d, 0x00,
+	0x15, 0x76, 0x10, 0x10, 0xcc, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1035,6 +1047,11 @@ func (m *SimulateRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Height != 0 {
+		i = encodeVarintService(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x18
+	}
 	if len(m.TxBytes) > 0 {
 		i -= len(m.TxBytes)
 		copy(dAtA[i:], m.TxBytes)
@@ -1282,6 +1299,9 @@ func (m *SimulateRequest) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovService(uint64(l))
 	}
+	if m.Height != 0 {
+		n += 1 + sovService(uint64(m.Height))
+	}
 	return n
 }
 
@@ -1917,6 +1937,25 @@ func (m *SimulateRequest) Unmarshal(dAtA []byte) error {
 				m.TxBytes = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowService
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipService(dAtA[iNdEx:])
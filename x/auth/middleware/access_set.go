@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// TxAccessSet is the set of store keys read and written by a tx, e.g. to
+// detect the conflicts between txs. The keys are recorded without the name of
+// their store, so two keys of different stores may be reported as the same.
+type TxAccessSet struct {
+	mtx    sync.Mutex
+	reads  map[string]struct{}
+	writes map[string]struct{}
+	// buf holds the incomplete trace operation being written.
+	buf []byte
+}
+
+// txAccessSetKey is the sdk.Context key under which the TxAccessSet of a tx is
+// stored.
+type txAccessSetKey struct{}
+
+// WithTxAccessSet returns a context holding a new, empty, TxAccessSet, into
+// which the AccessSet middleware records the keys accessed by the tx, so that
+// the caller can read them once the tx is executed.
+func WithTxAccessSet(sdkCtx sdk.Context) (sdk.Context, *TxAccessSet) {
+	accessSet := newTxAccessSet()
+	return sdkCtx.WithValue(txAccessSetKey{}, accessSet), accessSet
+}
+
+// GetTxAccessSet returns the TxAccessSet recorded by the AccessSet middleware,
+// or nil if the middleware isn't enabled.
+func GetTxAccessSet(sdkCtx sdk.Context) *TxAccessSet {
+	accessSet, _ := sdkCtx.Value(txAccessSetKey{}).(*TxAccessSet)
+	return accessSet
+}
+
+func newTxAccessSet() *TxAccessSet {
+	return &TxAccessSet{
+		reads:  make(map[string]struct{}),
+		writes: make(map[string]struct{}),
+	}
+}
+
+// traceOperation is a store operation, as written by the tracing KVStore.
+type traceOperation struct {
+	Operation string `json:"operation"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+}
+
+// Write implements io.Writer, to be used as the tracer of a MultiStore. It
+// records the keys of the traced operations, which are written as JSON lines.
+func (as *TxAccessSet) Write(p []byte) (int, error) {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	as.buf = append(as.buf, p...)
+	for {
+		i := bytes.IndexByte(as.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		line := as.buf[:i]
+		as.buf = as.buf[i+1:]
+
+		var op traceOperation
+		if err := json.Unmarshal(line, &op); err != nil {
+			return 0, err
+		}
+		key, err := base64.StdEncoding.DecodeString(op.Key)
+		if err != nil {
+			return 0, err
+		}
+
+		switch op.Operation {
+		case "read", "iterKey":
+			as.reads[string(key)] = struct{}{}
+		case "write", "delete":
+			as.writes[string(key)] = struct{}{}
+		}
+	}
+}
+
+// sortedKeys returns the keys of the set, sorted.
+func sortedKeys(set map[string]struct{}) [][]byte {
+	keys := make([][]byte, 0, len(set))
+	for key := range set {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	return keys
+}
+
+// Reads returns the keys read by the tx, sorted.
+func (as *TxAccessSet) Reads() [][]byte {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	return sortedKeys(as.reads)
+}
+
+// Writes returns the keys written or deleted by the tx, sorted.
+func (as *TxAccessSet) Writes() [][]byte {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	return sortedKeys(as.writes)
+}
+
+// HasRead reports whether the tx read the key.
+func (as *TxAccessSet) HasRead(key []byte) bool {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	_, ok := as.reads[string(key)]
+	return ok
+}
+
+// HasWritten reports whether the tx wrote or deleted the key.
+func (as *TxAccessSet) HasWritten(key []byte) bool {
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	_, ok := as.writes[string(key)]
+	return ok
+}
+
+type accessSetTxHandler struct {
+	next tx.Handler
+}
+
+// AccessSetMiddleware records the store keys read and written during
+// DeliverTx into the TxAccessSet of the context, see GetTxAccessSet. If the
+// caller set none with WithTxAccessSet, a new one is set for the inner
+// middlewares.
+//
+// The keys are recorded with the tracing facilities of the CacheMultiStore:
+// the tx is executed on a traced branch of the context's multistore, which is
+// always written back, so that the state changes are the same as without the
+// middleware. The traced stores sit below the branch's cache, so a key read
+// several times is only traced once, which is enough to build the set, and
+// the writes are traced when they are written through, so the set is only
+// complete once the middleware returns.
+func AccessSetMiddleware(txh tx.Handler) tx.Handler {
+	return accessSetTxHandler{
+		next: txh,
+	}
+}
+
+var _ tx.Handler = accessSetTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh accessSetTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh accessSetTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	accessSet := GetTxAccessSet(sdkCtx)
+	if accessSet == nil {
+		sdkCtx, accessSet = WithTxAccessSet(sdkCtx)
+	}
+
+	// The traced stores wrap the stores of msCache, so the operations of the
+	// branch on top of it are recorded.
+	msCache := sdkCtx.MultiStore().CacheMultiStore()
+	tracedCache := msCache.SetTracer(accessSet).CacheMultiStore()
+
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(sdkCtx.WithMultiStore(tracedCache)), sdkTx, req)
+	tracedCache.Write()
+	msCache.Write()
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh accessSetTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
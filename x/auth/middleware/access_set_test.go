@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestAccessSetMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	_, _, sender := testdata.KeyTestPubAddr()
+	_, _, recipient := testdata.KeyTestPubAddr()
+	s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, sender))
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, sender, sdk.NewCoins(sdk.NewInt64Coin("atom", 100))))
+
+	// the TestMsg sends 10atom from the sender to the recipient
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		return &sdk.Result{}, s.app.BankKeeper.SendCoins(ctx, sender, recipient, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.AccessSetMiddleware,
+	)
+
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(sender)))
+	testTx := txBuilder.GetTx()
+
+	txCtx, accessSet := middleware.WithTxAccessSet(ctx)
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(txCtx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Same(accessSet, middleware.GetTxAccessSet(txCtx))
+
+	senderBalanceKey := append(banktypes.CreateAccountBalancesPrefix(sender), []byte("atom")...)
+	recipientBalanceKey := append(banktypes.CreateAccountBalancesPrefix(recipient), []byte("atom")...)
+
+	// both balances are read and written, and the recipient account, which
+	// doesn't exist, is read and created
+	s.Require().True(accessSet.HasRead(senderBalanceKey))
+	s.Require().True(accessSet.HasWritten(senderBalanceKey))
+	s.Require().True(accessSet.HasRead(recipientBalanceKey))
+	s.Require().True(accessSet.HasWritten(recipientBalanceKey))
+	s.Require().True(accessSet.HasRead(authtypes.AddressStoreKey(recipient)))
+	s.Require().True(accessSet.HasWritten(authtypes.AddressStoreKey(recipient)))
+	s.Require().False(accessSet.HasWritten(authtypes.AddressStoreKey(sender)))
+
+	// the state changes are written as without the middleware
+	s.Require().Equal(int64(10), s.app.BankKeeper.GetBalance(ctx, recipient, "atom").Amount.Int64())
+	s.Require().Equal(int64(90), s.app.BankKeeper.GetBalance(ctx, sender, "atom").Amount.Int64())
+}
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"context"
+	"encoding/binary"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// AccountCreationHeightPrefix is the prefix of the store entries of the
+// KVAccountAgeStore holding the account creation heights.
+var AccountCreationHeightPrefix = []byte{0x01}
+
+// AccountAgeStore defines the storage of the account creation heights used by
+// the min account age middleware.
+type AccountAgeStore interface {
+	// CreationHeight returns the height at which the given account was first
+	// seen, if any.
+	CreationHeight(ctx sdk.Context, addr sdk.AccAddress) (int64, bool)
+	// SetCreationHeight records the height at which the given account was
+	// first seen.
+	SetCreationHeight(ctx sdk.Context, addr sdk.AccAddress, height int64)
+}
+
+// KVAccountAgeStore is an AccountAgeStore saving the account creation heights
+// in a KVStore.
+type KVAccountAgeStore struct {
+	key storetypes.StoreKey
+}
+
+var _ AccountAgeStore = KVAccountAgeStore{}
+
+// NewKVAccountAgeStore returns a KVAccountAgeStore saving the account creation
+// heights in the store of the given key.
+func NewKVAccountAgeStore(key storetypes.StoreKey) KVAccountAgeStore {
+	return KVAccountAgeStore{key: key}
+}
+
+// CreationHeight implements AccountAgeStore.CreationHeight.
+func (s KVAccountAgeStore) CreationHeight(ctx sdk.Context, addr sdk.AccAddress) (int64, bool) {
+	bz := prefix.NewStore(ctx.KVStore(s.key), AccountCreationHeightPrefix).Get(addr)
+	if bz == nil {
+		return 0, false
+	}
+
+	return int64(binary.BigEndian.Uint64(bz)), true
+}
+
+// SetCreationHeight implements AccountAgeStore.SetCreationHeight.
+func (s KVAccountAgeStore) SetCreationHeight(ctx sdk.Context, addr sdk.AccAddress, height int64) {
+	bz := make([]byte, 8)
+	binary.BigEndian.PutUint64(bz, uint64(height))
+	prefix.NewStore(ctx.KVStore(s.key), AccountCreationHeightPrefix).Set(addr, bz)
+}
+
+// MinAccountAgeConfig defines the transfers restricted by the min account age
+// middleware.
+type MinAccountAgeConfig struct {
+	// MinAge is the number of blocks an account must have existed for before
+	// it can send a high-value transfer.
+	MinAge int64
+	// Threshold defines the high-value transfers: a transfer is high-value if
+	// it sends more than the threshold amount of any of its denoms. Denoms
+	// absent from the threshold are not restricted.
+	Threshold sdk.Coins
+}
+
+type minAccountAgeTxHandler struct {
+	ak    AccountKeeper
+	store AccountAgeStore
+	cfg   MinAccountAgeConfig
+	next  tx.Handler
+}
+
+// NewMinAccountAgeMiddleware returns a middleware rejecting in CheckTx, with
+// ErrUnauthorized, the high-value bank transfers sent by accounts first seen
+// less than MinAge blocks ago, to mitigate drain attacks on freshly created
+// accounts. The transfers nested in other msgs, such as the ones of an authz
+// MsgExec, are checked too.
+//
+// The creation heights are recorded in DeliverTx, for the recipients of the
+// bank transfers of the successful txs which didn't exist yet, so that an
+// account created by a transfer ages from then on. The accounts without a
+// creation height record which exist in x/auth, e.g. the genesis accounts,
+// the ones created before the records were kept, or the ones created by other
+// modules, are considered old enough.
+func NewMinAccountAgeMiddleware(ak AccountKeeper, store AccountAgeStore, cfg MinAccountAgeConfig) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if cfg.MinAge <= 0 || cfg.Threshold.Empty() {
+			return txh
+		}
+
+		return minAccountAgeTxHandler{
+			ak:    ak,
+			store: store,
+			cfg:   cfg,
+			next:  txh,
+		}
+	}
+}
+
+var _ tx.Handler = minAccountAgeTxHandler{}
+
+// transfers calls onInput with the sender and amount of each input of the
+// bank transfers of the tx, including the ones nested in other msgs, and
+// onOutput with the recipient of each of their outputs.
+func transfers(sdkTx sdk.Tx, onInput func(sender sdk.AccAddress, amount sdk.Coins) error, onOutput func(recipient sdk.AccAddress)) error {
+	for _, msg := range sdkTx.GetMsgs() {
+		if err := msgTransfers(msg, onInput, onOutput); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// msgTransfers calls onInput and onOutput for the bank transfer of the given
+// msg, if any, and for the ones nested in it.
+func msgTransfers(msg sdk.Msg, onInput func(sender sdk.AccAddress, amount sdk.Coins) error, onOutput func(recipient sdk.AccAddress)) error {
+	switch msg := msg.(type) {
+	case *banktypes.MsgSend:
+		sender, err := sdk.AccAddressFromBech32(msg.FromAddress)
+		if err != nil {
+			return sdkerrors.Wrap(sdkerrors.ErrInvalidAddress, err.Error())
+		}
+		if err := onInput(sender, msg.Amount); err != nil {
+			return err
+		}
+		if recipient, err := sdk.AccAddressFromBech32(msg.ToAddress); err == nil {
+			onOutput(recipient)
+		}
+	case *banktypes.MsgMultiSend:
+		for _, in := range msg.Inputs {
+			sender, err := sdk.AccAddressFromBech32(in.Address)
+			if err != nil {
+				return sdkerrors.Wrap(sdkerrors.ErrInvalidAddress, err.Error())
+			}
+			if err := onInput(sender, in.Coins); err != nil {
+				return err
+			}
+		}
+		for _, out := range msg.Outputs {
+			if recipient, err := sdk.AccAddressFromBech32(out.Address); err == nil {
+				onOutput(recipient)
+			}
+		}
+	case nestedMsgsHolder:
+		nested, err := msg.GetMessages()
+		if err != nil {
+			return err
+		}
+		for _, nestedMsg := range nested {
+			if err := msgTransfers(nestedMsg, onInput, onOutput); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkAccountAge checks that the senders of the high-value transfers of the
+// tx are old enough.
+func (txh minAccountAgeTxHandler) checkAccountAge(sdkCtx sdk.Context, sdkTx sdk.Tx) error {
+	return transfers(sdkTx, func(sender sdk.AccAddress, amount sdk.Coins) error {
+		if !amount.IsAnyGT(txh.cfg.Threshold) {
+			return nil
+		}
+
+		created, found := txh.store.CreationHeight(sdkCtx, sender)
+		if !found && txh.ak.GetAccount(sdkCtx, sender) != nil {
+			return nil
+		}
+		if !found || sdkCtx.BlockHeight()-created < txh.cfg.MinAge {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized,
+				"account %s must be at least %d blocks old to send more than %s", sender, txh.cfg.MinAge, txh.cfg.Threshold)
+		}
+
+		return nil
+	}, func(sdk.AccAddress) {})
+}
+
+// newRecipients returns the recipients of the bank transfers of the tx which
+// don't exist yet.
+func (txh minAccountAgeTxHandler) newRecipients(sdkCtx sdk.Context, sdkTx sdk.Tx) []sdk.AccAddress {
+	var recipients []sdk.AccAddress
+	_ = transfers(sdkTx, func(sdk.AccAddress, sdk.Coins) error { return nil }, func(addr sdk.AccAddress) {
+		if txh.ak.GetAccount(sdkCtx, addr) == nil {
+			recipients = append(recipients, addr)
+		}
+	})
+
+	return recipients
+}
+
+// recordAccounts records the current height as the creation height of the
+// given accounts which have none yet.
+func (txh minAccountAgeTxHandler) recordAccounts(sdkCtx sdk.Context, addrs []sdk.AccAddress) {
+	for _, addr := range addrs {
+		if _, found := txh.store.CreationHeight(sdkCtx, addr); !found {
+			txh.store.SetCreationHeight(sdkCtx, addr, sdkCtx.BlockHeight())
+		}
+	}
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh minAccountAgeTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkAccountAge(sdk.UnwrapSDKContext(ctx), sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh minAccountAgeTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	recipients := txh.newRecipients(sdkCtx, sdkTx)
+
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	txh.recordAccounts(sdkCtx, recipients)
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh minAccountAgeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
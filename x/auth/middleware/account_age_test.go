@@ -0,0 +1,101 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// existingAccountKeeper is an AccountKeeper holding the given accounts.
+type existingAccountKeeper struct {
+	middleware.AccountKeeper
+	accounts map[string]bool
+}
+
+func (ak existingAccountKeeper) GetAccount(_ sdk.Context, addr sdk.AccAddress) authtypes.AccountI {
+	if !ak.accounts[addr.String()] {
+		return nil
+	}
+	return authtypes.NewBaseAccountWithAddress(addr)
+}
+
+func (s *MWTestSuite) TestMinAccountAgeMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("account_age")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_account_age"))
+
+	_, _, agedAddr := testdata.KeyTestPubAddr()
+	_, _, newAddr := testdata.KeyTestPubAddr()
+	_, _, genesisAddr := testdata.KeyTestPubAddr()
+	_, _, recipient := testdata.KeyTestPubAddr()
+
+	ak := existingAccountKeeper{accounts: map[string]bool{agedAddr.String(): true, genesisAddr.String(): true}}
+	store := middleware.NewKVAccountAgeStore(key)
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMinAccountAgeMiddleware(ak, store, middleware.MinAccountAgeConfig{
+		MinAge:    10,
+		Threshold: sdk.NewCoins(sdk.NewInt64Coin("stake", 1000)),
+	}))
+	store.SetCreationHeight(ctx, agedAddr, 1)
+	store.SetCreationHeight(ctx, newAddr, 95)
+
+	sendTx := func(from, to sdk.AccAddress, amount int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(banktypes.NewMsgSend(from, to, sdk.NewCoins(sdk.NewInt64Coin("stake", amount)))))
+		return txBuilder.GetTx()
+	}
+	execTx := func(from, to sdk.AccAddress, amount int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		msgExec := authz.NewMsgExec(recipient, []sdk.Msg{banktypes.NewMsgSend(from, to, sdk.NewCoins(sdk.NewInt64Coin("stake", amount)))})
+		s.Require().NoError(txBuilder.SetMsgs(&msgExec))
+		return txBuilder.GetTx()
+	}
+	checkTx := func(height int64, testTx sdk.Tx) error {
+		_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx.WithBlockHeight(height)), testTx, abci.RequestCheckTx{})
+		return err
+	}
+
+	// an aged account can send above the threshold, a new one can't, even
+	// through an authz MsgExec
+	s.Require().NoError(checkTx(100, sendTx(agedAddr, recipient, 5000)))
+	err := checkTx(100, sendTx(newAddr, recipient, 5000))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	err = checkTx(100, execTx(newAddr, recipient, 5000))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// an existing account without a record, e.g. a genesis account, is old
+	// enough
+	s.Require().NoError(checkTx(100, sendTx(genesisAddr, recipient, 5000)))
+	s.Require().NoError(checkTx(100, execTx(genesisAddr, recipient, 5000)))
+
+	// transfers up to the threshold are not restricted
+	s.Require().NoError(checkTx(100, sendTx(newAddr, recipient, 1000)))
+
+	// the account created in DeliverTx ages from that block on, unlike the
+	// existing accounts receiving a transfer
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithBlockHeight(100)), sendTx(agedAddr, recipient, 10), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithBlockHeight(100)), sendTx(agedAddr, genesisAddr, 10), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	created, found := store.CreationHeight(ctx, recipient)
+	s.Require().True(found)
+	s.Require().Equal(int64(100), created)
+	_, found = store.CreationHeight(ctx, genesisAddr)
+	s.Require().False(found)
+
+	err = checkTx(109, sendTx(recipient, agedAddr, 5000))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().NoError(checkTx(110, sendTx(recipient, agedAddr, 5000)))
+
+	// the aged account keeps its original creation height
+	created, _ = store.CreationHeight(ctx, agedAddr)
+	s.Require().Equal(int64(1), created)
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// AuditLogPrefix is the prefix of the store entries of the KVAuditStore.
+var AuditLogPrefix = []byte{0x01}
+
+// AuditEntry is the audit record of an executed tx.
+type AuditEntry struct {
+	Height      int64    `json:"height"`
+	TxHash      string   `json:"tx_hash"`
+	Signers     []string `json:"signers"`
+	MsgTypeURLs []string `json:"msg_type_urls"`
+	Data        []byte   `json:"data,omitempty"`
+	Log         string   `json:"log,omitempty"`
+}
+
+// AuditStore defines the storage of the audit log middleware.
+type AuditStore interface {
+	// Record saves the given entry. It's called on a branch of the tx state,
+	// which is only written if Record succeeds.
+	Record(ctx sdk.Context, entry AuditEntry) error
+	// EntriesAt returns the entries recorded at the given height.
+	EntriesAt(ctx sdk.Context, height int64) ([]AuditEntry, error)
+}
+
+// KVAuditStore is an AuditStore saving the entries as JSON in a KVStore,
+// keyed by height and tx hash.
+type KVAuditStore struct {
+	key storetypes.StoreKey
+}
+
+var _ AuditStore = KVAuditStore{}
+
+// NewKVAuditStore returns a KVAuditStore saving the entries in the store of
+// the given key.
+func NewKVAuditStore(key storetypes.StoreKey) KVAuditStore {
+	return KVAuditStore{key: key}
+}
+
+// auditHeightPrefix returns the prefix of the entries recorded at the given
+// height.
+func auditHeightPrefix(height int64) []byte {
+	return append(AuditLogPrefix, sdk.Uint64ToBigEndian(uint64(height))...)
+}
+
+// Record implements AuditStore.Record.
+func (s KVAuditStore) Record(ctx sdk.Context, entry AuditEntry) error {
+	bz, err := json.Marshal(entry)
+	if err != nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrJSONMarshal, "failed to marshal audit entry: %s", err)
+	}
+
+	store := prefix.NewStore(ctx.KVStore(s.key), auditHeightPrefix(entry.Height))
+	store.Set([]byte(entry.TxHash), bz)
+
+	return nil
+}
+
+// EntriesAt implements AuditStore.EntriesAt. The entries are ordered by tx
+// hash.
+func (s KVAuditStore) EntriesAt(ctx sdk.Context, height int64) ([]AuditEntry, error) {
+	store := prefix.NewStore(ctx.KVStore(s.key), auditHeightPrefix(height))
+	iter := store.Iterator(nil, nil)
+	defer iter.Close()
+
+	var entries []AuditEntry
+	for ; iter.Valid(); iter.Next() {
+		var entry AuditEntry
+		if err := json.Unmarshal(iter.Value(), &entry); err != nil {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrJSONUnmarshal, "invalid audit entry: %s", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+type auditLogTxHandler struct {
+	auditStore AuditStore
+	next       tx.Handler
+}
+
+// NewAuditLogMiddleware returns a middleware that records in the given
+// AuditStore an AuditEntry for each tx successfully executed in DeliverTx,
+// with its signers, msgs and result. The tx is executed on a branch of the
+// state, written along with the entry only if both the tx and the record
+// succeed, so that failed txs leave no entry.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewAuditLogMiddleware(auditStore AuditStore) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return auditLogTxHandler{
+			auditStore: auditStore,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = auditLogTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh auditLogTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh auditLogTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return abci.ResponseDeliverTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	branchCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.Tx)
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(branchCtx), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	entry := AuditEntry{
+		Height: branchCtx.BlockHeight(),
+		TxHash: fmt.Sprintf("%X", tmhash.Sum(req.Tx)),
+		Data:   res.Data,
+		Log:    res.Log,
+	}
+	for _, signer := range sigTx.GetSigners() {
+		entry.Signers = append(entry.Signers, signer.String())
+	}
+	for _, msg := range sigTx.GetMsgs() {
+		entry.MsgTypeURLs = append(entry.MsgTypeURLs, sdk.MsgTypeURL(msg))
+	}
+
+	if err := txh.auditStore.Record(branchCtx, entry); err != nil {
+		return abci.ResponseDeliverTx{}, sdkerrors.Wrap(err, "failed to record audit entry")
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh auditLogTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
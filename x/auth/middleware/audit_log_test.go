@@ -0,0 +1,77 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestAuditLogMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("audit")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_audit"))
+	auditStore := middleware.NewKVAuditStore(key)
+
+	// the msg handler writes to the store, and fails on demand
+	failing := false
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.KVStore(key).Set([]byte("executed"), []byte{1})
+		if failing {
+			return nil, sdkerrors.ErrInvalidRequest
+		}
+		return &sdk.Result{Data: []byte("ok")}, nil
+	}))
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry), legacyRouter),
+		middleware.NewAuditLogMiddleware(auditStore),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+	txBytes, err := s.clientCtx.TxConfig.TxEncoder()(testTx)
+	s.Require().NoError(err)
+
+	// a failed tx leaves no entry, and none of its state changes
+	failing = true
+	ctx = ctx.WithBlockHeight(1)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+	entries, err := auditStore.EntriesAt(ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Empty(entries)
+	s.Require().Nil(ctx.KVStore(key).Get([]byte("executed")))
+
+	// a successful tx is recorded at its height
+	failing = false
+	ctx = ctx.WithBlockHeight(2)
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+	s.Require().NotNil(ctx.KVStore(key).Get([]byte("executed")))
+
+	entries, err = auditStore.EntriesAt(ctx, 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]middleware.AuditEntry{{
+		Height:      2,
+		TxHash:      fmt.Sprintf("%X", tmhash.Sum(txBytes)),
+		Signers:     []string{addr1.String()},
+		MsgTypeURLs: []string{sdk.MsgTypeURL(&testdata.TestMsg{})},
+		Data:        res.Data,
+		Log:         res.Log,
+	}}, entries)
+
+	// other heights are not affected
+	entries, err = auditStore.EntriesAt(ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Empty(entries)
+}
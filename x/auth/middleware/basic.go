@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 
 	"github.com/cosmos/cosmos-sdk/codec/legacy"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
@@ -48,6 +49,45 @@ func validateBasicTxMsgs(msgs []sdk.Msg) error {
 	return nil
 }
 
+// validateBasicTx runs the ValidateBasic checks of the tx itself, which
+// compare its signatures to its msg signers. For the txs whose signers were
+// resolved by the ImplicitAuthz middleware, the ErrUnauthorized of that
+// comparison is ignored: the signature middlewares check the signatures
+// against the resolved signers instead.
+func validateBasicTx(ctx context.Context, tx sdk.Tx) error {
+	err := tx.ValidateBasic()
+	if _, ok := sdk.UnwrapSDKContext(ctx).Value(implicitAuthzSignersKey{}).([]sdk.AccAddress); ok && errors.Is(err, sdkerrors.ErrUnauthorized) {
+		return nil
+	}
+
+	return err
+}
+
+// validateBasicCheckTx runs the ValidateBasic checks of the tx in the CheckTx
+// order: the msgs first, then the tx.
+func validateBasicCheckTx(ctx context.Context, tx sdk.Tx) error {
+	if err := validateBasicTxMsgs(tx.GetMsgs()); err != nil {
+		return err
+	}
+
+	return validateBasicTx(ctx, tx)
+}
+
+// validateBasicDeliverTx runs the ValidateBasic checks of the tx in the
+// DeliverTx and SimulateTx order: the tx first, then the msgs.
+func validateBasicDeliverTx(ctx context.Context, tx sdk.Tx) error {
+	if err := validateBasicTx(ctx, tx); err != nil {
+		return err
+	}
+
+	return validateBasicTxMsgs(tx.GetMsgs())
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh validateBasicTxHandler) Named() string {
+	return "validate_basic"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (txh validateBasicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	// no need to validate basic on recheck tx, call next middleware
@@ -55,11 +95,13 @@ func (txh validateBasicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req ab
 		return txh.next.CheckTx(ctx, tx, req)
 	}
 
-	if err := validateBasicTxMsgs(tx.GetMsgs()); err != nil {
-		return abci.ResponseCheckTx{}, err
-	}
+	if err := validateBasicCheckTx(ctx, tx); err != nil {
+		// The signers of the tx may be resolved from the authz grants, see
+		// validateBasicTx, so a missing signature depends on the state.
+		if !errors.Is(err, sdkerrors.ErrUnauthorized) {
+			markStateIndependentFailure(ctx)
+		}
 
-	if err := tx.ValidateBasic(); err != nil {
 		return abci.ResponseCheckTx{}, err
 	}
 
@@ -68,11 +110,7 @@ func (txh validateBasicTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req ab
 
 // DeliverTx implements tx.Handler.DeliverTx.
 func (txh validateBasicTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
-	if err := tx.ValidateBasic(); err != nil {
-		return abci.ResponseDeliverTx{}, err
-	}
-
-	if err := validateBasicTxMsgs(tx.GetMsgs()); err != nil {
+	if err := validateBasicDeliverTx(ctx, tx); err != nil {
 		return abci.ResponseDeliverTx{}, err
 	}
 
@@ -81,11 +119,7 @@ func (txh validateBasicTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req
 
 // SimulateTx implements tx.Handler.SimulateTx.
 func (txh validateBasicTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
-	if err := sdkTx.ValidateBasic(); err != nil {
-		return tx.ResponseSimulateTx{}, err
-	}
-
-	if err := validateBasicTxMsgs(sdkTx.GetMsgs()); err != nil {
+	if err := validateBasicDeliverTx(ctx, sdkTx); err != nil {
 		return tx.ResponseSimulateTx{}, err
 	}
 
@@ -95,14 +129,29 @@ func (txh validateBasicTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx,
 var _ tx.Handler = txTimeoutHeightTxHandler{}
 
 type txTimeoutHeightTxHandler struct {
-	next tx.Handler
+	maxFuture int64
+	next      tx.Handler
 }
 
 // TxTimeoutHeightMiddleware defines a middleware that checks for a
 // tx height timeout.
 func TxTimeoutHeightMiddleware(txh tx.Handler) tx.Handler {
-	return txTimeoutHeightTxHandler{
-		next: txh,
+	return NewTimeoutHeightBoundsMiddleware(0)(txh)
+}
+
+// NewTimeoutHeightBoundsMiddleware returns a middleware that checks for a tx
+// height timeout, like TxTimeoutHeightMiddleware, and which additionally
+// rejects in CheckTx the txs whose timeout height is more than maxFuture
+// blocks ahead of the current block height, so that they can't sit in the
+// mempool for an arbitrarily long time. If maxFuture is not positive, the
+// timeout height is not bounded.
+// CONTRACT: Tx must implement TxWithTimeoutHeight interface
+func NewTimeoutHeightBoundsMiddleware(maxFuture int64) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return txTimeoutHeightTxHandler{
+			maxFuture: maxFuture,
+			next:      txh,
+		}
 	}
 }
 
@@ -123,12 +172,45 @@ func checkTimeout(ctx context.Context, tx sdk.Tx) error {
 	return nil
 }
 
+// checkTimeoutBound checks that the tx timeout height is at most maxFuture
+// blocks ahead of the current block height.
+func (txh txTimeoutHeightTxHandler) checkTimeoutBound(ctx context.Context, tx sdk.Tx) error {
+	if txh.maxFuture <= 0 {
+		return nil
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	timeoutTx, ok := tx.(sdk.TxWithTimeoutHeight)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "expected tx to implement TxWithTimeoutHeight")
+	}
+
+	maxTimeoutHeight := uint64(sdkCtx.BlockHeight()) + uint64(txh.maxFuture)
+	if timeoutHeight := timeoutTx.GetTimeoutHeight(); timeoutHeight > maxTimeoutHeight {
+		return sdkerrors.Wrapf(
+			sdkerrors.ErrTxTimeoutHeight, "timeout height %d is too far in the future; block height: %d, max timeout height: %d",
+			timeoutHeight, sdkCtx.BlockHeight(), maxTimeoutHeight,
+		)
+	}
+
+	return nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh txTimeoutHeightTxHandler) Named() string {
+	return "timeout_height"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (txh txTimeoutHeightTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := checkTimeout(ctx, tx); err != nil {
 		return abci.ResponseCheckTx{}, err
 	}
 
+	if err := txh.checkTimeoutBound(ctx, tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
 	return txh.next.CheckTx(ctx, tx, req)
 }
 
@@ -189,6 +271,11 @@ func (vmm validateMemoTxHandler) checkForValidMemo(ctx context.Context, tx sdk.T
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (vmm validateMemoTxHandler) Named() string {
+	return "validate_memo"
+}
+
 // CheckTx implements tx.Handler.CheckTx method.
 func (vmm validateMemoTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := vmm.checkForValidMemo(ctx, tx); err != nil {
@@ -224,7 +311,7 @@ type consumeTxSizeGasTxHandler struct {
 }
 
 // ConsumeTxSizeGasMiddleware will take in parameters and consume gas proportional
-// to the size of tx before calling next middleware. Note, the gas costs will be
+// to the size of tx, decompressed for a DecompressedTx, before calling next middleware. Note, the gas costs will be
 // slightly over estimated due to the fact that any given signing account may need
 // to be retrieved from state.
 //
@@ -257,7 +344,7 @@ func (cgts consumeTxSizeGasTxHandler) simulateSigGasCost(ctx context.Context, tx
 	}
 	n := len(sigs)
 
-	for i, signer := range sigTx.GetSigners() {
+	for i, signer := range txSigners(sdkCtx, sigTx) {
 		// if signature is already filled in, no need to simulate gas cost
 		if i < n && !isIncompleteSignature(sigs[i].Data) {
 			continue
@@ -296,10 +383,10 @@ func (cgts consumeTxSizeGasTxHandler) simulateSigGasCost(ctx context.Context, tx
 }
 
 //nolint:unparam
-func (cgts consumeTxSizeGasTxHandler) consumeTxSizeGas(ctx context.Context, _ sdk.Tx, txBytes []byte) error {
+func (cgts consumeTxSizeGasTxHandler) consumeTxSizeGas(ctx context.Context, sdkTx sdk.Tx, txBytes []byte) error {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
 	params := cgts.ak.GetParams(sdkCtx)
-	sdkCtx.GasMeter().ConsumeGas(params.TxSizeCostPerByte*sdk.Gas(len(txBytes)), "txSize")
+	sdkCtx.GasMeter().ConsumeGas(params.TxSizeCostPerByte*sdk.Gas(txSize(sdkTx, txBytes)), "txSize")
 
 	return nil
 }
@@ -1,15 +1,18 @@
 package middleware_test
 
 import (
+	"errors"
 	"strings"
 
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/crypto/types/multisig"
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
 	"github.com/tendermint/tendermint/abci/types"
 )
 
@@ -172,6 +175,42 @@ func (s *MWTestSuite) TestConsumeGasForTxSize() {
 	}
 }
 
+func (s *MWTestSuite) TestConsumeGasForCompressedTxSize() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.ConsumeTxSizeGasMiddleware(s.app.AccountKeeper))
+
+	// keys and addresses
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	txBuilder.SetMemo(strings.Repeat("01234567890", 10))
+
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	compressedBytes, err := authtx.CompressTxBytes(txBytes)
+	s.Require().NoError(err)
+	s.Require().Less(len(compressedBytes), len(txBytes))
+	compressedTx, err := authtx.CompressedTxDecoder(s.clientCtx.TxConfig.TxDecoder(), len(txBytes))(compressedBytes)
+	s.Require().NoError(err)
+
+	consumedGas := func(sdkTx sdk.Tx, txBytes []byte) sdk.Gas {
+		beforeGas := ctx.GasMeter().GasConsumed()
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sdkTx, types.RequestDeliverTx{Tx: txBytes})
+		s.Require().NoError(err)
+
+		return ctx.GasMeter().GasConsumed() - beforeGas
+	}
+
+	// the compressed tx pays the size gas of its decompressed encoding
+	s.Require().Equal(consumedGas(testTx, txBytes), consumedGas(compressedTx, compressedBytes))
+}
+
 func (s *MWTestSuite) TestTxHeightTimeoutMiddleware() {
 	ctx := s.SetupTest(true)
 
@@ -220,3 +259,53 @@ func (s *MWTestSuite) TestTxHeightTimeoutMiddleware() {
 		})
 	}
 }
+
+func (s *MWTestSuite) TestTimeoutHeightBoundsMiddleware() {
+	ctx := s.SetupTest(true)
+
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewTimeoutHeightBoundsMiddleware(5))
+
+	// keys and addresses
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name          string
+		timeout       uint64
+		height        int64
+		expCheckErr   bool
+		expDeliverErr bool
+	}{
+		{"no timeout", 0, 10, false, false},
+		{"in bounds", 12, 10, false, false},
+		{"at max future", 15, 10, false, false},
+		{"too far in the future", 16, 10, true, false},
+		{"already expired", 9, 10, true, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetTimeoutHeight(tc.timeout)
+			testTx := txBuilder.GetTx()
+
+			ctx := ctx.WithBlockHeight(tc.height)
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, types.RequestCheckTx{})
+			if tc.expCheckErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrTxTimeoutHeight))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// the bound is only enforced in CheckTx
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, types.RequestDeliverTx{})
+			if tc.expDeliverErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrTxTimeoutHeight))
+			} else {
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
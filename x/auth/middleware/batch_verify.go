@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"sort"
+
+	tmed25519 "github.com/tendermint/tendermint/crypto/ed25519"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+)
+
+type sigBatchEntry struct {
+	pubKey cryptotypes.PubKey
+	msg    []byte
+	sig    []byte
+}
+
+// SigBatchVerifier collects signatures to verify them together on Flush. The
+// signatures of the key types supporting it, i.e. ed25519, are verified as a
+// single batch, which is faster than verifying them one by one. The other
+// ones, e.g. secp256k1, are verified one by one.
+type SigBatchVerifier struct {
+	entries []sigBatchEntry
+}
+
+// NewSigBatchVerifier returns an empty SigBatchVerifier.
+func NewSigBatchVerifier() *SigBatchVerifier {
+	return &SigBatchVerifier{}
+}
+
+// Add collects the signature sig of msg by pubKey, and returns its index in
+// the results of the next Flush.
+func (v *SigBatchVerifier) Add(pubKey cryptotypes.PubKey, msg, sig []byte) int {
+	v.entries = append(v.entries, sigBatchEntry{pubKey: pubKey, msg: msg, sig: sig})
+	return len(v.entries) - 1
+}
+
+// Len returns the number of collected signatures.
+func (v *SigBatchVerifier) Len() int {
+	return len(v.entries)
+}
+
+// Flush verifies the collected signatures and returns the indexes of the
+// invalid ones, in increasing order. If the batch verification fails, the
+// batched signatures are verified one by one to find the offending ones. The
+// collected signatures are then discarded.
+func (v *SigBatchVerifier) Flush() []int {
+	entries := v.entries
+	v.entries = nil
+
+	var invalid, batched []int
+	batch := tmed25519.NewBatchVerifier()
+	for i, entry := range entries {
+		if pubKey, ok := entry.pubKey.(*ed25519.PubKey); ok {
+			if err := batch.Add(tmed25519.PubKey(pubKey.Key), entry.msg, entry.sig); err == nil {
+				batched = append(batched, i)
+				continue
+			}
+		}
+
+		if !entry.pubKey.VerifySignature(entry.msg, entry.sig) {
+			invalid = append(invalid, i)
+		}
+	}
+
+	if len(batched) > 0 {
+		if ok, _ := batch.Verify(); !ok {
+			for _, i := range batched {
+				if !entries[i].pubKey.VerifySignature(entries[i].msg, entries[i].sig) {
+					invalid = append(invalid, i)
+				}
+			}
+			sort.Ints(invalid)
+		}
+	}
+
+	return invalid
+}
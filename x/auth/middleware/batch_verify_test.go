@@ -0,0 +1,98 @@
+package middleware_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tmcrypto "github.com/tendermint/tendermint/crypto"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+type signedMsg struct {
+	pubKey cryptotypes.PubKey
+	msg    []byte
+	sig    []byte
+}
+
+func signedMsgs(t testing.TB, privs []cryptotypes.PrivKey) []signedMsg {
+	msgs := make([]signedMsg, len(privs))
+	for i, priv := range privs {
+		msg := tmcrypto.CRandBytes(200)
+		sig, err := priv.Sign(msg)
+		require.NoError(t, err)
+		msgs[i] = signedMsg{pubKey: priv.PubKey(), msg: msg, sig: sig}
+	}
+
+	return msgs
+}
+
+func TestSigBatchVerifier(t *testing.T) {
+	privs := make([]cryptotypes.PrivKey, 10)
+	for i := range privs {
+		if i < 8 {
+			privs[i] = ed25519.GenPrivKey()
+		} else {
+			privs[i] = secp256k1.GenPrivKey()
+		}
+	}
+	msgs := signedMsgs(t, privs)
+
+	v := middleware.NewSigBatchVerifier()
+	for i, m := range msgs {
+		require.Equal(t, i, v.Add(m.pubKey, m.msg, m.sig))
+	}
+	require.Equal(t, len(msgs), v.Len())
+	require.Empty(t, v.Flush())
+	require.Zero(t, v.Len())
+
+	// the invalid signatures are isolated, batched or not
+	for i, m := range msgs {
+		sig := m.sig
+		if i == 3 || i == 9 {
+			sig = append([]byte{}, m.sig...)
+			sig[0] ^= 0xff
+		}
+		v.Add(m.pubKey, m.msg, sig)
+	}
+	require.Equal(t, []int{3, 9}, v.Flush())
+
+	// so are the malformed ones
+	v.Add(msgs[0].pubKey, msgs[0].msg, msgs[0].sig[:10])
+	v.Add(msgs[1].pubKey, msgs[1].msg, msgs[1].sig)
+	require.Equal(t, []int{0}, v.Flush())
+}
+
+func BenchmarkSigBatchVerifier(b *testing.B) {
+	for _, n := range []int{8, 64} {
+		privs := make([]cryptotypes.PrivKey, n)
+		for i := range privs {
+			privs[i] = ed25519.GenPrivKey()
+		}
+		msgs := signedMsgs(b, privs)
+
+		b.Run(fmt.Sprintf("one by one/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, m := range msgs {
+					require.True(b, m.pubKey.VerifySignature(m.msg, m.sig))
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			v := middleware.NewSigBatchVerifier()
+			for i := 0; i < b.N; i++ {
+				for _, m := range msgs {
+					v.Add(m.pubKey, m.msg, m.sig)
+				}
+				require.Empty(b, v.Flush())
+			}
+		})
+	}
+}
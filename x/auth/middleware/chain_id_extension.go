@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+)
+
+// ChainIDTypeURL is the type URL of the extension option holding the chain-id
+// a tx is meant for.
+const ChainIDTypeURL = "/cosmos.tx.ext.v1.ChainID"
+
+type chainIDExtensionTxHandler struct {
+	next tx.Handler
+}
+
+// ChainIDExtensionMiddleware rejects in CheckTx and DeliverTx, with
+// ErrUnauthorized, the txs carrying a ChainID extension option different from
+// the chain-id of the node, see ChainIDTypeURL. It guards chains sharing the
+// same codebase and tooling against txs replayed from one chain to another,
+// on top of the chain-id already part of the sign bytes. Txs without a ChainID
+// extension option are not affected.
+//
+// The RejectExtensionOptions middleware must accept the ChainID extension
+// option.
+func ChainIDExtensionMiddleware(txh tx.Handler) tx.Handler {
+	return chainIDExtensionTxHandler{
+		next: txh,
+	}
+}
+
+var _ tx.Handler = chainIDExtensionTxHandler{}
+
+// checkChainIDExtension checks that all the ChainID extension options of the
+// tx match the chain-id of the node.
+func checkChainIDExtension(ctx context.Context, sdkTx sdk.Tx) error {
+	extTx, ok := sdkTx.(HasExtensionOptionsTx)
+	if !ok {
+		return nil
+	}
+
+	chainID := sdk.UnwrapSDKContext(ctx).ChainID()
+	for _, opt := range extTx.GetExtensionOptions() {
+		if opt.TypeUrl != ChainIDTypeURL {
+			continue
+		}
+
+		var txChainID ext.ChainID
+		if err := txChainID.Unmarshal(opt.Value); err != nil {
+			return sdkerrors.Wrapf(sdkerrors.ErrTxDecode, "invalid chain-id extension option: %s", err)
+		}
+		if txChainID.ChainId != chainID {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "tx is meant for chain-id %s, got %s", txChainID.ChainId, chainID)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh chainIDExtensionTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := checkChainIDExtension(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh chainIDExtensionTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := checkChainIDExtension(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh chainIDExtensionTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
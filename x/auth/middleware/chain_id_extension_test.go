@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+)
+
+func (s *MWTestSuite) TestChainIDExtensionMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithChainID("chain-a")
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.ChainIDExtensionMiddleware)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name    string
+		chainID string // no extension option if empty
+		expErr  bool
+	}{
+		{"no chain-id extension option", "", false},
+		{"matching chain-id", "chain-a", false},
+		{"mismatching chain-id", "chain-b", true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			if tc.chainID != "" {
+				value, err := (&ext.ChainID{ChainId: tc.chainID}).Marshal()
+				s.Require().NoError(err)
+				txBuilder.(authtx.ExtensionOptionsTxBuilder).SetExtensionOptions(&codectypes.Any{
+					TypeUrl: middleware.ChainIDTypeURL,
+					Value:   value,
+				})
+			}
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(checkErr, sdkerrors.ErrUnauthorized))
+				s.Require().True(errors.Is(deliverErr, sdkerrors.ErrUnauthorized))
+			} else {
+				s.Require().NoError(checkErr)
+				s.Require().NoError(deliverErr)
+			}
+		})
+	}
+}
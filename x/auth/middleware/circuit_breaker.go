@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+)
+
+// BannedMsgTypePrefix is the prefix of the store entries of the
+// KVCircuitStore holding the banned msg type URLs.
+var BannedMsgTypePrefix = []byte{0x01}
+
+// CircuitStore defines the storage of the msg types banned by the circuit
+// breaker middleware.
+type CircuitStore interface {
+	// IsBanned reports whether the msgs of the given type URL are banned.
+	IsBanned(ctx sdk.Context, msgTypeURL string) bool
+}
+
+// KVCircuitStore is a CircuitStore saving the banned msg type URLs in a
+// KVStore, which can only be updated by the given authority, e.g. the gov
+// module account, see NewCircuitMsgServerImpl.
+type KVCircuitStore struct {
+	key       storetypes.StoreKey
+	authority string
+}
+
+var _ CircuitStore = KVCircuitStore{}
+
+// NewKVCircuitStore returns a KVCircuitStore saving the banned msg types in
+// the store of the given key, updatable by the given authority.
+func NewKVCircuitStore(key storetypes.StoreKey, authority string) KVCircuitStore {
+	return KVCircuitStore{
+		key:       key,
+		authority: authority,
+	}
+}
+
+// IsBanned implements CircuitStore.IsBanned.
+func (s KVCircuitStore) IsBanned(ctx sdk.Context, msgTypeURL string) bool {
+	return prefix.NewStore(ctx.KVStore(s.key), BannedMsgTypePrefix).Has([]byte(msgTypeURL))
+}
+
+// SetBanned bans, or lifts the ban of, the msgs of the given type URL,
+// starting with the next tx. It returns ErrUnauthorized unless the given
+// authority is the store authority, so that it can back a gov-gated msg
+// server handler.
+func (s KVCircuitStore) SetBanned(ctx sdk.Context, authority, msgTypeURL string, banned bool) error {
+	if authority != s.authority {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "expected %s, got %s", s.authority, authority)
+	}
+	if msgTypeURL == "" {
+		return sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "empty msg type URL")
+	}
+
+	store := prefix.NewStore(ctx.KVStore(s.key), BannedMsgTypePrefix)
+	if banned {
+		store.Set([]byte(msgTypeURL), []byte{1})
+	} else {
+		store.Delete([]byte(msgTypeURL))
+	}
+
+	return nil
+}
+
+type circuitMsgServer struct {
+	store KVCircuitStore
+}
+
+var _ ext.MsgServer = circuitMsgServer{}
+
+// NewCircuitMsgServerImpl returns the ext.MsgServer updating the msg types
+// banned in the given KVCircuitStore with MsgSetCircuitBreaker, which must be
+// executed by the store authority. The msg type should also be gated to the
+// gov authority, see NewGovGatedMsgMiddleware. As the ban of a msg type
+// couldn't be lifted anymore, MsgSetCircuitBreaker itself can't be banned.
+func NewCircuitMsgServerImpl(store KVCircuitStore) ext.MsgServer {
+	return circuitMsgServer{store: store}
+}
+
+// SetCircuitBreaker implements ext.MsgServer.SetCircuitBreaker.
+func (s circuitMsgServer) SetCircuitBreaker(goCtx context.Context, msg *ext.MsgSetCircuitBreaker) (*ext.MsgSetCircuitBreakerResponse, error) {
+	ctx := sdk.UnwrapSDKContext(goCtx)
+	for _, typeURL := range msg.MsgTypeUrls {
+		if typeURL == sdk.MsgTypeURL(msg) {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "%s can't be banned", typeURL)
+		}
+		if err := s.store.SetBanned(ctx, msg.Authority, typeURL, msg.Banned); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ext.MsgSetCircuitBreakerResponse{}, nil
+}
+
+type circuitBreakerTxHandler struct {
+	store CircuitStore
+	next  tx.Handler
+}
+
+// NewCircuitBreakerMiddleware returns a middleware rejecting, with
+// ErrUnauthorized, the txs holding a msg whose type URL is banned in the given
+// CircuitStore, so that msg types can be disabled during an incident without a
+// chain upgrade. The msgs nested in other msgs, such as the ones of an authz
+// MsgExec, are checked too. The banned types are read from the store on each
+// tx, so that a ban applies right away. The check is enforced in all modes.
+func NewCircuitBreakerMiddleware(store CircuitStore) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return circuitBreakerTxHandler{
+			store: store,
+			next:  txh,
+		}
+	}
+}
+
+var _ tx.Handler = circuitBreakerTxHandler{}
+
+// nestedMsgsHolder is implemented by the msgs holding other msgs to execute,
+// such as authz MsgExec.
+type nestedMsgsHolder interface {
+	GetMessages() ([]sdk.Msg, error)
+}
+
+// checkCircuit checks that none of the msgs of the tx is banned.
+func (txh circuitBreakerTxHandler) checkCircuit(ctx context.Context, sdkTx sdk.Tx) error {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	for i, msg := range sdkTx.GetMsgs() {
+		if err := txh.checkMsg(sdkCtx, msg); err != nil {
+			return sdkerrors.Wrapf(err, "message index: %d", i)
+		}
+	}
+
+	return nil
+}
+
+// checkMsg checks that neither the given msg nor the msgs nested in it are
+// banned.
+func (txh circuitBreakerTxHandler) checkMsg(sdkCtx sdk.Context, msg sdk.Msg) error {
+	typeURL := sdk.MsgTypeURL(msg)
+	if txh.store.IsBanned(sdkCtx, typeURL) {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "%s is disabled by the circuit breaker", typeURL)
+	}
+
+	holder, ok := msg.(nestedMsgsHolder)
+	if !ok {
+		return nil
+	}
+
+	nested, err := holder.GetMessages()
+	if err != nil {
+		return err
+	}
+	for _, nestedMsg := range nested {
+		if err := txh.checkMsg(sdkCtx, nestedMsg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh circuitBreakerTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkCircuit(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh circuitBreakerTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkCircuit(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh circuitBreakerTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkCircuit(ctx, sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
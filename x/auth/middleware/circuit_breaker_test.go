@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+func (s *MWTestSuite) TestCircuitBreakerMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("circuit")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_circuit"))
+
+	_, _, authority := testdata.KeyTestPubAddr()
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	circuit := middleware.NewKVCircuitStore(key, authority.String())
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewCircuitBreakerMiddleware(circuit))
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+	execBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	msgExec := authz.NewMsgExec(addr1, []sdk.Msg{testdata.NewTestMsg(authority)})
+	s.Require().NoError(execBuilder.SetMsgs(&msgExec))
+	execTx := execBuilder.GetTx()
+	typeURL := sdk.MsgTypeURL(&testdata.TestMsg{})
+
+	requireCircuit := func(tripped bool) {
+		for _, testTx := range []sdk.Tx{testTx, execTx} {
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			_, simErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simErr} {
+				if tripped {
+					s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		}
+	}
+
+	requireCircuit(false)
+
+	// only the authority can update the banned msg types
+	err := circuit.SetBanned(ctx, addr1.String(), typeURL, true)
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	requireCircuit(false)
+
+	// banning a msg type applies to the next tx
+	s.Require().NoError(circuit.SetBanned(ctx, authority.String(), typeURL, true))
+	requireCircuit(true)
+
+	// lifting a ban applies right away too, and other msg types are not affected
+	s.Require().NoError(circuit.SetBanned(ctx, authority.String(), typeURL, false))
+	s.Require().NoError(circuit.SetBanned(ctx, authority.String(), "/cosmos.bank.v1beta1.MsgSend", true))
+	requireCircuit(false)
+
+	// the msg server updates the banned msg types on behalf of the authority
+	msgServer := middleware.NewCircuitMsgServerImpl(circuit)
+	_, err = msgServer.SetCircuitBreaker(sdk.WrapSDKContext(ctx), &ext.MsgSetCircuitBreaker{Authority: addr1.String(), MsgTypeUrls: []string{typeURL}, Banned: true})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	requireCircuit(false)
+
+	_, err = msgServer.SetCircuitBreaker(sdk.WrapSDKContext(ctx), &ext.MsgSetCircuitBreaker{Authority: authority.String(), MsgTypeUrls: []string{typeURL}, Banned: true})
+	s.Require().NoError(err)
+	requireCircuit(true)
+
+	_, err = msgServer.SetCircuitBreaker(sdk.WrapSDKContext(ctx), &ext.MsgSetCircuitBreaker{Authority: authority.String(), MsgTypeUrls: []string{typeURL}, Banned: false})
+	s.Require().NoError(err)
+	requireCircuit(false)
+
+	// the circuit breaker msg itself can't be banned
+	_, err = msgServer.SetCircuitBreaker(sdk.WrapSDKContext(ctx), &ext.MsgSetCircuitBreaker{
+		Authority:   authority.String(),
+		MsgTypeUrls: []string{sdk.MsgTypeURL(&ext.MsgSetCircuitBreaker{})},
+		Banned:      true,
+	})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+}
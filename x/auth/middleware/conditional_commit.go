@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// CommitPredicate decides, given the response and error of the inner
+// middlewares, whether their state writes should be committed.
+type CommitPredicate func(res tx.Response, err error) bool
+
+type conditionalCommitTxHandler struct {
+	predicate CommitPredicate
+	next      tx.Handler
+}
+
+// WithConditionalCommit returns a middleware that branches the multistore
+// before calling the inner middlewares, and only writes the branch back if
+// the predicate returns true. Otherwise all state writes of the inner
+// middlewares are discarded, but the response and error are returned as is.
+//
+// The GasMeter is not part of the multistore, so the gas consumed by the inner
+// middlewares stays metered even when their writes are discarded.
+func WithConditionalCommit(predicate CommitPredicate) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return conditionalCommitTxHandler{
+			predicate: predicate,
+			next:      txh,
+		}
+	}
+}
+
+var _ tx.Handler = conditionalCommitTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx method.
+func (txh conditionalCommitTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	cacheCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.Tx)
+
+	res, err := txh.next.CheckTx(sdk.WrapSDKContext(cacheCtx), sdkTx, req)
+	if txh.predicate(tx.Response{
+		GasWanted: uint64(res.GasWanted),
+		GasUsed:   uint64(res.GasUsed),
+		Data:      res.Data,
+		Log:       res.Log,
+		Events:    res.Events,
+	}, err) {
+		msCache.Write()
+	}
+
+	return res, err
+}
+
+// DeliverTx implements tx.Handler.DeliverTx method.
+func (txh conditionalCommitTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	cacheCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.Tx)
+
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(cacheCtx), sdkTx, req)
+	if txh.predicate(tx.Response{
+		GasWanted: uint64(res.GasWanted),
+		GasUsed:   uint64(res.GasUsed),
+		Data:      res.Data,
+		Log:       res.Log,
+		Events:    res.Events,
+	}, err) {
+		msCache.Write()
+	}
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh conditionalCommitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	cacheCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.TxBytes)
+
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(cacheCtx), sdkTx, req)
+	response := tx.Response{
+		GasWanted: res.GasInfo.GasWanted,
+		GasUsed:   res.GasInfo.GasUsed,
+	}
+	if res.Result != nil {
+		response.Data = res.Result.Data
+		response.Log = res.Result.Log
+		response.Events = res.Result.Events
+	}
+
+	if txh.predicate(response, err) {
+		msCache.Write()
+	}
+
+	return res, err
+}
@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/simapp"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// accountWritingTxHandler is a test middleware creating an account and
+// consuming gas.
+type accountWritingTxHandler struct {
+	app  *simapp.SimApp
+	addr sdk.AccAddress
+}
+
+var _ tx.Handler = accountWritingTxHandler{}
+
+func (txh accountWritingTxHandler) write(ctx context.Context) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	sdkCtx.GasMeter().ConsumeGas(1000, "test write")
+	txh.app.AccountKeeper.SetAccount(sdkCtx, txh.app.AccountKeeper.NewAccountWithAddress(sdkCtx, txh.addr))
+}
+
+func (txh accountWritingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	txh.write(ctx)
+	return abci.ResponseCheckTx{Log: "business rule violated"}, nil
+}
+func (txh accountWritingTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.write(ctx)
+	return abci.ResponseDeliverTx{Log: "business rule violated"}, nil
+}
+func (txh accountWritingTxHandler) SimulateTx(ctx context.Context, _ sdk.Tx, _ tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	txh.write(ctx)
+	return tx.ResponseSimulateTx{Result: &sdk.Result{Log: "business rule violated"}}, nil
+}
+
+func (s *MWTestSuite) TestConditionalCommitMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	for _, commit := range []bool{false, true} {
+		_, _, addr := testdata.KeyTestPubAddr()
+		var seenLog string
+		txHandler := middleware.ComposeMiddlewares(
+			accountWritingTxHandler{app: s.app, addr: addr},
+			middleware.WithConditionalCommit(func(res tx.Response, err error) bool {
+				seenLog = res.Log
+				return commit
+			}),
+		)
+
+		txCtx := ctx.WithGasMeter(sdk.NewGasMeter(10000))
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(txCtx), txTest{}, abci.RequestDeliverTx{})
+		s.Require().NoError(err)
+		s.Require().Equal("business rule violated", seenLog)
+
+		// state is only written if the predicate returns true, but gas is
+		// always metered
+		s.Require().Equal(commit, s.app.AccountKeeper.HasAccount(ctx, addr))
+		s.Require().GreaterOrEqual(txCtx.GasMeter().GasConsumed(), uint64(1000))
+	}
+}
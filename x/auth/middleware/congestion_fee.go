@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// CongestionLoadThreshold is the load above which the network is considered
+// congested by the congestion fee middleware.
+const CongestionLoadThreshold = 0.8
+
+type congestionFeeTxHandler struct {
+	loadFn func() float64
+	minFee sdk.Coins
+	next   tx.Handler
+}
+
+// NewCongestionFeeMiddleware returns a middleware that, while the load
+// reported by loadFn is above CongestionLoadThreshold, rejects in CheckTx the txs
+// whose fee is lower than minFeeWhenCongested, even if the validator minimum
+// gas prices are zero. Like MempoolFeeMiddleware, the fee must be at least the
+// minimum fee in any of its denoms. It is a local mempool protection, e.g. for
+// chains without fees, so it doesn't apply to DeliverTx and SimulateTx.
+//
+// loadFn must report the load as a ratio of the capacity, where 1 means full.
+// It is called on every CheckTx and must be cheap, e.g. reading the mempool
+// size ratio maintained by the node. A nil loadFn or an empty minimum
+// fee returns the given tx.Handler unchanged.
+// CONTRACT: Tx must implement FeeTx interface
+func NewCongestionFeeMiddleware(loadFn func() float64, minFeeWhenCongested sdk.Coins) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if loadFn == nil || minFeeWhenCongested.Empty() {
+			return txh
+		}
+
+		return congestionFeeTxHandler{
+			loadFn: loadFn,
+			minFee: minFeeWhenCongested,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = congestionFeeTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh congestionFeeTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if !IsGasMeteringDisabled(sdk.UnwrapSDKContext(ctx)) && txh.loadFn() > CongestionLoadThreshold {
+		feeTx, ok := sdkTx.(sdk.FeeTx)
+		if !ok {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+		}
+
+		if fee := feeTx.GetFee(); !fee.IsAnyGTE(txh.minFee) {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee,
+				"insufficient fees while the network is congested; got: %s required: %s", fee, txh.minFee)
+		}
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh congestionFeeTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh congestionFeeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
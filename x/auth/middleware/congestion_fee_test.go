@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestCongestionFeeMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	load := 0.0
+	minFee := sdk.NewCoins(sdk.NewInt64Coin("atom", 100))
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewCongestionFeeMiddleware(func() float64 { return load }, minFee),
+	)
+
+	newTx := func(fee sdk.Coins) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetFeeAmount(fee)
+		return txBuilder.GetTx()
+	}
+	zeroFeeTx := newTx(nil)
+	paidTx := newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 100)))
+
+	testCases := []struct {
+		name   string
+		load   float64
+		tx     sdk.Tx
+		expErr bool
+	}{
+		{"idle, zero fee", 0.1, zeroFeeTx, false},
+		{"at threshold, zero fee", middleware.CongestionLoadThreshold, zeroFeeTx, false},
+		{"congested, zero fee", 0.9, zeroFeeTx, true},
+		{"congested, low fee", 0.9, newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 99))), true},
+		{"congested, min fee", 0.9, paidTx, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			load = tc.load
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// DeliverTx and SimulateTx are not affected
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), tc.tx, tx.RequestSimulateTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// ContextEnricher transforms the sdk.Context a tx is processed with, e.g. to
+// inject chain-specific values for the msg handlers.
+type ContextEnricher func(ctx sdk.Context) sdk.Context
+
+type contextEnricherTxHandler struct {
+	enrich ContextEnricher
+	next   tx.Handler
+}
+
+// NewContextEnricherMiddleware returns a middleware that applies the given
+// enricher to the sdk.Context before passing it to the next tx.Handler, in
+// CheckTx, DeliverTx and SimulateTx. It is meant to be placed after the
+// ante-equivalent middlewares, right before the msgs are routed, so that the
+// values it sets are visible to all msg handlers.
+//
+// The enricher runs during DeliverTx, so it must be deterministic: it must
+// only depend on the given sdk.Context, e.g. on its header or its stores.
+// A nil enricher returns the given tx.Handler unchanged.
+func NewContextEnricherMiddleware(enrich ContextEnricher) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if enrich == nil {
+			return txh
+		}
+
+		return contextEnricherTxHandler{
+			enrich: enrich,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = contextEnricherTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh contextEnricherTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(txh.enrichContext(ctx), sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh contextEnricherTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(txh.enrichContext(ctx), sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh contextEnricherTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(txh.enrichContext(ctx), sdkTx, req)
+}
+
+func (txh contextEnricherTxHandler) enrichContext(ctx context.Context) context.Context {
+	return sdk.WrapSDKContext(txh.enrich(sdk.UnwrapSDKContext(ctx)))
+}
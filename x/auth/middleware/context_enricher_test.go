@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+type seedKey struct{}
+
+// seedCheckTxHandler records the seed seen in CheckTx, where the msgs are not
+// routed.
+type seedCheckTxHandler struct {
+	tx.Handler
+	seen *interface{}
+}
+
+func (txh seedCheckTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	*txh.seen = sdk.UnwrapSDKContext(ctx).Value(seedKey{})
+	return txh.Handler.CheckTx(ctx, sdkTx, req)
+}
+
+func (s *MWTestSuite) TestContextEnricherMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	// the TestMsg handler records the seed set by the enricher
+	var seen interface{}
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		seen = ctx.Value(seedKey{})
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	enrich := func(ctx sdk.Context) sdk.Context {
+		return ctx.WithValue(seedKey{}, ctx.BlockHeight())
+	}
+	txHandler := middleware.ComposeMiddlewares(
+		seedCheckTxHandler{Handler: middleware.NewRunMsgsTxHandler(msr, legacyRouter), seen: &seen},
+		middleware.NewContextEnricherMiddleware(enrich),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+
+	ctx = ctx.WithBlockHeight(7)
+
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(7), seen)
+
+	seen = nil
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(7), seen)
+
+	seen = nil
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(7), seen)
+
+	// the caller's context is left untouched
+	s.Require().Nil(ctx.Value(seedKey{}))
+}
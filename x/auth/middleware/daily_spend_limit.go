@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/address"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// DailySpendPrefix is the prefix of the store entries of the KVDailySpendStore
+// holding the amounts spent by each address.
+var DailySpendPrefix = []byte{0x01}
+
+// dailySpendWindow is the length, in seconds of block time, of the windows of
+// the daily spend limits. The windows are the UTC days.
+const dailySpendWindow = 24 * 60 * 60
+
+// DailySpendStore defines the storage of the amounts spent by each address,
+// used by the daily spend limit middleware.
+type DailySpendStore interface {
+	// DailySpent returns the amount spent by the given address during the
+	// given window, identified by its index since the Unix epoch.
+	DailySpent(ctx sdk.Context, addr sdk.AccAddress, window int64) (sdk.Coins, error)
+	// SetDailySpent records the amount spent by the given address during the
+	// given window, discarding the amounts of the previous windows.
+	SetDailySpent(ctx sdk.Context, addr sdk.AccAddress, window int64, spent sdk.Coins) error
+}
+
+// KVDailySpendStore is a DailySpendStore saving the spent amounts in a KVStore,
+// one proto encoded coin per address and denom, prefixed by its window. Only
+// the window of the last spend of each address is kept.
+type KVDailySpendStore struct {
+	key storetypes.StoreKey
+}
+
+var _ DailySpendStore = KVDailySpendStore{}
+
+// NewKVDailySpendStore returns a KVDailySpendStore saving the spent amounts in
+// the store of the given key.
+func NewKVDailySpendStore(key storetypes.StoreKey) KVDailySpendStore {
+	return KVDailySpendStore{key: key}
+}
+
+func (s KVDailySpendStore) addrStore(ctx sdk.Context, addr sdk.AccAddress) prefix.Store {
+	return prefix.NewStore(ctx.KVStore(s.key), append(DailySpendPrefix, address.MustLengthPrefix(addr)...))
+}
+
+// DailySpent implements DailySpendStore.DailySpent.
+func (s KVDailySpendStore) DailySpent(ctx sdk.Context, addr sdk.AccAddress, window int64) (sdk.Coins, error) {
+	iter := s.addrStore(ctx, addr).Iterator(nil, nil)
+	defer iter.Close()
+
+	spent := sdk.NewCoins()
+	for ; iter.Valid(); iter.Next() {
+		bz := iter.Value()
+		if len(bz) < 8 {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "invalid daily spend of %s", addr)
+		}
+		if int64(binary.BigEndian.Uint64(bz)) != window {
+			// the last spend is from a previous window, which rolled over
+			continue
+		}
+
+		var coin sdk.Coin
+		if err := coin.Unmarshal(bz[8:]); err != nil {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "invalid daily spend of %s: %s", addr, err)
+		}
+		spent = spent.Add(coin)
+	}
+
+	return spent, nil
+}
+
+// SetDailySpent implements DailySpendStore.SetDailySpent.
+func (s KVDailySpendStore) SetDailySpent(ctx sdk.Context, addr sdk.AccAddress, window int64, spent sdk.Coins) error {
+	store := s.addrStore(ctx, addr)
+
+	// discard the amounts of the previous windows
+	iter := store.Iterator(nil, nil)
+	var keys [][]byte
+	for ; iter.Valid(); iter.Next() {
+		keys = append(keys, iter.Key())
+	}
+	iter.Close()
+	for _, key := range keys {
+		store.Delete(key)
+	}
+
+	for _, coin := range spent {
+		coinBz, err := coin.Marshal()
+		if err != nil {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "invalid daily spend of %s: %s", addr, err)
+		}
+
+		bz := make([]byte, 8, 8+len(coinBz))
+		binary.BigEndian.PutUint64(bz, uint64(window))
+		store.Set([]byte(coin.Denom), append(bz, coinBz...))
+	}
+
+	return nil
+}
+
+type dailySpendLimitTxHandler struct {
+	bankKeeper BalanceKeeper
+	store      DailySpendStore
+	limits     map[string]sdk.Coins
+	// limited holds the addresses with a limit, sorted
+	limited []sdk.AccAddress
+	next    tx.Handler
+}
+
+// NewDailySpendLimitMiddleware returns a middleware capping the amount each
+// of the given addresses, keyed by their bech32 string, can spend per UTC day
+// of block time. The addresses without a limit, and the denoms absent from a
+// limit, are not restricted. The spent amounts are kept in the given store, so
+// that the limits are enforced deterministically by all the nodes, and they
+// reset when a new day starts. It panics if a key isn't a valid address.
+//
+// In DeliverTx, the amount spent by a tx is the decrease of the limited
+// balances of all the limited addresses during the execution of the next
+// handlers, which covers all the msgs moving funds, whoever signed them, e.g.
+// the authz MsgExec of a grantee. The txs that would exceed a limit fail with
+// ErrUnauthorized, and their state changes are discarded. The fees deducted by
+// the middlewares placed before this one are not counted. In CheckTx, where
+// the msgs are not executed, only the bank transfers, including the nested
+// ones, are checked against the remaining allowance.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewDailySpendLimitMiddleware(bankKeeper BalanceKeeper, store DailySpendStore, limits map[string]sdk.Coins) tx.Middleware {
+	limited := make([]sdk.AccAddress, 0, len(limits))
+	for bech32Addr := range limits {
+		addr, err := sdk.AccAddressFromBech32(bech32Addr)
+		if err != nil {
+			panic(fmt.Sprintf("invalid daily spend limit address %s: %s", bech32Addr, err))
+		}
+		limited = append(limited, addr)
+	}
+	sort.Slice(limited, func(i, j int) bool {
+		return bytes.Compare(limited[i], limited[j]) < 0
+	})
+
+	return func(txh tx.Handler) tx.Handler {
+		if len(limits) == 0 {
+			return txh
+		}
+
+		return dailySpendLimitTxHandler{
+			bankKeeper: bankKeeper,
+			store:      store,
+			limits:     limits,
+			limited:    limited,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = dailySpendLimitTxHandler{}
+
+// dailySpendWindowOf returns the index of the daily window holding the block
+// time of the context.
+func dailySpendWindowOf(sdkCtx sdk.Context) int64 {
+	return sdkCtx.BlockTime().Unix() / dailySpendWindow
+}
+
+// checkLimit checks that the amount spent by the address during the current
+// window doesn't exceed its limit.
+func (txh dailySpendLimitTxHandler) checkLimit(addr sdk.AccAddress, spent sdk.Coins) error {
+	limit := txh.limits[addr.String()]
+	for _, coin := range spent {
+		if containsDenom(limit, coin.Denom) && coin.Amount.GT(limit.AmountOf(coin.Denom)) {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized,
+				"%s would spend %s today, exceeding its daily limit of %s", addr, spent, limit)
+		}
+	}
+
+	return nil
+}
+
+// containsDenom reports whether the coins hold the given denom, even with a
+// zero amount.
+func containsDenom(coins sdk.Coins, denom string) bool {
+	for _, coin := range coins {
+		if coin.Denom == denom {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh dailySpendLimitTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if _, ok := sdkTx.(authsigning.SigVerifiableTx); !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	window := dailySpendWindowOf(sdkCtx)
+	spent := make(map[string]sdk.Coins)
+	err := transfers(sdkTx, func(sender sdk.AccAddress, amount sdk.Coins) error {
+		addr := sender.String()
+		if _, ok := txh.limits[addr]; !ok {
+			return nil
+		}
+		if _, ok := spent[addr]; !ok {
+			dailySpent, err := txh.store.DailySpent(sdkCtx, sender, window)
+			if err != nil {
+				return err
+			}
+			spent[addr] = dailySpent
+		}
+
+		spent[addr] = spent[addr].Add(amount...)
+		return txh.checkLimit(sender, spent[addr])
+	}, func(sdk.AccAddress) {})
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// limitedBalances returns the balances of the limited addresses, in the
+// denoms of their limits. They are read without consuming gas, so that the
+// gas used by a tx doesn't depend on the number of limited addresses.
+func (txh dailySpendLimitTxHandler) limitedBalances(sdkCtx sdk.Context) []sdk.Coins {
+	sdkCtx = sdkCtx.WithGasMeter(sdk.NewInfiniteGasMeter())
+	balances := make([]sdk.Coins, len(txh.limited))
+	for i, addr := range txh.limited {
+		balances[i] = sdk.NewCoins()
+		for _, limit := range txh.limits[addr.String()] {
+			balances[i] = balances[i].Add(txh.bankKeeper.GetBalance(sdkCtx, addr, limit.Denom))
+		}
+	}
+
+	return balances
+}
+
+// deliver runs the next handlers with the given run function on a branch of
+// the state, and writes it only if the limited addresses stay within their
+// limits, recording their new spent amounts.
+func (txh dailySpendLimitTxHandler) deliver(ctx context.Context, sdkTx sdk.Tx, txBytes []byte, run func(ctx context.Context) error) error {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if _, ok := sdkTx.(authsigning.SigVerifiableTx); !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	before := txh.limitedBalances(sdkCtx)
+
+	runCtx, msCache := cacheTxContext(sdkCtx, txBytes)
+	if err := run(sdk.WrapSDKContext(runCtx)); err != nil {
+		return err
+	}
+
+	after := txh.limitedBalances(runCtx)
+	window := dailySpendWindowOf(sdkCtx)
+	totals := make(map[int]sdk.Coins)
+	for i, addr := range txh.limited {
+		// only the decreases of the balances are spendings
+		spentNow := sdk.NewCoins()
+		for _, coin := range before[i] {
+			if decrease := coin.Amount.Sub(after[i].AmountOf(coin.Denom)); decrease.IsPositive() {
+				spentNow = spentNow.Add(sdk.NewCoin(coin.Denom, decrease))
+			}
+		}
+		if spentNow.IsZero() {
+			continue
+		}
+
+		spent, err := txh.store.DailySpent(sdkCtx, addr, window)
+		if err != nil {
+			return err
+		}
+		totals[i] = spent.Add(spentNow...)
+		if err := txh.checkLimit(addr, totals[i]); err != nil {
+			return err
+		}
+	}
+
+	// record the new spent amounts on the branch, to write them with the tx
+	for i, addr := range txh.limited {
+		if total, ok := totals[i]; ok {
+			if err := txh.store.SetDailySpent(runCtx, addr, window, total); err != nil {
+				return err
+			}
+		}
+	}
+	msCache.Write()
+
+	return nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh dailySpendLimitTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	var res abci.ResponseDeliverTx
+	err := txh.deliver(ctx, sdkTx, req.Tx, func(ctx context.Context) error {
+		var err error
+		res, err = txh.next.DeliverTx(ctx, sdkTx, req)
+		return err
+	})
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh dailySpendLimitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	var res tx.ResponseSimulateTx
+	err := txh.deliver(ctx, sdkTx, req.TxBytes, func(ctx context.Context) error {
+		var err error
+		res, err = txh.next.SimulateTx(ctx, sdkTx, req)
+		return err
+	})
+	if err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return res, nil
+}
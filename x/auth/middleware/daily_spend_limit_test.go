@@ -0,0 +1,207 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/address"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// kvBalanceKeeper is a BalanceKeeper keeping the balances in a KVStore, under
+// a prefix distinct from the KVDailySpendStore one.
+type kvBalanceKeeper struct {
+	key storetypes.StoreKey
+}
+
+func (k kvBalanceKeeper) balanceKey(addr sdk.AccAddress, denom string) []byte {
+	return append(append([]byte{0xff}, addr...), denom...)
+}
+
+func (k kvBalanceKeeper) GetBalance(ctx sdk.Context, addr sdk.AccAddress, denom string) sdk.Coin {
+	amount := sdk.ZeroInt()
+	if bz := ctx.KVStore(k.key).Get(k.balanceKey(addr, denom)); bz != nil {
+		if err := amount.Unmarshal(bz); err != nil {
+			panic(err)
+		}
+	}
+
+	return sdk.NewCoin(denom, amount)
+}
+
+func (k kvBalanceKeeper) setBalance(ctx sdk.Context, addr sdk.AccAddress, coin sdk.Coin) {
+	bz, err := coin.Amount.Marshal()
+	if err != nil {
+		panic(err)
+	}
+	ctx.KVStore(k.key).Set(k.balanceKey(addr, coin.Denom), bz)
+}
+
+// sendingTxHandler executes the bank MsgSends of the tx against the
+// kvBalanceKeeper balances.
+type sendingTxHandler struct {
+	bk kvBalanceKeeper
+}
+
+var _ txtypes.Handler = sendingTxHandler{}
+
+func (txh sendingTxHandler) send(ctx context.Context, sdkTx sdk.Tx) {
+	txh.sendMsgs(sdk.UnwrapSDKContext(ctx), sdkTx.GetMsgs())
+}
+
+func (txh sendingTxHandler) sendMsgs(sdkCtx sdk.Context, msgs []sdk.Msg) {
+	for _, msg := range msgs {
+		if exec, ok := msg.(*authz.MsgExec); ok {
+			nested, err := exec.GetMessages()
+			if err != nil {
+				panic(err)
+			}
+			txh.sendMsgs(sdkCtx, nested)
+			continue
+		}
+
+		send := msg.(*banktypes.MsgSend)
+		from, _ := sdk.AccAddressFromBech32(send.FromAddress)
+		to, _ := sdk.AccAddressFromBech32(send.ToAddress)
+		for _, coin := range send.Amount {
+			txh.bk.setBalance(sdkCtx, from, txh.bk.GetBalance(sdkCtx, from, coin.Denom).Sub(coin))
+			txh.bk.setBalance(sdkCtx, to, txh.bk.GetBalance(sdkCtx, to, coin.Denom).Add(coin))
+		}
+	}
+}
+
+func (txh sendingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return abci.ResponseCheckTx{}, nil
+}
+
+func (txh sendingTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.send(ctx, sdkTx)
+	return abci.ResponseDeliverTx{}, nil
+}
+
+func (txh sendingTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, _ txtypes.RequestSimulateTx) (txtypes.ResponseSimulateTx, error) {
+	txh.send(ctx, sdkTx)
+	return txtypes.ResponseSimulateTx{}, nil
+}
+
+func (s *MWTestSuite) TestDailySpendLimitMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("daily_spend")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_daily_spend"))
+
+	bk := kvBalanceKeeper{key: key}
+	store := middleware.NewKVDailySpendStore(key)
+	_, _, custody := testdata.KeyTestPubAddr()
+	_, _, other := testdata.KeyTestPubAddr()
+	_, _, recipient := testdata.KeyTestPubAddr()
+	bk.setBalance(ctx, custody, sdk.NewInt64Coin("stake", 10000))
+	bk.setBalance(ctx, other, sdk.NewInt64Coin("stake", 10000))
+
+	txHandler := middleware.ComposeMiddlewares(
+		sendingTxHandler{bk: bk},
+		middleware.NewDailySpendLimitMiddleware(bk, store, map[string]sdk.Coins{
+			custody.String(): sdk.NewCoins(sdk.NewInt64Coin("stake", 1000)),
+		}),
+	)
+
+	sendTx := func(from sdk.AccAddress, amount int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(banktypes.NewMsgSend(from, recipient, sdk.NewCoins(sdk.NewInt64Coin("stake", amount)))))
+		return txBuilder.GetTx()
+	}
+	day := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	deliverTx := func(blockTime time.Time, testTx sdk.Tx) error {
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithBlockTime(blockTime)), testTx, abci.RequestDeliverTx{})
+		return err
+	}
+
+	// the spendings add up until the cap
+	s.Require().NoError(deliverTx(day.Add(time.Hour), sendTx(custody, 600)))
+	s.Require().NoError(deliverTx(day.Add(2*time.Hour), sendTx(custody, 400)))
+
+	// exceeding the cap fails, without any state change
+	err := deliverTx(day.Add(3*time.Hour), sendTx(custody, 1))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().Equal(sdk.NewInt64Coin("stake", 9000), bk.GetBalance(ctx, custody, "stake"))
+	s.Require().Equal(sdk.NewInt64Coin("stake", 1000), bk.GetBalance(ctx, recipient, "stake"))
+
+	// CheckTx rejects the transfers exceeding the remaining allowance
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx.WithBlockTime(day.Add(4*time.Hour))), sendTx(custody, 1), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// signers without a limit are not restricted
+	s.Require().NoError(deliverTx(day.Add(5*time.Hour), sendTx(other, 5000)))
+
+	// the limit resets when the next day starts
+	nextDay := day.Add(24 * time.Hour)
+	err = deliverTx(nextDay.Add(-time.Second), sendTx(custody, 1))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().NoError(deliverTx(nextDay, sendTx(custody, 1000)))
+	err = deliverTx(nextDay.Add(time.Hour), sendTx(custody, 1))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// a single transfer above the cap never goes through
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx.WithBlockTime(nextDay.Add(24*time.Hour))), sendTx(custody, 1001), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// the spendings of a limited address are counted even when it doesn't
+	// sign the tx, e.g. when an authz grantee executes them
+	thirdDay := nextDay.Add(24 * time.Hour)
+	execTx := func(amount int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		msgExec := authz.NewMsgExec(other, []sdk.Msg{banktypes.NewMsgSend(custody, recipient, sdk.NewCoins(sdk.NewInt64Coin("stake", amount)))})
+		s.Require().NoError(txBuilder.SetMsgs(&msgExec))
+		return txBuilder.GetTx()
+	}
+	s.Require().NoError(deliverTx(thirdDay, execTx(700)))
+	err = deliverTx(thirdDay.Add(time.Hour), execTx(301))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	err = deliverTx(thirdDay.Add(time.Hour), sendTx(custody, 301))
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().Equal(sdk.NewInt64Coin("stake", 7300), bk.GetBalance(ctx, custody, "stake"))
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx.WithBlockTime(thirdDay.Add(time.Hour))), execTx(301), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+}
+
+func (s *MWTestSuite) TestKVDailySpendStore() {
+	key := sdk.NewKVStoreKey("daily_spend")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_daily_spend"))
+	store := middleware.NewKVDailySpendStore(key)
+	_, _, addr := testdata.KeyTestPubAddr()
+
+	spent, err := store.DailySpent(ctx, addr, 1)
+	s.Require().NoError(err)
+	s.Require().True(spent.IsZero())
+
+	coins := sdk.NewCoins(sdk.NewInt64Coin("atom", 5), sdk.NewInt64Coin("stake", 10))
+	s.Require().NoError(store.SetDailySpent(ctx, addr, 1, coins))
+	spent, err = store.DailySpent(ctx, addr, 1)
+	s.Require().NoError(err)
+	s.Require().Equal(coins, spent)
+
+	// the amounts of the previous windows are discarded
+	s.Require().NoError(store.SetDailySpent(ctx, addr, 2, sdk.NewCoins(sdk.NewInt64Coin("stake", 1))))
+	spent, err = store.DailySpent(ctx, addr, 1)
+	s.Require().NoError(err)
+	s.Require().True(spent.IsZero())
+	spent, err = store.DailySpent(ctx, addr, 2)
+	s.Require().NoError(err)
+	s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("stake", 1)), spent)
+
+	// corrupted entries are reported as errors
+	entryKey := append(append(middleware.DailySpendPrefix, address.MustLengthPrefix(addr)...), "stake"...)
+	ctx.KVStore(key).Set(entryKey, []byte{0, 0, 0, 0, 0, 0, 0, 2, 0xff})
+	_, err = store.DailySpent(ctx, addr, 2)
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidCoins))
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// decisionLogKey is the context key of the *decisionLogState of the tx being
+// handled.
+type decisionLogKey struct{}
+
+// decisionLogState remembers the error already attributed to a middleware,
+// so that the outer middlewares, which only propagate it, don't report it as
+// their own rejection.
+type decisionLogState struct {
+	rejection error
+}
+
+// decisionLogTxHandler wraps a layer of a middleware stack composed by
+// ComposeMiddlewaresWithDecisionLog, logging its decision.
+type decisionLogTxHandler struct {
+	name  string
+	named bool
+	inner tx.Handler
+}
+
+var _ tx.Handler = decisionLogTxHandler{}
+
+// ComposeMiddlewaresWithDecisionLog composes the middlewares like
+// ComposeMiddlewares, and also logs the decision of each layer of the stack
+// implementing NamedTxHandler, at debug level, with the sdk.Context logger.
+//
+// A layer passes a tx if it returns no error, or the error of a layer it
+// wraps. Otherwise, it rejects the tx, and the error is logged as the reason.
+// Layers which are not named are not logged, but their rejections are not
+// attributed to the named layers wrapping them.
+//
+// The stack should only be composed this way when debugging, as it logs
+// every tx handled.
+func ComposeMiddlewaresWithDecisionLog(txHandler tx.Handler, middlewares ...tx.Middleware) tx.Handler {
+	described := describeTxHandler(txHandler, nil)
+	txHandler = newDecisionLogTxHandler(txHandler)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next := middlewares[i](txHandler)
+		if sameTxHandler(next, txHandler) {
+			continue
+		}
+
+		described = describeTxHandler(next, described)
+		txHandler = newDecisionLogTxHandler(next)
+	}
+
+	return composedTxHandler{Handler: txHandler, middlewares: described}
+}
+
+func newDecisionLogTxHandler(txh tx.Handler) decisionLogTxHandler {
+	named, ok := txh.(NamedTxHandler)
+	if !ok {
+		return decisionLogTxHandler{inner: txh}
+	}
+
+	return decisionLogTxHandler{name: named.Named(), named: true, inner: txh}
+}
+
+// withDecisionLogState returns the decision log state of the tx being handled,
+// setting a new one on the context if this is the outermost layer.
+func withDecisionLogState(ctx context.Context) (context.Context, *decisionLogState) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if state, ok := sdkCtx.Value(decisionLogKey{}).(*decisionLogState); ok {
+		return ctx, state
+	}
+
+	state := &decisionLogState{}
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(decisionLogKey{}, state)), state
+}
+
+// logDecision logs whether the layer passed or rejected the tx, given the
+// error it returned.
+func (txh decisionLogTxHandler) logDecision(ctx context.Context, state *decisionLogState, mode string, err error) {
+	rejected := err != nil && (state.rejection == nil || !errors.Is(err, state.rejection))
+	if rejected {
+		state.rejection = err
+	}
+
+	if !txh.named {
+		return
+	}
+
+	logger := sdk.UnwrapSDKContext(ctx).Logger()
+	if rejected {
+		logger.Debug("middleware decision", "middleware", txh.name, "mode", mode, "decision", "reject", "reason", err.Error())
+		return
+	}
+
+	logger.Debug("middleware decision", "middleware", txh.name, "mode", mode, "decision", "pass")
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh decisionLogTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	ctx, state := withDecisionLogState(ctx)
+	res, err := txh.inner.CheckTx(ctx, sdkTx, req)
+	txh.logDecision(ctx, state, "check", err)
+
+	return res, err
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh decisionLogTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	ctx, state := withDecisionLogState(ctx)
+	res, err := txh.inner.DeliverTx(ctx, sdkTx, req)
+	txh.logDecision(ctx, state, "deliver", err)
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh decisionLogTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	ctx, state := withDecisionLogState(ctx)
+	res, err := txh.inner.SimulateTx(ctx, sdkTx, req)
+	txh.logDecision(ctx, state, "simulate", err)
+
+	return res, err
+}
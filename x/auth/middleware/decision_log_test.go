@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// decisionRecordingLogger is a test logger recording the middleware decisions
+// logged at the debug level, as "name: decision".
+type decisionRecordingLogger struct {
+	log.Logger
+	decisions *[]string
+}
+
+func (l decisionRecordingLogger) Debug(msg string, keyvals ...interface{}) {
+	fields := make(map[string]string)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[fmt.Sprint(keyvals[i])] = fmt.Sprint(keyvals[i+1])
+	}
+
+	*l.decisions = append(*l.decisions, fields["middleware"]+": "+fields["decision"])
+}
+
+func (l decisionRecordingLogger) With(_ ...interface{}) log.Logger {
+	return l
+}
+
+func (s *MWTestSuite) TestComposeMiddlewaresWithDecisionLog() {
+	ctx := s.SetupTest(true) // setup
+
+	var calls, decisions []string
+	ctx = ctx.WithLogger(decisionRecordingLogger{Logger: log.NewNopLogger(), decisions: &decisions})
+
+	txHandler := middleware.ComposeMiddlewaresWithDecisionLog(
+		noopTxHandler{},
+		recordingMiddleware("outer", &calls),
+		middleware.NewMaxMsgsMiddleware(1),
+		recordingMiddleware("inner", &calls),
+	)
+
+	_, _, addr := testdata.KeyTestPubAddr()
+	msg := testdata.NewTestMsg(addr)
+
+	// all named layers pass, from the inner to the outer one
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), msgsTx{msg}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"inner: pass", "max_msgs: pass", "outer: pass"}, decisions)
+
+	// the rejection is attributed to the max msgs middleware only
+	decisions = nil
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), msgsTx{msg, msg}, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+	s.Require().Equal([]string{"max_msgs: reject", "outer: pass"}, decisions)
+
+	// rejections of unnamed layers are not attributed to the named ones
+	decisions = nil
+	txHandler = middleware.ComposeMiddlewaresWithDecisionLog(
+		failingTxHandler{sdkerrors.ErrUnauthorized},
+		recordingMiddleware("outer", &calls),
+	)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), msgsTx{msg}, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().Equal([]string{"outer: pass"}, decisions)
+}
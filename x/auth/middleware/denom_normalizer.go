@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+type denomNormalizerTxHandler struct {
+	canonical map[string]string
+	next      tx.Handler
+}
+
+// NewDenomNormalizerMiddleware returns a middleware that rewrites, before the
+// msgs are routed, the coin denoms of the bank MsgSend and MsgMultiSend msgs
+// into their canonical spelling. The given mapping is keyed by the lower-cased
+// spellings of the denoms, e.g. "uatom" for "UAtom", and holds their canonical
+// denoms. Denoms missing from the mapping are left unchanged, and the amounts
+// are never changed: a msg with two spellings of the same denom in the same
+// coins is rejected with ErrInvalidCoins.
+//
+// The normalized msgs are executed in place of the tx msgs, which the other
+// middlewares still see unchanged. An empty mapping returns the given
+// tx.Handler unchanged.
+func NewDenomNormalizerMiddleware(canonical map[string]string) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if len(canonical) == 0 {
+			return txh
+		}
+
+		return denomNormalizerTxHandler{
+			canonical: canonical,
+			next:      txh,
+		}
+	}
+}
+
+var _ tx.Handler = denomNormalizerTxHandler{}
+
+// normalizeCoins returns the coins with their denoms normalized, and whether
+// any denom was changed.
+func (txh denomNormalizerTxHandler) normalizeCoins(coins sdk.Coins) (sdk.Coins, bool, error) {
+	changed := false
+	normalized := make(sdk.Coins, len(coins))
+	for i, coin := range coins {
+		normalized[i] = coin
+		if denom, ok := txh.canonical[strings.ToLower(coin.Denom)]; ok && denom != coin.Denom {
+			normalized[i].Denom = denom
+			changed = true
+		}
+	}
+
+	if !changed {
+		return coins, false, nil
+	}
+
+	normalized = normalized.Sort()
+	for i := 1; i < len(normalized); i++ {
+		if normalized[i].Denom == normalized[i-1].Denom {
+			return nil, false, sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "coins %s hold several spellings of %s", coins, normalized[i].Denom)
+		}
+	}
+
+	return normalized, true, nil
+}
+
+// normalizeMsg returns a copy of the msg with its denoms normalized, or the
+// msg itself if it has no denom to normalize.
+func (txh denomNormalizerTxHandler) normalizeMsg(msg sdk.Msg) (sdk.Msg, error) {
+	switch msg := msg.(type) {
+	case *banktypes.MsgSend:
+		amount, changed, err := txh.normalizeCoins(msg.Amount)
+		if err != nil || !changed {
+			return msg, err
+		}
+
+		normalized := *msg
+		normalized.Amount = amount
+		return &normalized, nil
+
+	case *banktypes.MsgMultiSend:
+		normalized := *msg
+		normalized.Inputs = make([]banktypes.Input, len(msg.Inputs))
+		normalized.Outputs = make([]banktypes.Output, len(msg.Outputs))
+		anyChanged := false
+		for i, input := range msg.Inputs {
+			coins, changed, err := txh.normalizeCoins(input.Coins)
+			if err != nil {
+				return nil, err
+			}
+			normalized.Inputs[i] = banktypes.Input{Address: input.Address, Coins: coins}
+			anyChanged = anyChanged || changed
+		}
+		for i, output := range msg.Outputs {
+			coins, changed, err := txh.normalizeCoins(output.Coins)
+			if err != nil {
+				return nil, err
+			}
+			normalized.Outputs[i] = banktypes.Output{Address: output.Address, Coins: coins}
+			anyChanged = anyChanged || changed
+		}
+
+		if !anyChanged {
+			return msg, nil
+		}
+		return &normalized, nil
+
+	default:
+		return msg, nil
+	}
+}
+
+// withNormalizedMsgs returns a context holding the msgs to route with their
+// denoms normalized.
+func (txh denomNormalizerTxHandler) withNormalizedMsgs(ctx context.Context, sdkTx sdk.Tx) (context.Context, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	msgs := routedMsgs(sdkCtx, sdkTx)
+
+	normalized := make([]sdk.Msg, len(msgs))
+	for i, msg := range msgs {
+		var err error
+		normalized[i], err = txh.normalizeMsg(msg)
+		if err != nil {
+			return nil, sdkerrors.Wrapf(err, "message index: %d", i)
+		}
+	}
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(routedMsgsKey{}, normalized)), nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh denomNormalizerTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	ctx, err := txh.withNormalizedMsgs(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh denomNormalizerTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	ctx, err := txh.withNormalizedMsgs(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh denomNormalizerTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	ctx, err := txh.withNormalizedMsgs(ctx, sdkTx)
+	if err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// recordingBankMsgServer records the bank sends it executes.
+type recordingBankMsgServer struct {
+	banktypes.MsgServer
+	sends *[]sdk.Coins
+}
+
+func (srv recordingBankMsgServer) Send(_ context.Context, msg *banktypes.MsgSend) (*banktypes.MsgSendResponse, error) {
+	*srv.sends = append(*srv.sends, msg.Amount)
+	return &banktypes.MsgSendResponse{}, nil
+}
+
+func (s *MWTestSuite) TestDenomNormalizerMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	var sends []sdk.Coins
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	banktypes.RegisterMsgServer(msr, recordingBankMsgServer{sends: &sends})
+
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, middleware.NewLegacyRouter()),
+		middleware.NewDenomNormalizerMiddleware(map[string]string{"uatom": "uatom", "ibc/abc": "ibc/ABC"}),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name      string
+		amount    sdk.Coins
+		expAmount sdk.Coins
+		expErr    error
+	}{
+		{
+			"non-canonical denoms are normalized",
+			sdk.NewCoins(sdk.NewInt64Coin("UAtom", 10), sdk.NewInt64Coin("ibc/abc", 3)),
+			sdk.NewCoins(sdk.NewInt64Coin("uatom", 10), sdk.NewInt64Coin("ibc/ABC", 3)),
+			nil,
+		},
+		{
+			"unknown denoms are left unchanged",
+			sdk.NewCoins(sdk.NewInt64Coin("UAtom", 10), sdk.NewInt64Coin("Foo", 5)),
+			sdk.NewCoins(sdk.NewInt64Coin("uatom", 10), sdk.NewInt64Coin("Foo", 5)),
+			nil,
+		},
+		{
+			"amounts of several spellings are not merged",
+			sdk.NewCoins(sdk.NewInt64Coin("UAtom", 10), sdk.NewInt64Coin("uatom", 5)),
+			nil,
+			sdkerrors.ErrInvalidCoins,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			sends = nil
+			msg := banktypes.NewMsgSend(addr1, addr2, tc.amount)
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(msg))
+			testTx := txBuilder.GetTx()
+
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			if tc.expErr != nil {
+				s.Require().True(errors.Is(err, tc.expErr))
+				s.Require().Empty(sends)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().Equal([]sdk.Coins{tc.expAmount}, sends)
+			// the tx msgs are left unchanged
+			s.Require().Equal(tc.amount, testTx.GetMsgs()[0].(*banktypes.MsgSend).Amount)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// NamedTxHandler can be implemented by middlewares to give themselves a name
+// in the middleware stack description. Middlewares which don't implement it
+// are described by their Go type name.
+type NamedTxHandler interface {
+	Named() string
+}
+
+// composedTxHandler is the tx.Handler returned by ComposeMiddlewares. It
+// remembers the middlewares it was composed of, so that the stack can be
+// introspected at runtime.
+type composedTxHandler struct {
+	tx.Handler
+	middlewares []tx.MiddlewareInfo
+}
+
+var (
+	_ tx.Handler             = composedTxHandler{}
+	_ tx.MiddlewareDescriber = composedTxHandler{}
+)
+
+// DescribeMiddlewares implements tx.MiddlewareDescriber.DescribeMiddlewares.
+func (txh composedTxHandler) DescribeMiddlewares() []tx.MiddlewareInfo {
+	return txh.middlewares
+}
+
+// sameTxHandler reports whether the two tx.Handlers are the same, i.e. whether
+// a middleware returned the tx.Handler it wraps. Most tx.Handlers hold fields
+// which can't be compared with ==, so they are compared by identity instead:
+// both interface values must hold the same dynamic type and data word.
+func sameTxHandler(a, b tx.Handler) bool {
+	type iface struct{ tab, data unsafe.Pointer }
+
+	return *(*iface)(unsafe.Pointer(&a)) == *(*iface)(unsafe.Pointer(&b))
+}
+
+// describeTxHandler describes the stack of the given tx.Handler, from outer to
+// inner. next is the description of the stack it wraps.
+func describeTxHandler(txh tx.Handler, next []tx.MiddlewareInfo) []tx.MiddlewareInfo {
+	// A composed stack already describes everything it wraps.
+	if describer, ok := txh.(tx.MiddlewareDescriber); ok {
+		return describer.DescribeMiddlewares()
+	}
+
+	name := reflect.TypeOf(txh).String()
+	if named, ok := txh.(NamedTxHandler); ok {
+		name = named.Named()
+	}
+
+	return append([]tx.MiddlewareInfo{{Name: name}}, next...)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// dryRunKey is the sdk.Context key marking a dry-run DeliverTx, see
+// DryRunDeliverTx. It is unexported so that the dry-run mode can't be enabled
+// by any other mean, in particular not by the baseapp's DeliverTx.
+type dryRunKey struct{}
+
+// isDryRun reports whether the tx is executed by DryRunDeliverTx.
+func isDryRun(sdkCtx sdk.Context) bool {
+	dryRun, _ := sdkCtx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// DryRunDeliverTx executes the tx through the DeliverTx method of the given
+// tx.Handler without committing any of its state changes, e.g. to replay a
+// historical tx against the current state. It returns the events and gas the
+// tx would have produced.
+//
+// The whole tx.Handler is run on a branch of the context's multistore which is
+// never written, so that neither the middlewares nor the msgs modify the
+// state, while the tx executes exactly like in a regular DeliverTx. The
+// middlewares keeping in-memory state across txs, such as the mempool and
+// block accounting ones, skip updating it for a dry-run tx, which isn't part
+// of the block. The dry-run mode can only be enabled through this function,
+// it can't be enabled during the consensus DeliverTx.
+func DryRunDeliverTx(ctx context.Context, txh tx.Handler, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	sdkCtx = sdkCtx.
+		WithMultiStore(sdkCtx.MultiStore().CacheMultiStore()).
+		WithValue(dryRunKey{}, true)
+
+	return txh.DeliverTx(sdk.WrapSDKContext(sdkCtx), sdkTx, req)
+}
@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"errors"
+	"strconv"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestDryRunDeliverTx() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	storeKey := s.app.GetKey(authtypes.StoreKey)
+	stateKey := []byte("dry-run")
+
+	// the TestMsg increments a counter in the store, consumes gas and emits an
+	// event holding the counter, so that each msg depends on the previous ones
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		count := byte(0)
+		if bz := ctx.KVStore(storeKey).Get(stateKey); len(bz) == 1 {
+			count = bz[0]
+		}
+		count++
+		ctx.KVStore(storeKey).Set(stateKey, []byte{count})
+		ctx.GasMeter().ConsumeGas(1000, "test msg")
+		events := sdk.Events{sdk.NewEvent("test", sdk.NewAttribute("count", strconv.Itoa(int(count))))}
+		return &sdk.Result{Events: events.ToABCIEvents()}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandlerWithOptions(msr, legacyRouter, middleware.RunMsgsOptions{NonAtomicMsgExecution: true}),
+		middleware.GasTxMiddleware,
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr1)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx := txBuilder.GetTx()
+
+	dryRes, err := middleware.DryRunDeliverTx(sdk.WrapSDKContext(ctx), txHandler, testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().False(ctx.KVStore(storeKey).Has(stateKey))
+	s.Require().GreaterOrEqual(dryRes.GasUsed, int64(2000))
+
+	// the second msg sees the state changes of the first one
+	var counts []string
+	for _, event := range dryRes.Events {
+		if event.Type == "test" {
+			counts = append(counts, string(event.Attributes[0].Value))
+		}
+	}
+	s.Require().Equal([]string{"1", "2"}, counts)
+
+	// a regular DeliverTx reports the same events and gas, and writes the state
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]byte{2}, ctx.KVStore(storeKey).Get(stateKey))
+	s.Require().Equal(res.Events, dryRes.Events)
+	s.Require().Equal(res.GasUsed, dryRes.GasUsed)
+}
+
+func (s *MWTestSuite) TestDryRunDeliverTxInMemoryState() {
+	ctx := s.SetupTest(true) // setup
+	_, pub1, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	// a dry-run tx doesn't free its mempool slot
+	limited := middleware.NewPerAccountMempoolLimitMiddleware(1)(noopTxHandler{})
+	limitedTx := s.sequencedTx(pub1, 0, 0)
+	_, err := limited.CheckTx(sdk.WrapSDKContext(ctx), limitedTx, abci.RequestCheckTx{Tx: []byte("tx1")})
+	s.Require().NoError(err)
+	_, err = middleware.DryRunDeliverTx(sdk.WrapSDKContext(ctx), limited, limitedTx, abci.RequestDeliverTx{Tx: []byte("tx1")})
+	s.Require().NoError(err)
+	_, err = limited.CheckTx(sdk.WrapSDKContext(ctx), limitedTx, abci.RequestCheckTx{Tx: []byte("tx2")})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// nor the pending timeout heights of the lower sequences
+	order := middleware.NewTimeoutHeightOrderMiddleware(middleware.TimeoutOrderReject)(noopTxHandler{})
+	_, err = order.CheckTx(sdk.WrapSDKContext(ctx), s.sequencedTx(pub1, 0, 20), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	_, err = middleware.DryRunDeliverTx(sdk.WrapSDKContext(ctx), order, s.sequencedTx(pub1, 1, 30), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	_, err = order.CheckTx(sdk.WrapSDKContext(ctx), s.sequencedTx(pub1, 2, 10), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTxTimeoutHeight))
+
+	// and it doesn't use the msg type gas budget of the block
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute(banktypes.RouterKey, func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(60000, "test bank msg")
+		return &sdk.Result{}, nil
+	}))
+	budgeted := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry), legacyRouter),
+		middleware.GasTxMiddleware,
+		middleware.NewMsgTypeGasBudgetMiddleware(sdk.MsgTypeURL(&banktypes.MsgSend{}), 100000),
+	)
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	sendTx := txBuilder.GetTx()
+
+	var logged []string
+	ctx = ctx.WithBlockHeight(1).WithLogger(errorRecordingLogger{Logger: log.NewNopLogger(), errors: &logged})
+	_, err = middleware.DryRunDeliverTx(sdk.WrapSDKContext(ctx), budgeted, sendTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	_, err = budgeted.DeliverTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Empty(logged)
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// GasPriceOracle defines the contract used by the dynamic min gas price
+// middleware to fetch the current minimum gas prices.
+type GasPriceOracle interface {
+	MinGasPrices(ctx sdk.Context) (sdk.DecCoins, error)
+}
+
+// cachedGasPrices caches the gas prices returned by the oracle for the block
+// height they were read at. It is shared by all copies of the
+// dynamicMinGasPriceTxHandler.
+type cachedGasPrices struct {
+	mtx       sync.Mutex
+	height    int64
+	gasPrices sdk.DecCoins
+	ok        bool
+}
+
+type dynamicMinGasPriceTxHandler struct {
+	oracle GasPriceOracle
+	cache  *cachedGasPrices
+	next   tx.Handler
+}
+
+// NewDynamicMinGasPriceMiddleware returns a middleware which, like the
+// MempoolFee middleware, checks that the tx's fee is at least as large as the
+// minimum gas prices times the tx's gas limit, but reads the minimum gas prices
+// from the given oracle instead of the node config. The oracle is queried at
+// most once per block.
+//
+// Note this only applies on CheckTx, as a local mempool protection.
+// CONTRACT: Tx must implement FeeTx to use this middleware
+func NewDynamicMinGasPriceMiddleware(oracle GasPriceOracle) tx.Middleware {
+	cache := &cachedGasPrices{}
+
+	return func(txh tx.Handler) tx.Handler {
+		return dynamicMinGasPriceTxHandler{
+			oracle: oracle,
+			cache:  cache,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = dynamicMinGasPriceTxHandler{}
+
+// minGasPrices returns the oracle's gas prices for the current block, reading
+// them from the cache if possible.
+func (txh dynamicMinGasPriceTxHandler) minGasPrices(sdkCtx sdk.Context) (sdk.DecCoins, error) {
+	txh.cache.mtx.Lock()
+	defer txh.cache.mtx.Unlock()
+
+	if txh.cache.ok && txh.cache.height == sdkCtx.BlockHeight() {
+		return txh.cache.gasPrices, nil
+	}
+
+	gasPrices, err := txh.oracle.MinGasPrices(sdkCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	txh.cache.height = sdkCtx.BlockHeight()
+	txh.cache.gasPrices = gasPrices
+	txh.cache.ok = true
+
+	return gasPrices, nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh dynamicMinGasPriceTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if IsGasMeteringDisabled(sdkCtx) {
+		return txh.next.CheckTx(ctx, tx, req)
+	}
+
+	feeTx, ok := tx.(sdk.FeeTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	minGasPrices, err := txh.minGasPrices(sdkCtx)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	if !minGasPrices.IsZero() {
+		requiredFees := computeRequiredFees(minGasPrices, feeTx.GetGas())
+
+		if !feeTx.GetFee().IsAnyGTE(requiredFees) {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee, "insufficient fees; got: %s required: %s", feeTx.GetFee(), requiredFees)
+		}
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh dynamicMinGasPriceTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh dynamicMinGasPriceTxHandler) SimulateTx(ctx context.Context, tx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, tx, req)
+}
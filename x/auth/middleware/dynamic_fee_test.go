@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// stubGasPriceOracle returns fixed gas prices and counts its reads.
+type stubGasPriceOracle struct {
+	gasPrices sdk.DecCoins
+	reads     int
+}
+
+var _ middleware.GasPriceOracle = &stubGasPriceOracle{}
+
+func (o *stubGasPriceOracle) MinGasPrices(sdk.Context) (sdk.DecCoins, error) {
+	o.reads++
+	return o.gasPrices, nil
+}
+
+func (s *MWTestSuite) TestDynamicMinGasPriceMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+
+	// the standard test fee is 150atom for 200000gas
+	oracle := &stubGasPriceOracle{gasPrices: sdk.NewDecCoins(sdk.NewDecCoinFromDec("atom", sdk.NewDecWithPrec(75, 5)))}
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewDynamicMinGasPriceMiddleware(oracle))
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx := txBuilder.GetTx()
+
+	// fee exactly at the threshold
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+
+	// the oracle price changes, but it is cached for the current block
+	oracle.gasPrices = sdk.NewDecCoins(sdk.NewDecCoinFromDec("atom", sdk.NewDecWithPrec(76, 5)))
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(1, oracle.reads)
+
+	// the new price is read on the next block, and the tx is underpriced
+	ctx = ctx.WithBlockHeight(2)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	s.Require().Equal(2, oracle.reads)
+
+	// DeliverTx is not affected
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Event type and attribute keys of the event emitted by the error middleware
+// when a tx is rejected in CheckTx.
+const (
+	EventTypeTxRejected = "tx_rejected"
+
+	AttributeKeyCodespace  = "codespace"
+	AttributeKeyCode       = "code"
+	AttributeKeyMsgTypeURL = "msg_type_url"
+)
+
+type errorTxHandler struct {
+	emitRejectEvents bool
+	negativeCache    *NegativeTxCache
+	next             tx.Handler
+}
+
+// ErrorTxOptions defines the behaviors of the error middleware.
+type ErrorTxOptions struct {
+	// EmitRejectEvents, if set, emits a `tx_rejected` event whenever the
+	// inner middlewares reject a tx in CheckTx.
+	EmitRejectEvents bool
+	// NegativeCache, if set, records the txs rejected by ValidateBasic in
+	// CheckTx, whose outcome doesn't depend on the state, so that they are
+	// fast-failed when they are checked again: the cached error is returned
+	// right away, and none of the inner middlewares run. The txs rejected by
+	// state-dependent checks, e.g. on an insufficient balance, are never
+	// cached.
+	//
+	// The cache is local to the node, so it is never consulted in DeliverTx:
+	// skipping the inner middlewares there would make the DeliverTx result,
+	// which is part of consensus, depend on which txs the node saw in
+	// CheckTx.
+	NegativeCache *NegativeTxCache
+}
+
+// NewErrorTxMiddleware returns a middleware that, if emitRejectEvents is set,
+// emits a `tx_rejected` event whenever the inner middlewares reject a tx in
+// CheckTx. The event holds the error codespace and code, and the type URL of
+// the tx's first msg. It is off by default to avoid bloating CheckTx responses.
+func NewErrorTxMiddleware(emitRejectEvents bool) tx.Middleware {
+	return NewErrorTxMiddlewareWithOptions(ErrorTxOptions{EmitRejectEvents: emitRejectEvents})
+}
+
+// NewErrorTxMiddlewareWithOptions is the same as NewErrorTxMiddleware, with
+// additional behaviors configured by the given ErrorTxOptions.
+func NewErrorTxMiddlewareWithOptions(opts ErrorTxOptions) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return errorTxHandler{
+			emitRejectEvents: opts.EmitRejectEvents,
+			negativeCache:    opts.NegativeCache,
+			next:             txh,
+		}
+	}
+}
+
+var _ tx.Handler = errorTxHandler{}
+
+// negativeCacheHash returns the hash under which the tx is cached, or nil if
+// the negative cache is disabled or the tx bytes are unknown.
+func (txh errorTxHandler) negativeCacheHash(txBytes []byte) []byte {
+	if txh.negativeCache == nil {
+		return nil
+	}
+
+	return negativeCacheTxHash(txBytes)
+}
+
+// CheckTx implements tx.Handler.CheckTx method.
+func (txh errorTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	res, err := txh.checkTx(ctx, tx, req)
+	if err == nil || !txh.emitRejectEvents {
+		return res, err
+	}
+
+	codespace, code, _ := sdkerrors.ABCIInfo(err, false)
+
+	var msgTypeURL string
+	if msgs := tx.GetMsgs(); len(msgs) > 0 {
+		msgTypeURL = sdk.MsgTypeURL(msgs[0])
+	}
+
+	event := sdk.NewEvent(EventTypeTxRejected,
+		sdk.NewAttribute(AttributeKeyCodespace, codespace),
+		sdk.NewAttribute(AttributeKeyCode, strconv.FormatUint(uint64(code), 10)),
+		sdk.NewAttribute(AttributeKeyMsgTypeURL, msgTypeURL),
+	)
+	res.Events = append(res.Events, abci.Event(event))
+
+	return res, err
+}
+
+// checkTx runs CheckTx through the negative cache, if any.
+func (txh errorTxHandler) checkTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	hash := txh.negativeCacheHash(req.Tx)
+	if hash == nil {
+		return txh.next.CheckTx(ctx, tx, req)
+	}
+
+	if err, ok := txh.negativeCache.get(hash); ok {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	ctx, failure := withStateIndependentFailure(ctx)
+	res, err := txh.next.CheckTx(ctx, tx, req)
+	if err != nil && failure.failed {
+		txh.negativeCache.add(hash, err)
+	}
+
+	return res, err
+}
+
+// DeliverTx implements tx.Handler.DeliverTx method.
+func (txh errorTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh errorTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
+
+// ErrorEncoder translates the error of a rejected tx into the codespace, code
+// and log of the ABCI response, e.g. to redact internal error messages. The
+// given codespace and code are the ones registered for err.
+type ErrorEncoder interface {
+	EncodeError(codespace string, code uint32, err error) (encodedCodespace string, encodedCode uint32, log string)
+}
+
+// DefaultErrorEncoder is the ErrorEncoder matching the BaseApp behavior: the
+// codespace and code are kept, and the log is the error message, with a stack
+// trace in debug mode.
+type DefaultErrorEncoder struct {
+	Debug bool
+}
+
+var _ ErrorEncoder = DefaultErrorEncoder{}
+
+// EncodeError implements ErrorEncoder.EncodeError.
+func (e DefaultErrorEncoder) EncodeError(codespace string, code uint32, err error) (string, uint32, string) {
+	_, _, log := sdkerrors.ABCIInfo(err, e.Debug)
+	return codespace, code, log
+}
+
+type errorEncoderTxHandler struct {
+	encoder ErrorEncoder
+	next    tx.Handler
+}
+
+// NewErrorEncoderMiddleware returns a middleware that converts the errors of
+// the inner middlewares into the ABCI responses of CheckTx and DeliverTx, using
+// the given encoder, instead of leaving the conversion to the BaseApp. A nil
+// encoder disables the middleware. SimulateTx errors are not converted.
+//
+// It must be the outermost middleware, as the errors are not returned to the
+// outer middlewares anymore.
+func NewErrorEncoderMiddleware(encoder ErrorEncoder) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return errorEncoderTxHandler{
+			encoder: encoder,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = errorEncoderTxHandler{}
+
+// encodeError returns the encoded codespace, code and log of err.
+func (txh errorEncoderTxHandler) encodeError(err error) (string, uint32, string) {
+	codespace, code, _ := sdkerrors.ABCIInfo(err, false)
+	return txh.encoder.EncodeError(codespace, code, err)
+}
+
+// CheckTx implements tx.Handler.CheckTx method.
+func (txh errorEncoderTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	res, err := txh.next.CheckTx(ctx, tx, req)
+	if err == nil || txh.encoder == nil {
+		return res, err
+	}
+
+	codespace, code, log := txh.encodeError(err)
+
+	// Keep the events returned alongside the error, like the BaseApp does.
+	return abci.ResponseCheckTx{
+		Codespace: codespace,
+		Code:      code,
+		Log:       log,
+		GasWanted: res.GasWanted,
+		GasUsed:   res.GasUsed,
+		Events:    res.Events,
+	}, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx method.
+func (txh errorEncoderTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	res, err := txh.next.DeliverTx(ctx, tx, req)
+	if err == nil || txh.encoder == nil {
+		return res, err
+	}
+
+	codespace, code, log := txh.encodeError(err)
+
+	return abci.ResponseDeliverTx{
+		Codespace: codespace,
+		Code:      code,
+		Log:       log,
+		GasWanted: res.GasWanted,
+		GasUsed:   res.GasUsed,
+	}, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh errorEncoderTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
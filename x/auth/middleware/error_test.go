@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestErrorTxMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	msg := testdata.NewTestMsg(addr1)
+	s.Require().NoError(txBuilder.SetMsgs(msg))
+	testTx := txBuilder.GetTx()
+
+	failing := failingTxHandler{sdkerrors.Wrap(sdkerrors.ErrInsufficientFee, "forced failure")}
+
+	// no event is emitted when disabled
+	txHandler := middleware.ComposeMiddlewares(failing, middleware.NewErrorTxMiddleware(false))
+	res, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().Error(err)
+	s.Require().Empty(res.Events)
+
+	// no event is emitted on success
+	txHandler = middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewErrorTxMiddleware(true))
+	res, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Empty(res.Events)
+
+	txHandler = middleware.ComposeMiddlewares(failing, middleware.NewErrorTxMiddleware(true))
+	res, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().Error(err)
+	s.Require().Len(res.Events, 1)
+	s.Require().Equal(middleware.EventTypeTxRejected, res.Events[0].Type)
+	s.Require().Equal(
+		[]abci.EventAttribute{
+			{Key: middleware.AttributeKeyCodespace, Value: sdkerrors.RootCodespace},
+			{Key: middleware.AttributeKeyCode, Value: "13"},
+			{Key: middleware.AttributeKeyMsgTypeURL, Value: sdk.MsgTypeURL(msg)},
+		},
+		res.Events[0].Attributes,
+	)
+
+	// DeliverTx is not affected
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().Error(err)
+}
+
+// redactingErrorEncoder is a test ErrorEncoder hiding the error messages and
+// remapping the codes of the root codespace.
+type redactingErrorEncoder struct{}
+
+func (redactingErrorEncoder) EncodeError(codespace string, code uint32, _ error) (string, uint32, string) {
+	if codespace == sdkerrors.RootCodespace {
+		code += 1000
+	}
+
+	return codespace, code, "redacted"
+}
+
+func (s *MWTestSuite) TestErrorEncoderMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+
+	failureErr := sdkerrors.Wrap(sdkerrors.ErrInsufficientFee, "forced failure")
+	failing := failingTxHandler{failureErr}
+
+	// errors are returned as is without an encoder
+	txHandler := middleware.ComposeMiddlewares(failing, middleware.NewErrorEncoderMiddleware(nil))
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+
+	// the default encoder matches the BaseApp responses
+	txHandler = middleware.ComposeMiddlewares(failing, middleware.NewErrorEncoderMiddleware(middleware.DefaultErrorEncoder{}))
+	checkRes, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdkerrors.ResponseCheckTx(failureErr, 0, 0, false), checkRes)
+	deliverRes, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdkerrors.ResponseDeliverTx(failureErr, 0, 0, false), deliverRes)
+
+	// a custom encoder changes the code and log
+	txHandler = middleware.ComposeMiddlewares(failing, middleware.NewErrorEncoderMiddleware(redactingErrorEncoder{}))
+	checkRes, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdkerrors.RootCodespace, checkRes.Codespace)
+	s.Require().Equal(sdkerrors.ErrInsufficientFee.ABCICode()+1000, checkRes.Code)
+	s.Require().Equal("redacted", checkRes.Log)
+	deliverRes, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdkerrors.ErrInsufficientFee.ABCICode()+1000, deliverRes.Code)
+	s.Require().Equal("redacted", deliverRes.Log)
+
+	// successful txs and simulations are not affected
+	txHandler = middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewErrorEncoderMiddleware(redactingErrorEncoder{}))
+	checkRes, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(abci.ResponseCheckTx{}, checkRes)
+	txHandler = middleware.ComposeMiddlewares(failing, middleware.NewErrorEncoderMiddleware(redactingErrorEncoder{}))
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"math"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type estimateFeeTxHandler struct {
+	next tx.Handler
+}
+
+// EstimateFeeMiddleware sets the EstimatedFee of simulated txs, computed from
+// the node's min gas prices and the simulated gas used, where
+// fee = ceil(gasPrice * gasUsed) for each min gas price denom. This is the fee
+// the MempoolFee middleware would require for a gas limit equal to the gas
+// used.
+//
+// It also sets the RecommendedGasLimit of simulated txs, i.e.
+// ceil(gasUsed * GasAdjustment) capped to the block gas limit, if any.
+//
+// The gas used is read from the response GasInfo, so this middleware must be
+// placed outside of the Gas middleware.
+func EstimateFeeMiddleware(txh tx.Handler) tx.Handler {
+	return estimateFeeTxHandler{
+		next: txh,
+	}
+}
+
+var _ tx.Handler = estimateFeeTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh estimateFeeTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh estimateFeeTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh estimateFeeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	res, err := txh.next.SimulateTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	res.EstimatedFee = sdk.NewCoins()
+	minGasPrices := sdk.UnwrapSDKContext(ctx).MinGasPrices()
+	if !minGasPrices.IsZero() {
+		res.EstimatedFee = sdk.NewCoins(computeRequiredFees(minGasPrices, res.GasInfo.GasUsed)...)
+	}
+
+	res.RecommendedGasLimit = recommendedGasLimit(sdk.UnwrapSDKContext(ctx), res.GasInfo.GasUsed, req.GasAdjustment)
+
+	return res, nil
+}
+
+// recommendedGasLimit returns ceil(gasUsed * adjustment), capped to the block
+// gas limit of the consensus params, if any.
+func recommendedGasLimit(sdkCtx sdk.Context, gasUsed uint64, adjustment float64) uint64 {
+	if adjustment <= 0 {
+		adjustment = 1
+	}
+
+	limit := uint64(math.MaxUint64)
+	if adjusted := math.Ceil(float64(gasUsed) * adjustment); adjusted < math.MaxUint64 {
+		limit = uint64(adjusted)
+	}
+
+	if cp := sdkCtx.ConsensusParams(); cp != nil && cp.Block != nil && cp.Block.MaxGas > 0 && limit > uint64(cp.Block.MaxGas) {
+		limit = uint64(cp.Block.MaxGas)
+	}
+
+	return limit
+}
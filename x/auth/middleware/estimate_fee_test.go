@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestEstimateFeeMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	txHandler := middleware.ComposeMiddlewares(
+		gasUsingTxHandler{gasUsed: 15000},
+		middleware.EstimateFeeMiddleware,
+		middleware.GasTxMiddleware,
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx := txBuilder.GetTx()
+
+	// no min gas prices
+	res, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(15000), res.GasInfo.GasUsed)
+	s.Require().True(res.EstimatedFee.Empty())
+
+	// fee = ceil(gas * price) for each denom
+	ctx = ctx.WithMinGasPrices(sdk.NewDecCoins(
+		sdk.NewDecCoinFromDec("atom", sdk.NewDecWithPrec(2, 2)),
+		sdk.NewDecCoinFromDec("stake", sdk.NewDecWithPrec(1, 4)),
+	))
+	res, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 300), sdk.NewInt64Coin("stake", 2)), res.EstimatedFee)
+
+	// CheckTx and DeliverTx are unaffected
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+}
+
+func (s *MWTestSuite) TestRecommendedGasLimit() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	txHandler := middleware.ComposeMiddlewares(
+		gasUsingTxHandler{gasUsed: 15000},
+		middleware.EstimateFeeMiddleware,
+		middleware.GasTxMiddleware,
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx := txBuilder.GetTx()
+
+	testCases := []struct {
+		name       string
+		adjustment float64
+		maxGas     int64
+		expLimit   uint64
+	}{
+		{"default adjustment", 0, 0, 15000},
+		{"adjustment is rounded up", 1.00001, 0, 15001},
+		{"adjustment", 1.5, 0, 22500},
+		{"unlimited block gas", 1.5, -1, 22500},
+		{"under block gas limit", 1.5, 30000, 22500},
+		{"clamped to block gas limit", 1.5, 20000, 20000},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			ctx := ctx.WithConsensusParams(&tmproto.ConsensusParams{Block: &tmproto.BlockParams{MaxGas: tc.maxGas}})
+			res, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{GasAdjustment: tc.adjustment})
+			s.Require().NoError(err)
+			s.Require().Equal(uint64(15000), res.GasInfo.GasUsed)
+			s.Require().Equal(tc.expLimit, res.RecommendedGasLimit)
+		})
+	}
+}
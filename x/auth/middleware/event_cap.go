@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type eventCapTxHandler struct {
+	maxEvents int
+	next      tx.Handler
+}
+
+// NewEventCapMiddleware returns a middleware that fails the txs emitting more
+// than maxEvents events, with ErrInvalidRequest, e.g. to protect the indexers
+// from a msg emitting a huge number of events. The events counted are the ones
+// returned by the msg router, which include the events emitted by the
+// middlewares before it. The cap is applied in DeliverTx and SimulateTx, and as
+// it affects consensus, it must be the same on all nodes. A non-positive
+// maxEvents returns the given tx.Handler unchanged.
+//
+// The inner tx.Handler is run on a branch of the multistore, which is
+// discarded if the cap is exceeded, so the middleware must be placed right
+// before the msg router: the fees of the txs exceeding the cap are still
+// charged, and the sequences of their signers still incremented.
+func NewEventCapMiddleware(maxEvents int) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if maxEvents <= 0 {
+			return txh
+		}
+
+		return eventCapTxHandler{
+			maxEvents: maxEvents,
+			next:      txh,
+		}
+	}
+}
+
+var _ tx.Handler = eventCapTxHandler{}
+
+// checkEventCount checks the number of events emitted by a tx against the cap.
+func (txh eventCapTxHandler) checkEventCount(events []abci.Event) error {
+	if len(events) > txh.maxEvents {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "tx emitted too many events; got: %d, max: %d", len(events), txh.maxEvents)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh eventCapTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh eventCapTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	branchCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.Tx)
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(branchCtx), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	if err := txh.checkEventCount(res.Events); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh eventCapTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	branchCtx, msCache := cacheTxContext(sdk.UnwrapSDKContext(ctx), req.TxBytes)
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(branchCtx), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	if res.Result != nil {
+		if err := txh.checkEventCount(res.Result.Events); err != nil {
+			return tx.ResponseSimulateTx{}, err
+		}
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
@@ -0,0 +1,122 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestEventCapMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	storeKey := s.app.GetKey(authtypes.StoreKey)
+
+	// each TestMsg writes its signer to the store and emits one event per
+	// TestMsg signer, on top of the `message` event
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		var events sdk.Events
+		for i, signer := range msg.GetSigners() {
+			ctx.KVStore(storeKey).Set(signer, []byte{1})
+			events = append(events, sdk.NewEvent("test", sdk.NewAttribute("index", fmt.Sprint(i))))
+		}
+		return &sdk.Result{Events: events.ToABCIEvents()}, nil
+	}))
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry), legacyRouter),
+		middleware.NewEventCapMiddleware(3),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, addr3 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name    string
+		signers []sdk.AccAddress
+		expErr  bool
+	}{
+		{"under cap", []sdk.AccAddress{addr1}, false},
+		{"at cap", []sdk.AccAddress{addr1, addr2}, false},
+		{"over cap", []sdk.AccAddress{addr1, addr2, addr3}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(tc.signers...)))
+			testTx := txBuilder.GetTx()
+
+			simCtx, _ := ctx.CacheContext()
+			_, err := txHandler.SimulateTx(sdk.WrapSDKContext(simCtx), testTx, tx.RequestSimulateTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			ctx, _ := ctx.CacheContext()
+			res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				// the state changes are rolled back
+				s.Require().False(ctx.KVStore(storeKey).Has(addr1))
+			} else {
+				s.Require().NoError(err)
+				s.Require().Len(res.Events, len(tc.signers)+1)
+				s.Require().True(ctx.KVStore(storeKey).Has(addr1))
+			}
+		})
+	}
+}
+
+func (s *MWTestSuite) TestEventCapDefaultTxHandler() {
+	ctx := s.SetupTest(false) // setup
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	banktypes.RegisterMsgServer(msr, bankkeeper.NewMsgServerImpl(s.app.BankKeeper))
+	txHandler, err := middleware.NewDefaultTxHandler(middleware.TxHandlerOptions{
+		MsgServiceRouter: msr,
+		LegacyRouter:     middleware.NewLegacyRouter(),
+		AccountKeeper:    s.app.AccountKeeper,
+		BankKeeper:       s.app.BankKeeper,
+		FeegrantKeeper:   s.app.FeeGrantKeeper,
+		SignModeHandler:  s.clientCtx.TxConfig.SignModeHandler(),
+		SigGasConsumer:   middleware.DefaultSigVerificationGasConsumer,
+		MaxTxEvents:      1,
+	})
+	s.Require().NoError(err)
+
+	accounts := s.createTestAccounts(ctx, 1, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	sender := accounts[0]
+	_, _, recipient := testdata.KeyTestPubAddr()
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(banktypes.NewMsgSend(sender.acc.GetAddress(), recipient, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx, txBytes, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{sender.priv}, []uint64{sender.accNum}, []uint64{0}, ctx.ChainID())
+	s.Require().NoError(err)
+	feeCollector := s.app.AccountKeeper.GetModuleAddress(authtypes.FeeCollectorName)
+	collected := s.app.BankKeeper.GetAllBalances(ctx, feeCollector)
+
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+
+	// the send is discarded, but the fees are charged and the sequence
+	// incremented
+	s.Require().True(s.app.BankKeeper.GetAllBalances(ctx, recipient).IsZero())
+	s.Require().Equal(collected.Add(testdata.NewTestFeeAmount()...), s.app.BankKeeper.GetAllBalances(ctx, feeCollector))
+	s.Require().Equal(uint64(1), s.app.AccountKeeper.GetAccount(ctx, sender.acc.GetAddress()).GetSequence())
+}
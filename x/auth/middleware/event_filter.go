@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type eventFilterTxHandler struct {
+	drop map[string]struct{}
+	next tx.Handler
+}
+
+// NewEventFilterMiddleware returns a middleware that strips the events whose
+// type is in the drop set from the DeliverTx response, e.g. to keep verbose
+// debug events of a module off-chain. Events are not part of the app hash, so
+// filtering them doesn't affect consensus, but the dropped events are not
+// seen by indexers nor by clients querying the tx results.
+//
+// If drop is empty, the middleware is a no-op.
+func NewEventFilterMiddleware(drop map[string]struct{}) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if len(drop) == 0 {
+			return txh
+		}
+
+		return eventFilterTxHandler{
+			drop: drop,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = eventFilterTxHandler{}
+
+// filterEvents returns the events whose type is not in the drop set.
+func (txh eventFilterTxHandler) filterEvents(events []abci.Event) []abci.Event {
+	if len(events) == 0 {
+		return events
+	}
+
+	filtered := make([]abci.Event, 0, len(events))
+	for _, event := range events {
+		if _, ok := txh.drop[event.Type]; !ok {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh eventFilterTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh eventFilterTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	res.Events = txh.filterEvents(res.Events)
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh eventFilterTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,34 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestEventFilterMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.NewEventFilterMiddleware(map[string]struct{}{"transfer": {}})(eventsTxHandler{})
+
+	// the dropped event types are stripped from DeliverTx
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Len(res.Events, 1)
+	s.Require().Equal("message", res.Events[0].Type)
+
+	// CheckTx and SimulateTx are left untouched
+	checkRes, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Len(checkRes.Events, 2)
+	simRes, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), txTest{}, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Len(simRes.Result.Events, 2)
+
+	// an empty drop set keeps all events
+	txHandler = middleware.NewEventFilterMiddleware(nil)(eventsTxHandler{})
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Len(res.Events, 2)
+}
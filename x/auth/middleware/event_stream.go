@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// DefaultEventStreamBufferSize is the default number of txs the event stream
+// middleware buffers before applying its EventStreamPolicy.
+const DefaultEventStreamBufferSize = 1000
+
+// EventSink receives the events of the txs successfully delivered, see
+// NewEventStreamMiddleware.
+type EventSink interface {
+	// Emit is called with the events of each delivered tx, in the order the
+	// txs are delivered. It is called from a single goroutine.
+	Emit(height int64, txHash []byte, events []abci.Event)
+}
+
+// EventStreamPolicy defines what the event stream middleware does when its
+// buffer is full, i.e. when the EventSink is slower than the txs delivery.
+type EventStreamPolicy int
+
+const (
+	// EventStreamDrop drops the events of the txs delivered while the buffer
+	// is full, so that a slow sink never delays the block execution.
+	EventStreamDrop EventStreamPolicy = iota
+	// EventStreamBlock waits for the buffer to have room, so that no events
+	// are lost. A slow sink then slows down the block execution.
+	EventStreamBlock
+)
+
+// EventStreamOptions defines the optional behaviors of the event stream
+// middleware.
+type EventStreamOptions struct {
+	// BufferSize is the number of txs buffered for the sink. If zero,
+	// DefaultEventStreamBufferSize is used.
+	BufferSize int
+	// Policy defines what to do when the buffer is full. Defaults to
+	// EventStreamDrop.
+	Policy EventStreamPolicy
+}
+
+// streamedTx is a delivered tx waiting to be emitted to the sink.
+type streamedTx struct {
+	height int64
+	txHash []byte
+	events []abci.Event
+}
+
+type eventStreamTxHandler struct {
+	buffer chan<- streamedTx
+	done   <-chan struct{}
+	policy EventStreamPolicy
+	next   tx.Handler
+}
+
+// NewEventStreamMiddleware returns a middleware that pushes the events of each
+// successful DeliverTx to the given sink, with the default options, and the
+// function stopping it, see NewEventStreamMiddlewareWithOptions.
+func NewEventStreamMiddleware(sink EventSink) (tx.Middleware, func()) {
+	return NewEventStreamMiddlewareWithOptions(sink, EventStreamOptions{})
+}
+
+// NewEventStreamMiddlewareWithOptions returns a middleware that pushes the
+// events of each successful DeliverTx to the given sink. The events are
+// buffered and emitted by a background goroutine, so that the sink doesn't run
+// during the block execution. Dry runs are not streamed.
+//
+// As the events are pushed once the inner middlewares succeed, it must be the
+// outermost middleware, otherwise the events of the txs failed by the outer
+// middlewares are streamed.
+//
+// The returned function stops the background goroutine, once it has emitted
+// the buffered events, and must be called when the app is closed. The events
+// of the txs delivered afterwards are dropped.
+func NewEventStreamMiddlewareWithOptions(sink EventSink, opts EventStreamOptions) (tx.Middleware, func()) {
+	size := opts.BufferSize
+	if size <= 0 {
+		size = DefaultEventStreamBufferSize
+	}
+
+	buffer := make(chan streamedTx, size)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case stx := <-buffer:
+				sink.Emit(stx.height, stx.txHash, stx.events)
+			case <-done:
+				for {
+					select {
+					case stx := <-buffer:
+						sink.Emit(stx.height, stx.txHash, stx.events)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return eventStreamTxHandler{
+			buffer: buffer,
+			done:   done,
+			policy: opts.Policy,
+			next:   txh,
+		}
+	}, stop
+}
+
+var _ tx.Handler = eventStreamTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh eventStreamTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh eventStreamTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if isDryRun(sdkCtx) {
+		return res, nil
+	}
+
+	stx := streamedTx{
+		height: sdkCtx.BlockHeight(),
+		txHash: tmhash.Sum(req.Tx),
+		events: append([]abci.Event(nil), res.Events...),
+	}
+
+	select {
+	case <-txh.done:
+		return res, nil
+	default:
+	}
+
+	if txh.policy == EventStreamBlock {
+		select {
+		case txh.buffer <- stx:
+		case <-txh.done:
+		}
+		return res, nil
+	}
+
+	select {
+	case txh.buffer <- stx:
+	default:
+		sdkCtx.Logger().Error("event stream buffer full, dropping tx events", "height", stx.height, "hash", fmt.Sprintf("%X", stx.txHash))
+	}
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh eventStreamTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
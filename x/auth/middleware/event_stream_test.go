@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// chanEventSink forwards the emitted txs to a channel, optionally waiting
+// for unblock to be closed first.
+type chanEventSink struct {
+	emitted chan []byte
+	unblock chan struct{}
+}
+
+func (sink chanEventSink) Emit(height int64, txHash []byte, events []abci.Event) {
+	if sink.unblock != nil {
+		<-sink.unblock
+	}
+	sink.emitted <- txHash
+}
+
+func (s *MWTestSuite) TestEventStreamMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+
+	txBytes := func(i int) []byte { return []byte{byte(i)} }
+
+	s.Run("block policy emits all txs in order", func() {
+		sink := chanEventSink{emitted: make(chan []byte, 10)}
+		eventStream, stop := middleware.NewEventStreamMiddlewareWithOptions(sink, middleware.EventStreamOptions{
+			BufferSize: 1,
+			Policy:     middleware.EventStreamBlock,
+		})
+		defer stop()
+		txHandler := middleware.ComposeMiddlewares(eventsTxHandler{}, eventStream)
+
+		for i := 0; i < 10; i++ {
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes(i)})
+			s.Require().NoError(err)
+		}
+
+		for i := 0; i < 10; i++ {
+			s.Require().Equal(tmhash.Sum(txBytes(i)), <-sink.emitted)
+		}
+	})
+
+	s.Run("drop policy doesn't wait for a slow sink", func() {
+		sink := chanEventSink{emitted: make(chan []byte, 10), unblock: make(chan struct{})}
+		eventStream, stop := middleware.NewEventStreamMiddlewareWithOptions(sink, middleware.EventStreamOptions{BufferSize: 1})
+		defer stop()
+		txHandler := middleware.ComposeMiddlewares(eventsTxHandler{}, eventStream)
+
+		for i := 0; i < 10; i++ {
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes(i)})
+			s.Require().NoError(err)
+		}
+		close(sink.unblock)
+
+		// the first tx is always emitted, and at most one more tx fits in
+		// the buffer while the sink is blocked
+		s.Require().Equal(tmhash.Sum(txBytes(0)), <-sink.emitted)
+		select {
+		case hash := <-sink.emitted:
+			s.Require().NotEqual(tmhash.Sum(txBytes(0)), hash)
+		case <-time.After(100 * time.Millisecond):
+		}
+		select {
+		case <-sink.emitted:
+			s.Fail("the txs delivered while the buffer is full must be dropped")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+	s.Run("stop emits the buffered txs and drops the later ones", func() {
+		sink := chanEventSink{emitted: make(chan []byte, 10), unblock: make(chan struct{})}
+		eventStream, stop := middleware.NewEventStreamMiddlewareWithOptions(sink, middleware.EventStreamOptions{
+			BufferSize: 1,
+			Policy:     middleware.EventStreamBlock,
+		})
+		txHandler := middleware.ComposeMiddlewares(eventsTxHandler{}, eventStream)
+
+		for i := 0; i < 2; i++ {
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes(i)})
+			s.Require().NoError(err)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			stop()
+			close(stopped)
+		}()
+		close(sink.unblock)
+		<-stopped
+
+		// the stopped middleware doesn't block on the full buffer
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes(2)})
+		s.Require().NoError(err)
+		s.Require().NotPanics(stop)
+
+		s.Require().Len(sink.emitted, 2)
+		for i := 0; i < 2; i++ {
+			s.Require().Equal(tmhash.Sum(txBytes(i)), <-sink.emitted)
+		}
+	})
+}
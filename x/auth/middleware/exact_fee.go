@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type exactFeeTxHandler struct {
+	required sdk.Coins
+	next     tx.Handler
+}
+
+// NewExactFeeMiddleware returns a middleware for the chains charging a flat
+// fee per tx: it rejects, with ErrInvalidFee, the txs paying a fee different
+// from the required one, whether they underpay or overpay it, as overpaying is
+// a user error worth surfacing. The fee is checked in CheckTx and DeliverTx;
+// simulations, which are commonly run without fee, are not affected. An empty
+// required fee returns the given tx.Handler unchanged.
+// CONTRACT: Tx must implement FeeTx interface
+func NewExactFeeMiddleware(required sdk.Coins) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if required.Empty() {
+			return txh
+		}
+
+		return exactFeeTxHandler{
+			required: required,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = exactFeeTxHandler{}
+
+// checkExactFee checks that the tx pays exactly the required fee.
+func (txh exactFeeTxHandler) checkExactFee(sdkTx sdk.Tx) error {
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	// Coins.IsEqual panics on different denoms, so the fees are compared both
+	// ways instead
+	fee := feeTx.GetFee()
+	if !fee.IsAllGTE(txh.required) {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidFee, "insufficient fee; got: %s required exactly: %s", fee, txh.required)
+	}
+	if !txh.required.IsAllGTE(fee) {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidFee, "fee overpayment; got: %s required exactly: %s", fee, txh.required)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh exactFeeTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkExactFee(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh exactFeeTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkExactFee(sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh exactFeeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestExactFeeMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewExactFeeMiddleware(sdk.NewCoins(sdk.NewInt64Coin("atom", 100))),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name     string
+		fee      sdk.Coins
+		rejected bool
+	}{
+		{"exact payment", sdk.NewCoins(sdk.NewInt64Coin("atom", 100)), false},
+		{"underpayment", sdk.NewCoins(sdk.NewInt64Coin("atom", 99)), true},
+		{"no fee", sdk.NewCoins(), true},
+		{"overpayment", sdk.NewCoins(sdk.NewInt64Coin("atom", 101)), true},
+		{"overpayment in another denom", sdk.NewCoins(sdk.NewInt64Coin("atom", 100), sdk.NewInt64Coin("stake", 1)), true},
+		{"payment in another denom", sdk.NewCoins(sdk.NewInt64Coin("stake", 100)), true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetFeeAmount(tc.fee)
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			for _, err := range []error{checkErr, deliverErr} {
+				if tc.rejected {
+					s.Require().True(errors.Is(err, sdkerrors.ErrInvalidFee))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package middleware
 
 import (
+	"time"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 )
 
 // AccountKeeper defines the contract needed for AccountKeeper related APIs.
@@ -18,3 +23,71 @@ type AccountKeeper interface {
 type FeegrantKeeper interface {
 	UseGrantedFees(ctx sdk.Context, granter, grantee sdk.AccAddress, fee sdk.Coins, msgs []sdk.Msg) error
 }
+
+// FeegrantGraceKeeper is an optional extension of FeegrantKeeper, needed by
+// the DeductFee middleware to honor the fee grants which just expired, see
+// FeegrantGraceBlocks.
+type FeegrantGraceKeeper interface {
+	FeegrantKeeper
+	// UseGrantedFeesWithGrace behaves like UseGrantedFees, but accepts a grant
+	// which expired less than grace ago.
+	UseGrantedFeesWithGrace(ctx sdk.Context, granter, grantee sdk.AccAddress, fee sdk.Coins, msgs []sdk.Msg, grace time.Duration) error
+}
+
+// FeeBurnerBankKeeper is an optional extension of the bank keeper of the
+// DeductFee middleware, needed to burn a fraction of the fees.
+type FeeBurnerBankKeeper interface {
+	types.BankKeeper
+	BurnCoins(ctx sdk.Context, moduleName string, amt sdk.Coins) error
+}
+
+// FeegrantAllowanceKeeper defines the expected feegrant keeper of the
+// FeegrantRemaining middleware.
+type FeegrantAllowanceKeeper interface {
+	GetAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress) (feegrant.FeeAllowanceI, error)
+}
+
+// RefundBankKeeper defines the expected bank keeper of the GasRefund
+// middleware.
+type RefundBankKeeper interface {
+	SendCoinsFromModuleToAccount(ctx sdk.Context, senderModule string, recipientAddr sdk.AccAddress, amt sdk.Coins) error
+}
+
+// RefundBurnerBankKeeper is an optional extension of RefundBankKeeper, needed
+// by the GasRefund middleware to burn the refunds.
+type RefundBurnerBankKeeper interface {
+	RefundBankKeeper
+	BurnCoins(ctx sdk.Context, moduleName string, amt sdk.Coins) error
+}
+
+// CommunityPoolKeeper defines the expected distribution keeper of the
+// GasRefund middleware, used to send the refunds to the community pool.
+type CommunityPoolKeeper interface {
+	FundCommunityPool(ctx sdk.Context, amount sdk.Coins, sender sdk.AccAddress) error
+}
+
+// BalanceKeeper defines the expected bank keeper of the SignerFundedCheck
+// middleware.
+type BalanceKeeper interface {
+	GetBalance(ctx sdk.Context, addr sdk.AccAddress, denom string) sdk.Coin
+}
+
+// DistributionKeeper defines the expected distribution keeper of the
+// DeductFee middleware, used to pay fees with staking rewards.
+type DistributionKeeper interface {
+	GetDelegatorWithdrawAddr(ctx sdk.Context, delAddr sdk.AccAddress) sdk.AccAddress
+	WithdrawDelegationRewards(ctx sdk.Context, delAddr sdk.AccAddress, valAddr sdk.ValAddress) (sdk.Coins, error)
+}
+
+// StakingKeeper defines the expected staking keeper of the DeductFee
+// middleware, used to pay fees with staking rewards.
+type StakingKeeper interface {
+	IterateDelegations(ctx sdk.Context, delegator sdk.AccAddress, fn func(index int64, delegation stakingtypes.DelegationI) (stop bool))
+}
+
+// AuthzKeeper defines the expected authz keeper.
+type AuthzKeeper interface {
+	GetCleanAuthorization(ctx sdk.Context, grantee, granter sdk.AccAddress, msgType string) (authz.Authorization, time.Time)
+	SaveGrant(ctx sdk.Context, grantee, granter sdk.AccAddress, authorization authz.Authorization, expiration time.Time) error
+	DeleteGrant(ctx sdk.Context, grantee, granter sdk.AccAddress, msgType string) error
+}
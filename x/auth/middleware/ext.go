@@ -43,6 +43,11 @@ func checkExtOpts(tx sdk.Tx) error {
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (txh rejectExtensionOptionsTxHandler) Named() string {
+	return "reject_extension_options"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (txh rejectExtensionOptionsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := checkExtOpts(tx); err != nil {
@@ -3,12 +3,14 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/auth/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 	abci "github.com/tendermint/tendermint/abci/types"
 )
 
@@ -30,9 +32,17 @@ func MempoolFeeMiddleware(txh tx.Handler) tx.Handler {
 	}
 }
 
+// Named implements NamedTxHandler.Named.
+func (txh mempoolFeeTxHandler) Named() string {
+	return "mempool_fee"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (txh mempoolFeeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if IsGasMeteringDisabled(sdkCtx) {
+		return txh.next.CheckTx(ctx, tx, req)
+	}
 
 	feeTx, ok := tx.(sdk.FeeTx)
 	if !ok {
@@ -47,15 +57,7 @@ func (txh mempoolFeeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.
 	// is only ran on check tx.
 	minGasPrices := sdkCtx.MinGasPrices()
 	if !minGasPrices.IsZero() {
-		requiredFees := make(sdk.Coins, len(minGasPrices))
-
-		// Determine the required fees by multiplying each required minimum gas
-		// price by the gas limit, where fee = ceil(minGasPrice * gasLimit).
-		glDec := sdk.NewDec(int64(gas))
-		for i, gp := range minGasPrices {
-			fee := gp.Amount.Mul(glDec)
-			requiredFees[i] = sdk.NewCoin(gp.Denom, fee.Ceil().RoundInt())
-		}
+		requiredFees := computeRequiredFees(minGasPrices, gas)
 
 		if !feeCoins.IsAnyGTE(requiredFees) {
 			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee, "insufficient fees; got: %s required: %s", feeCoins, requiredFees)
@@ -65,6 +67,20 @@ func (txh mempoolFeeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.
 	return txh.next.CheckTx(ctx, tx, req)
 }
 
+// computeRequiredFees determines the required fees by multiplying each gas
+// price by the gas limit, where fee = ceil(gasPrice * gasLimit).
+func computeRequiredFees(gasPrices sdk.DecCoins, gas uint64) sdk.Coins {
+	requiredFees := make(sdk.Coins, len(gasPrices))
+
+	glDec := sdk.NewDec(int64(gas))
+	for i, gp := range gasPrices {
+		fee := gp.Amount.Mul(glDec)
+		requiredFees[i] = sdk.NewCoin(gp.Denom, fee.Ceil().RoundInt())
+	}
+
+	return requiredFees
+}
+
 // DeliverTx implements tx.Handler.DeliverTx.
 func (txh mempoolFeeTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
 	return txh.next.DeliverTx(ctx, tx, req)
@@ -77,10 +93,152 @@ func (txh mempoolFeeTxHandler) SimulateTx(ctx context.Context, tx sdk.Tx, req tx
 
 var _ tx.Handler = deductFeeTxHandler{}
 
+// FeeConverter defines the contract used by the DeductFee middleware to accept
+// fees offered in denominations other than the chain's base fee denom.
+//
+// CONTRACT: Implementations must be deterministic, i.e. return the same result
+// on all nodes for the same state, so they must not rely on floating point
+// arithmetic or any off-chain data.
+type FeeConverter interface {
+	// ConvertFee reports whether the offered coins are worth at least the
+	// required fee, expressed in the base denom, and returns the conversion
+	// rate used.
+	ConvertFee(ctx sdk.Context, offered sdk.Coins, required sdk.Coin) (sufficient bool, rate sdk.Dec, err error)
+}
+
+// DeductFeeOptions defines the optional behaviors of the DeductFee
+// middleware.
+type DeductFeeOptions struct {
+	// FeeConverter, if set, is used to accept fees offered in denominations
+	// other than BaseGasPrice's denom.
+	FeeConverter FeeConverter
+	// BaseGasPrice is the gas price, in the base fee denom, used to compute the
+	// required fee when FeeConverter is set.
+	BaseGasPrice sdk.DecCoin
+	// FeeSplits, if set, routes the deducted fees to the given collectors
+	// proportionally to their weights, see SplitFees, instead of sending them
+	// all to the fee collector module account. The weights must sum to
+	// FeeSplitTotalWeight.
+	FeeSplits []FeeSplit
+	// DistributionKeeper and StakingKeeper, if both set, let fee payers with
+	// an insufficient balance pay the fees with their pending staking rewards,
+	// see deductFeesWithRewards. It doesn't apply to fees paid by a granter,
+	// nor to fee payers with another withdraw address.
+	DistributionKeeper DistributionKeeper
+	StakingKeeper      StakingKeeper
+	// OnFeeDeductionFailure, if set, is called in DeliverTx when the fees
+	// can't be deducted from the fee payer or granter account, e.g. because
+	// its balance is insufficient, with the address of that account. It
+	// doesn't alter the tx failure, and as the state changes of the failed tx
+	// are discarded, it should only record the address, e.g. to prune the
+	// dust accounts at the end of the block.
+	OnFeeDeductionFailure func(ctx sdk.Context, payer sdk.AccAddress, err error)
+	// BurnFraction, if positive, is the fraction of each fee burned instead
+	// of being collected, see BurnFees. The remainder is collected as usual,
+	// by the fee collector or the FeeSplits. It must be at most 1, the bank
+	// keeper must implement FeeBurnerBankKeeper and the fee collector module
+	// account must have the Burner permission.
+	BurnFraction sdk.Dec
+	// FeeExempt, if set, is called on each tx, and the fees of the txs it
+	// returns true for are not deducted, e.g. for the price feed txs of the
+	// bonded validators. The fees are still checked against the node's
+	// minimum gas prices in CheckTx by the MempoolFee middleware. As it runs
+	// in DeliverTx, it must be deterministic, i.e. only depend on the tx and
+	// the state.
+	FeeExempt func(ctx sdk.Context, tx sdk.Tx) bool
+	// FeegrantGraceBlocks, if positive, is a grace window, in blocks, during
+	// which a fee grant which just expired is still honored, so that the txs
+	// broadcast right before the expiration of their grant don't fail
+	// confusingly once included in a block. As grants expire at a block time,
+	// the window is converted into a duration using FeegrantGraceBlockTime,
+	// the expected time between two blocks, and has no effect if it isn't
+	// set. The feegrant keeper must implement FeegrantGraceKeeper.
+	FeegrantGraceBlocks    uint64
+	FeegrantGraceBlockTime time.Duration
+}
+
+// feegrantGracePeriod returns the duration during which an expired fee grant
+// is still honored.
+func (opts DeductFeeOptions) feegrantGracePeriod() time.Duration {
+	return time.Duration(opts.FeegrantGraceBlocks) * opts.FeegrantGraceBlockTime
+}
+
+// FeeSplitTotalWeight is the total the weights of the FeeSplits must sum to,
+// i.e. a weight is expressed in basis points of the fees.
+const FeeSplitTotalWeight = 10_000
+
+// FeeSplit is a collector receiving a share of the fees deducted by the
+// DeductFee middleware, see DeductFeeOptions.FeeSplits.
+type FeeSplit struct {
+	Address sdk.AccAddress
+	Weight  uint64
+}
+
+// validateFeeSplits checks that the splits have valid addresses and positive
+// weights summing to FeeSplitTotalWeight.
+func validateFeeSplits(splits []FeeSplit) error {
+	var total uint64
+	for i, split := range splits {
+		if split.Address.Empty() {
+			return fmt.Errorf("empty fee split address; index: %d", i)
+		}
+		if split.Weight == 0 || split.Weight > FeeSplitTotalWeight {
+			return fmt.Errorf("invalid fee split weight %d; index: %d", split.Weight, i)
+		}
+
+		total += split.Weight
+	}
+
+	if total != FeeSplitTotalWeight {
+		return fmt.Errorf("fee split weights must sum to %d, got %d", FeeSplitTotalWeight, total)
+	}
+
+	return nil
+}
+
+// SplitFees returns the share of the fees of each split, indexed like the
+// splits. Each share is rounded down, and the remainder of each denom goes to
+// the first split, so that the shares always sum to the fees.
+func SplitFees(fees sdk.Coins, splits []FeeSplit) []sdk.Coins {
+	shares := make([]sdk.Coins, len(splits))
+	total := sdk.NewIntFromUint64(FeeSplitTotalWeight)
+	for _, fee := range fees {
+		remainder := fee.Amount
+		for i := len(splits) - 1; i > 0; i-- {
+			amount := fee.Amount.Mul(sdk.NewIntFromUint64(splits[i].Weight)).Quo(total)
+			shares[i] = shares[i].Add(sdk.NewCoin(fee.Denom, amount))
+			remainder = remainder.Sub(amount)
+		}
+
+		shares[0] = shares[0].Add(sdk.NewCoin(fee.Denom, remainder))
+	}
+
+	return shares
+}
+
+// burnsFees reports whether the options burn a fraction of the fees.
+func (opts DeductFeeOptions) burnsFees() bool {
+	return !opts.BurnFraction.IsNil() && opts.BurnFraction.IsPositive()
+}
+
+// BurnFees splits the fees into the burned and the collected fees, given the
+// fraction of the fees to burn. The burned amount of each denom is rounded
+// down, so that the rounding goes to the collected fees and both always sum to
+// the fees.
+func BurnFees(fees sdk.Coins, fraction sdk.Dec) (burned, collected sdk.Coins) {
+	burned = sdk.NewCoins()
+	for _, fee := range fees {
+		burned = burned.Add(sdk.NewCoin(fee.Denom, fraction.MulInt(fee.Amount).TruncateInt()))
+	}
+
+	return burned, fees.Sub(burned)
+}
+
 type deductFeeTxHandler struct {
 	accountKeeper  AccountKeeper
 	bankKeeper     types.BankKeeper
 	feegrantKeeper FeegrantKeeper
+	opts           DeductFeeOptions
 	next           tx.Handler
 }
 
@@ -89,25 +247,174 @@ type deductFeeTxHandler struct {
 // Call next middleware if fees successfully deducted
 // CONTRACT: Tx must implement FeeTx interface to use deductFeeTxHandler
 func DeductFeeMiddleware(ak AccountKeeper, bk types.BankKeeper, fk FeegrantKeeper) tx.Middleware {
+	return NewDeductFeeMiddleware(ak, bk, fk, DeductFeeOptions{})
+}
+
+// NewDeductFeeMiddleware is the same as DeductFeeMiddleware, with additional
+// behaviors configured by the given DeductFeeOptions.
+// CONTRACT: Tx must implement FeeTx interface to use deductFeeTxHandler
+func NewDeductFeeMiddleware(ak AccountKeeper, bk types.BankKeeper, fk FeegrantKeeper, opts DeductFeeOptions) tx.Middleware {
+	if len(opts.FeeSplits) > 0 {
+		if err := validateFeeSplits(opts.FeeSplits); err != nil {
+			panic(err)
+		}
+	}
+	if opts.burnsFees() {
+		if opts.BurnFraction.GT(sdk.OneDec()) {
+			panic(fmt.Errorf("fee burn fraction must be at most 1, got %s", opts.BurnFraction))
+		}
+		if _, ok := bk.(FeeBurnerBankKeeper); !ok {
+			panic(fmt.Errorf("bank keeper %T can't burn fees", bk))
+		}
+	}
+
 	return func(txh tx.Handler) tx.Handler {
 		return deductFeeTxHandler{
 			accountKeeper:  ak,
 			bankKeeper:     bk,
 			feegrantKeeper: fk,
+			opts:           opts,
 			next:           txh,
 		}
 	}
 }
 
-func (dfd deductFeeTxHandler) checkDeductFee(ctx context.Context, tx sdk.Tx) error {
+// DeductFeeWithConversionMiddleware is the same as DeductFeeMiddleware, but
+// additionally requires the offered fee to be worth at least baseGasPrice
+// times the tx gas limit, as reported by the given FeeConverter. The offered
+// coins are deducted as is, and not the converted amount.
+// CONTRACT: Tx must implement FeeTx interface to use deductFeeTxHandler
+func DeductFeeWithConversionMiddleware(ak AccountKeeper, bk types.BankKeeper, fk FeegrantKeeper, fc FeeConverter, baseGasPrice sdk.DecCoin) tx.Middleware {
+	return NewDeductFeeMiddleware(ak, bk, fk, DeductFeeOptions{
+		FeeConverter: fc,
+		BaseGasPrice: baseGasPrice,
+	})
+}
+
+// checkConvertedFee checks, using the FeeConverter, that the offered fee covers
+// the required fee in the base denom, and emits the conversion rate used.
+func (dfd deductFeeTxHandler) checkConvertedFee(sdkCtx sdk.Context, feeTx sdk.FeeTx) error {
+	// required fee = ceil(baseGasPrice * gasLimit)
+	requiredAmt := dfd.opts.BaseGasPrice.Amount.Mul(sdk.NewDecFromInt(sdk.NewIntFromUint64(feeTx.GetGas()))).Ceil().RoundInt()
+	required := sdk.NewCoin(dfd.opts.BaseGasPrice.Denom, requiredAmt)
+
+	sufficient, rate, err := dfd.opts.FeeConverter.ConvertFee(sdkCtx, feeTx.GetFee(), required)
+	if err != nil {
+		return err
+	}
+
+	if rate.IsNil() || !rate.IsPositive() {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "invalid fee conversion rate: %s", rate)
+	}
+
+	if !sufficient {
+		return sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee,
+			"insufficient fees; got: %s required: %s at conversion rate %s", feeTx.GetFee(), required, rate,
+		)
+	}
+
+	sdkCtx.EventManager().EmitEvent(sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(sdk.AttributeKeyFeeConversionRate, rate.String()),
+	))
+
+	return nil
+}
+
+// feePayerKey is the sdk.Context key under which the fee payer and granter
+// resolved by the DeductFee middleware are stored.
+type feePayerKey struct{}
+
+// feePayerInfo holds the fee payer and granter of a tx, whether the tx is
+// exempt from fees, and the fees credited to the fee collector.
+type feePayerInfo struct {
+	payer     sdk.AccAddress
+	granter   sdk.AccAddress
+	exempt    bool
+	collected sdk.Coins
+}
+
+// GetFeePayer returns the fee payer of the tx, as resolved by the DeductFee
+// middleware, or nil if the middleware didn't run.
+func GetFeePayer(ctx sdk.Context) sdk.AccAddress {
+	info, _ := ctx.Value(feePayerKey{}).(feePayerInfo)
+	return info.payer
+}
+
+// GetFeeGranter returns the granter which paid the fees of the tx through a
+// fee grant, as resolved by the DeductFee middleware, or nil if the fee payer
+// paid its own fees.
+func GetFeeGranter(ctx sdk.Context) sdk.AccAddress {
+	info, _ := ctx.Value(feePayerKey{}).(feePayerInfo)
+	return info.granter
+}
+
+// isFeeExempt reports whether the DeductFee middleware exempted the tx from
+// fees, see DeductFeeOptions.FeeExempt.
+func isFeeExempt(ctx sdk.Context) bool {
+	info, _ := ctx.Value(feePayerKey{}).(feePayerInfo)
+	return info.exempt
+}
+
+// collectedFees returns the part of the fees of the tx which the DeductFee
+// middleware credited to the fee collector, i.e. neither burned nor sent to
+// another FeeSplits collector.
+func collectedFees(ctx sdk.Context) sdk.Coins {
+	info, _ := ctx.Value(feePayerKey{}).(feePayerInfo)
+	return info.collected
+}
+
+// checkFeeCollector checks that the fee collector module account is correctly
+// set up, as the bank keeper panics when sending fees to a misconfigured module
+// account.
+func (dfd deductFeeTxHandler) checkFeeCollector(sdkCtx sdk.Context) error {
+	addr := dfd.accountKeeper.GetModuleAddress(types.FeeCollectorName)
+	if addr == nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrLogic,
+			"%s module account has not been set; register it in the account keeper module account permissions", types.FeeCollectorName)
+	}
+
+	// the lookup is not charged, so that the tx gas consumption is the same as
+	// without the check
+	acc := dfd.accountKeeper.GetAccount(sdkCtx.WithGasMeter(sdk.NewInfiniteGasMeter()), addr)
+	if acc == nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrLogic,
+			"%s module account %s does not exist; it must be initialized in the auth genesis", types.FeeCollectorName, addr)
+	}
+	if _, ok := acc.(types.ModuleAccountI); !ok {
+		return sdkerrors.Wrapf(sdkerrors.ErrLogic,
+			"account %s is not the %s module account; fix it in the auth genesis", addr, types.FeeCollectorName)
+	}
+
+	return nil
+}
+
+// checkDeductFee deducts the fees of the tx, and returns a context holding the
+// resolved fee payer and granter. isDeliverTx defines whether the
+// OnFeeDeductionFailure hook is called.
+func (dfd deductFeeTxHandler) checkDeductFee(ctx context.Context, tx sdk.Tx, isDeliverTx bool) (context.Context, error) {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if IsGasMeteringDisabled(sdkCtx) {
+		return ctx, nil
+	}
+
 	feeTx, ok := tx.(sdk.FeeTx)
 	if !ok {
-		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+		return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	if dfd.opts.FeeExempt != nil && dfd.opts.FeeExempt(sdkCtx, tx) {
+		sdkCtx = sdkCtx.WithValue(feePayerKey{}, feePayerInfo{payer: feeTx.FeePayer(), exempt: true})
+		return sdk.WrapSDKContext(sdkCtx), nil
 	}
 
-	if addr := dfd.accountKeeper.GetModuleAddress(types.FeeCollectorName); addr == nil {
-		panic(fmt.Sprintf("%s module account has not been set", types.FeeCollectorName))
+	if err := dfd.checkFeeCollector(sdkCtx); err != nil {
+		return nil, err
+	}
+
+	if dfd.opts.FeeConverter != nil {
+		if err := dfd.checkConvertedFee(sdkCtx, feeTx); err != nil {
+			return nil, err
+		}
 	}
 
 	fee := feeTx.GetFee()
@@ -115,18 +422,20 @@ func (dfd deductFeeTxHandler) checkDeductFee(ctx context.Context, tx sdk.Tx) err
 	feeGranter := feeTx.FeeGranter()
 
 	deductFeesFrom := feePayer
+	var usedGranter sdk.AccAddress
 
 	// if feegranter set deduct fee from feegranter account.
 	// this works with only when feegrant enabled.
 	if feeGranter != nil {
 		if dfd.feegrantKeeper == nil {
-			return sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "fee grants are not enabled")
+			return nil, sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "fee grants are not enabled")
 		} else if !feeGranter.Equals(feePayer) {
-			err := dfd.feegrantKeeper.UseGrantedFees(sdkCtx, feeGranter, feePayer, fee, tx.GetMsgs())
-
+			err := dfd.useGrantedFeesWithGrace(sdkCtx, feeGranter, feePayer, fee, tx.GetMsgs())
 			if err != nil {
-				return sdkerrors.Wrapf(err, "%s not allowed to pay fees from %s", feeGranter, feePayer)
+				return nil, sdkerrors.Wrapf(err, "%s not allowed to pay fees from %s", feeGranter, feePayer)
 			}
+
+			usedGranter = feeGranter
 		}
 
 		deductFeesFrom = feeGranter
@@ -134,14 +443,23 @@ func (dfd deductFeeTxHandler) checkDeductFee(ctx context.Context, tx sdk.Tx) err
 
 	deductFeesFromAcc := dfd.accountKeeper.GetAccount(sdkCtx, deductFeesFrom)
 	if deductFeesFromAcc == nil {
-		return sdkerrors.Wrapf(sdkerrors.ErrUnknownAddress, "fee payer address: %s does not exist", deductFeesFrom)
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrUnknownAddress, "fee payer address: %s does not exist", deductFeesFrom)
 	}
 
 	// deduct the fees
+	var collected sdk.Coins
 	if !feeTx.GetFee().IsZero() {
-		err := DeductFees(dfd.bankKeeper, sdkCtx, deductFeesFromAcc, feeTx.GetFee())
+		var err error
+		if usedGranter == nil && dfd.opts.DistributionKeeper != nil && dfd.opts.StakingKeeper != nil {
+			collected, err = dfd.deductFeesWithRewards(sdkCtx, deductFeesFromAcc, feeTx.GetFee())
+		} else {
+			collected, err = dfd.deductFees(sdkCtx, deductFeesFromAcc, feeTx.GetFee())
+		}
 		if err != nil {
-			return err
+			if isDeliverTx && dfd.opts.OnFeeDeductionFailure != nil {
+				dfd.opts.OnFeeDeductionFailure(sdkCtx, deductFeesFrom, err)
+			}
+			return nil, err
 		}
 	}
 
@@ -150,12 +468,137 @@ func (dfd deductFeeTxHandler) checkDeductFee(ctx context.Context, tx sdk.Tx) err
 	)}
 	sdkCtx.EventManager().EmitEvents(events)
 
-	return nil
+	sdkCtx = sdkCtx.WithValue(feePayerKey{}, feePayerInfo{payer: feePayer, granter: usedGranter, collected: collected})
+	return sdk.WrapSDKContext(sdkCtx), nil
+}
+
+// useGrantedFeesWithGrace uses the fee grant of the granter to the grantee
+// like FeegrantKeeper.UseGrantedFees, but if the grant has expired less than
+// the FeegrantGraceBlocks window ago, it is still accepted. Only the
+// expiration is affected by the window: the grants which expired before it
+// are revoked as usual, and the rest of the allowance is evaluated at the
+// block time.
+func (dfd deductFeeTxHandler) useGrantedFeesWithGrace(sdkCtx sdk.Context, granter, grantee sdk.AccAddress, fee sdk.Coins, msgs []sdk.Msg) error {
+	graceKeeper, ok := dfd.feegrantKeeper.(FeegrantGraceKeeper)
+	grace := dfd.opts.feegrantGracePeriod()
+	if !ok || grace <= 0 {
+		return dfd.feegrantKeeper.UseGrantedFees(sdkCtx, granter, grantee, fee, msgs)
+	}
+
+	return graceKeeper.UseGrantedFeesWithGrace(sdkCtx, granter, grantee, fee, msgs, grace)
+}
+
+// deductFees deducts the fees from the given account, burning a fraction of
+// them if BurnFraction is set, and splitting the collected fees if FeeSplits
+// are set. It returns the fees left in the fee collector.
+func (dfd deductFeeTxHandler) deductFees(sdkCtx sdk.Context, acc types.AccountI, fees sdk.Coins) (sdk.Coins, error) {
+	if dfd.opts.burnsFees() {
+		if !fees.IsValid() {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee, "invalid fee amount: %s", fees)
+		}
+
+		var burned sdk.Coins
+		burned, fees = BurnFees(fees, dfd.opts.BurnFraction)
+		if !burned.IsZero() {
+			if err := DeductFees(dfd.bankKeeper, sdkCtx, acc, burned); err != nil {
+				return nil, err
+			}
+			if err := dfd.bankKeeper.(FeeBurnerBankKeeper).BurnCoins(sdkCtx, types.FeeCollectorName, burned); err != nil {
+				return nil, sdkerrors.Wrapf(err, "failed to burn fees")
+			}
+		}
+
+		if fees.IsZero() {
+			return sdk.NewCoins(), nil
+		}
+	}
+
+	if len(dfd.opts.FeeSplits) > 0 {
+		if err := deductSplitFees(dfd.bankKeeper, sdkCtx, acc, fees, dfd.opts.FeeSplits); err != nil {
+			return nil, err
+		}
+
+		// the fee collector may itself be one of the split collectors
+		collector := types.NewModuleAddress(types.FeeCollectorName)
+		collected := sdk.NewCoins()
+		for i, share := range SplitFees(fees, dfd.opts.FeeSplits) {
+			if dfd.opts.FeeSplits[i].Address.Equals(collector) {
+				collected = collected.Add(share...)
+			}
+		}
+
+		return collected, nil
+	}
+
+	return fees, DeductFees(dfd.bankKeeper, sdkCtx, acc, fees)
+}
+
+// deductFeesWithRewards deducts the fees from the given account and, if its
+// balance is insufficient, withdraws its pending staking rewards one
+// delegation at a time, in the staking store order, until the fees can be
+// paid. The withdrawals and the deduction are atomic: if the fees can't be
+// paid even with all the rewards, no rewards are withdrawn. As the rewards
+// are paid to the withdraw address of the account, none are withdrawn if it
+// is another account.
+func (dfd deductFeeTxHandler) deductFeesWithRewards(sdkCtx sdk.Context, acc types.AccountI, fees sdk.Coins) (sdk.Coins, error) {
+	rewardsCtx, writeRewards := sdkCtx.CacheContext()
+
+	var collected sdk.Coins
+	tryDeduct := func() error {
+		deductCtx, writeDeduct := rewardsCtx.CacheContext()
+		var err error
+		if collected, err = dfd.deductFees(deductCtx, acc, fees); err != nil {
+			return err
+		}
+
+		writeDeduct()
+		rewardsCtx.EventManager().EmitEvents(deductCtx.EventManager().Events())
+		return nil
+	}
+
+	err := tryDeduct()
+	if err == nil {
+		writeRewards()
+		sdkCtx.EventManager().EmitEvents(rewardsCtx.EventManager().Events())
+		return collected, nil
+	}
+
+	if withdrawAddr := dfd.opts.DistributionKeeper.GetDelegatorWithdrawAddr(rewardsCtx, acc.GetAddress()); !withdrawAddr.Equals(acc.GetAddress()) {
+		return nil, sdkerrors.Wrapf(err, "staking rewards are withdrawn to %s", withdrawAddr)
+	}
+
+	// collect the validators first, so that the store isn't written while
+	// iterating over it
+	var valAddrs []sdk.ValAddress
+	dfd.opts.StakingKeeper.IterateDelegations(rewardsCtx, acc.GetAddress(), func(_ int64, delegation stakingtypes.DelegationI) bool {
+		valAddrs = append(valAddrs, delegation.GetValidatorAddr())
+		return false
+	})
+
+	for _, valAddr := range valAddrs {
+		if _, withdrawErr := dfd.opts.DistributionKeeper.WithdrawDelegationRewards(rewardsCtx, acc.GetAddress(), valAddr); withdrawErr != nil {
+			return nil, sdkerrors.Wrapf(withdrawErr, "failed to withdraw rewards from %s to pay fees", valAddr)
+		}
+
+		if err = tryDeduct(); err == nil {
+			writeRewards()
+			sdkCtx.EventManager().EmitEvents(rewardsCtx.EventManager().Events())
+			return collected, nil
+		}
+	}
+
+	return nil, sdkerrors.Wrap(err, "insufficient funds even after withdrawing staking rewards")
+}
+
+// Named implements NamedTxHandler.Named.
+func (dfd deductFeeTxHandler) Named() string {
+	return "deduct_fee"
 }
 
 // CheckTx implements tx.Handler.CheckTx.
 func (dfd deductFeeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
-	if err := dfd.checkDeductFee(ctx, tx); err != nil {
+	ctx, err := dfd.checkDeductFee(ctx, tx, false)
+	if err != nil {
 		return abci.ResponseCheckTx{}, err
 	}
 
@@ -164,15 +607,18 @@ func (dfd deductFeeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.R
 
 // DeliverTx implements tx.Handler.DeliverTx.
 func (dfd deductFeeTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
-	if err := dfd.checkDeductFee(ctx, tx); err != nil {
+	ctx, err := dfd.checkDeductFee(ctx, tx, true)
+	if err != nil {
 		return abci.ResponseDeliverTx{}, err
 	}
 
 	return dfd.next.DeliverTx(ctx, tx, req)
 }
 
+// SimulateTx implements tx.Handler.SimulateTx.
 func (dfd deductFeeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
-	if err := dfd.checkDeductFee(ctx, sdkTx); err != nil {
+	ctx, err := dfd.checkDeductFee(ctx, sdkTx, false)
+	if err != nil {
 		return tx.ResponseSimulateTx{}, err
 	}
 
@@ -192,3 +638,24 @@ func DeductFees(bankKeeper types.BankKeeper, ctx sdk.Context, acc types.AccountI
 
 	return nil
 }
+
+// deductSplitFees deducts fees from the given account, sending each split its
+// share of the fees.
+func deductSplitFees(bankKeeper types.BankKeeper, ctx sdk.Context, acc types.AccountI, fees sdk.Coins, splits []FeeSplit) error {
+	if !fees.IsValid() {
+		return sdkerrors.Wrapf(sdkerrors.ErrInsufficientFee, "invalid fee amount: %s", fees)
+	}
+
+	for i, share := range SplitFees(fees, splits) {
+		if share.IsZero() {
+			continue
+		}
+
+		err := bankKeeper.SendCoins(ctx, acc.GetAddress(), splits[i].Address, share)
+		if err != nil {
+			return sdkerrors.Wrapf(sdkerrors.ErrInsufficientFunds, err.Error())
+		}
+	}
+
+	return nil
+}
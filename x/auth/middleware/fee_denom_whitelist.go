@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type feeDenomWhitelistTxHandler struct {
+	denoms map[string]struct{}
+	next   tx.Handler
+}
+
+// NewFeeDenomWhitelistMiddleware returns a middleware that rejects, with
+// ErrInvalidCoins, the txs whose fee contains a denom which is not in the given
+// whitelist. It must be placed before the DeductFee middleware. The whitelist
+// is enforced in all modes, so it must be the same on all nodes. An empty
+// whitelist accepts all denoms.
+// CONTRACT: Tx must implement FeeTx interface
+func NewFeeDenomWhitelistMiddleware(denoms []string) tx.Middleware {
+	whitelist := make(map[string]struct{}, len(denoms))
+	for _, denom := range denoms {
+		whitelist[denom] = struct{}{}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return feeDenomWhitelistTxHandler{
+			denoms: whitelist,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = feeDenomWhitelistTxHandler{}
+
+// checkFeeDenoms checks that all the fee denoms of the tx are whitelisted.
+func (txh feeDenomWhitelistTxHandler) checkFeeDenoms(sdkTx sdk.Tx) error {
+	if len(txh.denoms) == 0 {
+		return nil
+	}
+
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	for _, coin := range feeTx.GetFee() {
+		if _, ok := txh.denoms[coin.Denom]; !ok {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidCoins, "fee denom %s is not accepted", coin.Denom)
+		}
+	}
+
+	return nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh feeDenomWhitelistTxHandler) Named() string {
+	return "fee_denom_whitelist"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh feeDenomWhitelistTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkFeeDenoms(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh feeDenomWhitelistTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkFeeDenoms(sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh feeDenomWhitelistTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkFeeDenoms(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
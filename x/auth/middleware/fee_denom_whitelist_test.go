@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestFeeDenomWhitelist() {
+	ctx := s.SetupTest(true) // setup
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name      string
+		whitelist []string
+		fee       sdk.Coins
+		expErr    bool
+	}{
+		{"allowed denom", []string{"atom", "photon"}, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)), false},
+		{"allowed denoms", []string{"atom", "photon"}, sdk.NewCoins(sdk.NewInt64Coin("atom", 10), sdk.NewInt64Coin("photon", 10)), false},
+		{"no fee", []string{"atom", "photon"}, sdk.NewCoins(), false},
+		{"disallowed denom", []string{"atom", "photon"}, sdk.NewCoins(sdk.NewInt64Coin("stake", 10)), true},
+		{"mixed denoms", []string{"atom", "photon"}, sdk.NewCoins(sdk.NewInt64Coin("atom", 10), sdk.NewInt64Coin("stake", 10)), true},
+		{"empty whitelist", nil, sdk.NewCoins(sdk.NewInt64Coin("stake", 10)), false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetFeeAmount(tc.fee)
+			testTx := txBuilder.GetTx()
+
+			txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewFeeDenomWhitelistMiddleware(tc.whitelist))
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			_, simulateErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simulateErr} {
+				if tc.expErr {
+					s.Require().True(errors.Is(err, sdkerrors.ErrInvalidCoins))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
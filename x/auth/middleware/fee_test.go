@@ -1,11 +1,21 @@
 package middleware_test
 
 import (
+	"errors"
+	"math"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/simapp"
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/staking/teststaking"
 	abci "github.com/tendermint/tendermint/abci/types"
 )
 
@@ -97,3 +107,495 @@ func (s *MWTestSuite) TestDeductFees() {
 
 	s.Require().Nil(err, "Tx errored after account has been set with sufficient funds")
 }
+
+func (s *MWTestSuite) TestDeductFeesFailureHook() {
+	ctx := s.SetupTest(false) // setup
+
+	var failedPayers []sdk.AccAddress
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, nil, middleware.DeductFeeOptions{
+			OnFeeDeductionFailure: func(_ sdk.Context, payer sdk.AccAddress, err error) {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+				failedPayers = append(failedPayers, payer)
+			},
+		}),
+	)
+
+	// the account can only pay 10atom
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1))
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 10))))
+
+	newTx := func(fee int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetFeeAmount(sdk.NewCoins(sdk.NewInt64Coin("atom", fee)))
+		return txBuilder.GetTx()
+	}
+
+	// the hook is only called on DeliverTx failures
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(100), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), newTx(100), tx.RequestSimulateTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+	s.Require().Empty(failedPayers)
+
+	// the failure is returned unchanged
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(100), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+	s.Require().Equal([]sdk.AccAddress{addr1}, failedPayers)
+
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(10), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Len(failedPayers, 1)
+}
+
+// fixedRateFeeConverter converts fees offered in a single denom into the base
+// denom using a fixed rate.
+type fixedRateFeeConverter struct {
+	denom string
+	rate  sdk.Dec
+}
+
+var _ middleware.FeeConverter = fixedRateFeeConverter{}
+
+func (c fixedRateFeeConverter) ConvertFee(_ sdk.Context, offered sdk.Coins, required sdk.Coin) (bool, sdk.Dec, error) {
+	converted := c.rate.MulInt(offered.AmountOf(c.denom)).TruncateInt()
+	return converted.GTE(required.Amount), c.rate, nil
+}
+
+func (s *MWTestSuite) TestDeductFeesWithConversion() {
+	ctx := s.SetupTest(false) // setup
+
+	// keys and addresses
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("photon", 1000)))
+	s.Require().NoError(err)
+
+	// 1photon is worth 2atom, and the required fee is 200000gas * 0.001atom = 200atom
+	baseGasPrice := sdk.NewDecCoinFromDec("atom", sdk.NewDecWithPrec(1, 3))
+	gasLimit := testdata.NewTestGasLimit()
+
+	testCases := []struct {
+		desc     string
+		rate     sdk.Dec
+		fee      sdk.Coins
+		gasLimit uint64
+		expErr   error
+		expRate  string
+	}{
+		{"sufficient converted fee", sdk.NewDec(2), sdk.NewCoins(sdk.NewInt64Coin("photon", 100)), gasLimit, nil, sdk.NewDec(2).String()},
+		{"insufficient converted fee", sdk.NewDec(2), sdk.NewCoins(sdk.NewInt64Coin("photon", 99)), gasLimit, sdkerrors.ErrInsufficientFee, ""},
+		{"zero conversion rate", sdk.ZeroDec(), sdk.NewCoins(sdk.NewInt64Coin("photon", 100)), gasLimit, sdkerrors.ErrInvalidCoins, ""},
+		{"gas limit above max int64", sdk.NewDec(2), sdk.NewCoins(sdk.NewInt64Coin("photon", 100)), math.MaxUint64, sdkerrors.ErrInsufficientFee, ""},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			txHandler := middleware.ComposeMiddlewares(
+				noopTxHandler{},
+				middleware.DeductFeeWithConversionMiddleware(
+					s.app.AccountKeeper,
+					s.app.BankKeeper,
+					s.app.FeeGrantKeeper,
+					fixedRateFeeConverter{denom: "photon", rate: tc.rate},
+					baseGasPrice,
+				),
+			)
+
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetFeeAmount(tc.fee)
+			txBuilder.SetGasLimit(tc.gasLimit)
+			privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+			testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+			s.Require().NoError(err)
+
+			cacheCtx, _ := ctx.CacheContext()
+			balanceBefore := s.app.BankKeeper.GetBalance(cacheCtx, addr1, "photon")
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestDeliverTx{})
+			if tc.expErr != nil {
+				s.Require().True(errors.Is(err, tc.expErr))
+				return
+			}
+			s.Require().NoError(err)
+
+			// the offered coins are deducted, not the converted amount
+			balanceAfter := s.app.BankKeeper.GetBalance(cacheCtx, addr1, "photon")
+			s.Require().Equal(tc.fee.AmountOf("photon"), balanceBefore.Amount.Sub(balanceAfter.Amount))
+
+			var rate string
+			for _, event := range cacheCtx.EventManager().Events() {
+				for _, attr := range event.Attributes {
+					if string(attr.Key) == sdk.AttributeKeyFeeConversionRate {
+						rate = string(attr.Value)
+					}
+				}
+			}
+			s.Require().Equal(tc.expRate, rate)
+		})
+	}
+}
+
+func (s *MWTestSuite) TestSplitFees() {
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, addr3 := testdata.KeyTestPubAddr()
+	splits := []middleware.FeeSplit{{addr1, 3333}, {addr2, 3333}, {addr3, 3334}}
+
+	testCases := []struct {
+		desc      string
+		fees      sdk.Coins
+		expShares []sdk.Coins
+	}{
+		{
+			"divisible fees",
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 10000)),
+			[]sdk.Coins{
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 3333)),
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 3333)),
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 3334)),
+			},
+		},
+		{
+			"remainders go to the first collector",
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 7), sdk.NewInt64Coin("photon", 1)),
+			[]sdk.Coins{
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 3), sdk.NewInt64Coin("photon", 1)),
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 2)),
+				sdk.NewCoins(sdk.NewInt64Coin("atom", 2)),
+			},
+		},
+		{
+			"no fees",
+			sdk.NewCoins(),
+			[]sdk.Coins{nil, nil, nil},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			s.Require().Equal(tc.expShares, middleware.SplitFees(tc.fees, splits))
+		})
+	}
+}
+
+func (s *MWTestSuite) TestDeductFeesWithSplits() {
+	ctx := s.SetupTest(false) // setup
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	s.Require().NoError(err)
+
+	feeCollector := s.app.AccountKeeper.GetModuleAddress(types.FeeCollectorName)
+	_, _, grantsPool := testdata.KeyTestPubAddr()
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDeductFeeMiddleware(
+			s.app.AccountKeeper,
+			s.app.BankKeeper,
+			s.app.FeeGrantKeeper,
+			middleware.DeductFeeOptions{
+				FeeSplits: []middleware.FeeSplit{{feeCollector, 7000}, {grantsPool, 3000}},
+			},
+		),
+	)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(sdk.NewCoins(sdk.NewInt64Coin("atom", 155)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	collectorBefore := s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom")
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// 155 * 30% = 46.5 is rounded down, the remainder goes to the fee collector
+	s.Require().Equal(sdk.NewInt(109), s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom").Amount.Sub(collectorBefore.Amount))
+	s.Require().Equal(sdk.NewInt(46), s.app.BankKeeper.GetBalance(ctx, grantsPool, "atom").Amount)
+	s.Require().Equal(sdk.NewInt(845), s.app.BankKeeper.GetBalance(ctx, addr1, "atom").Amount)
+
+	// the weights must sum to FeeSplitTotalWeight
+	s.Require().Panics(func() {
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{
+			FeeSplits: []middleware.FeeSplit{{feeCollector, 7000}, {grantsPool, 2000}},
+		})
+	})
+}
+
+func (s *MWTestSuite) TestBurnFees() {
+	testCases := []struct {
+		desc         string
+		fees         sdk.Coins
+		fraction     sdk.Dec
+		expBurned    sdk.Coins
+		expCollected sdk.Coins
+	}{
+		{
+			"exact fraction",
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 100)),
+			sdk.NewDecWithPrec(3, 1),
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 30)),
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 70)),
+		},
+		{
+			"rounding goes to collection",
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 7), sdk.NewInt64Coin("photon", 1)),
+			sdk.NewDecWithPrec(5, 1),
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 3)),
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 4), sdk.NewInt64Coin("photon", 1)),
+		},
+		{
+			"burn everything",
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 7)),
+			sdk.OneDec(),
+			sdk.NewCoins(sdk.NewInt64Coin("atom", 7)),
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			burned, collected := middleware.BurnFees(tc.fees, tc.fraction)
+			s.Require().Equal(tc.expBurned, burned)
+			s.Require().Equal(tc.expCollected, collected)
+			s.Require().Equal(tc.fees, burned.Add(collected...))
+		})
+	}
+}
+
+func (s *MWTestSuite) TestDeductFeesWithBurn() {
+	ctx := s.SetupTest(false) // setup
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	s.Require().NoError(err)
+
+	feeCollector := s.app.AccountKeeper.GetModuleAddress(types.FeeCollectorName)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDeductFeeMiddleware(
+			s.app.AccountKeeper,
+			s.app.BankKeeper,
+			s.app.FeeGrantKeeper,
+			middleware.DeductFeeOptions{
+				BurnFraction: sdk.NewDecWithPrec(25, 2),
+			},
+		),
+	)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(sdk.NewCoins(sdk.NewInt64Coin("atom", 155)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	collectorBefore := s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom")
+	supplyBefore := s.app.BankKeeper.GetSupply(ctx, "atom")
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// 155 * 25% = 38.75 is rounded down, the remainder is collected
+	burned := supplyBefore.Amount.Sub(s.app.BankKeeper.GetSupply(ctx, "atom").Amount)
+	collected := s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom").Amount.Sub(collectorBefore.Amount)
+	s.Require().Equal(sdk.NewInt(38), burned)
+	s.Require().Equal(sdk.NewInt(117), collected)
+	s.Require().Equal(sdk.NewInt(155), burned.Add(collected))
+	s.Require().Equal(sdk.NewInt(845), s.app.BankKeeper.GetBalance(ctx, addr1, "atom").Amount)
+
+	// the fraction must be at most 1
+	s.Require().Panics(func() {
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{
+			BurnFraction: sdk.NewDecWithPrec(11, 1),
+		})
+	})
+}
+
+func (s *MWTestSuite) TestDeductFeesExempt() {
+	ctx := s.SetupTest(false) // setup
+
+	validators := s.app.StakingKeeper.GetAllValidators(ctx)
+	s.Require().NotEmpty(validators)
+	s.Require().True(validators[0].IsBonded())
+	operator := sdk.AccAddress(validators[0].GetOperator())
+
+	_, _, user := testdata.KeyTestPubAddr()
+	s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, user))
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, user, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	s.Require().NoError(err)
+
+	// the txs of bonded validator operators holding a single TestMsg, standing
+	// for a price feed, are exempt
+	feeExempt := func(ctx sdk.Context, tx sdk.Tx) bool {
+		msgs := tx.GetMsgs()
+		if len(msgs) != 1 || sdk.MsgTypeURL(msgs[0]) != sdk.MsgTypeURL(&testdata.TestMsg{}) {
+			return false
+		}
+
+		signers := msgs[0].GetSigners()
+		if len(signers) != 1 {
+			return false
+		}
+
+		validator, found := s.app.StakingKeeper.GetValidator(ctx, sdk.ValAddress(signers[0]))
+		return found && validator.IsBonded()
+	}
+
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{
+			FeeExempt: feeExempt,
+		}),
+	)
+
+	fee := sdk.NewCoins(sdk.NewInt64Coin("atom", 100))
+	newTx := func(signer sdk.AccAddress) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(signer)))
+		txBuilder.SetFeeAmount(fee)
+		txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+		return txBuilder.GetTx()
+	}
+
+	// the validator tx is exempt
+	operatorBalance := s.app.BankKeeper.GetBalance(ctx, operator, "atom")
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(operator), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(operatorBalance, s.app.BankKeeper.GetBalance(ctx, operator, "atom"))
+
+	// the user tx pays its fees
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(user), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdk.NewInt(900), s.app.BankKeeper.GetBalance(ctx, user, "atom").Amount)
+}
+
+func (s *MWTestSuite) TestDeductFeesMissingFeeCollector() {
+	ctx := s.SetupTest(false) // setup
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	s.Require().NoError(err)
+
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{}),
+	)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(sdk.NewCoins(sdk.NewInt64Coin("atom", 150)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	// remove the fee collector account
+	feeCollector := s.app.AccountKeeper.GetModuleAccount(ctx, types.FeeCollectorName)
+	s.app.AccountKeeper.RemoveAccount(ctx, feeCollector)
+
+	s.Require().NotPanics(func() {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	})
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+	s.Require().Contains(err.Error(), "auth genesis")
+
+	// replace the fee collector by a regular account
+	s.app.AccountKeeper.SetAccount(ctx, types.NewBaseAccountWithAddress(feeCollector.GetAddress()))
+
+	s.Require().NotPanics(func() {
+		_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	})
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+
+	// no fees have been deducted
+	s.Require().Equal(sdk.NewInt(1000), s.app.BankKeeper.GetBalance(ctx, addr1, "atom").Amount)
+}
+
+func (s *MWTestSuite) TestDeductFeesWithStakingRewards() {
+	bondDenom := sdk.DefaultBondDenom
+	stake := func(amount int64) sdk.Coins { return sdk.NewCoins(sdk.NewInt64Coin(bondDenom, amount)) }
+
+	testCases := []struct {
+		name       string
+		fee        int64
+		expErr     bool
+		expBalance int64
+		expRewards bool // whether the rewards are still pending afterwards
+		withdrawTo bool // whether the rewards are withdrawn to another account
+	}{
+		{"sufficient liquid balance", 5, false, 5, true, false},
+		{"insufficient liquid balance, enough rewards", 50, false, 60, false, false},
+		{"insufficient even after rewards", 500, true, 10, true, false},
+		{"rewards withdrawn to another account", 50, true, 10, true, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			ctx := s.SetupTest(false) // setup
+			app := s.app
+
+			// the delegator self-delegates all but 10stake, and has 100stake of
+			// pending rewards
+			addr := simapp.AddTestAddrs(app, ctx, 1, sdk.NewInt(1_000_000))[0]
+			valAddr := sdk.ValAddress(addr)
+			tstaking := teststaking.NewHelper(s.T(), ctx, app.StakingKeeper)
+			tstaking.CreateValidator(valAddr, ed25519.GenPrivKey().PubKey(), sdk.NewInt(1_000_000-10), true)
+			staking.EndBlocker(ctx, app.StakingKeeper)
+			ctx = ctx.WithBlockHeight(ctx.BlockHeight() + 1)
+
+			distrAcc := app.DistrKeeper.GetDistributionAccount(ctx)
+			s.Require().NoError(testutil.FundModuleAccount(app.BankKeeper, ctx, distrAcc.GetName(), stake(100)))
+			app.DistrKeeper.AllocateTokensToValidator(ctx, app.StakingKeeper.Validator(ctx, valAddr), sdk.NewDecCoinsFromCoins(stake(100)...))
+			withdrawAddr := addr
+			if tc.withdrawTo {
+				withdrawAddr = sdk.AccAddress(ed25519.GenPrivKey().PubKey().Address())
+				app.DistrKeeper.SetDelegatorWithdrawAddr(ctx, addr, withdrawAddr)
+			}
+
+			txHandler := middleware.ComposeMiddlewares(
+				noopTxHandler{},
+				middleware.NewDeductFeeMiddleware(app.AccountKeeper, app.BankKeeper, nil, middleware.DeductFeeOptions{
+					DistributionKeeper: app.DistrKeeper,
+					StakingKeeper:      app.StakingKeeper,
+				}),
+			)
+
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr)))
+			txBuilder.SetFeeAmount(stake(tc.fee))
+
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestDeliverTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// the withdrawn rewards may be truncated by one token
+			balance := app.BankKeeper.GetBalance(ctx, addr, bondDenom).Amount
+			s.Require().InDelta(tc.expBalance, balance.Int64(), 1)
+
+			rewards := app.DistrKeeper.GetValidatorCurrentRewards(ctx, valAddr).Rewards
+			s.Require().Equal(tc.expRewards, !rewards.IsZero())
+			if tc.withdrawTo {
+				s.Require().Contains(err.Error(), "staking rewards are withdrawn to "+withdrawAddr.String())
+				s.Require().True(app.BankKeeper.GetAllBalances(ctx, withdrawAddr).IsZero())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+)
+
+// Event type and attribute keys of the event emitted by the FeegrantRemaining
+// middleware.
+const (
+	EventTypeFeegrantRemaining = "feegrant_remaining"
+
+	AttributeKeyGranter   = "granter"
+	AttributeKeyGrantee   = "grantee"
+	AttributeKeyRemaining = "remaining"
+)
+
+type feegrantRemainingTxHandler struct {
+	fk           FeegrantAllowanceKeeper
+	minRemaining sdk.Coins
+	next         tx.Handler
+}
+
+// NewFeegrantRemainingMiddleware returns a middleware that, for txs whose fees
+// are paid through a fee grant, emits a `feegrant_remaining` event holding the
+// allowance left to the grantee after the tx fees. If minRemaining is set, txs
+// which would leave less than minRemaining of the allowance are rejected in
+// CheckTx, so that grantees don't broadcast txs exhausting their allowance.
+//
+// Only allowances with a spend limit are reported. The middleware must be
+// placed after the DeductFee middleware, which uses the granted fees.
+func NewFeegrantRemainingMiddleware(fk FeegrantAllowanceKeeper, minRemaining sdk.Coins) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return feegrantRemainingTxHandler{
+			fk:           fk,
+			minRemaining: minRemaining,
+			next:         txh,
+		}
+	}
+}
+
+var _ tx.Handler = feegrantRemainingTxHandler{}
+
+// remainingAllowance returns the spend limit left in the given allowance,
+// and false if the allowance is not limited.
+func remainingAllowance(allowance feegrant.FeeAllowanceI) (sdk.Coins, bool) {
+	switch allowance := allowance.(type) {
+	case *feegrant.BasicAllowance:
+		return allowance.SpendLimit, allowance.SpendLimit != nil
+	case *feegrant.PeriodicAllowance:
+		// the period limit is capped by the basic spend limit
+		return allowance.PeriodCanSpend, true
+	case *feegrant.AllowedMsgAllowance:
+		inner, err := allowance.GetAllowance()
+		if err != nil {
+			return nil, false
+		}
+
+		return remainingAllowance(inner)
+	default:
+		return nil, false
+	}
+}
+
+// checkRemaining emits the remaining allowance of the tx fee grant, if any. If
+// isCheckTx is set, it also checks the remaining allowance against the
+// configured minimum.
+func (txh feegrantRemainingTxHandler) checkRemaining(ctx context.Context, isCheckTx bool) error {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	granter := GetFeeGranter(sdkCtx)
+	if granter == nil {
+		return nil
+	}
+
+	grantee := GetFeePayer(sdkCtx)
+	var remaining sdk.Coins
+	allowance, err := txh.fk.GetAllowance(sdkCtx, granter, grantee)
+	if err == nil {
+		var limited bool
+		if remaining, limited = remainingAllowance(allowance); !limited {
+			return nil
+		}
+	}
+	// otherwise the grant has been used up and removed by the DeductFee
+	// middleware, nothing remains
+
+	if isCheckTx && !txh.minRemaining.Empty() && !remaining.IsAllGTE(txh.minRemaining) {
+		return sdkerrors.Wrapf(feegrant.ErrFeeLimitExceeded,
+			"remaining fee allowance %s of %s would be below %s", remaining, grantee, txh.minRemaining)
+	}
+
+	sdkCtx.EventManager().EmitEvent(sdk.NewEvent(EventTypeFeegrantRemaining,
+		sdk.NewAttribute(AttributeKeyGranter, granter.String()),
+		sdk.NewAttribute(AttributeKeyGrantee, grantee.String()),
+		sdk.NewAttribute(AttributeKeyRemaining, remaining.String()),
+	))
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh feegrantRemainingTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkRemaining(ctx, true); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh feegrantRemainingTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkRemaining(ctx, false); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh feegrantRemainingTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkRemaining(ctx, false); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/simapp/helpers"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+)
+
+func (s *MWTestSuite) TestFeegrantRemaining() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	priv2, _, addr2 := testdata.KeyTestPubAddr()
+
+	err := testutil.FundAccount(app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 99999)))
+	s.Require().NoError(err)
+	err = app.FeeGrantKeeper.GrantAllowance(ctx, addr1, addr2, &feegrant.BasicAllowance{
+		SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("atom", 500)),
+	})
+	s.Require().NoError(err)
+
+	newTxHandler := func(minRemaining sdk.Coins) txtypes.Handler {
+		return middleware.ComposeMiddlewares(
+			noopTxHandler{},
+			middleware.DeductFeeMiddleware(app.AccountKeeper, app.BankKeeper, app.FeeGrantKeeper),
+			middleware.NewFeegrantRemainingMiddleware(app.FeeGrantKeeper, minRemaining),
+		)
+	}
+
+	testCases := []struct {
+		name         string
+		fee          int64
+		minRemaining sdk.Coins
+		expCheckErr  bool
+		expRemaining string
+	}{
+		{"sufficient grant", 50, nil, false, "450atom"},
+		{"sufficient grant above threshold", 50, sdk.NewCoins(sdk.NewInt64Coin("atom", 400)), false, "450atom"},
+		{"soon exhausted grant", 450, sdk.NewCoins(sdk.NewInt64Coin("atom", 100)), true, "50atom"},
+		{"exhausted grant", 500, nil, false, ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			txHandler := newTxHandler(tc.minRemaining)
+			fee := sdk.NewCoins(sdk.NewInt64Coin("atom", tc.fee))
+			msgs := []sdk.Msg{testdata.NewTestMsg(addr2)}
+			privs, accNums, seqs := []cryptotypes.PrivKey{priv2}, []uint64{0}, []uint64{0}
+			testTx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, helpers.DefaultGenTxGas, ctx.ChainID(), accNums, seqs, addr1, privs...)
+			s.Require().NoError(err)
+
+			cacheCtx, _ := ctx.CacheContext()
+			_, err = txHandler.CheckTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestCheckTx{})
+			if tc.expCheckErr {
+				s.Require().ErrorIs(err, feegrant.ErrFeeLimitExceeded)
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// the threshold is not enforced in DeliverTx
+			cacheCtx, _ = ctx.CacheContext()
+			cacheCtx = cacheCtx.WithEventManager(sdk.NewEventManager())
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+
+			var remaining []sdk.Event
+			for _, event := range cacheCtx.EventManager().Events() {
+				if event.Type == middleware.EventTypeFeegrantRemaining {
+					remaining = append(remaining, event)
+				}
+			}
+			s.Require().Len(remaining, 1)
+			s.Require().Equal([]abci.EventAttribute{
+				{Key: middleware.AttributeKeyGranter, Value: addr1.String()},
+				{Key: middleware.AttributeKeyGrantee, Value: addr2.String()},
+				{Key: middleware.AttributeKeyRemaining, Value: tc.expRemaining},
+			}, remaining[0].Attributes)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware_test
 
 import (
+	"context"
 	"math/rand"
 	"testing"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/simulation"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
 	authsign "github.com/cosmos/cosmos-sdk/x/auth/signing"
@@ -172,6 +174,102 @@ func SigGasNoConsumer(meter sdk.GasMeter, sig []byte, pubkey crypto.PubKey, para
 	return nil
 }
 
+// feePayerRecordingTxHandler is a test tx.Handler recording the fee payer and
+// granter found in the sdk.Context.
+type feePayerRecordingTxHandler struct {
+	payers, granters *[]sdk.AccAddress
+}
+
+var _ txtypes.Handler = feePayerRecordingTxHandler{}
+
+func (txh feePayerRecordingTxHandler) record(ctx context.Context) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	*txh.payers = append(*txh.payers, middleware.GetFeePayer(sdkCtx))
+	*txh.granters = append(*txh.granters, middleware.GetFeeGranter(sdkCtx))
+}
+
+func (txh feePayerRecordingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	txh.record(ctx)
+	return abci.ResponseCheckTx{}, nil
+}
+func (txh feePayerRecordingTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.record(ctx)
+	return abci.ResponseDeliverTx{}, nil
+}
+func (txh feePayerRecordingTxHandler) SimulateTx(ctx context.Context, _ sdk.Tx, _ txtypes.RequestSimulateTx) (txtypes.ResponseSimulateTx, error) {
+	txh.record(ctx)
+	return txtypes.ResponseSimulateTx{}, nil
+}
+
+func (s *MWTestSuite) TestFeePayerAccessors() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	var payers, granters []sdk.AccAddress
+	txHandler := middleware.ComposeMiddlewares(
+		feePayerRecordingTxHandler{payers: &payers, granters: &granters},
+		middleware.DeductFeeMiddleware(
+			s.app.AccountKeeper,
+			s.app.BankKeeper,
+			s.app.FeeGrantKeeper,
+		),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	priv2, _, addr2 := testdata.KeyTestPubAddr()
+
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, []sdk.Coin{sdk.NewCoin("atom", sdk.NewInt(99999))})
+	s.Require().NoError(err)
+	err = app.FeeGrantKeeper.GrantAllowance(ctx, addr1, addr2, &feegrant.BasicAllowance{
+		SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("atom", 500)),
+	})
+	s.Require().NoError(err)
+
+	cases := map[string]struct {
+		signerKey  cryptotypes.PrivKey
+		signer     sdk.AccAddress
+		feeAccount sdk.AccAddress
+	}{
+		"self pay": {
+			signerKey: priv1,
+			signer:    addr1,
+		},
+		"fee grant": {
+			signerKey:  priv2,
+			signer:     addr2,
+			feeAccount: addr1,
+		},
+	}
+
+	for name, stc := range cases {
+		tc := stc // to make scopelint happy
+		s.T().Run(name, func(t *testing.T) {
+			payers, granters = nil, nil
+			fee := sdk.NewCoins(sdk.NewInt64Coin("atom", 50))
+			msgs := []sdk.Msg{testdata.NewTestMsg(tc.signer)}
+			privs, accNums, seqs := []cryptotypes.PrivKey{tc.signerKey}, []uint64{0}, []uint64{0}
+			tx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, helpers.DefaultGenTxGas, ctx.ChainID(), accNums, seqs, tc.feeAccount, privs...)
+			s.Require().NoError(err)
+
+			cacheCtx, _ := ctx.CacheContext()
+			_, err = txHandler.CheckTx(sdk.WrapSDKContext(cacheCtx), tx, abci.RequestCheckTx{})
+			s.Require().NoError(err)
+			cacheCtx, _ = ctx.CacheContext()
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), tx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			cacheCtx, _ = ctx.CacheContext()
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(cacheCtx), tx, txtypes.RequestSimulateTx{})
+			s.Require().NoError(err)
+
+			// the same payer and granter are set in all modes
+			s.Require().Equal([]sdk.AccAddress{tc.signer, tc.signer, tc.signer}, payers)
+			s.Require().Equal([]sdk.AccAddress{tc.feeAccount, tc.feeAccount, tc.feeAccount}, granters)
+		})
+	}
+}
+
 func genTxWithFeeGranter(gen client.TxConfig, msgs []sdk.Msg, feeAmt sdk.Coins, gas uint64, chainID string, accNums,
 	accSeqs []uint64, feeGranter sdk.AccAddress, priv ...cryptotypes.PrivKey) (sdk.Tx, error) {
 	sigs := make([]signing.SignatureV2, len(priv))
@@ -235,3 +333,109 @@ func genTxWithFeeGranter(gen client.TxConfig, msgs []sdk.Msg, feeAmt sdk.Coins,
 
 	return tx.GetTx(), nil
 }
+
+func (s *MWTestSuite) TestDeductFeesFeegrantGracePeriod() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+	ctx = ctx.WithBlockTime(time.Unix(1_000_000, 0))
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr2, []sdk.Coin{sdk.NewCoin("atom", sdk.NewInt(99999))})
+	s.Require().NoError(err)
+
+	basic := func(expiration time.Time) feegrant.FeeAllowanceI {
+		return &feegrant.BasicAllowance{
+			SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("atom", 500)),
+			Expiration: &expiration,
+		}
+	}
+	cases := map[string]struct {
+		graceBlocks uint64
+		expiredFor  time.Duration
+		allowance   func(expiration time.Time) feegrant.FeeAllowanceI
+		valid       bool
+	}{
+		"no grace period": {
+			expiredFor: time.Second,
+			allowance:  basic,
+		},
+		"within the grace period": {
+			graceBlocks: 5,
+			expiredFor:  3 * time.Second,
+			allowance:   basic,
+			valid:       true,
+		},
+		"beyond the grace period": {
+			graceBlocks: 5,
+			expiredFor:  10 * time.Second,
+			allowance:   basic,
+		},
+		"periodic allowance reset after its expiration": {
+			graceBlocks: 5,
+			expiredFor:  3 * time.Second,
+			allowance: func(expiration time.Time) feegrant.FeeAllowanceI {
+				// the period is only reset at the block time, not at the
+				// expiration nor at the start of the grace window
+				return &feegrant.PeriodicAllowance{
+					Basic: feegrant.BasicAllowance{
+						SpendLimit: sdk.NewCoins(sdk.NewInt64Coin("atom", 500)),
+						Expiration: &expiration,
+					},
+					Period:           10 * time.Second,
+					PeriodSpendLimit: sdk.NewCoins(sdk.NewInt64Coin("atom", 100)),
+					PeriodReset:      expiration.Add(time.Second),
+				}
+			},
+			valid: true,
+		},
+	}
+
+	for name, stc := range cases {
+		tc := stc // to make scopelint happy
+		s.T().Run(name, func(t *testing.T) {
+			cacheCtx, _ := ctx.CacheContext()
+			err := app.FeeGrantKeeper.GrantAllowance(cacheCtx, addr2, addr1, tc.allowance(cacheCtx.BlockTime().Add(-tc.expiredFor)))
+			s.Require().NoError(err)
+
+			txHandler := middleware.ComposeMiddlewares(
+				noopTxHandler{},
+				middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{
+					FeegrantGraceBlocks:    tc.graceBlocks,
+					FeegrantGraceBlockTime: time.Second,
+				}),
+			)
+
+			fee := sdk.NewCoins(sdk.NewInt64Coin("atom", 50))
+			msgs := []sdk.Msg{testdata.NewTestMsg(addr1)}
+			tx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, helpers.DefaultGenTxGas, ctx.ChainID(), []uint64{0}, []uint64{0}, addr2, priv1)
+			s.Require().NoError(err)
+
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), tx, abci.RequestDeliverTx{})
+			if tc.valid {
+				s.Require().NoError(err)
+
+				// the grant is still used, and keeps its expiration
+				allowance, err := app.FeeGrantKeeper.GetAllowance(cacheCtx, addr2, addr1)
+				s.Require().NoError(err)
+				basic, ok := allowance.(*feegrant.BasicAllowance)
+				if periodic, isPeriodic := allowance.(*feegrant.PeriodicAllowance); isPeriodic {
+					s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 50)), periodic.PeriodCanSpend)
+					basic, ok = &periodic.Basic, true
+				}
+				s.Require().True(ok)
+				s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 450)), basic.SpendLimit)
+				s.Require().True(cacheCtx.BlockTime().Add(-tc.expiredFor).Equal(*basic.Expiration))
+			} else {
+				s.Require().ErrorIs(err, feegrant.ErrFeeLimitExpired)
+
+				// the grant expired beyond the grace period is revoked
+				_, err = app.FeeGrantKeeper.GetAllowance(cacheCtx, addr2, addr1)
+				s.Require().Error(err)
+			}
+		})
+	}
+}
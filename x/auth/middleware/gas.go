@@ -2,12 +2,15 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
 	abci "github.com/tendermint/tendermint/abci/types"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
 )
 
 // GasTx defines a Tx with a GetGas() method which is needed to use gasTxHandler.
@@ -16,8 +19,117 @@ type GasTx interface {
 	GetGas() uint64
 }
 
+// GasTracer is an optional hook used to inspect the gas consumed by each
+// sdk.Msg of a tx. It is called by the msg router after each msg is executed,
+// with the value of the GasMeter the msg is executed with (the tx GasMeter
+// unless a MsgGasMeterSelector selects another one) before and after the msg
+// execution.
+type GasTracer interface {
+	TraceMsgGas(msgIndex int, msgTypeURL string, gasBefore, gasAfter sdk.Gas)
+}
+
+// gasTracerKey is the sdk.Context key under which the GasTracer is stored.
+type gasTracerKey struct{}
+
+// MsgGasMeterSelector selects the GasMeter an sdk.Msg is executed with. If it
+// returns nil, the msg is executed with the tx GasMeter, bounded by the tx gas
+// limit. The gas consumed on the selected GasMeters is not bounded by the tx
+// gas limit, so it is not part of the GasUsed of the tx: it is reported
+// separately, under the `msg_meter_gas_used` attribute of a `tx` event.
+type MsgGasMeterSelector func(msg sdk.Msg) sdk.GasMeter
+
+// msgGasMeters holds the MsgGasMeterSelector for the msg router to pick up,
+// and the gas the msgs consumed on the selected GasMeters.
+type msgGasMeters struct {
+	selector MsgGasMeterSelector
+	consumed sdk.Gas
+}
+
+// msgGasMetersKey is the sdk.Context key under which the msgGasMeters are
+// stored.
+type msgGasMetersKey struct{}
+
+// anteGasSnapshot holds the gas consumed by a tx before its msgs are
+// executed, i.e. by the middlewares running before the msg router.
+type anteGasSnapshot struct {
+	gas   sdk.Gas
+	taken bool
+}
+
+// anteGasSnapshotKey is the sdk.Context key under which the anteGasSnapshot is
+// stored.
+type anteGasSnapshotKey struct{}
+
+// gasMeteringWaiver holds the infinite GasMeter set by the
+// DisableGasMetering middleware, once the signatures of the tx are verified.
+type gasMeteringWaiver struct {
+	meter sdk.GasMeter
+}
+
+// gasMeteringDisabledKey is the sdk.Context key under which the
+// gasMeteringWaiver of the txs of internal signers is stored.
+type gasMeteringDisabledKey struct{}
+
+// IsGasMeteringDisabled reports whether the tx of the given sdk.Context is
+// executed without gas metering, see GasTxOptions.DisableGasMetering. The fee
+// middlewares don't check nor deduct the fees of such txs. As they run before
+// the signature verification, the fees are waived based on the signers the tx
+// claims, but such a tx fails if its signatures don't verify.
+func IsGasMeteringDisabled(ctx sdk.Context) bool {
+	return gasMeteringWaiverFromContext(ctx) != nil
+}
+
+// gasMeteringWaiverFromContext returns the gasMeteringWaiver set by the Gas
+// middleware, or nil if the tx is metered.
+func gasMeteringWaiverFromContext(ctx sdk.Context) *gasMeteringWaiver {
+	waiver, _ := ctx.Value(gasMeteringDisabledKey{}).(*gasMeteringWaiver)
+	return waiver
+}
+
+// GasTxOptions defines the optional behaviors of the Gas middleware.
+type GasTxOptions struct {
+	// Tracer, if set, is reported the gas consumed by each msg.
+	Tracer GasTracer
+	// MeterSelector, if set, selects the GasMeter each msg is executed with,
+	// see NewGasTxMiddlewareWithMeterSelector.
+	MeterSelector MsgGasMeterSelector
+	// RecordAnteGas adds a `tx` event to the DeliverTx and SimulateTx
+	// responses, holding the gas consumed before the msgs execution, e.g. by
+	// the signature verification and the fee deduction, under the
+	// `ante_gas_used` attribute. The event is added on failures too, and as it
+	// is added by the Gas middleware, it isn't marked by the IndexEvents
+	// middleware.
+	RecordAnteGas bool
+	// DescriptorLogSize, if positive, records the descriptors of the last
+	// DescriptorLogSize gas consumptions of each tx on its GasMeter. When the
+	// tx runs out of gas, the Recovery middleware reports the descriptors
+	// consuming the most gas in the error log. It is off by default, as it
+	// slows down every gas consumption.
+	DescriptorLogSize int
+	// DisableGasMetering executes the txs whose signers are all
+	// InternalSigners with an infinite GasMeter, whatever their gas limit, and
+	// makes the fee middlewares skip their fee checks and deduction, see
+	// IsGasMeteringDisabled. The txs are metered as usual until their
+	// signatures are verified, so their gas limit must still cover the gas
+	// consumed up to the signature verification: the infinite GasMeter is only
+	// set by the DisableGasMetering middleware, which must be placed right
+	// after the SigVerification middleware. It is meant for private chains
+	// only: as the internal signers can then consume unbounded resources for
+	// free, it must never be enabled on a public chain.
+	DisableGasMetering bool
+	// InternalSigners are the signers whose txs are executed without gas
+	// metering. They are ignored unless DisableGasMetering is set, which
+	// requires at least one internal signer.
+	InternalSigners []sdk.AccAddress
+}
+
 type gasTxHandler struct {
-	next tx.Handler
+	tracer          GasTracer
+	meterSelector   MsgGasMeterSelector
+	recordAnteGas   bool
+	logSize         int
+	internalSigners map[string]struct{}
+	next            tx.Handler
 }
 
 // GasTxMiddleware defines a simple middleware that sets a new GasMeter on
@@ -27,6 +139,53 @@ func GasTxMiddleware(txh tx.Handler) tx.Handler {
 	return gasTxHandler{next: txh}
 }
 
+// NewGasTxMiddleware is the same as GasTxMiddleware, but additionally reports
+// the gas consumed by each msg to the given GasTracer. A nil tracer disables
+// tracing.
+func NewGasTxMiddleware(tracer GasTracer) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return gasTxHandler{tracer: tracer, next: txh}
+	}
+}
+
+// NewGasTxMiddlewareWithMeterSelector is the same as NewGasTxMiddleware, but
+// additionally executes each msg with the GasMeter returned by the given
+// selector, e.g. to not bound the gas of governance msgs. The gas consumed on
+// the selected GasMeters does not count against the tx gas limit, and is
+// reported apart from GasUsed, see MsgGasMeterSelector, so that GasUsed never
+// exceeds GasWanted.
+func NewGasTxMiddlewareWithMeterSelector(tracer GasTracer, selector MsgGasMeterSelector) tx.Middleware {
+	return NewGasTxMiddlewareWithOptions(GasTxOptions{Tracer: tracer, MeterSelector: selector})
+}
+
+// NewGasTxMiddlewareWithOptions is the same as GasTxMiddleware, configured
+// with the given options. It panics if DisableGasMetering is set without
+// InternalSigners.
+func NewGasTxMiddlewareWithOptions(opts GasTxOptions) tx.Middleware {
+	var internalSigners map[string]struct{}
+	if opts.DisableGasMetering {
+		if len(opts.InternalSigners) == 0 {
+			panic(fmt.Errorf("gas metering can only be disabled for a non-empty set of internal signers"))
+		}
+
+		internalSigners = make(map[string]struct{}, len(opts.InternalSigners))
+		for _, signer := range opts.InternalSigners {
+			internalSigners[signer.String()] = struct{}{}
+		}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return gasTxHandler{
+			tracer:          opts.Tracer,
+			meterSelector:   opts.MeterSelector,
+			recordAnteGas:   opts.RecordAnteGas,
+			logSize:         opts.DescriptorLogSize,
+			internalSigners: internalSigners,
+			next:            txh,
+		}
+	}
+}
+
 var _ tx.Handler = gasTxHandler{}
 
 // CheckTx implements tx.Handler.CheckTx.
@@ -35,11 +194,17 @@ func (txh gasTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.Request
 	if err != nil {
 		return abci.ResponseCheckTx{}, err
 	}
+	sdkCtx = txh.withDescriptorLog(txh.withGasMeteringWaiver(sdkCtx, tx))
 
-	res, err := txh.next.CheckTx(sdk.WrapSDKContext(sdkCtx), tx, req)
-	res.GasUsed = int64(sdkCtx.GasMeter().GasConsumed())
+	msgCtx, meters := txh.withMsgGasMeters(txh.withTracer(sdkCtx))
+	res, err := txh.next.CheckTx(sdk.WrapSDKContext(msgCtx), tx, req)
+	res.GasUsed = int64(gasConsumed(sdkCtx))
 	res.GasWanted = int64(sdkCtx.GasMeter().Limit())
 
+	if event, ok := msgMetersGasEvent(meters); ok {
+		res.Events = append(res.Events, event)
+	}
+
 	return res, err
 }
 
@@ -49,30 +214,237 @@ func (txh gasTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.Reque
 	if err != nil {
 		return abci.ResponseDeliverTx{}, err
 	}
+	sdkCtx = txh.withDescriptorLog(txh.withGasMeteringWaiver(sdkCtx, tx))
 
-	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(sdkCtx), tx, req)
-	res.GasUsed = int64(sdkCtx.GasMeter().GasConsumed())
+	msgCtx, meters := txh.withMsgGasMeters(txh.withTracer(sdkCtx))
+	msgCtx, snapshot := txh.withAnteGasSnapshot(msgCtx)
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(msgCtx), tx, req)
+	res.GasUsed = int64(gasConsumed(sdkCtx))
 	res.GasWanted = int64(sdkCtx.GasMeter().Limit())
 
+	if event, ok := msgMetersGasEvent(meters); ok {
+		res.Events = append(res.Events, event)
+	}
+
+	if snapshot != nil {
+		res.Events = append(res.Events, anteGasEvent(snapshot, uint64(res.GasUsed)))
+	}
+
 	return res, err
 }
 
 // SimulateTx implements tx.Handler.SimulateTx method.
 func (txh gasTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
-	sdkCtx, err := gasContext(sdk.UnwrapSDKContext(ctx), sdkTx, true)
+	sdkCtx, err := gasContext(sdk.UnwrapSDKContext(ctx), sdkTx, !req.SimulateOptions.EnforceGasLimit)
 	if err != nil {
 		return tx.ResponseSimulateTx{}, err
 	}
+	sdkCtx = txh.withDescriptorLog(txh.withGasMeteringWaiver(sdkCtx, sdkTx))
 
-	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(sdkCtx), sdkTx, req)
+	msgCtx, meters := txh.withMsgGasMeters(txh.withTracer(sdkCtx))
+	msgCtx, snapshot := txh.withAnteGasSnapshot(msgCtx)
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(msgCtx), sdkTx, req)
 	res.GasInfo = sdk.GasInfo{
 		GasWanted: sdkCtx.GasMeter().Limit(),
-		GasUsed:   sdkCtx.GasMeter().GasConsumed(),
+		GasUsed:   gasConsumed(sdkCtx),
+	}
+
+	if event, ok := msgMetersGasEvent(meters); ok && res.Result != nil {
+		res.Result.Events = append(res.Result.Events, event)
+	}
+
+	if snapshot != nil && res.Result != nil {
+		res.Result.Events = append(res.Result.Events, anteGasEvent(snapshot, res.GasInfo.GasUsed))
 	}
 
 	return res, err
 }
 
+// withGasMeteringWaiver sets a gasMeteringWaiver on the sdk.Context, for the
+// fee middlewares to skip the fees and for the DisableGasMetering middleware
+// to set an infinite GasMeter, if gas metering is disabled and all the
+// signers of the tx are internal signers.
+func (txh gasTxHandler) withGasMeteringWaiver(sdkCtx sdk.Context, sdkTx sdk.Tx) sdk.Context {
+	if txh.internalSigners == nil {
+		return sdkCtx
+	}
+
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return sdkCtx
+	}
+
+	signers := sigTx.GetSigners()
+	if len(signers) == 0 {
+		return sdkCtx
+	}
+	for _, signer := range signers {
+		if _, ok := txh.internalSigners[signer.String()]; !ok {
+			return sdkCtx
+		}
+	}
+
+	return sdkCtx.WithValue(gasMeteringDisabledKey{}, &gasMeteringWaiver{})
+}
+
+// gasConsumed returns the gas consumed by the tx of the given sdk.Context,
+// read from the infinite GasMeter set by the DisableGasMetering middleware if
+// any, as it carries over the gas consumed before it.
+func gasConsumed(sdkCtx sdk.Context) sdk.Gas {
+	if waiver := gasMeteringWaiverFromContext(sdkCtx); waiver != nil && waiver.meter != nil {
+		return waiver.meter.GasConsumed()
+	}
+
+	return sdkCtx.GasMeter().GasConsumed()
+}
+
+type disableGasMeteringTxHandler struct {
+	next tx.Handler
+}
+
+// NewDisableGasMeteringMiddleware returns a middleware setting an infinite
+// GasMeter on the sdk.Context of the txs of internal signers, see
+// GasTxOptions.DisableGasMetering. The infinite GasMeter carries over the gas
+// consumed so far. It must be placed right after the SigVerification
+// middleware, so that the txs are metered until their signatures are
+// verified. It is a no-op for the other txs, and if enabled is false.
+func NewDisableGasMeteringMiddleware(enabled bool) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if !enabled {
+			return txh
+		}
+
+		return disableGasMeteringTxHandler{next: txh}
+	}
+}
+
+var _ tx.Handler = disableGasMeteringTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh disableGasMeteringTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(withoutGasMetering(ctx), tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh disableGasMeteringTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(withoutGasMetering(ctx), tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh disableGasMeteringTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(withoutGasMetering(ctx), sdkTx, req)
+}
+
+// withoutGasMetering sets an infinite GasMeter on the context, if the Gas
+// middleware set a gasMeteringWaiver on it, and records it on the waiver for
+// the Gas middleware to report the gas used.
+func withoutGasMetering(ctx context.Context) context.Context {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	waiver := gasMeteringWaiverFromContext(sdkCtx)
+	if waiver == nil {
+		return ctx
+	}
+
+	waiver.meter = sdk.NewInfiniteGasMeter()
+	waiver.meter.ConsumeGas(sdkCtx.GasMeter().GasConsumed(), "gas consumed before the signature verification")
+
+	return sdk.WrapSDKContext(sdkCtx.WithGasMeter(waiver.meter))
+}
+
+// withDescriptorLog wraps the GasMeter of the sdk.Context to record the gas
+// descriptors, if enabled.
+func (txh gasTxHandler) withDescriptorLog(sdkCtx sdk.Context) sdk.Context {
+	if txh.logSize <= 0 {
+		return sdkCtx
+	}
+
+	return sdkCtx.WithGasMeter(newDescriptorLogGasMeter(sdkCtx.GasMeter(), txh.logSize))
+}
+
+// withTracer sets the GasTracer, if any, on the sdk.Context for the msg router
+// to pick up.
+func (txh gasTxHandler) withTracer(sdkCtx sdk.Context) sdk.Context {
+	if txh.tracer == nil {
+		return sdkCtx
+	}
+
+	return sdkCtx.WithValue(gasTracerKey{}, txh.tracer)
+}
+
+// gasTracerFromContext returns the GasTracer set by the Gas middleware, or nil
+// if none is set.
+func gasTracerFromContext(sdkCtx sdk.Context) GasTracer {
+	tracer, _ := sdkCtx.Value(gasTracerKey{}).(GasTracer)
+	return tracer
+}
+
+// withMsgGasMeters sets the MsgGasMeterSelector, if any, on the sdk.Context
+// for the msg router to pick up. The returned msgGasMeters collect the gas
+// consumed on the selected GasMeters.
+func (txh gasTxHandler) withMsgGasMeters(sdkCtx sdk.Context) (sdk.Context, *msgGasMeters) {
+	if txh.meterSelector == nil {
+		return sdkCtx, nil
+	}
+
+	meters := &msgGasMeters{selector: txh.meterSelector}
+	return sdkCtx.WithValue(msgGasMetersKey{}, meters), meters
+}
+
+// msgGasMetersFromContext returns the msgGasMeters set by the Gas middleware,
+// or nil if none are set.
+func msgGasMetersFromContext(sdkCtx sdk.Context) *msgGasMeters {
+	meters, _ := sdkCtx.Value(msgGasMetersKey{}).(*msgGasMeters)
+	return meters
+}
+
+// msgMetersGasEvent returns the event holding the gas consumed on the
+// GasMeters selected for the msgs, if any was consumed.
+func msgMetersGasEvent(meters *msgGasMeters) (abci.Event, bool) {
+	if meters == nil || meters.consumed == 0 {
+		return abci.Event{}, false
+	}
+
+	return abci.Event(sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(sdk.AttributeKeyMsgMeterGasUsed, strconv.FormatUint(meters.consumed, 10)),
+	)), true
+}
+
+// withAnteGasSnapshot sets an anteGasSnapshot on the sdk.Context, if ante gas
+// recording is enabled, for the msg router to take.
+func (txh gasTxHandler) withAnteGasSnapshot(sdkCtx sdk.Context) (sdk.Context, *anteGasSnapshot) {
+	if !txh.recordAnteGas {
+		return sdkCtx, nil
+	}
+
+	snapshot := &anteGasSnapshot{}
+	return sdkCtx.WithValue(anteGasSnapshotKey{}, snapshot), snapshot
+}
+
+// markAnteGasBoundary takes the anteGasSnapshot set by the Gas middleware, if
+// any. It is called by the msg router right before executing the msgs.
+func markAnteGasBoundary(sdkCtx sdk.Context) {
+	snapshot, _ := sdkCtx.Value(anteGasSnapshotKey{}).(*anteGasSnapshot)
+	if snapshot == nil || snapshot.taken {
+		return
+	}
+
+	snapshot.gas = sdkCtx.GasMeter().GasConsumed()
+	snapshot.taken = true
+}
+
+// anteGasEvent returns the event holding the ante gas of the snapshot. If the
+// tx failed before its msgs were executed, all of its gas is ante gas.
+func anteGasEvent(snapshot *anteGasSnapshot, gasUsed sdk.Gas) abci.Event {
+	anteGas := gasUsed
+	if snapshot.taken {
+		anteGas = snapshot.gas
+	}
+
+	return abci.Event(sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(sdk.AttributeKeyAnteGasUsed, strconv.FormatUint(anteGas, 10)),
+	))
+}
+
 // gasContext returns a new context with a gas meter set from a given context.
 func gasContext(ctx sdk.Context, tx sdk.Tx, isSimulate bool) (sdk.Context, error) {
 	// all transactions must implement GasTx
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type gasDeclarationTxHandler struct {
+	maxOverDeclarePct float64
+	next              tx.Handler
+}
+
+// NewGasDeclarationMiddleware returns a mempool heuristic keeping the block
+// gas accurate: in CheckTx, once the inner middlewares have accepted a new tx,
+// i.e. verified its signatures and deducted its fee, it simulates the tx and
+// rejects, with ErrInvalidRequest, the txs whose gas limit is more than
+// maxOverDeclarePct percent above the simulated gas used. Rechecked txs are
+// not simulated again, and DeliverTx is not affected. A non-positive
+// maxOverDeclarePct disables the middleware.
+//
+// The simulation runs on a branch of the state from before the inner CheckTx,
+// which is discarded, and is metered with the tx's gas limit. If it fails,
+// e.g. by running out of gas, the tx isn't rejected by this middleware. The
+// state changes of the inner CheckTx are only kept if the tx is accepted. The
+// gas used is read from the simulation response GasInfo, so this middleware
+// must be placed outside of the Gas middleware.
+// CONTRACT: Tx must implement GasTx interface
+func NewGasDeclarationMiddleware(maxOverDeclarePct float64) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if maxOverDeclarePct <= 0 {
+			return txh
+		}
+
+		return gasDeclarationTxHandler{
+			maxOverDeclarePct: maxOverDeclarePct,
+			next:              txh,
+		}
+	}
+}
+
+var _ tx.Handler = gasDeclarationTxHandler{}
+
+// simulateGasUsed simulates the tx on the given sdk.Context, metered with the
+// tx's gas limit, and returns its gas used. ok is false if the simulation
+// failed or ran out of gas.
+func (txh gasDeclarationTxHandler) simulateGasUsed(simCtx sdk.Context, sdkTx sdk.Tx, txBytes []byte) (gasUsed uint64, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, outOfGas := r.(sdk.ErrorOutOfGas); !outOfGas {
+				panic(r)
+			}
+			gasUsed, ok = 0, false
+		}
+	}()
+
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(simCtx), sdkTx, tx.RequestSimulateTx{
+		TxBytes:         txBytes,
+		SimulateOptions: tx.SimulateOptions{EnforceGasLimit: true},
+	})
+	if err != nil {
+		return 0, false
+	}
+
+	return res.GasInfo.GasUsed, true
+}
+
+// checkGasDeclaration checks the gas limit of the tx against its gas used,
+// simulated on the given sdk.Context.
+func (txh gasDeclarationTxHandler) checkGasDeclaration(simCtx sdk.Context, sdkTx sdk.Tx, txBytes []byte) error {
+	gasTx, ok := sdkTx.(GasTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be GasTx")
+	}
+
+	estimate, ok := txh.simulateGasUsed(simCtx, sdkTx, txBytes)
+	if !ok {
+		return nil
+	}
+
+	// this is a local mempool heuristic, floating point arithmetic is fine
+	maxGas := float64(estimate) * (1 + txh.maxOverDeclarePct/100)
+	if float64(gasTx.GetGas()) > maxGas {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest,
+			"gas limit %d is more than %g%% above the estimated gas %d", gasTx.GetGas(), txh.maxOverDeclarePct, estimate)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh gasDeclarationTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if req.Type == abci.CheckTxType_Recheck {
+		return txh.next.CheckTx(ctx, sdkTx, req)
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	simCtx := sdkCtx.
+		WithMultiStore(sdkCtx.MultiStore().CacheMultiStore()).
+		WithEventManager(sdk.NewEventManager())
+
+	msCache := sdkCtx.MultiStore().CacheMultiStore()
+	res, err := txh.next.CheckTx(sdk.WrapSDKContext(sdkCtx.WithMultiStore(msCache)), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	if err := txh.checkGasDeclaration(simCtx, sdkTx, req.Tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh gasDeclarationTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh gasDeclarationTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
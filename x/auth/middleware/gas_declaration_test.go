@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// simulateLimitTxHandler is a gasUsingTxHandler recording the gas limit of
+// the simulations.
+type simulateLimitTxHandler struct {
+	gasUsingTxHandler
+	limits *[]uint64
+}
+
+func (txh simulateLimitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req txtypes.RequestSimulateTx) (txtypes.ResponseSimulateTx, error) {
+	*txh.limits = append(*txh.limits, sdk.UnwrapSDKContext(ctx).GasMeter().Limit())
+	return txh.gasUsingTxHandler.SimulateTx(ctx, sdkTx, req)
+}
+
+func (s *MWTestSuite) TestGasDeclarationMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name     string
+		gasLimit uint64
+		recheck  bool
+		rejected bool
+	}{
+		{"gas limit equal to the estimate", 10000, false, false},
+		{"gas limit within tolerance", 12000, false, false},
+		{"grossly over-declared gas limit", 100000, false, true},
+		{"over-declared gas limit on recheck", 100000, true, false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txHandler := middleware.ComposeMiddlewares(
+				gasUsingTxHandler{gasUsed: 10000},
+				middleware.NewGasDeclarationMiddleware(20),
+				middleware.GasTxMiddleware,
+			)
+
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetGasLimit(tc.gasLimit)
+
+			req := abci.RequestCheckTx{}
+			if tc.recheck {
+				req.Type = abci.CheckTxType_Recheck
+			}
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), req)
+			if tc.rejected {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// DeliverTx is never affected
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
+
+func (s *MWTestSuite) TestGasDeclarationMiddlewareSimulation() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetGasLimit(11000)
+
+	// the simulation is metered with the tx's gas limit
+	var limits []uint64
+	txHandler := middleware.ComposeMiddlewares(
+		simulateLimitTxHandler{gasUsingTxHandler{gasUsed: 10000}, &limits},
+		middleware.NewGasDeclarationMiddleware(20),
+		middleware.GasTxMiddleware,
+	)
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]uint64{11000}, limits)
+
+	// a tx rejected by the inner middlewares is not simulated
+	limits = nil
+	txHandler = middleware.ComposeMiddlewares(
+		simulateLimitTxHandler{gasUsingTxHandler{gasUsed: 10000, err: sdkerrors.ErrUnauthorized}, &limits},
+		middleware.NewGasDeclarationMiddleware(20),
+		middleware.GasTxMiddleware,
+	)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().Empty(limits)
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// maxReportedGasConsumers is the number of descriptors reported in the
+// out-of-gas error log.
+const maxReportedGasConsumers = 5
+
+// gasDescriptorEntry is a single ConsumeGas call recorded by a
+// descriptorLogGasMeter.
+type gasDescriptorEntry struct {
+	descriptor string
+	amount     sdk.Gas
+}
+
+// descriptorLogGasMeter is a GasMeter recording the descriptors of its last
+// ConsumeGas calls, in a ring buffer.
+type descriptorLogGasMeter struct {
+	sdk.GasMeter
+	entries []gasDescriptorEntry
+	next    int
+	full    bool
+}
+
+var _ sdk.GasMeter = &descriptorLogGasMeter{}
+
+// newDescriptorLogGasMeter wraps the given GasMeter, recording the descriptors
+// of its last size ConsumeGas calls.
+func newDescriptorLogGasMeter(meter sdk.GasMeter, size int) *descriptorLogGasMeter {
+	return &descriptorLogGasMeter{
+		GasMeter: meter,
+		entries:  make([]gasDescriptorEntry, size),
+	}
+}
+
+// ConsumeGas implements sdk.GasMeter.ConsumeGas. The call is recorded before
+// being forwarded, so that the call running out of gas is recorded too.
+func (g *descriptorLogGasMeter) ConsumeGas(amount sdk.Gas, descriptor string) {
+	g.entries[g.next] = gasDescriptorEntry{descriptor: descriptor, amount: amount}
+	g.next = (g.next + 1) % len(g.entries)
+	if g.next == 0 {
+		g.full = true
+	}
+
+	g.GasMeter.ConsumeGas(amount, descriptor)
+}
+
+// topConsumers returns the recorded descriptors consuming the most gas, with
+// the gas they consumed, in decreasing order of gas.
+func (g *descriptorLogGasMeter) topConsumers(n int) []gasDescriptorEntry {
+	entries := g.entries[:g.next]
+	if g.full {
+		entries = g.entries
+	}
+
+	byDescriptor := make(map[string]sdk.Gas)
+	for _, entry := range entries {
+		byDescriptor[entry.descriptor] += entry.amount
+	}
+
+	consumers := make([]gasDescriptorEntry, 0, len(byDescriptor))
+	for descriptor, amount := range byDescriptor {
+		consumers = append(consumers, gasDescriptorEntry{descriptor: descriptor, amount: amount})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].amount != consumers[j].amount {
+			return consumers[i].amount > consumers[j].amount
+		}
+		return consumers[i].descriptor < consumers[j].descriptor
+	})
+
+	if len(consumers) > n {
+		consumers = consumers[:n]
+	}
+
+	return consumers
+}
+
+// gasConsumersLog returns the top gas consumers recorded by the GasMeter of
+// the given context, formatted for the out-of-gas error log, or an empty
+// string if the GasMeter doesn't record its descriptors.
+func gasConsumersLog(sdkCtx sdk.Context) string {
+	meter, ok := sdkCtx.GasMeter().(*descriptorLogGasMeter)
+	if !ok {
+		return ""
+	}
+
+	consumers := meter.topConsumers(maxReportedGasConsumers)
+	parts := make([]string, len(consumers))
+	for i, consumer := range consumers {
+		parts[i] = fmt.Sprintf("%s: %d", consumer.descriptor, consumer.amount)
+	}
+
+	return fmt.Sprintf("; top gas consumers: %s", strings.Join(parts, ", "))
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/types"
+)
+
+// RefundTarget defines where the GasRefund middleware sends the refunds.
+type RefundTarget int
+
+const (
+	// RefundTargetPayer refunds the account which paid the fees.
+	RefundTargetPayer RefundTarget = iota
+	// RefundTargetCommunityPool sends the refunds to the community pool.
+	RefundTargetCommunityPool
+	// RefundTargetBurn burns the refunds.
+	RefundTargetBurn
+)
+
+// String implements fmt.Stringer.
+func (t RefundTarget) String() string {
+	switch t {
+	case RefundTargetPayer:
+		return "payer"
+	case RefundTargetCommunityPool:
+		return "community_pool"
+	case RefundTargetBurn:
+		return "burn"
+	default:
+		return fmt.Sprintf("RefundTarget(%d)", int(t))
+	}
+}
+
+// GasRefundOptions defines the optional behaviors of the GasRefund
+// middleware.
+type GasRefundOptions struct {
+	// Target defines where the refunds go, by default to the fee payer.
+	// RefundTargetBurn requires the bank keeper to implement
+	// RefundBurnerBankKeeper and the fee collector module account to have the
+	// Burner permission.
+	Target RefundTarget
+	// CommunityPoolKeeper is required by RefundTargetCommunityPool.
+	CommunityPoolKeeper CommunityPoolKeeper
+}
+
+// AttributeKeyRefundTarget is the attribute key of the refund target in the
+// gas refund event.
+const AttributeKeyRefundTarget = "refund_target"
+
+type gasRefundTxHandler struct {
+	bankKeeper  RefundBankKeeper
+	refundRatio sdk.Dec
+	opts        GasRefundOptions
+	next        tx.Handler
+}
+
+// NewGasRefundMiddleware returns a middleware that, after a successful
+// DeliverTx, refunds the fees paid for the unused gas of the tx, i.e. its gas
+// limit minus the gas used, multiplied by refundRatio. Only the fees credited
+// to the fee collector are refunded, i.e. neither the burned fees, see
+// DeductFeeOptions.BurnFraction, nor the fees sent to other collectors, see
+// DeductFeeOptions.FeeSplits. The refund is sent from the fee collector to the
+// account which paid the fees, i.e. the fee granter for feegranted txs, and is
+// rounded down in each denom. See NewGasRefundMiddlewareWithOptions to send
+// the refunds elsewhere.
+//
+// This middleware must be placed inside of the Gas middleware, which sets the
+// GasMeter read here, and after the DeductFee middleware, which resolves the
+// account to refund. The refund itself doesn't consume the tx's gas.
+//
+// It panics if refundRatio is not in [0, 1].
+// CONTRACT: Tx must implement FeeTx interface
+func NewGasRefundMiddleware(bankKeeper RefundBankKeeper, refundRatio sdk.Dec) tx.Middleware {
+	return NewGasRefundMiddlewareWithOptions(bankKeeper, refundRatio, GasRefundOptions{})
+}
+
+// NewGasRefundMiddlewareWithOptions is the same as NewGasRefundMiddleware,
+// with the refunds sent to the target of the given GasRefundOptions.
+//
+// It panics if refundRatio is not in [0, 1], or if the keepers needed by the
+// target are missing.
+// CONTRACT: Tx must implement FeeTx interface
+func NewGasRefundMiddlewareWithOptions(bankKeeper RefundBankKeeper, refundRatio sdk.Dec, opts GasRefundOptions) tx.Middleware {
+	if refundRatio.IsNil() || refundRatio.IsNegative() || refundRatio.GT(sdk.OneDec()) {
+		panic(fmt.Sprintf("invalid gas refund ratio %s, must be between 0 and 1", refundRatio))
+	}
+
+	switch opts.Target {
+	case RefundTargetPayer:
+	case RefundTargetCommunityPool:
+		if opts.CommunityPoolKeeper == nil {
+			panic("the community pool refund target requires a CommunityPoolKeeper")
+		}
+	case RefundTargetBurn:
+		if _, ok := bankKeeper.(RefundBurnerBankKeeper); !ok {
+			panic(fmt.Sprintf("bank keeper %T can't burn refunds", bankKeeper))
+		}
+	default:
+		panic(fmt.Sprintf("invalid gas refund target %s", opts.Target))
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return gasRefundTxHandler{
+			bankKeeper:  bankKeeper,
+			refundRatio: refundRatio,
+			opts:        opts,
+			next:        txh,
+		}
+	}
+}
+
+var _ tx.Handler = gasRefundTxHandler{}
+
+// computeGasRefund returns the part of fee paid for the unused gas, multiplied
+// by refundRatio and rounded down. fee is the part of the tx fee credited to
+// the fee collector.
+func computeGasRefund(fee sdk.Coins, gasWanted, gasUsed uint64, refundRatio sdk.Dec) sdk.Coins {
+	if gasWanted == 0 || gasUsed >= gasWanted {
+		return nil
+	}
+
+	// refund = floor(fee * (gasWanted - gasUsed) / gasWanted * refundRatio)
+	unused := sdk.NewDecFromInt(sdk.NewIntFromUint64(gasWanted - gasUsed))
+	wanted := sdk.NewDecFromInt(sdk.NewIntFromUint64(gasWanted))
+
+	refund := sdk.NewCoins()
+	for _, coin := range fee {
+		amt := coin.Amount.ToDec().Mul(unused).Quo(wanted).Mul(refundRatio).TruncateInt()
+		refund = refund.Add(sdk.NewCoin(coin.Denom, amt))
+	}
+
+	return refund
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh gasRefundTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh gasRefundTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return abci.ResponseDeliverTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	// no fee was deducted
+	if IsGasMeteringDisabled(sdkCtx) || isFeeExempt(sdkCtx) {
+		return res, nil
+	}
+
+	// the gas used is the one reported by the Gas middleware
+	gasUsed := sdkCtx.GasMeter().GasConsumed()
+	refund := computeGasRefund(collectedFees(sdkCtx), feeTx.GetGas(), gasUsed, txh.refundRatio)
+	if refund.IsZero() {
+		return res, nil
+	}
+
+	// don't charge the refund to the tx, so that the gas used stays the one the
+	// refund was computed from
+	refundCtx := sdkCtx.WithGasMeter(sdk.NewInfiniteGasMeter())
+	if err := txh.sendRefund(refundCtx, refund); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	// the msgs events are already collected, append the refund event to them
+	res.Events = append(res.Events, sdk.Events{sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(sdk.AttributeKeyFeeRefund, refund.String()),
+		sdk.NewAttribute(AttributeKeyRefundTarget, txh.opts.Target.String()),
+	)}.ToABCIEvents()...)
+
+	return res, nil
+}
+
+// sendRefund sends the refund from the fee collector to the refund target.
+func (txh gasRefundTxHandler) sendRefund(sdkCtx sdk.Context, refund sdk.Coins) error {
+	switch txh.opts.Target {
+	case RefundTargetCommunityPool:
+		return txh.opts.CommunityPoolKeeper.FundCommunityPool(sdkCtx, refund, types.NewModuleAddress(types.FeeCollectorName))
+	case RefundTargetBurn:
+		return txh.bankKeeper.(RefundBurnerBankKeeper).BurnCoins(sdkCtx, types.FeeCollectorName, refund)
+	default:
+		recipient := GetFeeGranter(sdkCtx)
+		if recipient == nil {
+			recipient = GetFeePayer(sdkCtx)
+		}
+		if recipient == nil {
+			return sdkerrors.Wrap(sdkerrors.ErrLogic, "no fee payer to refund, the DeductFee middleware must run before the GasRefund middleware")
+		}
+
+		return txh.bankKeeper.SendCoinsFromModuleToAccount(sdkCtx, types.FeeCollectorName, recipient, refund)
+	}
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh gasRefundTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,279 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+	"github.com/cosmos/cosmos-sdk/x/feegrant"
+)
+
+// gasUsingTxHandler is a test tx.Handler consuming gas up to a total of
+// gasUsed on the tx GasMeter, then returning err.
+type gasUsingTxHandler struct {
+	gasUsed uint64
+	err     error
+}
+
+var _ txtypes.Handler = gasUsingTxHandler{}
+
+func (txh gasUsingTxHandler) consume(ctx context.Context) {
+	meter := sdk.UnwrapSDKContext(ctx).GasMeter()
+	meter.ConsumeGas(txh.gasUsed-meter.GasConsumed(), "test")
+}
+
+func (txh gasUsingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	txh.consume(ctx)
+	return abci.ResponseCheckTx{}, txh.err
+}
+func (txh gasUsingTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.consume(ctx)
+	return abci.ResponseDeliverTx{}, txh.err
+}
+func (txh gasUsingTxHandler) SimulateTx(ctx context.Context, _ sdk.Tx, _ txtypes.RequestSimulateTx) (txtypes.ResponseSimulateTx, error) {
+	txh.consume(ctx)
+	return txtypes.ResponseSimulateTx{}, txh.err
+}
+
+func (s *MWTestSuite) TestGasRefundMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	priv2, _, addr2 := testdata.KeyTestPubAddr()
+
+	initialBalance := sdk.NewInt64Coin("atom", 100000)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(initialBalance))
+	s.Require().NoError(err)
+	err = testutil.FundAccount(s.app.BankKeeper, ctx, addr2, sdk.NewCoins(initialBalance))
+	s.Require().NoError(err)
+	err = app.FeeGrantKeeper.GrantAllowance(ctx, addr1, addr2, &feegrant.BasicAllowance{})
+	s.Require().NoError(err)
+
+	const gasLimit = 100000
+	refundRatio := sdk.NewDecWithPrec(5, 1)
+
+	cases := map[string]struct {
+		signerKey  cryptotypes.PrivKey
+		signer     sdk.AccAddress
+		feeAccount sdk.AccAddress
+		fee        int64
+		gasUsed    uint64
+		err        error
+		expRefund  int64
+	}{
+		"refund half of the unused gas": {
+			signerKey: priv1,
+			signer:    addr1,
+			fee:       1000,
+			gasUsed:   40000,
+			// 1000 * 60000 / 100000 * 0.5
+			expRefund: 300,
+		},
+		"refund is rounded down": {
+			signerKey: priv1,
+			signer:    addr1,
+			fee:       999,
+			gasUsed:   40000,
+			// 999 * 60000 / 100000 * 0.5 = 299.7
+			expRefund: 299,
+		},
+		"no unused gas": {
+			signerKey: priv1,
+			signer:    addr1,
+			fee:       1000,
+			gasUsed:   gasLimit,
+			expRefund: 0,
+		},
+		"feegranted tx refunds the granter": {
+			signerKey:  priv2,
+			signer:     addr2,
+			feeAccount: addr1,
+			fee:        1000,
+			gasUsed:    40000,
+			expRefund:  300,
+		},
+		"failed tx refunds nothing": {
+			signerKey: priv1,
+			signer:    addr1,
+			fee:       1000,
+			gasUsed:   40000,
+			err:       sdkerrors.ErrInvalidRequest,
+			expRefund: 0,
+		},
+	}
+
+	for name, stc := range cases {
+		tc := stc // to make scopelint happy
+		s.T().Run(name, func(t *testing.T) {
+			txHandler := middleware.ComposeMiddlewares(
+				gasUsingTxHandler{gasUsed: tc.gasUsed, err: tc.err},
+				middleware.GasTxMiddleware,
+				middleware.DeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper),
+				middleware.NewGasRefundMiddleware(s.app.BankKeeper, refundRatio),
+			)
+
+			fee := sdk.NewCoins(sdk.NewInt64Coin("atom", tc.fee))
+			msgs := []sdk.Msg{testdata.NewTestMsg(tc.signer)}
+			privs, accNums, seqs := []cryptotypes.PrivKey{tc.signerKey}, []uint64{0}, []uint64{0}
+			testTx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, gasLimit, ctx.ChainID(), accNums, seqs, tc.feeAccount, privs...)
+			s.Require().NoError(err)
+
+			feeAccount := tc.signer
+			if tc.feeAccount != nil {
+				feeAccount = tc.feeAccount
+			}
+
+			cacheCtx, _ := ctx.CacheContext()
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestDeliverTx{})
+			if tc.err != nil {
+				s.Require().True(errors.Is(err, tc.err))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			expBalance := initialBalance.SubAmount(sdk.NewInt(tc.fee)).AddAmount(sdk.NewInt(tc.expRefund))
+			s.Require().Equal(expBalance, s.app.BankKeeper.GetBalance(cacheCtx, feeAccount, "atom"))
+		})
+	}
+}
+
+func (s *MWTestSuite) TestGasRefundMiddlewareTargets() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	initialBalance := sdk.NewInt64Coin("atom", 100000)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(initialBalance))
+	s.Require().NoError(err)
+
+	const gasLimit = 100000
+	fee := sdk.NewCoins(sdk.NewInt64Coin("atom", 999))
+	msgs := []sdk.Msg{testdata.NewTestMsg(addr1)}
+	testTx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, gasLimit, ctx.ChainID(), []uint64{0}, []uint64{0}, nil, priv1)
+	s.Require().NoError(err)
+
+	// 999 * 60000 / 100000 * 0.5 = 299.7 is rounded down
+	refund := sdk.NewInt(299)
+
+	cases := map[string]struct {
+		target          middleware.RefundTarget
+		err             error
+		expPayerRefund  sdk.Int
+		expPoolRefund   sdk.Int
+		expBurnedRefund sdk.Int
+	}{
+		"payer":           {middleware.RefundTargetPayer, nil, refund, sdk.ZeroInt(), sdk.ZeroInt()},
+		"community pool":  {middleware.RefundTargetCommunityPool, nil, sdk.ZeroInt(), refund, sdk.ZeroInt()},
+		"burn":            {middleware.RefundTargetBurn, nil, sdk.ZeroInt(), sdk.ZeroInt(), refund},
+		"failed tx, pool": {middleware.RefundTargetCommunityPool, sdkerrors.ErrInvalidRequest, sdk.ZeroInt(), sdk.ZeroInt(), sdk.ZeroInt()},
+		"failed tx, burn": {middleware.RefundTargetBurn, sdkerrors.ErrInvalidRequest, sdk.ZeroInt(), sdk.ZeroInt(), sdk.ZeroInt()},
+	}
+
+	for name, stc := range cases {
+		tc := stc // to make scopelint happy
+		s.T().Run(name, func(t *testing.T) {
+			txHandler := middleware.ComposeMiddlewares(
+				gasUsingTxHandler{gasUsed: 40000, err: tc.err},
+				middleware.GasTxMiddleware,
+				middleware.DeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper),
+				middleware.NewGasRefundMiddlewareWithOptions(s.app.BankKeeper, sdk.NewDecWithPrec(5, 1), middleware.GasRefundOptions{
+					Target:              tc.target,
+					CommunityPoolKeeper: s.app.DistrKeeper,
+				}),
+			)
+
+			cacheCtx, _ := ctx.CacheContext()
+			poolBefore := s.app.DistrKeeper.GetFeePoolCommunityCoins(cacheCtx).AmountOf("atom")
+			supplyBefore := s.app.BankKeeper.GetSupply(cacheCtx, "atom").Amount
+
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestDeliverTx{})
+			if tc.err != nil {
+				s.Require().True(errors.Is(err, tc.err))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			expBalance := initialBalance.SubAmount(fee.AmountOf("atom")).AddAmount(tc.expPayerRefund)
+			s.Require().Equal(expBalance, s.app.BankKeeper.GetBalance(cacheCtx, addr1, "atom"))
+			poolRefund := s.app.DistrKeeper.GetFeePoolCommunityCoins(cacheCtx).AmountOf("atom").Sub(poolBefore)
+			s.Require().True(tc.expPoolRefund.ToDec().Equal(poolRefund), poolRefund)
+			burned := supplyBefore.Sub(s.app.BankKeeper.GetSupply(cacheCtx, "atom").Amount)
+			s.Require().True(tc.expBurnedRefund.Equal(burned), burned)
+		})
+	}
+
+	// the community pool target requires a CommunityPoolKeeper
+	s.Require().Panics(func() {
+		middleware.NewGasRefundMiddlewareWithOptions(s.app.BankKeeper, sdk.NewDecWithPrec(5, 1), middleware.GasRefundOptions{
+			Target: middleware.RefundTargetCommunityPool,
+		})
+	})
+}
+
+// TestGasRefundMiddlewareFeeSplitsAndBurn checks that only the fees left in
+// the fee collector once burned and split are refunded.
+func (s *MWTestSuite) TestGasRefundMiddlewareFeeSplitsAndBurn() {
+	ctx := s.SetupTest(false) // setup
+	app := s.app
+
+	protoTxCfg := tx.NewTxConfig(codec.NewProtoCodec(app.InterfaceRegistry()), tx.DefaultSignModes)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	initialBalance := sdk.NewInt64Coin("atom", 100000)
+	err := testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(initialBalance))
+	s.Require().NoError(err)
+
+	feeCollector := s.app.AccountKeeper.GetModuleAddress(types.FeeCollectorName)
+	_, _, grantsPool := testdata.KeyTestPubAddr()
+	txHandler := middleware.ComposeMiddlewares(
+		gasUsingTxHandler{gasUsed: 400000},
+		middleware.GasTxMiddleware,
+		middleware.NewDeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper, middleware.DeductFeeOptions{
+			FeeSplits:    []middleware.FeeSplit{{feeCollector, 5000}, {grantsPool, 5000}},
+			BurnFraction: sdk.NewDecWithPrec(2, 1),
+		}),
+		middleware.NewGasRefundMiddleware(s.app.BankKeeper, sdk.NewDecWithPrec(5, 1)),
+	)
+
+	const gasLimit = 1000000
+	fee := sdk.NewCoins(sdk.NewInt64Coin("atom", 1000))
+	msgs := []sdk.Msg{testdata.NewTestMsg(addr1)}
+	testTx, err := genTxWithFeeGranter(protoTxCfg, msgs, fee, gasLimit, ctx.ChainID(), []uint64{0}, []uint64{0}, nil, priv1)
+	s.Require().NoError(err)
+
+	collectorBefore := s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom")
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// 200 are burned, and the 800 left are split between the fee collector
+	// and the grants pool, so 400 * 600000 / 1000000 * 0.5 are refunded
+	s.Require().Equal(initialBalance.SubAmount(sdk.NewInt(1000)).AddAmount(sdk.NewInt(120)), s.app.BankKeeper.GetBalance(ctx, addr1, "atom"))
+	s.Require().Equal(sdk.NewInt(280), s.app.BankKeeper.GetBalance(ctx, feeCollector, "atom").Amount.Sub(collectorBefore.Amount))
+	s.Require().Equal(sdk.NewInt(400), s.app.BankKeeper.GetBalance(ctx, grantsPool, "atom").Amount)
+}
+
+func (s *MWTestSuite) TestGasRefundMiddlewareInvalidRatio() {
+	s.Require().Panics(func() {
+		middleware.NewGasRefundMiddleware(s.app.BankKeeper, sdk.NewDecWithPrec(15, 1))
+	})
+	s.Require().Panics(func() {
+		middleware.NewGasRefundMiddleware(s.app.BankKeeper, sdk.NewDec(-1))
+	})
+}
@@ -3,6 +3,8 @@ package middleware_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 
 	abci "github.com/tendermint/tendermint/abci/types"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
 	"github.com/cosmos/cosmos-sdk/x/auth/signing"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
 )
 
 // txTest is a dummy tx that doesn't implement GasTx. It should set the GasMeter
@@ -89,6 +93,179 @@ func (s *MWTestSuite) TestRecoverPanic() {
 	s.Require().Panics(func() { txHandler.CheckTx(sdk.WrapSDKContext(ctx), tx, abci.RequestCheckTx{Tx: txBytes}) }, "Recovered from non-Out-of-Gas panic")
 }
 
+func (s *MWTestSuite) TestGasDescriptorLog() {
+	tx, txBytes, ctx, gasLimit := s.setupGasTx()
+
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		for i := 0; i < 3; i++ {
+			ctx.GasMeter().ConsumeGas(1000, "alpha")
+		}
+		ctx.GasMeter().ConsumeGas(500, "beta")
+		ctx.GasMeter().ConsumeGas(gasLimit, "gamma")
+
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	testCases := []struct {
+		name    string
+		logSize int
+		expLog  string
+	}{
+		{"off", 0, ""},
+		{"all consumptions recorded", 10, fmt.Sprintf("; top gas consumers: gamma: %d, alpha: 3000, beta: 500", gasLimit)},
+		{"last consumptions recorded", 2, fmt.Sprintf("; top gas consumers: gamma: %d, beta: 500", gasLimit)},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txHandler := middleware.ComposeMiddlewares(
+				middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+				middleware.NewGasTxMiddlewareWithOptions(middleware.GasTxOptions{DescriptorLogSize: tc.logSize}),
+				middleware.RecoveryTxMiddleware,
+			)
+
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), tx, abci.RequestDeliverTx{Tx: txBytes})
+			s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+
+			expLog := fmt.Sprintf("out of gas in location: gamma; gasWanted: %d, gasUsed: %d%s: out of gas", gasLimit, gasLimit+3500, tc.expLog)
+			s.Require().Equal(expLog, err.Error())
+		})
+	}
+}
+
+// gasTraceEntry records a single call to GasTracer.TraceMsgGas.
+type gasTraceEntry struct {
+	msgIndex   int
+	msgTypeURL string
+	gasBefore  sdk.Gas
+	gasAfter   sdk.Gas
+}
+
+// recordingGasTracer is a GasTracer that records all its calls.
+type recordingGasTracer struct {
+	entries []gasTraceEntry
+}
+
+var _ middleware.GasTracer = &recordingGasTracer{}
+
+func (t *recordingGasTracer) TraceMsgGas(msgIndex int, msgTypeURL string, gasBefore, gasAfter sdk.Gas) {
+	t.entries = append(t.entries, gasTraceEntry{msgIndex, msgTypeURL, gasBefore, gasAfter})
+}
+
+func (s *MWTestSuite) TestGasTracer() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	// each TestMsg consumes 1000 gas per signer
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(uint64(1000*len(msg.GetSigners())), "test msg")
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	tracer := &recordingGasTracer{}
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewGasTxMiddleware(tracer),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	msgs := []sdk.Msg{testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr1, addr2)}
+	s.Require().NoError(txBuilder.SetMsgs(msgs...))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	ctx = ctx.WithBlockHeight(1)
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+
+	s.Require().Len(tracer.entries, 2)
+	var total sdk.Gas
+	for i, entry := range tracer.entries {
+		s.Require().Equal(i, entry.msgIndex)
+		s.Require().Equal(sdk.MsgTypeURL(msgs[i]), entry.msgTypeURL)
+		s.Require().Equal(uint64(1000*(i+1)), entry.gasAfter-entry.gasBefore)
+		total += entry.gasAfter - entry.gasBefore
+	}
+	s.Require().Equal(uint64(res.GasUsed), total)
+}
+
+func (s *MWTestSuite) TestGasMeterSelector() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	// governance msgs consume more gas than the tx gas limit, bank msgs fit in
+	// it
+	const govGas, bankGas = 500000, 1000
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute(govtypes.RouterKey, func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(govGas, "test gov msg")
+		return &sdk.Result{}, nil
+	}))
+	legacyRouter.AddRoute(sdk.NewRoute(banktypes.RouterKey, func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(bankGas, "test bank msg")
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	tracer := &recordingGasTracer{}
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewGasTxMiddlewareWithMeterSelector(tracer, func(msg sdk.Msg) sdk.GasMeter {
+			if _, ok := msg.(*govtypes.MsgVote); ok {
+				return sdk.NewInfiniteGasMeter()
+			}
+
+			return nil
+		}),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	msgs := []sdk.Msg{
+		govtypes.NewMsgVote(addr1, 1, govtypes.OptionYes),
+		banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10))),
+	}
+	s.Require().NoError(txBuilder.SetMsgs(msgs...))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	ctx = ctx.WithBlockHeight(1)
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+
+	// the governance msg doesn't count against the tx gas limit, its gas is
+	// reported apart from GasUsed, which stays within GasWanted
+	s.Require().Equal(int64(testdata.NewTestGasLimit()), res.GasWanted)
+	s.Require().Equal(int64(bankGas), res.GasUsed)
+	s.Require().LessOrEqual(res.GasUsed, res.GasWanted)
+	s.Require().Equal(abci.Event(sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(sdk.AttributeKeyMsgMeterGasUsed, strconv.Itoa(govGas)),
+	)), res.Events[len(res.Events)-1])
+	s.Require().Len(tracer.entries, 2)
+	s.Require().Equal(uint64(govGas), tracer.entries[0].gasAfter-tracer.entries[0].gasBefore)
+	s.Require().Equal(uint64(bankGas), tracer.entries[1].gasAfter-tracer.entries[1].gasBefore)
+
+	// without the selector, the governance msg runs out of gas
+	txHandler = middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.GasTxMiddleware,
+	)
+	s.Require().Panics(func() {
+		_, _ = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	})
+}
+
 // outOfGasTxHandler is a test middleware that will throw OutOfGas panic.
 type outOfGasTxHandler struct{}
 
@@ -136,3 +313,158 @@ func (txh noopTxHandler) SimulateTx(_ context.Context, _ sdk.Tx, _ tx.RequestSim
 func (txh noopTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
 	return abci.ResponseDeliverTx{}, nil
 }
+
+// anteGasTxHandler is a test middleware consuming a fixed amount of gas
+// before calling the next tx.Handler, like signature verification would.
+type anteGasTxHandler struct {
+	gas  uint64
+	next tx.Handler
+}
+
+var _ tx.Handler = anteGasTxHandler{}
+
+func (txh anteGasTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sdk.UnwrapSDKContext(ctx).GasMeter().ConsumeGas(txh.gas, "test ante")
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+func (txh anteGasTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdk.UnwrapSDKContext(ctx).GasMeter().ConsumeGas(txh.gas, "test ante")
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+func (txh anteGasTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	sdk.UnwrapSDKContext(ctx).GasMeter().ConsumeGas(txh.gas, "test ante")
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
+
+func (s *MWTestSuite) TestRecordAnteGas() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	const anteGas, msgGas = 5000, 1000
+	msgErr := sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "test msg failure")
+	var failMsgs bool
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(msgGas, "test msg")
+		if failMsgs {
+			return nil, msgErr
+		}
+
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	tracer := &recordingGasTracer{}
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewGasTxMiddlewareWithOptions(middleware.GasTxOptions{Tracer: tracer, RecordAnteGas: true}),
+		func(txh tx.Handler) tx.Handler { return anteGasTxHandler{gas: anteGas, next: txh} },
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	ctx = ctx.WithBlockHeight(1)
+
+	// requireAnteGas checks the ante gas event, and that the ante gas plus the
+	// traced msg gas equals the total gas used.
+	requireAnteGas := func(events []abci.Event, gasUsed uint64) {
+		s.Require().NotEmpty(events)
+		event := events[len(events)-1]
+		s.Require().Equal(sdk.EventTypeTx, event.Type)
+		s.Require().Equal([]abci.EventAttribute{{Key: sdk.AttributeKeyAnteGasUsed, Value: "5000"}}, event.Attributes)
+
+		s.Require().Len(tracer.entries, 1)
+		tracedMsgGas := tracer.entries[0].gasAfter - tracer.entries[0].gasBefore
+		s.Require().Equal(uint64(msgGas), tracedMsgGas)
+		s.Require().Equal(gasUsed, anteGas+tracedMsgGas)
+	}
+
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+	requireAnteGas(res.Events, uint64(res.GasUsed))
+
+	tracer.entries = nil
+	simRes, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+	requireAnteGas(simRes.Result.Events, simRes.GasInfo.GasUsed)
+
+	// the snapshot is taken on failures too
+	failMsgs = true
+	tracer.entries = nil
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+	requireAnteGas(res.Events, uint64(res.GasUsed))
+}
+
+func (s *MWTestSuite) TestDisableGasMetering() {
+	ctx := s.SetupTest(false) // setup
+	ctx = ctx.WithBlockHeight(1).WithMinGasPrices(sdk.NewDecCoins(sdk.NewInt64DecCoin("atom", 1)))
+
+	internalPriv, _, internal := testdata.KeyTestPubAddr()
+	userPriv, _, user := testdata.KeyTestPubAddr()
+	for _, addr := range []sdk.AccAddress{internal, user} {
+		s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, addr))
+	}
+
+	txHandler := middleware.ComposeMiddlewares(
+		gasUsingTxHandler{gasUsed: 50000},
+		middleware.NewGasTxMiddlewareWithOptions(middleware.GasTxOptions{
+			DisableGasMetering: true,
+			InternalSigners:    []sdk.AccAddress{internal},
+		}),
+		middleware.RecoveryTxMiddleware,
+		middleware.MempoolFeeMiddleware,
+		middleware.DeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper),
+		middleware.SetPubKeyMiddleware(s.app.AccountKeeper),
+		middleware.SigVerificationMiddleware(s.app.AccountKeeper, s.clientCtx.TxConfig.SignModeHandler()),
+		middleware.NewDisableGasMeteringMiddleware(true),
+	)
+
+	// the txs pay no fee, and have a gas limit covering the signature
+	// verification but below the gas they use
+	newTx := func(priv cryptotypes.PrivKey, signer sdk.AccAddress, gasLimit uint64, chainID string) sdk.Tx {
+		acc := s.app.AccountKeeper.GetAccount(ctx, signer)
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(signer)))
+		txBuilder.SetGasLimit(gasLimit)
+		testTx, _, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{priv}, []uint64{acc.GetAccountNumber()}, []uint64{acc.GetSequence()}, chainID)
+		s.Require().NoError(err)
+		return testTx
+	}
+
+	// the internal signer's txs are neither gas limited nor charged fees once
+	// their signatures are verified
+	internalTx := newTx(internalPriv, internal, 20000, ctx.ChainID())
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), internalTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), internalTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(50000), res.GasUsed)
+	s.Require().Equal(int64(20000), res.GasWanted)
+
+	// they are metered until then: the gas limit must cover the signature
+	// verification, and a tx with an invalid signature never gets unbounded gas
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(internalPriv, internal, 100, ctx.ChainID()), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(internalPriv, internal, 20000, "other-chain"), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	s.Require().Less(res.GasUsed, int64(20000))
+
+	// the other txs are metered as usual
+	userTx := newTx(userPriv, user, 20000, ctx.ChainID())
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), userTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), userTx, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+
+	// gas metering can't be disabled without internal signers
+	s.Require().Panics(func() {
+		middleware.NewGasTxMiddlewareWithOptions(middleware.GasTxOptions{DisableGasMetering: true})
+	})
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+)
+
+// GlobalPauseKey is the store key of the KVPauseStore entry set while the
+// chain is paused.
+var GlobalPauseKey = []byte{0x01}
+
+// PauseStore defines the storage of the switch of the global pause
+// middleware.
+type PauseStore interface {
+	// IsPaused reports whether the txs are paused.
+	IsPaused(ctx sdk.Context) bool
+}
+
+// KVPauseStore is a PauseStore saving the pause switch in a KVStore, which
+// can only be updated by the given authority, e.g. the gov module account, see
+// NewPauseMsgServerImpl.
+type KVPauseStore struct {
+	key       storetypes.StoreKey
+	authority string
+}
+
+var _ PauseStore = KVPauseStore{}
+
+// NewKVPauseStore returns a KVPauseStore saving the pause switch in the store
+// of the given key, updatable by the given authority.
+func NewKVPauseStore(key storetypes.StoreKey, authority string) KVPauseStore {
+	return KVPauseStore{
+		key:       key,
+		authority: authority,
+	}
+}
+
+// IsPaused implements PauseStore.IsPaused.
+func (s KVPauseStore) IsPaused(ctx sdk.Context) bool {
+	return ctx.KVStore(s.key).Has(GlobalPauseKey)
+}
+
+// SetPaused pauses, or resumes, the txs, starting with the next tx. It returns
+// ErrUnauthorized unless the given authority is the store authority, so that
+// it can back a gov-gated msg server handler.
+func (s KVPauseStore) SetPaused(ctx sdk.Context, authority string, paused bool) error {
+	if authority != s.authority {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "expected %s, got %s", s.authority, authority)
+	}
+
+	store := ctx.KVStore(s.key)
+	if paused {
+		store.Set(GlobalPauseKey, []byte{1})
+	} else {
+		store.Delete(GlobalPauseKey)
+	}
+
+	return nil
+}
+
+type pauseMsgServer struct {
+	store KVPauseStore
+}
+
+var _ ext.PauseMsgServer = pauseMsgServer{}
+
+// NewPauseMsgServerImpl returns the ext.PauseMsgServer pausing, or resuming,
+// the txs of the given KVPauseStore with MsgSetGlobalPause, which must be
+// executed by the store authority, e.g. by a gov proposal. The msg type should
+// also be gated to the gov authority, see NewGovGatedMsgMiddleware.
+func NewPauseMsgServerImpl(store KVPauseStore) ext.PauseMsgServer {
+	return pauseMsgServer{store: store}
+}
+
+// SetGlobalPause implements ext.PauseMsgServer.SetGlobalPause.
+func (s pauseMsgServer) SetGlobalPause(goCtx context.Context, msg *ext.MsgSetGlobalPause) (*ext.MsgSetGlobalPauseResponse, error) {
+	if err := s.store.SetPaused(sdk.UnwrapSDKContext(goCtx), msg.Authority, msg.Paused); err != nil {
+		return nil, err
+	}
+
+	return &ext.MsgSetGlobalPauseResponse{}, nil
+}
+
+type globalPauseTxHandler struct {
+	store        PauseStore
+	allowedTypes map[string]struct{}
+	next         tx.Handler
+}
+
+// NewGlobalPauseMiddleware returns a middleware which, while the given
+// PauseStore is paused, rejects with ErrUnauthorized all txs except the ones
+// holding only msgs of the allowed type URLs, e.g. the gov msgs needed to
+// resume the chain. The switch is read from the store on each tx, so that a
+// pause applies right away. The check is enforced in all modes.
+func NewGlobalPauseMiddleware(pauseStore PauseStore, allowedTypes []string) tx.Middleware {
+	allowed := make(map[string]struct{}, len(allowedTypes))
+	for _, typeURL := range allowedTypes {
+		allowed[typeURL] = struct{}{}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return globalPauseTxHandler{
+			store:        pauseStore,
+			allowedTypes: allowed,
+			next:         txh,
+		}
+	}
+}
+
+var _ tx.Handler = globalPauseTxHandler{}
+
+// checkPause checks that the txs are not paused, or that all the msgs of the
+// tx are allowed.
+func (txh globalPauseTxHandler) checkPause(ctx context.Context, sdkTx sdk.Tx) error {
+	if !txh.store.IsPaused(sdk.UnwrapSDKContext(ctx)) {
+		return nil
+	}
+
+	for i, msg := range sdkTx.GetMsgs() {
+		typeURL := sdk.MsgTypeURL(msg)
+		if _, ok := txh.allowedTypes[typeURL]; !ok {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "txs are paused, %s is not allowed; message index: %d", typeURL, i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh globalPauseTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkPause(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh globalPauseTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkPause(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh globalPauseTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkPause(ctx, sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func (s *MWTestSuite) TestGlobalPauseMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("pause")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_pause"))
+
+	_, _, authority := testdata.KeyTestPubAddr()
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	pause := middleware.NewKVPauseStore(key, authority.String())
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewGlobalPauseMiddleware(pause, []string{
+		sdk.MsgTypeURL(&govtypes.MsgVote{}),
+	}))
+
+	newTx := func(msgs ...sdk.Msg) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(msgs...))
+		return txBuilder.GetTx()
+	}
+	vote := govtypes.NewMsgVote(addr1, 1, govtypes.OptionYes)
+	send := banktypes.NewMsgSend(addr1, authority, sdk.NewCoins(sdk.NewInt64Coin("stake", 10)))
+	voteTx, sendTx, mixedTx := newTx(vote), newTx(send), newTx(vote, send)
+
+	requirePaused := func(testTx sdk.Tx, rejected bool) {
+		_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+		_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+		_, simErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+		for _, err := range []error{checkErr, deliverErr, simErr} {
+			if rejected {
+				s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+			} else {
+				s.Require().NoError(err)
+			}
+		}
+	}
+
+	// all txs are accepted until the chain is paused
+	requirePaused(sendTx, false)
+	requirePaused(mixedTx, false)
+
+	// only the authority can pause the txs
+	err := pause.SetPaused(ctx, addr1.String(), true)
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	requirePaused(sendTx, false)
+
+	// while paused, only the txs holding allowed msgs only are accepted
+	s.Require().NoError(pause.SetPaused(ctx, authority.String(), true))
+	requirePaused(voteTx, false)
+	requirePaused(sendTx, true)
+	requirePaused(mixedTx, true)
+
+	// resuming applies right away
+	s.Require().NoError(pause.SetPaused(ctx, authority.String(), false))
+	requirePaused(sendTx, false)
+
+	// the msg server toggles the pause on behalf of the authority
+	msgServer := middleware.NewPauseMsgServerImpl(pause)
+	_, err = msgServer.SetGlobalPause(sdk.WrapSDKContext(ctx), &ext.MsgSetGlobalPause{Authority: addr1.String(), Paused: true})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	requirePaused(sendTx, false)
+
+	_, err = msgServer.SetGlobalPause(sdk.WrapSDKContext(ctx), &ext.MsgSetGlobalPause{Authority: authority.String(), Paused: true})
+	s.Require().NoError(err)
+	requirePaused(sendTx, true)
+
+	_, err = msgServer.SetGlobalPause(sdk.WrapSDKContext(ctx), &ext.MsgSetGlobalPause{Authority: authority.String(), Paused: false})
+	s.Require().NoError(err)
+	requirePaused(sendTx, false)
+}
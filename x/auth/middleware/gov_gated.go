@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type govGatedMsgTxHandler struct {
+	gated        map[string]struct{}
+	govAuthority string
+	next         tx.Handler
+}
+
+// NewGovGatedMsgMiddleware returns a middleware rejecting the txs holding a
+// msg with one of the gated type URLs, e.g. parameter changes, unless the gov
+// authority is the only signer of the msg. It guards against a msg handler
+// that doesn't check its authority itself. The msgs executed by x/gov
+// proposals don't go through the tx.Handler, so they are not affected.
+//
+// The check is enforced in all modes, and returns ErrUnauthorized. An empty
+// set of gated type URLs returns the given tx.Handler unchanged.
+func NewGovGatedMsgMiddleware(gated map[string]struct{}, govAuthority string) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if len(gated) == 0 {
+			return txh
+		}
+
+		return govGatedMsgTxHandler{
+			gated:        gated,
+			govAuthority: govAuthority,
+			next:         txh,
+		}
+	}
+}
+
+var _ tx.Handler = govGatedMsgTxHandler{}
+
+// checkGatedMsgs checks that the gated msgs of the tx are only signed by the
+// gov authority.
+func (txh govGatedMsgTxHandler) checkGatedMsgs(sdkTx sdk.Tx) error {
+	for i, msg := range sdkTx.GetMsgs() {
+		typeURL := sdk.MsgTypeURL(msg)
+		if _, ok := txh.gated[typeURL]; !ok {
+			continue
+		}
+
+		signers := msg.GetSigners()
+		if len(signers) != 1 || signers[0].String() != txh.govAuthority {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized,
+				"%s can only be executed by the gov authority %s; message index: %d", typeURL, txh.govAuthority, i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh govGatedMsgTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkGatedMsgs(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh govGatedMsgTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkGatedMsgs(sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh govGatedMsgTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkGatedMsgs(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
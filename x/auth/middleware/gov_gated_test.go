@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func (s *MWTestSuite) TestGovGatedMsgMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	govAddr := authtypes.NewModuleAddress(govtypes.ModuleName)
+	_, _, userAddr := testdata.KeyTestPubAddr()
+
+	gated := map[string]struct{}{sdk.MsgTypeURL(&testdata.TestMsg{}): {}}
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewGovGatedMsgMiddleware(gated, govAddr.String()),
+	)
+
+	coins := sdk.NewCoins(sdk.NewInt64Coin("atom", 1))
+	testCases := []struct {
+		name   string
+		msgs   []sdk.Msg
+		expErr bool
+	}{
+		{"gated msg signed by gov", []sdk.Msg{testdata.NewTestMsg(govAddr)}, false},
+		{"gated msg signed by a user", []sdk.Msg{testdata.NewTestMsg(userAddr)}, true},
+		{"gated msg co-signed by a user", []sdk.Msg{testdata.NewTestMsg(govAddr, userAddr)}, true},
+		{"non-gated msg signed by a user", []sdk.Msg{banktypes.NewMsgSend(userAddr, govAddr, coins)}, false},
+		{"gated msg after a non-gated msg", []sdk.Msg{banktypes.NewMsgSend(userAddr, govAddr, coins), testdata.NewTestMsg(userAddr)}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(tc.msgs...))
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			_, simErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simErr} {
+				if tc.expErr {
+					s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// IBCTimeout is the timeout of an IBC packet, as defined by ibc-go.
+type IBCTimeout struct {
+	// RevisionNumber and RevisionHeight are the timeout height, or 0 if the
+	// packet has no timeout height.
+	RevisionNumber uint64
+	RevisionHeight uint64
+	// Timestamp is the timeout timestamp, in nanoseconds since the UNIX
+	// epoch, or 0 if the packet has no timeout timestamp.
+	Timestamp uint64
+	// Received is true if the packet is received by this chain, e.g. by
+	// ibc-go's MsgRecvPacket, in which case the timeout height refers to this
+	// chain. Otherwise, the packet is sent by this chain, e.g. by ibc-go's
+	// MsgTransfer, and the timeout height refers to the counterparty chain.
+	Received bool
+}
+
+// IBCTimeoutExtractor returns the timeout of the IBC packet sent or received
+// by the given msg, so that the IBC timeout middleware doesn't depend on
+// ibc-go.
+type IBCTimeoutExtractor func(msg sdk.Msg) (IBCTimeout, error)
+
+// IBCTimeoutExtractorRegistry holds the IBCTimeoutExtractors consulted by the
+// IBC timeout middleware, keyed by msg type URL. The extractors must be
+// registered when wiring the app, before any tx is executed, as the registry
+// is not safe for concurrent use.
+type IBCTimeoutExtractorRegistry struct {
+	extractors map[string]IBCTimeoutExtractor
+}
+
+// NewIBCTimeoutExtractorRegistry returns an empty IBCTimeoutExtractorRegistry.
+func NewIBCTimeoutExtractorRegistry() *IBCTimeoutExtractorRegistry {
+	return &IBCTimeoutExtractorRegistry{extractors: make(map[string]IBCTimeoutExtractor)}
+}
+
+// RegisterIBCTimeoutExtractor registers the extractor of the IBC timeout of
+// the msgs with the given type URL, e.g. "/ibc.applications.transfer.v1.MsgTransfer".
+func (r *IBCTimeoutExtractorRegistry) RegisterIBCTimeoutExtractor(typeURL string, e IBCTimeoutExtractor) {
+	if typeURL == "" || e == nil {
+		panic(fmt.Errorf("invalid IBC timeout extractor for type URL %q", typeURL))
+	}
+	if _, ok := r.extractors[typeURL]; ok {
+		panic(fmt.Errorf("IBC timeout extractor already registered for type URL %q", typeURL))
+	}
+
+	r.extractors[typeURL] = e
+}
+
+type ibcTimeoutTxHandler struct {
+	registry *IBCTimeoutExtractorRegistry
+	next     tx.Handler
+}
+
+// NewIBCTimeoutMiddleware returns a middleware rejecting in CheckTx, with
+// ErrInvalidRequest, the txs holding an IBC msg whose packet is certain to time
+// out, as extracted by the extractors of the given registry: a packet with
+// neither a timeout height nor a timeout timestamp, or with a timeout
+// timestamp not after the block time. The timeout height of a received packet
+// is checked against the current height, and the one of a sent packet is left
+// to the IBC handlers, as it refers to the counterparty chain. It saves the
+// senders and relayers the fees of txs certain to fail. DeliverTx and
+// SimulateTx are not restricted. A nil registry returns the given tx.Handler
+// unchanged.
+func NewIBCTimeoutMiddleware(registry *IBCTimeoutExtractorRegistry) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if registry == nil {
+			return txh
+		}
+
+		return ibcTimeoutTxHandler{
+			registry: registry,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = ibcTimeoutTxHandler{}
+
+// chainIDRevisionRegex matches the chain-ids holding a revision number, as
+// defined by ibc-go, e.g. "cosmoshub-4".
+var chainIDRevisionRegex = regexp.MustCompile(`^.*[^\n-]-{1}[1-9][0-9]*$`)
+
+// chainRevision returns the revision number of the given chain-id, or 0 if it
+// has none.
+func chainRevision(chainID string) uint64 {
+	if !chainIDRevisionRegex.MatchString(chainID) {
+		return 0
+	}
+
+	revision, err := strconv.ParseUint(chainID[strings.LastIndex(chainID, "-")+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
+
+// checkIBCTimeouts checks the timeouts of the IBC msgs of the tx against the
+// current block.
+func (txh ibcTimeoutTxHandler) checkIBCTimeouts(ctx context.Context, sdkTx sdk.Tx) error {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	blockTime := sdkCtx.BlockTime()
+	revision, height := chainRevision(sdkCtx.ChainID()), uint64(sdkCtx.BlockHeight())
+	for i, msg := range sdkTx.GetMsgs() {
+		extract, ok := txh.registry.extractors[sdk.MsgTypeURL(msg)]
+		if !ok {
+			continue
+		}
+
+		timeout, err := extract(msg)
+		if err != nil {
+			return sdkerrors.Wrapf(err, "invalid IBC packet timeout; message index: %d", i)
+		}
+		hasTimeoutHeight := timeout.RevisionNumber != 0 || timeout.RevisionHeight != 0
+		if !hasTimeoutHeight && timeout.Timestamp == 0 {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "IBC packet has neither a timeout height nor a timeout timestamp; message index: %d", i)
+		}
+		if timeout.Timestamp != 0 && timeout.Timestamp <= uint64(blockTime.UnixNano()) {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "IBC packet timeout timestamp %s has already passed, block time: %s; message index: %d",
+				time.Unix(0, int64(timeout.Timestamp)).UTC(), blockTime, i)
+		}
+		if timeout.Received && hasTimeoutHeight &&
+			(timeout.RevisionNumber < revision || timeout.RevisionNumber == revision && timeout.RevisionHeight <= height) {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "IBC packet timeout height %d-%d has already passed, height: %d-%d; message index: %d",
+				timeout.RevisionNumber, timeout.RevisionHeight, revision, height, i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh ibcTimeoutTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkIBCTimeouts(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh ibcTimeoutTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh ibcTimeoutTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// ibcTestMsg is a test msg sending or receiving an IBC packet with the given
+// timeout.
+type ibcTestMsg struct {
+	*testdata.TestMsg
+	timeout middleware.IBCTimeout
+}
+
+// XXX_MessageName gives ibcTestMsg its own type URL, as sdk.MsgTypeURL
+// doesn't resolve the name of the embedded TestMsg.
+func (ibcTestMsg) XXX_MessageName() string { return "testdata.IBCTestMsg" }
+
+// invalidMsg is a test msg with the type URL of ibcTestMsg but no timeout.
+type invalidMsg struct {
+	*testdata.TestMsg
+}
+
+func (invalidMsg) XXX_MessageName() string { return "testdata.IBCTestMsg" }
+
+// msgsTx is a test tx holding the given msgs.
+type msgsTx []sdk.Msg
+
+var _ sdk.Tx = msgsTx{}
+
+func (t msgsTx) GetMsgs() []sdk.Msg   { return t }
+func (t msgsTx) ValidateBasic() error { return nil }
+
+func (s *MWTestSuite) TestIBCTimeoutMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	blockTime := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx = ctx.WithBlockTime(blockTime).WithBlockHeight(100).WithChainID("testchain-2")
+
+	registry := middleware.NewIBCTimeoutExtractorRegistry()
+	registry.RegisterIBCTimeoutExtractor(sdk.MsgTypeURL(ibcTestMsg{}), func(msg sdk.Msg) (middleware.IBCTimeout, error) {
+		ibcMsg, ok := msg.(ibcTestMsg)
+		if !ok {
+			return middleware.IBCTimeout{}, sdkerrors.ErrInvalidType
+		}
+		return ibcMsg.timeout, nil
+	})
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewIBCTimeoutMiddleware(registry))
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	newMsg := func(timeout middleware.IBCTimeout) sdk.Msg {
+		return ibcTestMsg{testdata.NewTestMsg(addr1), timeout}
+	}
+	otherMsg := banktypes.NewMsgSend(addr1, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 1)))
+
+	testCases := []struct {
+		name   string
+		msgs   []sdk.Msg
+		expErr bool
+	}{
+		{"not an IBC msg", []sdk.Msg{otherMsg}, false},
+		{"timeout height only", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 1, RevisionHeight: 100})}, false},
+		{"future timeout timestamp", []sdk.Msg{newMsg(middleware.IBCTimeout{Timestamp: uint64(blockTime.Add(time.Minute).UnixNano())})}, false},
+		{"no timeout", []sdk.Msg{newMsg(middleware.IBCTimeout{})}, true},
+		{"expired timeout timestamp", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionHeight: 100, Timestamp: uint64(blockTime.Add(-time.Minute).UnixNano())})}, true},
+		{"timeout timestamp at block time", []sdk.Msg{newMsg(middleware.IBCTimeout{Timestamp: uint64(blockTime.UnixNano())})}, true},
+		{"missing timeout after a valid msg", []sdk.Msg{otherMsg, newMsg(middleware.IBCTimeout{})}, true},
+		{"sent packet with a passed height", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 2, RevisionHeight: 50})}, false},
+		{"received packet with a future height", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 2, RevisionHeight: 101, Received: true})}, false},
+		{"received packet with a later revision", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 3, RevisionHeight: 1, Received: true})}, false},
+		{"received packet at its timeout height", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 2, RevisionHeight: 100, Received: true})}, true},
+		{"received packet with an earlier revision", []sdk.Msg{newMsg(middleware.IBCTimeout{RevisionNumber: 1, RevisionHeight: 1000, Received: true})}, true},
+		{"received packet with an expired timestamp", []sdk.Msg{newMsg(middleware.IBCTimeout{Timestamp: uint64(blockTime.UnixNano()), Received: true})}, true},
+		{"invalid IBC msg", []sdk.Msg{invalidMsg{testdata.NewTestMsg(addr1)}}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), msgsTx(tc.msgs), abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().Error(err)
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// DeliverTx is left to the IBC handlers
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), msgsTx(tc.msgs), abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/store/prefix"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/address"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+)
+
+const (
+	// IdempotencyKeyTypeURL is the type URL of the extension option holding the
+	// idempotency key of a tx.
+	IdempotencyKeyTypeURL = "/cosmos.tx.ext.v1.IdempotencyKey"
+
+	// MaxIdempotencyKeyLength is the maximum length of an idempotency key.
+	MaxIdempotencyKeyLength = 64
+
+	// EventTypeIdempotentReplay is the type of the event emitted instead of the
+	// msg events when a tx is not executed as its idempotency key was already
+	// processed.
+	EventTypeIdempotentReplay = "idempotent_replay"
+
+	AttributeKeyIdempotencyKey = "idempotency_key"
+	AttributeKeyHeight         = "height"
+)
+
+var (
+	// IdempotencyKeyPrefix is the prefix of the store entries holding the
+	// results of the txs processed with an idempotency key.
+	IdempotencyKeyPrefix = []byte{0x01}
+
+	// IdempotencyExpiryPrefix is the prefix of the store entries indexing the
+	// results by expiry height, so that they can be pruned.
+	IdempotencyExpiryPrefix = []byte{0x02}
+)
+
+type idempotencyTxHandler struct {
+	key  storetypes.StoreKey
+	ttl  int64
+	next tx.Handler
+}
+
+// NewIdempotencyMiddleware returns a middleware that executes at most once the
+// txs carrying the same idempotency key, so that clients can safely retry a tx.
+// The key is read from the tx extension options, see IdempotencyKeyTypeURL, and
+// is scoped to the tx fee payer. The result of the first successful DeliverTx
+// of a key is saved in the given store. The next DeliverTx and SimulateTx of
+// that key don't execute their msgs, and return the saved data with an
+// EventTypeIdempotentReplay event instead of the msg events.
+//
+// A key is retained for ttl blocks after its execution, after which a tx with
+// the same key is executed again. Each DeliverTx prunes the expired keys.
+//
+// The middleware must be placed right before the msg router, so that retries
+// are still charged fees and increment the signers sequence. The
+// RejectExtensionOptions middleware must only accept the idempotency key
+// extension option.
+// CONTRACT: Tx must implement FeeTx interface
+func NewIdempotencyMiddleware(key storetypes.StoreKey, ttl int64) tx.Middleware {
+	if ttl <= 0 {
+		panic("idempotency key ttl must be positive")
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return idempotencyTxHandler{
+			key:  key,
+			ttl:  ttl,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = idempotencyTxHandler{}
+
+// IdempotencyResultKey returns the store key of the result of the tx processed
+// with the given fee payer and idempotency key.
+func IdempotencyResultKey(feePayer sdk.AccAddress, idempotencyKey string) []byte {
+	key := append(IdempotencyKeyPrefix, address.MustLengthPrefix(feePayer)...)
+	return append(key, idempotencyKey...)
+}
+
+// idempotencyKey returns the tx idempotency key and its store key, or an empty
+// key if the tx has none.
+func idempotencyKey(sdkTx sdk.Tx) (string, []byte, error) {
+	extTx, ok := sdkTx.(HasExtensionOptionsTx)
+	if !ok {
+		return "", nil, nil
+	}
+
+	var found *ext.IdempotencyKey
+	for _, opt := range extTx.GetExtensionOptions() {
+		if opt.TypeUrl != IdempotencyKeyTypeURL {
+			continue
+		}
+		if found != nil {
+			return "", nil, sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "tx holds more than one idempotency key")
+		}
+
+		found = &ext.IdempotencyKey{}
+		if err := found.Unmarshal(opt.Value); err != nil {
+			return "", nil, sdkerrors.Wrapf(sdkerrors.ErrTxDecode, "invalid idempotency key: %s", err)
+		}
+	}
+
+	if found == nil {
+		return "", nil, nil
+	}
+
+	if len(found.Key) == 0 || len(found.Key) > MaxIdempotencyKeyLength {
+		return "", nil, sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest,
+			"idempotency key length must be between 1 and %d, got %d", MaxIdempotencyKeyLength, len(found.Key))
+	}
+
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return "", nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	return found.Key, IdempotencyResultKey(feeTx.FeePayer(), found.Key), nil
+}
+
+// expiryKey returns the key of the expiry index entry of the given result key,
+// relative to IdempotencyExpiryPrefix.
+func expiryKey(expiryHeight int64, resultKey []byte) []byte {
+	return append(sdk.Uint64ToBigEndian(uint64(expiryHeight)), resultKey...)
+}
+
+// priorRecord returns the saved result of the tx processed with the given
+// store key, if any and not expired.
+func (txh idempotencyTxHandler) priorRecord(sdkCtx sdk.Context, key []byte) (*ext.IdempotencyRecord, error) {
+	bz := sdkCtx.KVStore(txh.key).Get(key)
+	if bz == nil {
+		return nil, nil
+	}
+
+	var record ext.IdempotencyRecord
+	if err := record.Unmarshal(bz); err != nil {
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrLogic, "invalid idempotency record: %s", err)
+	}
+	if record.Height+txh.ttl <= sdkCtx.BlockHeight() {
+		return nil, nil
+	}
+
+	return &record, nil
+}
+
+// prune deletes the results expired at the current block height. It doesn't
+// consume the gas of the tx, as the pruned keys weren't set by it.
+func (txh idempotencyTxHandler) prune(sdkCtx sdk.Context) {
+	store := sdkCtx.WithGasMeter(sdk.NewInfiniteGasMeter()).KVStore(txh.key)
+	expiryStore := prefix.NewStore(store, IdempotencyExpiryPrefix)
+
+	iter := expiryStore.Iterator(nil, sdk.Uint64ToBigEndian(uint64(sdkCtx.BlockHeight())+1))
+	var expired [][]byte
+	for ; iter.Valid(); iter.Next() {
+		expired = append(expired, iter.Key())
+	}
+	iter.Close()
+
+	for _, key := range expired {
+		expiryStore.Delete(key)
+		store.Delete(key[8:])
+	}
+}
+
+// replay emits the EventTypeIdempotentReplay event of the given idempotency
+// key, and returns the events of the tx.
+func replay(sdkCtx sdk.Context, idemKey string, record *ext.IdempotencyRecord) []abci.Event {
+	sdkCtx.EventManager().EmitEvent(sdk.NewEvent(EventTypeIdempotentReplay,
+		sdk.NewAttribute(AttributeKeyIdempotencyKey, idemKey),
+		sdk.NewAttribute(AttributeKeyHeight, strconv.FormatInt(record.Height, 10)),
+	))
+
+	return sdkCtx.EventManager().ABCIEvents()
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh idempotencyTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if _, _, err := idempotencyKey(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh idempotencyTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	idemKey, key, err := idempotencyKey(sdkTx)
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	txh.prune(sdkCtx)
+	if key == nil {
+		return txh.next.DeliverTx(ctx, sdkTx, req)
+	}
+
+	prior, err := txh.priorRecord(sdkCtx, key)
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+	if prior != nil {
+		return abci.ResponseDeliverTx{Data: prior.Data, Log: prior.Log, Events: replay(sdkCtx, idemKey, prior)}, nil
+	}
+
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	bz, err := (&ext.IdempotencyRecord{Data: res.Data, Log: res.Log, Height: sdkCtx.BlockHeight()}).Marshal()
+	if err != nil {
+		return abci.ResponseDeliverTx{}, sdkerrors.Wrapf(sdkerrors.ErrLogic, "failed to marshal idempotency record: %s", err)
+	}
+	store := sdkCtx.KVStore(txh.key)
+	store.Set(key, bz)
+	prefix.NewStore(store, IdempotencyExpiryPrefix).Set(expiryKey(sdkCtx.BlockHeight()+txh.ttl, key), []byte{})
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh idempotencyTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	idemKey, key, err := idempotencyKey(sdkTx)
+	if err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	if key != nil {
+		sdkCtx := sdk.UnwrapSDKContext(ctx)
+		prior, err := txh.priorRecord(sdkCtx, key)
+		if err != nil {
+			return tx.ResponseSimulateTx{}, err
+		}
+		if prior != nil {
+			return tx.ResponseSimulateTx{Result: &sdk.Result{Data: prior.Data, Log: prior.Log, Events: replay(sdkCtx, idemKey, prior)}}, nil
+		}
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
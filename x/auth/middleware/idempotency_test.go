@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/testutil"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+)
+
+func (s *MWTestSuite) TestIdempotencyMiddleware() {
+	s.SetupTest(true) // setup
+	key := sdk.NewKVStoreKey("idempotency")
+	ctx := testutil.DefaultContext(key, sdk.NewTransientStoreKey("transient_idempotency")).WithBlockHeight(10)
+
+	// each execution returns its execution count as data
+	executions := 0
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		executions++
+		return &sdk.Result{Data: []byte(fmt.Sprintf("execution %d", executions))}, nil
+	}))
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry), legacyRouter),
+		middleware.NewIdempotencyMiddleware(key, 5),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	newTx := func(signer sdk.AccAddress, idempotencyKey string) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(signer)))
+		if idempotencyKey != "" {
+			value, err := (&ext.IdempotencyKey{Key: idempotencyKey}).Marshal()
+			s.Require().NoError(err)
+			txBuilder.(authtx.ExtensionOptionsTxBuilder).SetExtensionOptions(&codectypes.Any{
+				TypeUrl: middleware.IdempotencyKeyTypeURL,
+				Value:   value,
+			})
+		}
+
+		return txBuilder.GetTx()
+	}
+
+	testCases := []struct {
+		name          string
+		tx            sdk.Tx
+		expExecutions int
+		expData       string
+		expReplay     bool
+	}{
+		{"first submission", newTx(addr1, "order-1"), 1, "execution 1", false},
+		{"retry with the same key", newTx(addr1, "order-1"), 1, "execution 1", true},
+		{"another key", newTx(addr1, "order-2"), 2, "execution 2", false},
+		{"same key for another fee payer", newTx(addr2, "order-1"), 3, "execution 3", false},
+		{"no key", newTx(addr1, ""), 4, "execution 4", false},
+		{"no key again", newTx(addr1, ""), 5, "execution 5", false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithEventManager(sdk.NewEventManager())), tc.tx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			s.Require().Equal(tc.expExecutions, executions)
+			s.Require().Equal(tc.expReplay, hasEvent(res.Events, middleware.EventTypeIdempotentReplay))
+
+			var txMsgData sdk.TxMsgData
+			s.Require().NoError(s.clientCtx.Codec.Unmarshal(res.Data, &txMsgData))
+			s.Require().Equal(tc.expData, string(txMsgData.Data[0].Data))
+		})
+	}
+
+	// simulations of processed keys return the prior result too
+	simRes, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), newTx(addr1, "order-1"), tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(5, executions)
+	var txMsgData sdk.TxMsgData
+	s.Require().NoError(s.clientCtx.Codec.Unmarshal(simRes.Result.Data, &txMsgData))
+	s.Require().Equal("execution 1", string(txMsgData.Data[0].Data))
+	s.Require().True(hasEvent(simRes.Result.Events, middleware.EventTypeIdempotentReplay))
+
+	// the keys expire after the ttl, and their results are pruned
+	resultKey := middleware.IdempotencyResultKey(addr1, "order-1")
+	ctx = ctx.WithBlockHeight(15).WithEventManager(sdk.NewEventManager())
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(addr1, ""), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(6, executions)
+	s.Require().False(ctx.KVStore(key).Has(resultKey))
+
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(addr1, "order-1"), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(7, executions)
+	s.Require().False(hasEvent(res.Events, middleware.EventTypeIdempotentReplay))
+	s.Require().True(ctx.KVStore(key).Has(resultKey))
+}
+
+// hasEvent returns true if the events contain an event of the given type.
+func hasEvent(events []abci.Event, eventType string) bool {
+	for _, event := range events {
+		if event.Type == eventType {
+			return true
+		}
+	}
+
+	return false
+}
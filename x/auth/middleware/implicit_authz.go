@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+)
+
+type implicitAuthzTxHandler struct {
+	authzKeeper AuthzKeeper
+	next        tx.Handler
+}
+
+// implicitAuthzSignersKey is the sdk.Context key of the signers of the tx
+// resolved by the ImplicitAuthz middleware.
+type implicitAuthzSignersKey struct{}
+
+// txSigners returns the addresses which must sign the tx: its msg signers, or
+// the signers resolved by the ImplicitAuthz middleware, which leave out the
+// msg signers covered by a grant.
+func txSigners(sdkCtx sdk.Context, sigTx authsigning.SigVerifiableTx) []sdk.AccAddress {
+	if signers, ok := sdkCtx.Value(implicitAuthzSignersKey{}).([]sdk.AccAddress); ok {
+		return signers
+	}
+
+	return sigTx.GetSigners()
+}
+
+// NewImplicitAuthzMiddleware returns a middleware that lets the signers of a
+// tx submit msgs on behalf of other accounts without wrapping them in a
+// MsgExec.
+//
+// The signers of such a tx are identified by the public keys of its
+// signatures, which must all be set, rather than by its msgs. For each msg
+// signer which didn't sign the tx, a valid authz grant from that signer to
+// one of the tx signers is required and consumed like MsgExec would,
+// otherwise the tx is rejected with ErrUnauthorized. The fee payer must be
+// one of the tx signers. The signature verification middlewares then only
+// expect the signatures of the tx signers, in the order of the signatures.
+// Txs signed by all their msg signers, in order, are left untouched.
+//
+// The grants of a tx are consumed in a branch of the state, which is only
+// written once all msgs were accepted and the rest of the tx succeeded, so a
+// rejected or failed tx leaves the grants untouched.
+//
+// This middleware must be placed before the ValidateBasic and signature
+// verification middlewares. If authzKeeper is nil, it is a no-op.
+// CONTRACT: Tx must implement FeeTx interface
+func NewImplicitAuthzMiddleware(authzKeeper AuthzKeeper) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if authzKeeper == nil {
+			return txh
+		}
+
+		return implicitAuthzTxHandler{
+			authzKeeper: authzKeeper,
+			next:        txh,
+		}
+	}
+}
+
+var _ tx.Handler = implicitAuthzTxHandler{}
+
+// resolveSigners returns the signers of the tx, identified by the public keys
+// of its signatures, if they differ from its msg signers.
+func resolveSigners(sigTx authsigning.SigVerifiableTx) ([]sdk.AccAddress, bool, error) {
+	sigs, err := sigTx.GetSignaturesV2()
+	if err != nil {
+		return nil, false, err
+	}
+
+	msgSigners := sigTx.GetSigners()
+	signers := make([]sdk.AccAddress, len(sigs))
+	implicit := len(sigs) != len(msgSigners)
+	for i, sig := range sigs {
+		// the signers of the signatures without public key are unknown
+		if sig.PubKey == nil {
+			return nil, false, nil
+		}
+
+		signers[i] = sdk.AccAddress(sig.PubKey.Address())
+		for _, signer := range signers[:i] {
+			if signer.Equals(signers[i]) {
+				return nil, false, sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "duplicate signer %s", signer)
+			}
+		}
+
+		if !implicit && !signers[i].Equals(msgSigners[i]) {
+			implicit = true
+		}
+	}
+
+	return signers, implicit, nil
+}
+
+// consumeGrants checks and consumes the grants needed for the signers of the
+// tx to submit its msgs. It returns the context holding the resolved signers,
+// and a function writing the consumed grants to the state.
+func (txh implicitAuthzTxHandler) consumeGrants(ctx context.Context, sdkTx sdk.Tx) (context.Context, func(), error) {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return nil, nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return nil, nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	signers, implicit, err := resolveSigners(sigTx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !implicit {
+		return ctx, func() {}, nil
+	}
+
+	if !containsAddress(signers, feeTx.FeePayer()) {
+		return nil, nil, sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "fee payer %s didn't sign the tx", feeTx.FeePayer())
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	// the grant events are emitted directly, the writes are deferred
+	cacheCtx, write := sdkCtx.CacheContext()
+	cacheCtx = cacheCtx.WithEventManager(sdkCtx.EventManager())
+
+	for _, msg := range sdkTx.GetMsgs() {
+		for _, granter := range msg.GetSigners() {
+			if containsAddress(signers, granter) {
+				continue
+			}
+
+			if err := txh.consumeGrant(cacheCtx, signers, granter, msg); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(implicitAuthzSignersKey{}, signers)), write, nil
+}
+
+// containsAddress reports whether addrs contains addr.
+func containsAddress(addrs []sdk.AccAddress, addr sdk.AccAddress) bool {
+	for _, a := range addrs {
+		if a.Equals(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// consumeGrant accepts the msg with the grant from granter to the first of
+// the grantees holding one, and updates or deletes the grant accordingly.
+func (txh implicitAuthzTxHandler) consumeGrant(sdkCtx sdk.Context, grantees []sdk.AccAddress, granter sdk.AccAddress, msg sdk.Msg) error {
+	msgType := sdk.MsgTypeURL(msg)
+	var (
+		grantee       sdk.AccAddress
+		authorization authz.Authorization
+		expiration    time.Time
+	)
+	for _, grantee = range grantees {
+		// expired grants are returned as nil
+		authorization, expiration = txh.authzKeeper.GetCleanAuthorization(sdkCtx, grantee, granter, msgType)
+		if authorization != nil {
+			break
+		}
+	}
+	if authorization == nil {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "no authorization found from %s to the tx signers for %s", granter, msgType)
+	}
+
+	resp, err := authorization.Accept(sdkCtx, msg)
+	if err != nil {
+		return err
+	}
+
+	if resp.Delete {
+		err = txh.authzKeeper.DeleteGrant(sdkCtx, grantee, granter, msgType)
+	} else if resp.Updated != nil {
+		err = txh.authzKeeper.SaveGrant(sdkCtx, grantee, granter, resp.Updated, expiration)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !resp.Accept {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "authorization from %s to %s rejected %s", granter, grantee, msgType)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh implicitAuthzTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	ctx, write, err := txh.consumeGrants(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	res, err := txh.next.CheckTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	write()
+
+	return res, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh implicitAuthzTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	ctx, write, err := txh.consumeGrants(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	write()
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh implicitAuthzTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	ctx, write, err := txh.consumeGrants(ctx, sdkTx)
+	if err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	res, err := txh.next.SimulateTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	write()
+
+	return res, nil
+}
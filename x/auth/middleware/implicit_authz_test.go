@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"errors"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestImplicitAuthzMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	now := time.Now().UTC()
+	ctx = ctx.WithBlockHeader(tmproto.Header{Time: now})
+
+	granteePriv, _, grantee := testdata.KeyTestPubAddr()
+	_, _, granter := testdata.KeyTestPubAddr()
+	_, _, other := testdata.KeyTestPubAddr()
+	msgType := sdk.MsgTypeURL(&banktypes.MsgSend{})
+
+	authzKeeper := s.app.AuthzKeeper
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewImplicitAuthzMiddleware(authzKeeper))
+
+	// sendTx returns a tx signed by grantee, sending amount on behalf of from.
+	sendTx := func(from sdk.AccAddress, amount int64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		msg := banktypes.NewMsgSend(from, other, sdk.NewCoins(sdk.NewInt64Coin("atom", amount)))
+		s.Require().NoError(txBuilder.SetMsgs(msg))
+		txBuilder.SetFeePayer(grantee)
+		testTx, _, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{granteePriv}, []uint64{0}, []uint64{0}, ctx.ChainID())
+		s.Require().NoError(err)
+
+		return testTx
+	}
+
+	// msgs signed by the tx signer itself don't need a grant
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(grantee, 10), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// missing grant
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 10), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// expired grant
+	expiredAuth := banktypes.NewSendAuthorization(sdk.NewCoins(sdk.NewInt64Coin("atom", 100)))
+	s.Require().NoError(authzKeeper.SaveGrant(ctx, grantee, granter, expiredAuth, now.Add(-time.Hour)))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 10), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	// valid grant, the spend limit is decremented
+	expiration := now.Add(time.Hour)
+	auth := banktypes.NewSendAuthorization(sdk.NewCoins(sdk.NewInt64Coin("atom", 100)))
+	s.Require().NoError(authzKeeper.SaveGrant(ctx, grantee, granter, auth, expiration))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 60), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	updated, updatedExpiration := authzKeeper.GetCleanAuthorization(ctx, grantee, granter, msgType)
+	s.Require().NotNil(updated)
+	s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 40)), updated.(*banktypes.SendAuthorization).SpendLimit)
+	s.Require().True(expiration.Equal(updatedExpiration))
+
+	// the spend limit can't be exceeded
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 60), abci.RequestDeliverTx{})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "spend limit")
+
+	// a failed tx doesn't consume the grant
+	failedTxHandler := middleware.ComposeMiddlewares(failingTxHandler{err: sdkerrors.ErrOutOfGas}, middleware.NewImplicitAuthzMiddleware(authzKeeper))
+	_, err = failedTxHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 40), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+	updated, _ = authzKeeper.GetCleanAuthorization(ctx, grantee, granter, msgType)
+	s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 40)), updated.(*banktypes.SendAuthorization).SpendLimit)
+
+	// using up the spend limit deletes the grant
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), sendTx(granter, 40), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	updated, _ = authzKeeper.GetCleanAuthorization(ctx, grantee, granter, msgType)
+	s.Require().Nil(updated)
+}
+
+func (s *MWTestSuite) TestImplicitAuthzDefaultTxHandler() {
+	ctx := s.SetupTest(false) // setup
+	now := time.Now().UTC()
+	ctx = ctx.WithBlockHeader(tmproto.Header{Height: ctx.BlockHeight(), ChainID: ctx.ChainID(), Time: now})
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	banktypes.RegisterMsgServer(msr, bankkeeper.NewMsgServerImpl(s.app.BankKeeper))
+	txHandler, err := middleware.NewDefaultTxHandler(middleware.TxHandlerOptions{
+		MsgServiceRouter: msr,
+		LegacyRouter:     middleware.NewLegacyRouter(),
+		AccountKeeper:    s.app.AccountKeeper,
+		BankKeeper:       s.app.BankKeeper,
+		FeegrantKeeper:   s.app.FeeGrantKeeper,
+		AuthzKeeper:      s.app.AuthzKeeper,
+		SignModeHandler:  s.clientCtx.TxConfig.SignModeHandler(),
+		SigGasConsumer:   middleware.DefaultSigVerificationGasConsumer,
+	})
+	s.Require().NoError(err)
+
+	accounts := s.createTestAccounts(ctx, 2, sdk.NewCoins(sdk.NewInt64Coin("atom", 1000)))
+	grantee, granter := accounts[0], accounts[1]
+	_, _, other := testdata.KeyTestPubAddr()
+
+	// deliver delivers a tx signed by the grantee only, sending 10atom on
+	// behalf of the granter.
+	deliver := func(feePayer sdk.AccAddress) error {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		msg := banktypes.NewMsgSend(granter.acc.GetAddress(), other, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))
+		s.Require().NoError(txBuilder.SetMsgs(msg))
+		txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+		txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+		txBuilder.SetFeePayer(feePayer)
+		seq := s.app.AccountKeeper.GetAccount(ctx, grantee.acc.GetAddress()).GetSequence()
+		testTx, txBytes, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{grantee.priv}, []uint64{grantee.accNum}, []uint64{seq}, ctx.ChainID())
+		s.Require().NoError(err)
+
+		_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+		return err
+	}
+
+	// the granter's signature is required without a grant
+	err = deliver(grantee.acc.GetAddress())
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	auth := banktypes.NewSendAuthorization(sdk.NewCoins(sdk.NewInt64Coin("atom", 100)))
+	s.Require().NoError(s.app.AuthzKeeper.SaveGrant(ctx, grantee.acc.GetAddress(), granter.acc.GetAddress(), auth, now.Add(time.Hour)))
+
+	// the granter can't be charged the fees without its signature
+	err = deliver(granter.acc.GetAddress())
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	s.Require().NoError(deliver(grantee.acc.GetAddress()))
+	s.Require().Equal(sdk.NewInt64Coin("atom", 10), s.app.BankKeeper.GetBalance(ctx, other, "atom"))
+	s.Require().Equal(uint64(1), s.app.AccountKeeper.GetAccount(ctx, grantee.acc.GetAddress()).GetSequence())
+	s.Require().Equal(uint64(0), s.app.AccountKeeper.GetAccount(ctx, granter.acc.GetAddress()).GetSequence())
+
+	updated, _ := s.app.AuthzKeeper.GetCleanAuthorization(ctx, grantee.acc.GetAddress(), granter.acc.GetAddress(), sdk.MsgTypeURL(&banktypes.MsgSend{}))
+	s.Require().Equal(sdk.NewCoins(sdk.NewInt64Coin("atom", 90)), updated.(*banktypes.SendAuthorization).SpendLimit)
+}
@@ -13,22 +13,58 @@ type indexEventsTxHandler struct {
 	// indexEvents defines the set of events in the form {eventType}.{attributeKey},
 	// which informs Tendermint what to index. If empty, all events will be indexed.
 	indexEvents map[string]struct{}
-	inner       tx.Handler
+	// valuePredicates optionally restricts the indexing of an attribute,
+	// keyed by {eventType}.{attributeKey}, to the values it matches.
+	valuePredicates map[string]sdk.EventValuePredicate
+	inner           tx.Handler
+}
+
+// indexEventsOptions are the options of the IndexEvents middleware.
+type indexEventsOptions struct {
+	valuePredicates map[string]sdk.EventValuePredicate
+}
+
+// IndexEventsOption configures the IndexEvents middleware.
+type IndexEventsOption func(*indexEventsOptions)
+
+// WithValuePredicates makes the IndexEvents middleware only index the
+// attributes with a predicate, keyed by {eventType}.{attributeKey}, when the
+// predicate matches their value. See sdk.MarkEventsToIndexWithPredicates.
+func WithValuePredicates(predicates map[string]sdk.EventValuePredicate) IndexEventsOption {
+	return func(opts *indexEventsOptions) {
+		opts.valuePredicates = predicates
+	}
 }
 
 // NewIndexEventsTxMiddleware defines a middleware to optionally only index a
 // subset of the emitted events inside the Tendermint events indexer.
-func NewIndexEventsTxMiddleware(indexEvents map[string]struct{}) tx.Middleware {
+func NewIndexEventsTxMiddleware(indexEvents map[string]struct{}, options ...IndexEventsOption) tx.Middleware {
+	var opts indexEventsOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
 	return func(txHandler tx.Handler) tx.Handler {
 		return indexEventsTxHandler{
-			indexEvents: indexEvents,
-			inner:       txHandler,
+			indexEvents:     indexEvents,
+			valuePredicates: opts.valuePredicates,
+			inner:           txHandler,
 		}
 	}
 }
 
 var _ tx.Handler = indexEventsTxHandler{}
 
+// markEvents marks the events to index. The marking is skipped for txs
+// flagged with MemoFlagNoIndex.
+func (txh indexEventsTxHandler) markEvents(ctx context.Context, events []abci.Event) []abci.Event {
+	if noIndexFromContext(sdk.UnwrapSDKContext(ctx)) {
+		return events
+	}
+
+	return sdk.MarkEventsToIndexWithPredicates(events, txh.indexEvents, txh.valuePredicates)
+}
+
 // CheckTx implements tx.Handler.CheckTx method.
 func (txh indexEventsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	res, err := txh.inner.CheckTx(ctx, tx, req)
@@ -36,7 +72,7 @@ func (txh indexEventsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci
 		return res, err
 	}
 
-	res.Events = sdk.MarkEventsToIndex(res.Events, txh.indexEvents)
+	res.Events = txh.markEvents(ctx, res.Events)
 	return res, nil
 }
 
@@ -47,7 +83,7 @@ func (txh indexEventsTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req ab
 		return res, err
 	}
 
-	res.Events = sdk.MarkEventsToIndex(res.Events, txh.indexEvents)
+	res.Events = txh.markEvents(ctx, res.Events)
 	return res, nil
 }
 
@@ -58,6 +94,6 @@ func (txh indexEventsTxHandler) SimulateTx(ctx context.Context, tx sdk.Tx, req t
 		return res, err
 	}
 
-	res.Result.Events = sdk.MarkEventsToIndex(res.Result.Events, txh.indexEvents)
+	res.Result.Events = txh.markEvents(ctx, res.Result.Events)
 	return res, nil
 }
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// eventsTxHandler is a test tx.Handler returning new events on each call.
+type eventsTxHandler struct{}
+
+var _ tx.Handler = eventsTxHandler{}
+
+func (eventsTxHandler) events() []abci.Event {
+	return sdk.Events{
+		sdk.NewEvent("transfer", sdk.NewAttribute("sender", "alice"), sdk.NewAttribute("amount", "10atom")),
+		sdk.NewEvent("message", sdk.NewAttribute("action", "send")),
+	}.ToABCIEvents()
+}
+
+func (txh eventsTxHandler) CheckTx(context.Context, sdk.Tx, abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return abci.ResponseCheckTx{Events: txh.events()}, nil
+}
+func (txh eventsTxHandler) DeliverTx(context.Context, sdk.Tx, abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return abci.ResponseDeliverTx{Events: txh.events()}, nil
+}
+func (txh eventsTxHandler) SimulateTx(context.Context, sdk.Tx, tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return tx.ResponseSimulateTx{Result: &sdk.Result{Events: txh.events()}}, nil
+}
+
+func (s *MWTestSuite) TestIndexEventsValuePredicates() {
+	ctx := s.SetupTest(true) // setup
+	predicates := map[string]sdk.EventValuePredicate{
+		"transfer.sender": func(value string) bool { return value == "alice" },
+		"message.action":  func(value string) bool { return value == "vote" },
+	}
+
+	txHandler := middleware.NewIndexEventsTxMiddleware(nil, middleware.WithValuePredicates(predicates))(eventsTxHandler{})
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// only the attribute whose value matches is indexed
+	s.Require().True(res.Events[0].Attributes[0].Index)
+	s.Require().False(res.Events[0].Attributes[1].Index)
+	s.Require().False(res.Events[1].Attributes[0].Index)
+}
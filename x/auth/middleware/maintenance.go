@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type maintenanceWindowTxHandler struct {
+	start int64
+	end   int64
+	next  tx.Handler
+}
+
+// NewMaintenanceWindowMiddleware returns a middleware that rejects new txs in
+// CheckTx with ErrServiceUnavailable while the block height is within the
+// [start, end] window, both bounds included, e.g. during a coordinated
+// upgrade.
+//
+// ReCheckTx, DeliverTx and SimulateTx are not affected, so that the blocks
+// proposed during the window are still processed.
+func NewMaintenanceWindowMiddleware(start, end int64) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return maintenanceWindowTxHandler{
+			start: start,
+			end:   end,
+			next:  txh,
+		}
+	}
+}
+
+var _ tx.Handler = maintenanceWindowTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh maintenanceWindowTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if req.Type != abci.CheckTxType_Recheck {
+		height := sdk.UnwrapSDKContext(ctx).BlockHeight()
+		if height >= txh.start && height <= txh.end {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrServiceUnavailable, "maintenance window from height %d to %d, current height %d", txh.start, txh.end, height)
+		}
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh maintenanceWindowTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh maintenanceWindowTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMaintenanceWindowMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMaintenanceWindowMiddleware(10, 20))
+
+	testCases := []struct {
+		name     string
+		height   int64
+		inWindow bool
+	}{
+		{"before the window", 9, false},
+		{"start of the window", 10, true},
+		{"inside the window", 15, true},
+		{"end of the window", 20, true},
+		{"after the window", 21, false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			goCtx := sdk.WrapSDKContext(ctx.WithBlockHeight(tc.height))
+
+			_, err := txHandler.CheckTx(goCtx, txTest{}, abci.RequestCheckTx{})
+			if tc.inWindow {
+				s.Require().True(errors.Is(err, sdkerrors.ErrServiceUnavailable))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// recheck, DeliverTx and SimulateTx are never blocked
+			_, err = txHandler.CheckTx(goCtx, txTest{}, abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+			s.Require().NoError(err)
+			_, err = txHandler.DeliverTx(goCtx, txTest{}, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			_, err = txHandler.SimulateTx(goCtx, txTest{}, tx.RequestSimulateTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type maxGasWantedTxHandler struct {
+	max  uint64
+	next tx.Handler
+}
+
+// NewMaxGasWantedMiddleware returns a middleware that rejects, in CheckTx, the
+// txs declaring a gas limit above max, to bound the mempool memory pressure of
+// high-gas txs. It should be placed before the signature verification
+// middlewares, so that such txs fail fast. A zero max disables the limit.
+// CONTRACT: Tx must implement FeeTx interface
+func NewMaxGasWantedMiddleware(max uint64) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return maxGasWantedTxHandler{
+			max:  max,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = maxGasWantedTxHandler{}
+
+// Named implements NamedTxHandler.Named.
+func (txh maxGasWantedTxHandler) Named() string {
+	return "max_gas_wanted"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh maxGasWantedTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if txh.max > 0 {
+		feeTx, ok := sdkTx.(sdk.FeeTx)
+		if !ok {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+		}
+
+		if gas := feeTx.GetGas(); gas > txh.max {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrInvalidGasLimit, "tx gas limit %d exceeds the max %d", gas, txh.max)
+		}
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh maxGasWantedTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh maxGasWantedTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,50 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMaxGasWantedMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		name   string
+		max    uint64
+		gas    uint64
+		expErr bool
+	}{
+		{"below max", 1000, 999, false},
+		{"at max", 1000, 1000, false},
+		{"above max", 1000, 1001, true},
+		{"zero max is unlimited", 0, 1<<63 - 1, false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetGasLimit(tc.gas)
+			testTx := txBuilder.GetTx()
+
+			txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMaxGasWantedMiddleware(tc.max))
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidGasLimit))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// DeliverTx is not limited
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
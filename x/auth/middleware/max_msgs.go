@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type maxMsgsTxHandler struct {
+	max  int
+	next tx.Handler
+}
+
+// NewMaxMsgsMiddleware returns a middleware that rejects txs with more than max
+// msgs, to bound the worst-case execution of a tx. It should be placed before
+// the signature verification middlewares, so that such txs fail fast. A
+// non-positive max disables the limit.
+func NewMaxMsgsMiddleware(max int) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return maxMsgsTxHandler{
+			max:  max,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = maxMsgsTxHandler{}
+
+func (txh maxMsgsTxHandler) checkMsgsCount(tx sdk.Tx) error {
+	if n := len(tx.GetMsgs()); txh.max > 0 && n > txh.max {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "tx has %d msgs, max is %d", n, txh.max)
+	}
+
+	return nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh maxMsgsTxHandler) Named() string {
+	return "max_msgs"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh maxMsgsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkMsgsCount(tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh maxMsgsTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkMsgsCount(tx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh maxMsgsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkMsgsCount(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
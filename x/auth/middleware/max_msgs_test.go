@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMaxMsgsMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMaxMsgsMiddleware(2))
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		desc    string
+		numMsgs int
+		expErr  error
+	}{
+		{"tx under the limit", 1, nil},
+		{"tx exactly at the limit", 2, nil},
+		{"tx one msg over the limit", 3, sdkerrors.ErrInvalidRequest},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			msgs := make([]sdk.Msg, tc.numMsgs)
+			for i := range msgs {
+				msgs[i] = testdata.NewTestMsg(addr1)
+			}
+
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(msgs...))
+			privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+			testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+			s.Require().NoError(err)
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: txBytes})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+			_, simulateErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+
+			for _, err := range []error{checkErr, deliverErr, simulateErr} {
+				if tc.expErr != nil {
+					s.Require().True(errors.Is(err, tc.expErr))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+
+	// a zero max is unlimited
+	txHandler = middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMaxMsgsMiddleware(0))
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr1)))
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+type maxSignersTxHandler struct {
+	max  int
+	next tx.Handler
+}
+
+// NewMaxSignersMiddleware returns a middleware that rejects in CheckTx, with
+// ErrTooManyRequests, the txs with more than max distinct required signers,
+// to bound the signature verification work of the mempool. A multisig account
+// counts as a single signer, its keys are bounded by the TxSigLimit param
+// instead. It should be placed before the signature verification middlewares,
+// so that such txs fail fast. A non-positive max returns the given tx.Handler
+// unchanged.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewMaxSignersMiddleware(max int) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if max <= 0 {
+			return txh
+		}
+
+		return maxSignersTxHandler{
+			max:  max,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = maxSignersTxHandler{}
+
+// Named implements NamedTxHandler.Named.
+func (txh maxSignersTxHandler) Named() string {
+	return "max_signers"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh maxSignersTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	// GetSigners already dedups the signers of the msgs
+	if n := len(sigTx.GetSigners()); n > txh.max {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrTooManyRequests, "tx has %d signers, max is %d", n, txh.max)
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh maxSignersTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh maxSignersTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	kmultisig "github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMaxSignersMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMaxSignersMiddleware(3))
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, addr3 := testdata.KeyTestPubAddr()
+	_, _, addr4 := testdata.KeyTestPubAddr()
+	pubKeys, _ := generatePubKeysAndSignatures(5, nil, false)
+	multisig := sdk.AccAddress(kmultisig.NewLegacyAminoPubKey(3, pubKeys).Address())
+
+	testCases := []struct {
+		name   string
+		msgs   []sdk.Msg
+		expErr bool
+	}{
+		{"at the limit", []sdk.Msg{testdata.NewTestMsg(addr1, addr2, addr3)}, false},
+		{"duplicate signers count once", []sdk.Msg{testdata.NewTestMsg(addr1, addr2), testdata.NewTestMsg(addr2, addr3)}, false},
+		{"a multisig counts as one signer", []sdk.Msg{testdata.NewTestMsg(addr1, addr2, multisig)}, false},
+		{"over the limit", []sdk.Msg{testdata.NewTestMsg(addr1, addr2), testdata.NewTestMsg(addr3, addr4)}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(tc.msgs...))
+			testTx := txBuilder.GetTx()
+
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// the limit only protects the mempool
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
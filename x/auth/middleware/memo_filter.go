@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"regexp"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type memoFilterTxHandler struct {
+	patterns []*regexp.Regexp
+	next     tx.Handler
+}
+
+// NewMemoFilterMiddleware returns a middleware that rejects txs whose memo
+// matches any of the given denylist patterns. Txs without a memo, or not
+// implementing TxWithMemo, are never rejected.
+func NewMemoFilterMiddleware(patterns []*regexp.Regexp) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return memoFilterTxHandler{
+			patterns: patterns,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = memoFilterTxHandler{}
+
+func (txh memoFilterTxHandler) checkMemo(tx sdk.Tx) error {
+	memoTx, ok := tx.(sdk.TxWithMemo)
+	if !ok {
+		return nil
+	}
+
+	memo := memoTx.GetMemo()
+	if memo == "" {
+		return nil
+	}
+
+	for _, pattern := range txh.patterns {
+		if pattern.MatchString(memo) {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "memo matches denied pattern %s", pattern)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh memoFilterTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkMemo(tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh memoFilterTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkMemo(tx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh memoFilterTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkMemo(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"errors"
+	"regexp"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMemoFilterMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewMemoFilterMiddleware([]*regexp.Regexp{
+			regexp.MustCompile(`^exchange:`),
+			regexp.MustCompile(`(?i)unsupported`),
+		}),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		desc   string
+		memo   string
+		expErr error
+	}{
+		{"empty memo", "", nil},
+		{"non-matching memo", "hello exchange:123", nil},
+		{"memo matching first pattern", "exchange:123", sdkerrors.ErrInvalidRequest},
+		{"memo matching second pattern", "an UNSUPPORTED tag", sdkerrors.ErrInvalidRequest},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetMemo(tc.memo)
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			for _, err := range []error{checkErr, deliverErr} {
+				if tc.expErr != nil {
+					s.Require().True(errors.Is(err, tc.expErr))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+
+	// txs without memo support are not rejected
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+}
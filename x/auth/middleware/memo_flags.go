@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// MemoFlagNoIndex is the memo flag disabling the indexing of the tx's events,
+// see NoIndexFlagHandler.
+const MemoFlagNoIndex = "#noindex"
+
+// FlagHandler applies a memo flag to the sdk.Context of a tx, for the inner
+// middlewares to pick up.
+type FlagHandler func(sdkCtx sdk.Context) sdk.Context
+
+// noIndexKey is the sdk.Context key set by the NoIndexFlagHandler.
+type noIndexKey struct{}
+
+// NoIndexFlagHandler is the FlagHandler making the IndexEvents middleware skip
+// the marking of the tx's events, so that none of them is indexed.
+func NoIndexFlagHandler(sdkCtx sdk.Context) sdk.Context {
+	return sdkCtx.WithValue(noIndexKey{}, true)
+}
+
+// noIndexFromContext reports whether the NoIndexFlagHandler was applied.
+func noIndexFromContext(sdkCtx sdk.Context) bool {
+	noIndex, _ := sdkCtx.Value(noIndexKey{}).(bool)
+	return noIndex
+}
+
+// ParseMemoFlags returns the flags the memo starts with, i.e. its leading
+// whitespace-separated words starting with `#`. For example, the memo
+// "#noindex #foo hello #bar" has the flags "#noindex" and "#foo".
+func ParseMemoFlags(memo string) []string {
+	var flags []string
+	for _, word := range strings.Fields(memo) {
+		if !strings.HasPrefix(word, "#") {
+			break
+		}
+
+		flags = append(flags, word)
+	}
+
+	return flags
+}
+
+type memoFlagsTxHandler struct {
+	flags map[string]FlagHandler
+	next  tx.Handler
+}
+
+// NewMemoFlagsMiddleware returns a middleware that parses the flags of the tx
+// memo, see ParseMemoFlags, and applies the handlers of the recognized ones to
+// the sdk.Context of the inner middlewares. Unknown flags are ignored.
+//
+// For the IndexEvents middleware to pick up the MemoFlagNoIndex flag, this
+// middleware must be placed outside of it.
+func NewMemoFlagsMiddleware(flags map[string]FlagHandler) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return memoFlagsTxHandler{
+			flags: flags,
+			next:  txh,
+		}
+	}
+}
+
+var _ tx.Handler = memoFlagsTxHandler{}
+
+// applyFlags returns the context with the handlers of the memo flags applied.
+func (txh memoFlagsTxHandler) applyFlags(ctx context.Context, sdkTx sdk.Tx) context.Context {
+	memoTx, ok := sdkTx.(sdk.TxWithMemo)
+	if len(txh.flags) == 0 || !ok {
+		return ctx
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	for _, flag := range ParseMemoFlags(memoTx.GetMemo()) {
+		if handler, ok := txh.flags[flag]; ok {
+			sdkCtx = handler(sdkCtx)
+		}
+	}
+
+	return sdk.WrapSDKContext(sdkCtx)
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh memoFlagsTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(txh.applyFlags(ctx, sdkTx), sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh memoFlagsTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(txh.applyFlags(ctx, sdkTx), sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh memoFlagsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(txh.applyFlags(ctx, sdkTx), sdkTx, req)
+}
@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestParseMemoFlags() {
+	testCases := []struct {
+		memo     string
+		expFlags []string
+	}{
+		{"", nil},
+		{"hello", nil},
+		{"#noindex", []string{"#noindex"}},
+		{"  #noindex   #foo hello", []string{"#noindex", "#foo"}},
+		{"#noindex hello #foo", []string{"#noindex"}},
+		{"hello #noindex", nil},
+	}
+
+	for _, tc := range testCases {
+		s.Require().Equal(tc.expFlags, middleware.ParseMemoFlags(tc.memo), tc.memo)
+	}
+}
+
+func (s *MWTestSuite) TestMemoFlagsNoIndex() {
+	ctx := s.SetupTest(true) // setup
+
+	var customApplied bool
+	txHandler := middleware.ComposeMiddlewares(
+		eventsTxHandler{},
+		middleware.NewMemoFlagsMiddleware(map[string]middleware.FlagHandler{
+			middleware.MemoFlagNoIndex: middleware.NoIndexFlagHandler,
+			"#custom": func(sdkCtx sdk.Context) sdk.Context {
+				customApplied = true
+				return sdkCtx
+			},
+		}),
+		middleware.NewIndexEventsTxMiddleware(nil),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	// newTx returns a tx with the given memo.
+	newTx := func(memo string) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetMemo(memo)
+
+		return txBuilder.GetTx()
+	}
+
+	// requireIndexed checks whether all the event attributes are indexed.
+	requireIndexed := func(expIndexed bool, events []abci.Event) {
+		for _, event := range events {
+			for _, attr := range event.Attributes {
+				s.Require().Equal(expIndexed, attr.Index)
+			}
+		}
+	}
+
+	// without flags, all events are indexed
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx("hello"), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	requireIndexed(true, res.Events)
+
+	// unknown flags are ignored
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx("#unknown hello"), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	requireIndexed(true, res.Events)
+
+	// the noindex flag skips the indexing, alongside other flags
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx("#custom #noindex hello"), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	requireIndexed(false, res.Events)
+	s.Require().True(customApplied)
+
+	checkRes, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx("#noindex"), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	requireIndexed(false, checkRes.Events)
+
+	// flags must prefix the memo
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx("hello #noindex"), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	requireIndexed(true, res.Events)
+}
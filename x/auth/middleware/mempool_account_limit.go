@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// pendingTx is a tx accepted in the mempool, as tracked by the
+// pendingTxCounter.
+type pendingTx struct {
+	signers []string
+	// height is the height of the last block after which the tx was checked.
+	height int64
+}
+
+// pendingTxCounter holds the in-memory set of txs accepted in the mempool but
+// not yet committed, keyed by tx hash, and their count by signer address. It is
+// shared by all copies of the perAccountMempoolLimitTxHandler.
+type pendingTxCounter struct {
+	mtx     sync.Mutex
+	max     int
+	height  int64
+	txs     map[string]pendingTx
+	pending map[string]int
+}
+
+type perAccountMempoolLimitTxHandler struct {
+	counter *pendingTxCounter
+	next    tx.Handler
+}
+
+// NewPerAccountMempoolLimitMiddleware returns a middleware that limits the
+// number of outstanding txs each signer can have in the mempool. A tx accepted
+// in CheckTx counts against the limit of each of its signers until it is
+// delivered in a block, or leaves the mempool. Once a signer has max
+// outstanding txs, further txs are rejected with ErrTooManyRequests.
+//
+// As Tendermint rechecks the txs left in the mempool after each block, before
+// checking new txs, a tx which isn't rechecked by then is considered evicted
+// and is no longer counted. If the mempool recheck is disabled, the txs are
+// only counted until the next block.
+//
+// The limit is local mempool protection and never fails DeliverTx. A max of 0
+// or less disables the middleware.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewPerAccountMempoolLimitMiddleware(max int) tx.Middleware {
+	if max <= 0 {
+		return func(txh tx.Handler) tx.Handler { return txh }
+	}
+
+	counter := &pendingTxCounter{
+		max:     max,
+		txs:     make(map[string]pendingTx),
+		pending: make(map[string]int),
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return perAccountMempoolLimitTxHandler{
+			counter: counter,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = perAccountMempoolLimitTxHandler{}
+
+// add counts the tx with the given hash as outstanding for each given signer,
+// as checked after the block of the given height, and returns true if it wasn't
+// already counted. The txs which weren't rechecked after that block are no
+// longer counted. If any signer already reached the limit, nothing is counted
+// and an error is returned.
+func (c *pendingTxCounter) add(hash []byte, signers []sdk.AccAddress, height int64) (bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if height > c.height {
+		for txHash, pending := range c.txs {
+			if pending.height < height {
+				c.removeLocked(txHash)
+			}
+		}
+		c.height = height
+	}
+
+	if _, ok := c.txs[string(hash)]; ok {
+		return false, nil
+	}
+
+	addrs := make([]string, len(signers))
+	for i, signer := range signers {
+		addrs[i] = signer.String()
+		if c.pending[addrs[i]] >= c.max {
+			return false, sdkerrors.Wrapf(sdkerrors.ErrTooManyRequests, "signer %s has %d outstanding txs in the mempool", signer, c.max)
+		}
+	}
+
+	for _, addr := range addrs {
+		c.pending[addr]++
+	}
+	c.txs[string(hash)] = pendingTx{signers: addrs, height: height}
+
+	return true, nil
+}
+
+// recheck marks the tx with the given hash as checked after the block of the
+// given height, if it is counted.
+func (c *pendingTxCounter) recheck(hash []byte, height int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if pending, ok := c.txs[string(hash)]; ok {
+		pending.height = height
+		c.txs[string(hash)] = pending
+	}
+}
+
+// remove stops counting the tx with the given hash, if it is counted.
+func (c *pendingTxCounter) remove(hash []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.removeLocked(string(hash))
+}
+
+// removeLocked stops counting the tx with the given hash, if it is counted.
+// c.mtx must be held.
+func (c *pendingTxCounter) removeLocked(hash string) {
+	pending, ok := c.txs[hash]
+	if !ok {
+		// the tx may not have gone through this node's mempool
+		return
+	}
+
+	for _, addr := range pending.signers {
+		if c.pending[addr] <= 1 {
+			delete(c.pending, addr)
+			continue
+		}
+
+		c.pending[addr]--
+	}
+	delete(c.txs, hash)
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh perAccountMempoolLimitTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+	hash := tmhash.Sum(req.Tx)
+	height := sdk.UnwrapSDKContext(ctx).BlockHeight()
+
+	// the tx is already counted, it is only released if it gets evicted
+	if req.Type == abci.CheckTxType_Recheck {
+		res, err := txh.next.CheckTx(ctx, sdkTx, req)
+		if err != nil {
+			txh.counter.remove(hash)
+		} else {
+			txh.counter.recheck(hash, height)
+		}
+
+		return res, err
+	}
+
+	added, err := txh.counter.add(hash, sigTx.GetSigners(), height)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	res, err := txh.next.CheckTx(ctx, sdkTx, req)
+	if err != nil && added {
+		// the tx doesn't enter the mempool
+		txh.counter.remove(hash)
+	}
+
+	return res, err
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh perAccountMempoolLimitTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	// the tx is committed in the block and leaves the mempool, whatever its
+	// result, unless it's a dry-run tx, which isn't part of the block
+	if !isDryRun(sdk.UnwrapSDKContext(ctx)) {
+		defer txh.counter.remove(tmhash.Sum(req.Tx))
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh perAccountMempoolLimitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
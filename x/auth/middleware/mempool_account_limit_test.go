@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestPerAccountMempoolLimitMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewPerAccountMempoolLimitMiddleware(2),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+
+	checkTx := func(sdkCtx sdk.Context, sdkTx sdk.Tx, txBytes string, checkType abci.CheckTxType) error {
+		_, err := txHandler.CheckTx(sdk.WrapSDKContext(sdkCtx), sdkTx, abci.RequestCheckTx{Tx: []byte(txBytes), Type: checkType})
+		return err
+	}
+	deliverTx := func(txBytes string) {
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: []byte(txBytes)})
+		s.Require().NoError(err)
+	}
+
+	// txs are accepted up to the limit
+	s.Require().NoError(checkTx(ctx, testTx, "tx1", abci.CheckTxType_New))
+	s.Require().NoError(checkTx(ctx, testTx, "tx2", abci.CheckTxType_New))
+	err := checkTx(ctx, testTx, "tx3", abci.CheckTxType_New)
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// other signers are not affected
+	otherBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(otherBuilder.SetMsgs(testdata.NewTestMsg(addr2)))
+	s.Require().NoError(checkTx(ctx, otherBuilder.GetTx(), "other1", abci.CheckTxType_New))
+
+	// rechecking an outstanding tx doesn't count it twice
+	s.Require().NoError(checkTx(ctx, testTx, "tx1", abci.CheckTxType_Recheck))
+
+	// delivering a tx unknown to the mempool doesn't free a slot
+	deliverTx("unknown")
+	err = checkTx(ctx, testTx, "tx3", abci.CheckTxType_New)
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// committing a tx frees a slot
+	deliverTx("tx1")
+	s.Require().NoError(checkTx(ctx, testTx, "tx3", abci.CheckTxType_New))
+	err = checkTx(ctx, testTx, "tx4", abci.CheckTxType_New)
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// a tx which isn't rechecked after the next block was evicted, and frees
+	// its slot
+	nextCtx := ctx.WithBlockHeight(ctx.BlockHeight() + 1)
+	s.Require().NoError(checkTx(nextCtx, testTx, "tx3", abci.CheckTxType_Recheck))
+	s.Require().NoError(checkTx(nextCtx, testTx, "tx4", abci.CheckTxType_New))
+	err = checkTx(nextCtx, testTx, "tx5", abci.CheckTxType_New)
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+}
+
+func (s *MWTestSuite) TestPerAccountMempoolLimitMiddlewareRejectedTx() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+
+	limit := middleware.NewPerAccountMempoolLimitMiddleware(1)
+	failing := limit(failingTxHandler{sdkerrors.ErrInsufficientFee})
+	passing := limit(noopTxHandler{})
+
+	// txs rejected by the next handlers don't count against the limit
+	_, err := failing.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: []byte("tx1")})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: []byte("tx2")})
+	s.Require().NoError(err)
+
+	// a tx evicted on recheck frees its slot
+	_, err = failing.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: []byte("tx2"), Type: abci.CheckTxType_Recheck})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: []byte("tx3")})
+	s.Require().NoError(err)
+}
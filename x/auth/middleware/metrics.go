@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Phases reported by the latency metrics middleware.
+const (
+	PhaseCheckTx    = "check"
+	PhaseDeliverTx  = "deliver"
+	PhaseSimulateTx = "simulate"
+)
+
+// MetricsReporter defines the contract used by the latency metrics middleware
+// to report the processing time of txs.
+type MetricsReporter interface {
+	ObserveLatency(phase string, d time.Duration)
+}
+
+type latencyMetricsTxHandler struct {
+	reporter MetricsReporter
+	next     tx.Handler
+}
+
+// NewLatencyMetricsMiddleware returns a middleware that measures the wall-clock
+// time spent in the inner middlewares, and reports it to the given
+// MetricsReporter along with the phase (check, deliver or simulate).
+func NewLatencyMetricsMiddleware(reporter MetricsReporter) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return latencyMetricsTxHandler{
+			reporter: reporter,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = latencyMetricsTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx method.
+func (txh latencyMetricsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	defer txh.observeSince(PhaseCheckTx, time.Now())
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx method.
+func (txh latencyMetricsTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	defer txh.observeSince(PhaseDeliverTx, time.Now())
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh latencyMetricsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	defer txh.observeSince(PhaseSimulateTx, time.Now())
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
+
+func (txh latencyMetricsTxHandler) observeSince(phase string, start time.Time) {
+	txh.reporter.ObserveLatency(phase, time.Since(start))
+}
@@ -0,0 +1,40 @@
+package middleware_test
+
+import (
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// fakeMetricsReporter records the phases of all observations.
+type fakeMetricsReporter struct {
+	phases []string
+}
+
+var _ middleware.MetricsReporter = &fakeMetricsReporter{}
+
+func (r *fakeMetricsReporter) ObserveLatency(phase string, _ time.Duration) {
+	r.phases = append(r.phases, phase)
+}
+
+func (s *MWTestSuite) TestLatencyMetricsMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	reporter := &fakeMetricsReporter{}
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewLatencyMetricsMiddleware(reporter))
+
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{middleware.PhaseCheckTx}, reporter.phases)
+
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{middleware.PhaseCheckTx, middleware.PhaseDeliverTx}, reporter.phases)
+
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), txTest{}, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{middleware.PhaseCheckTx, middleware.PhaseDeliverTx, middleware.PhaseSimulateTx}, reporter.phases)
+}
@@ -1,6 +1,11 @@
 package middleware
 
 import (
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
@@ -22,19 +27,156 @@ import (
 // A.post
 // ```
 // is created by calling `ComposeMiddlewares(H, A, B)`.
+//
+// The returned tx.Handler implements tx.MiddlewareDescriber, listing A, B
+// and H.
 func ComposeMiddlewares(txHandler tx.Handler, middlewares ...tx.Middleware) tx.Handler {
+	described := describeTxHandler(txHandler, nil)
 	for i := len(middlewares) - 1; i >= 0; i-- {
-		txHandler = middlewares[i](txHandler)
+		next := middlewares[i](txHandler)
+		// A disabled middleware returns the tx.Handler it wraps, which is
+		// already described.
+		if sameTxHandler(next, txHandler) {
+			continue
+		}
+
+		txHandler = next
+		described = describeTxHandler(txHandler, described)
+	}
+
+	return composedTxHandler{Handler: txHandler, middlewares: described}
+}
+
+// PrioritizedMiddleware is a tx.Middleware associated with a priority, which
+// determines its position in the middleware stack.
+type PrioritizedMiddleware struct {
+	Middleware tx.Middleware
+	Priority   int
+}
+
+// MiddlewareWithPriority associates a priority to a middleware, to be used with
+// ComposeMiddlewaresWithPriority.
+func MiddlewareWithPriority(m tx.Middleware, p int) PrioritizedMiddleware {
+	return PrioritizedMiddleware{Middleware: m, Priority: p}
+}
+
+// ComposeMiddlewaresWithPriority composes multiple middlewares on top of a
+// tx.Handler, ordered by their priority instead of their position in the
+// variadic arguments. Middlewares with a lower priority are outer, i.e. they
+// run first on the way in, and last on the way out.
+//
+// Two middlewares with the same priority would have an ambiguous order, so
+// duplicate priorities are rejected.
+func ComposeMiddlewaresWithPriority(txHandler tx.Handler, middlewares ...PrioritizedMiddleware) (tx.Handler, error) {
+	sorted := make([]PrioritizedMiddleware, len(middlewares))
+	copy(sorted, middlewares)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+
+	ordered := make([]tx.Middleware, len(sorted))
+	for i, m := range sorted {
+		if i > 0 && sorted[i-1].Priority == m.Priority {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrLogic, "duplicate middleware priority %d", m.Priority)
+		}
+
+		ordered[i] = m.Middleware
 	}
 
-	return txHandler
+	return ComposeMiddlewares(txHandler, ordered...), nil
 }
 
 type TxHandlerOptions struct {
 	Debug bool
+	// LogMiddlewareDecisions defines whether the decision of each named
+	// middleware is logged at debug level, see
+	// ComposeMiddlewaresWithDecisionLog.
+	LogMiddlewareDecisions bool
 	// IndexEvents defines the set of events in the form {eventType}.{attributeKey},
 	// which informs Tendermint what to index. If empty, all events will be indexed.
 	IndexEvents map[string]struct{}
+	// MaxTxBytes defines the maximum size in bytes of a tx. If zero, the tx
+	// size is not limited.
+	MaxTxBytes int
+	// MaxMsgs defines the maximum number of msgs in a tx. If zero, the number
+	// of msgs is not limited.
+	MaxMsgs int
+	// MaxSigners defines the maximum number of distinct signers of the txs
+	// accepted in CheckTx. If zero, the number of signers is not bounded.
+	MaxSigners int
+	// MsgCombinationRules defines the policies on the msg types a tx can
+	// combine, see NewMsgCombinationPolicyMiddleware.
+	MsgCombinationRules []CombinationRule
+	// MsgDedup defines how the txs holding identical msgs are handled. By
+	// default, they are left untouched.
+	MsgDedup MsgDedupMode
+	// MsgOrder defines the msg type URLs whose msgs are executed first, in
+	// that order, see NewMsgReorderMiddleware. If empty, the msgs are executed
+	// in the tx order.
+	MsgOrder []string
+	// CanonicalDenoms maps the lower-cased spellings of the denoms to their
+	// canonical denoms, used to normalize the bank msgs before execution, see
+	// NewDenomNormalizerMiddleware. If empty, the denoms are left unchanged.
+	CanonicalDenoms map[string]string
+	// GovGatedMsgs defines the msg type URLs which can only be signed by
+	// GovAuthority, see NewGovGatedMsgMiddleware. If empty, no msg is gated.
+	GovGatedMsgs map[string]struct{}
+	GovAuthority string
+	// MaxGasWanted defines the maximum gas limit of the txs accepted in
+	// CheckTx. If zero, the gas limit is not bounded.
+	MaxGasWanted uint64
+	// GasDescriptorLogSize defines the number of gas consumptions recorded for
+	// each tx, to report the top gas consumers of the txs running out of gas.
+	// If zero, no gas consumption is recorded.
+	GasDescriptorLogSize int
+	// DisableGasMetering executes the txs of the InternalSigners without gas
+	// metering nor fees, see GasTxOptions.DisableGasMetering. It must never
+	// be set on a public chain.
+	DisableGasMetering bool
+	InternalSigners    []sdk.AccAddress
+	// FeeDenoms defines the denoms accepted in tx fees. If empty, all denoms
+	// are accepted.
+	FeeDenoms []string
+	// MaxTimeoutHeightFuture defines how many blocks ahead of the current block
+	// height the timeout height of the txs accepted in CheckTx can be. If zero,
+	// the timeout height is not bounded.
+	MaxTimeoutHeightFuture int64
+	// RecordAnteGas defines whether a `tx` event holding the gas consumed
+	// before the msgs execution is added to the DeliverTx and SimulateTx
+	// responses, see GasTxOptions.RecordAnteGas.
+	RecordAnteGas bool
+	// EmitRejectEvents defines whether a `tx_rejected` event is emitted when a
+	// tx is rejected in CheckTx.
+	EmitRejectEvents bool
+	// NegativeTxCache, if set, lets the error middleware fast-fail the txs
+	// already rejected by ValidateBasic, see ErrorTxOptions.NegativeCache.
+	NegativeTxCache *NegativeTxCache
+	// EmitTxSummary defines whether a `tx_summary` event, holding the fee and
+	// the gas of the tx, is appended to the DeliverTx responses.
+	EmitTxSummary bool
+	// SimulateTimeout defines the maximum wall-clock time a SimulateTx call
+	// can spend. If zero, simulations are not bounded.
+	SimulateTimeout time.Duration
+	// SimulateSequenceCheck makes the SigVerification middleware check the
+	// signature sequences in simulations, see
+	// SigVerificationOptions.SimulateSequenceCheck.
+	SimulateSequenceCheck bool
+	// NonAtomicMsgExecution defines whether the messages of a tx are executed
+	// independently, see RunMsgsOptions. Defaults to atomic execution.
+	NonAtomicMsgExecution bool
+	// MsgTypeCounter, if set, counts the msgs executed in DeliverTx per msg
+	// type, see RunMsgsOptions.
+	MsgTypeCounter *MsgTypeCounter
+	// RetryableMsgErrors defines the errors on which a msg execution is
+	// retried once, see RunMsgsOptions.RetryableErrors.
+	RetryableMsgErrors []error
+	// RecoveryHandlers defines custom handlers for the panics caught by the
+	// Recovery middleware, see RecoveryMiddleware.AddRecoveryHandler.
+	RecoveryHandlers []RecoveryHandler
+	// ErrorEncoder, if set, converts the errors of rejected txs into the
+	// CheckTx and DeliverTx ABCI responses, see NewErrorEncoderMiddleware.
+	ErrorEncoder ErrorEncoder
+	// MemoFlags defines the handlers of the flags recognized in tx memos, e.g.
+	// NoIndexFlagHandler for MemoFlagNoIndex. See NewMemoFlagsMiddleware.
+	MemoFlags map[string]FlagHandler
 
 	LegacyRouter     sdk.Router
 	MsgServiceRouter *MsgServiceRouter
@@ -44,6 +186,62 @@ type TxHandlerOptions struct {
 	FeegrantKeeper  FeegrantKeeper
 	SignModeHandler authsigning.SignModeHandler
 	SigGasConsumer  func(meter sdk.GasMeter, sig signing.SignatureV2, params types.Params) error
+	// SigVerifyCosts overrides the signature verification gas of the given
+	// public key types, see NewSigVerificationGasConsumer. It is ignored if
+	// SigGasConsumer is set.
+	SigVerifyCosts map[string]uint64
+	// DisallowedSignModes defines the sign modes the tx signatures can't use,
+	// see NewSignModePolicyMiddleware. If empty, all sign modes are allowed.
+	DisallowedSignModes []signing.SignMode
+	// AuthzKeeper, if set, lets the tx signers submit msgs on behalf of the
+	// accounts which granted them, see NewImplicitAuthzMiddleware.
+	AuthzKeeper AuthzKeeper
+
+	// FeeSplits defines the collectors the DeductFee middleware splits the
+	// fees between. By default, all fees go to the fee collector.
+	FeeSplits []FeeSplit
+	// DistributionKeeper and StakingKeeper, if both set, let the DeductFee
+	// middleware pay fees with the fee payer's pending staking rewards.
+	DistributionKeeper DistributionKeeper
+	StakingKeeper      StakingKeeper
+	// OnFeeDeductionFailure, if set, is called when the fees of a tx can't be
+	// deducted in DeliverTx, see DeductFeeOptions.
+	OnFeeDeductionFailure func(ctx sdk.Context, payer sdk.AccAddress, err error)
+	// FeeBurnFraction defines the fraction of each fee the DeductFee
+	// middleware burns, see DeductFeeOptions.BurnFraction. By default, no fee
+	// is burned.
+	FeeBurnFraction sdk.Dec
+	// FeeExempt, if set, defines the txs whose fees the DeductFee middleware
+	// doesn't deduct, see DeductFeeOptions.FeeExempt.
+	FeeExempt func(ctx sdk.Context, tx sdk.Tx) bool
+	// FeegrantGraceBlocks and FeegrantGraceBlockTime define the grace window
+	// during which the DeductFee middleware still honors the expired fee
+	// grants, see DeductFeeOptions.FeegrantGraceBlocks. By default, there is
+	// no grace window.
+	FeegrantGraceBlocks    uint64
+	FeegrantGraceBlockTime time.Duration
+	// SequenceGapTolerance defines how many sequences ahead of a signer's
+	// account sequence the SigVerification middleware accepts in CheckTx.
+	SequenceGapTolerance uint64
+	// BatchVerify makes the SigVerification middleware batch verify the
+	// signatures of each tx in CheckTx, see SigVerificationOptions.BatchVerify.
+	BatchVerify bool
+	// OTelTracer, if set, records an OpenTelemetry span for each tx and each
+	// of its msgs.
+	OTelTracer trace.Tracer
+	// DropEvents defines the event types stripped from the DeliverTx
+	// responses. The dropped events are not seen by indexers.
+	DropEvents map[string]struct{}
+	// MaxTxEvents defines the maximum number of events a tx can emit, see
+	// NewEventCapMiddleware. If zero, the number of events is not limited.
+	MaxTxEvents int
+	// ContextEnricher, if set, transforms the sdk.Context passed to the msg
+	// handlers, see NewContextEnricherMiddleware.
+	ContextEnricher ContextEnricher
+	// MsgValidators, if set, holds the stateful pre-execution checks of the
+	// msgs, consulted by the MsgValidator middleware after the signatures are
+	// verified, see NewMsgValidatorMiddleware.
+	MsgValidators *MsgValidatorRegistry
 }
 
 // NewDefaultTxHandler defines a TxHandler middleware stacks that should work
@@ -64,36 +262,128 @@ func NewDefaultTxHandler(options TxHandlerOptions) (tx.Handler, error) {
 	var sigGasConsumer = options.SigGasConsumer
 	if sigGasConsumer == nil {
 		sigGasConsumer = DefaultSigVerificationGasConsumer
+		if len(options.SigVerifyCosts) > 0 {
+			sigGasConsumer = NewSigVerificationGasConsumer(options.SigVerifyCosts)
+		}
+	}
+
+	recovery := NewRecoveryMiddleware()
+	for _, h := range options.RecoveryHandlers {
+		recovery.AddRecoveryHandler(h)
+	}
+
+	compose := ComposeMiddlewares
+	if options.LogMiddlewareDecisions {
+		compose = ComposeMiddlewaresWithDecisionLog
 	}
 
-	return ComposeMiddlewares(
-		NewRunMsgsTxHandler(options.MsgServiceRouter, options.LegacyRouter),
+	return compose(
+		NewRunMsgsTxHandlerWithOptions(options.MsgServiceRouter, options.LegacyRouter, RunMsgsOptions{
+			NonAtomicMsgExecution: options.NonAtomicMsgExecution,
+			MsgTypeCounter:        options.MsgTypeCounter,
+			RetryableErrors:       options.RetryableMsgErrors,
+		}),
+		// Optionally encode the errors of rejected txs. It must be the
+		// outermost middleware, as it doesn't return the errors anymore.
+		NewErrorEncoderMiddleware(options.ErrorEncoder),
+		// Optionally summarize the fee and gas of the txs, once the gas used
+		// is final.
+		NewTxSummaryMiddleware(options.EmitTxSummary),
+		// Estimate the fee of simulated txs from the gas used, which is set by
+		// the Gas middleware.
+		EstimateFeeMiddleware,
+		// Bound the time spent in simulations, checked between each msg.
+		NewSimulateTimeoutMiddleware(options.SimulateTimeout),
+		// Optionally trace the txs, reporting the gas set by the Gas
+		// middleware.
+		NewOTelMiddleware(options.OTelTracer),
 		// Set a new GasMeter on sdk.Context.
 		//
 		// Make sure the Gas middleware is outside of all other middlewares
 		// that reads the GasMeter. In our case, the Recovery middleware reads
 		// the GasMeter to populate GasInfo.
-		GasTxMiddleware,
+		NewGasTxMiddlewareWithOptions(GasTxOptions{
+			DescriptorLogSize:  options.GasDescriptorLogSize,
+			RecordAnteGas:      options.RecordAnteGas,
+			DisableGasMetering: options.DisableGasMetering,
+			InternalSigners:    options.InternalSigners,
+		}),
+		// Optionally emit an event on rejected txs, and fast-fail the txs
+		// known to be invalid. It is placed outside of the Recovery
+		// middleware so that recovered panics are reported too.
+		NewErrorTxMiddlewareWithOptions(ErrorTxOptions{
+			EmitRejectEvents: options.EmitRejectEvents,
+			NegativeCache:    options.NegativeTxCache,
+		}),
 		// Recover from panics. Panics outside of this middleware won't be
 		// caught, be careful!
-		RecoveryTxMiddleware,
+		recovery.Middleware,
+		// Apply the memo flags, which may disable the events indexing, so it
+		// must be outside of the IndexEvents middleware.
+		NewMemoFlagsMiddleware(options.MemoFlags),
 		// Choose which events to index in Tendermint. Make sure no events are
 		// emitted outside of this middleware.
 		NewIndexEventsTxMiddleware(options.IndexEvents),
+		// Optionally strip some event types from the DeliverTx responses.
+		NewEventFilterMiddleware(options.DropEvents),
 		// Reject all extension options which can optionally be included in the
 		// tx.
 		RejectExtensionOptionsMiddleware,
+		// Reject oversized txs, or txs with too many msgs, before doing any
+		// expensive work on them.
+		NewTxSizeLimitMiddleware(options.MaxTxBytes),
+		NewMaxMsgsMiddleware(options.MaxMsgs),
+		NewMaxSignersMiddleware(options.MaxSigners),
+		NewMsgCombinationPolicyMiddleware(options.MsgCombinationRules),
+		NewMsgDedupMiddleware(options.MsgDedup),
+		NewMsgReorderMiddleware(options.MsgOrder),
+		NewDenomNormalizerMiddleware(options.CanonicalDenoms),
+		NewGovGatedMsgMiddleware(options.GovGatedMsgs, options.GovAuthority),
+		NewMaxGasWantedMiddleware(options.MaxGasWanted),
+		// Reject txs with msgs that can't be routed before verifying their
+		// signatures.
+		NewRejectUnknownMsgsMiddleware(options.MsgServiceRouter, options.LegacyRouter),
 		MempoolFeeMiddleware,
+		NewFeeDenomWhitelistMiddleware(options.FeeDenoms),
+		// Optionally resolve the signers of the txs from their signatures,
+		// before the signatures are counted.
+		NewImplicitAuthzMiddleware(options.AuthzKeeper),
 		ValidateBasicMiddleware,
-		TxTimeoutHeightMiddleware,
+		NewTimeoutHeightBoundsMiddleware(options.MaxTimeoutHeightFuture),
 		ValidateMemoMiddleware(options.AccountKeeper),
 		ConsumeTxSizeGasMiddleware(options.AccountKeeper),
-		DeductFeeMiddleware(options.AccountKeeper, options.BankKeeper, options.FeegrantKeeper),
+		NewDeductFeeMiddleware(options.AccountKeeper, options.BankKeeper, options.FeegrantKeeper, DeductFeeOptions{
+			FeeSplits:              options.FeeSplits,
+			DistributionKeeper:     options.DistributionKeeper,
+			StakingKeeper:          options.StakingKeeper,
+			OnFeeDeductionFailure:  options.OnFeeDeductionFailure,
+			BurnFraction:           options.FeeBurnFraction,
+			FeeExempt:              options.FeeExempt,
+			FeegrantGraceBlocks:    options.FeegrantGraceBlocks,
+			FeegrantGraceBlockTime: options.FeegrantGraceBlockTime,
+		}),
 		SetPubKeyMiddleware(options.AccountKeeper),
 		ValidateSigCountMiddleware(options.AccountKeeper),
+		NewSignModePolicyMiddleware(options.DisallowedSignModes),
 		SigGasConsumeMiddleware(options.AccountKeeper, sigGasConsumer),
-		SigVerificationMiddleware(options.AccountKeeper, options.SignModeHandler),
+		NewSigVerificationMiddleware(options.AccountKeeper, options.SignModeHandler, SigVerificationOptions{
+			SimulateSequenceCheck: options.SimulateSequenceCheck,
+			SequenceGapTolerance:  options.SequenceGapTolerance,
+			BatchVerify:           options.BatchVerify,
+		}),
+		// Lift the gas metering of the txs of internal signers, if enabled,
+		// only once their signatures are verified.
+		NewDisableGasMeteringMiddleware(options.DisableGasMetering),
 		NewTipMiddleware(options.BankKeeper),
 		IncrementSequenceMiddleware(options.AccountKeeper),
+		// Consult the registered msg validators right before the msgs are
+		// routed.
+		NewMsgValidatorMiddleware(options.MsgValidators),
+		// Optionally enrich the sdk.Context of the msg handlers, after all
+		// the ante-equivalent middlewares.
+		NewContextEnricherMiddleware(options.ContextEnricher),
+		// Fail the txs emitting too many events. Only the msg execution is
+		// discarded, so it must be right before the msg router.
+		NewEventCapMiddleware(options.MaxTxEvents),
 	), nil
 }
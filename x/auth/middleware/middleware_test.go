@@ -1,6 +1,7 @@
 package middleware_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
 	"github.com/cosmos/cosmos-sdk/x/auth/types"
@@ -1151,3 +1153,126 @@ func (s *MWTestSuite) TestTxHandlerReCheck() {
 	_, err = s.txHandler.CheckTx(sdk.WrapSDKContext(ctx), tx, abci.RequestCheckTx{})
 	s.Require().NotNil(err, "txhandler on recheck did not fail once feePayer no longer has sufficient funds")
 }
+
+// recordingTxHandler is a test middleware recording its name in calls when
+// its CheckTx is called.
+type recordingTxHandler struct {
+	name  string
+	calls *[]string
+	next  txtypes.Handler
+}
+
+var _ txtypes.Handler = recordingTxHandler{}
+
+func recordingMiddleware(name string, calls *[]string) txtypes.Middleware {
+	return func(txh txtypes.Handler) txtypes.Handler {
+		return recordingTxHandler{name: name, calls: calls, next: txh}
+	}
+}
+
+func (txh recordingTxHandler) Named() string { return txh.name }
+
+func (txh recordingTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	*txh.calls = append(*txh.calls, txh.name)
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+func (txh recordingTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	*txh.calls = append(*txh.calls, txh.name)
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+func (txh recordingTxHandler) SimulateTx(ctx context.Context, tx sdk.Tx, req txtypes.RequestSimulateTx) (txtypes.ResponseSimulateTx, error) {
+	*txh.calls = append(*txh.calls, txh.name)
+	return txh.next.SimulateTx(ctx, tx, req)
+}
+
+func (s *MWTestSuite) TestComposeMiddlewaresWithPriority() {
+	ctx := s.SetupTest(true) // setup
+
+	var calls []string
+	txHandler, err := middleware.ComposeMiddlewaresWithPriority(
+		noopTxHandler{},
+		middleware.MiddlewareWithPriority(recordingMiddleware("c", &calls), 30),
+		middleware.MiddlewareWithPriority(recordingMiddleware("a", &calls), 10),
+		middleware.MiddlewareWithPriority(recordingMiddleware("b", &calls), 20),
+	)
+	s.Require().NoError(err)
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"a", "b", "c"}, calls)
+
+	// duplicate priorities are rejected
+	_, err = middleware.ComposeMiddlewaresWithPriority(
+		noopTxHandler{},
+		middleware.MiddlewareWithPriority(recordingMiddleware("a", &calls), 10),
+		middleware.MiddlewareWithPriority(recordingMiddleware("b", &calls), 10),
+	)
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+}
+
+func (s *MWTestSuite) TestDescribeMiddlewares() {
+	var calls []string
+	inner := middleware.ComposeMiddlewares(noopTxHandler{}, recordingMiddleware("b", &calls))
+	txHandler := middleware.ComposeMiddlewares(inner, recordingMiddleware("a", &calls), middleware.GasTxMiddleware)
+
+	describer, ok := txHandler.(txtypes.MiddlewareDescriber)
+	s.Require().True(ok)
+
+	// named middlewares report their name, the others their Go type name,
+	// and nested stacks are flattened
+	s.Require().Equal([]txtypes.MiddlewareInfo{
+		{Name: "a"},
+		{Name: "middleware.gasTxHandler"},
+		{Name: "b"},
+		{Name: "middleware_test.noopTxHandler"},
+	}, describer.DescribeMiddlewares())
+}
+
+func (s *MWTestSuite) TestDescribeDefaultMiddlewares() {
+	s.SetupTest(true) // setup
+
+	txHandler, err := middleware.NewDefaultTxHandler(middleware.TxHandlerOptions{
+		AccountKeeper:   s.app.AccountKeeper,
+		BankKeeper:      s.app.BankKeeper,
+		SignModeHandler: s.clientCtx.TxConfig.SignModeHandler(),
+	})
+	s.Require().NoError(err)
+
+	describer, ok := txHandler.(txtypes.MiddlewareDescriber)
+	s.Require().True(ok)
+
+	// the middlewares disabled by their unset option return the tx.Handler
+	// they wrap, and aren't described
+	s.Require().Equal([]txtypes.MiddlewareInfo{
+		{Name: "middleware.errorEncoderTxHandler"},
+		{Name: "middleware.estimateFeeTxHandler"},
+		{Name: "middleware.simulateTimeoutTxHandler"},
+		{Name: "middleware.gasTxHandler"},
+		{Name: "middleware.errorTxHandler"},
+		{Name: "middleware.recoveryTxHandler"},
+		{Name: "middleware.memoFlagsTxHandler"},
+		{Name: "middleware.indexEventsTxHandler"},
+		{Name: "reject_extension_options"},
+		{Name: "tx_size_limit"},
+		{Name: "max_msgs"},
+		{Name: "middleware.msgCombinationTxHandler"},
+		{Name: "max_gas_wanted"},
+		{Name: "reject_unknown_msgs"},
+		{Name: "mempool_fee"},
+		{Name: "fee_denom_whitelist"},
+		{Name: "validate_basic"},
+		{Name: "timeout_height"},
+		{Name: "validate_memo"},
+		{Name: "middleware.consumeTxSizeGasTxHandler"},
+		{Name: "deduct_fee"},
+		{Name: "set_pubkey"},
+		{Name: "validate_sig_count"},
+		{Name: "sig_gas_consume"},
+		{Name: "sig_verification"},
+		{Name: "middleware.tipsTxHandler"},
+		{Name: "increment_sequence"},
+		{Name: "run_msgs"},
+	}, describer.DescribeMiddlewares())
+}
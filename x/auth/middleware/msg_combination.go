@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// CombinationRuleKind defines how a CombinationRule constrains its msg types.
+type CombinationRuleKind int
+
+const (
+	// CombinationForbidden rejects the txs containing all the msg types of the
+	// rule.
+	CombinationForbidden CombinationRuleKind = iota
+	// CombinationRequired rejects the txs containing some, but not all, of the
+	// msg types of the rule, i.e. the msgs must appear together.
+	CombinationRequired
+)
+
+// CombinationRule is a policy on the msg types a tx combines, see
+// NewMsgCombinationPolicyMiddleware.
+type CombinationRule struct {
+	Kind        CombinationRuleKind
+	MsgTypeURLs []string
+}
+
+type msgCombinationTxHandler struct {
+	rules []CombinationRule
+	next  tx.Handler
+}
+
+// NewMsgCombinationPolicyMiddleware returns a middleware that rejects, with
+// ErrInvalidRequest, the txs whose msg types break any of the given rules,
+// e.g. to forbid a staking delegation and a governance vote in the same tx.
+// The rules are enforced in all modes, so they must be the same on all nodes.
+func NewMsgCombinationPolicyMiddleware(rules []CombinationRule) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return msgCombinationTxHandler{
+			rules: rules,
+			next:  txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgCombinationTxHandler{}
+
+// checkCombinations checks the msg types of the tx against the rules.
+func (txh msgCombinationTxHandler) checkCombinations(tx sdk.Tx) error {
+	if len(txh.rules) == 0 {
+		return nil
+	}
+
+	present := make(map[string]bool)
+	for _, msg := range tx.GetMsgs() {
+		present[sdk.MsgTypeURL(msg)] = true
+	}
+
+	for _, rule := range txh.rules {
+		found := 0
+		for _, typeURL := range rule.MsgTypeURLs {
+			if present[typeURL] {
+				found++
+			}
+		}
+
+		switch {
+		case rule.Kind == CombinationForbidden && found > 0 && found == len(rule.MsgTypeURLs):
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "msgs %s can't be combined in a tx", strings.Join(rule.MsgTypeURLs, ", "))
+		case rule.Kind == CombinationRequired && found > 0 && found < len(rule.MsgTypeURLs):
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "msgs %s must appear together in a tx", strings.Join(rule.MsgTypeURLs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgCombinationTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkCombinations(tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgCombinationTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkCombinations(tx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgCombinationTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkCombinations(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestMsgCombinationPolicy() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	testMsg := testdata.NewTestMsg(addr1)
+	dogMsg := &testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}}
+	sendMsg := &banktypes.MsgSend{FromAddress: addr1.String(), ToAddress: addr1.String()}
+	multiSendMsg := &banktypes.MsgMultiSend{}
+
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewMsgCombinationPolicyMiddleware([]middleware.CombinationRule{
+			{Kind: middleware.CombinationForbidden, MsgTypeURLs: []string{sdk.MsgTypeURL(testMsg), sdk.MsgTypeURL(dogMsg)}},
+			{Kind: middleware.CombinationRequired, MsgTypeURLs: []string{sdk.MsgTypeURL(sendMsg), sdk.MsgTypeURL(multiSendMsg)}},
+		}),
+	)
+
+	testCases := []struct {
+		name   string
+		msgs   []sdk.Msg
+		expErr bool
+	}{
+		{"single msg of a forbidden set", []sdk.Msg{testMsg, testMsg}, false},
+		{"forbidden combination", []sdk.Msg{testMsg, sendMsg, multiSendMsg, dogMsg}, true},
+		{"required combination", []sdk.Msg{sendMsg, multiSendMsg}, false},
+		{"incomplete required combination", []sdk.Msg{sendMsg, testMsg}, true},
+		{"no msg of a required set", []sdk.Msg{dogMsg}, false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(tc.msgs...))
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			_, simulateErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simulateErr} {
+				if tc.expErr {
+					s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// MsgDedupMode defines how the MsgDedup middleware handles txs holding
+// identical msgs.
+type MsgDedupMode int
+
+const (
+	// MsgDedupOff disables msg deduplication.
+	MsgDedupOff MsgDedupMode = iota
+	// MsgDedupReject rejects the txs holding identical msgs.
+	MsgDedupReject
+	// MsgDedupCollapse only executes the first of identical msgs.
+	MsgDedupCollapse
+)
+
+// routedMsgsKey is the context key under which the MsgDedup, MsgReorder and
+// DenomNormalizer middlewares store the msgs of the tx for the msg router.
+type routedMsgsKey struct{}
+
+type msgDedupTxHandler struct {
+	mode MsgDedupMode
+	next tx.Handler
+}
+
+// NewMsgDedupMiddleware returns a middleware handling the txs which hold the
+// same msg more than once, two msgs being identical if they have the same type
+// and proto-marshaled bytes. With MsgDedupReject, such txs are rejected with
+// ErrInvalidRequest. With MsgDedupCollapse, the duplicates are removed before
+// the msgs are routed; the other middlewares still see all the msgs of the tx.
+// MsgDedupOff returns the given tx.Handler unchanged.
+func NewMsgDedupMiddleware(mode MsgDedupMode) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if mode == MsgDedupOff {
+			return txh
+		}
+
+		return msgDedupTxHandler{
+			mode: mode,
+			next: txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgDedupTxHandler{}
+
+// dedupMsgs returns the given msgs without their duplicates, and whether any
+// duplicates were found. With MsgDedupReject, an error is returned on the
+// first duplicate.
+func (txh msgDedupTxHandler) dedupMsgs(msgs []sdk.Msg) ([]sdk.Msg, bool, error) {
+	seen := make(map[string]int, len(msgs))
+	deduped := make([]sdk.Msg, 0, len(msgs))
+	for i, msg := range msgs {
+		bz, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, false, sdkerrors.Wrapf(sdkerrors.ErrTxDecode, "failed to marshal msg; message index: %d: %s", i, err)
+		}
+
+		key := sdk.MsgTypeURL(msg) + "/" + string(bz)
+		if first, ok := seen[key]; ok {
+			if txh.mode == MsgDedupReject {
+				return nil, false, sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "duplicate msg; message index: %d, duplicate of: %d", i, first)
+			}
+
+			continue
+		}
+
+		seen[key] = i
+		deduped = append(deduped, msg)
+	}
+
+	return deduped, len(deduped) != len(msgs), nil
+}
+
+// withDedupedMsgs checks the msgs to route for duplicates, and returns a
+// context holding the collapsed msgs if any were removed.
+func (txh msgDedupTxHandler) withDedupedMsgs(ctx context.Context, sdkTx sdk.Tx) (context.Context, error) {
+	deduped, collapsed, err := txh.dedupMsgs(routedMsgs(sdk.UnwrapSDKContext(ctx), sdkTx))
+	if err != nil {
+		return nil, err
+	}
+
+	if !collapsed {
+		return ctx, nil
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx).WithValue(routedMsgsKey{}, deduped)
+	return sdk.WrapSDKContext(sdkCtx), nil
+}
+
+// routedMsgs returns the msgs of the tx to route, i.e. the msgs set by the
+// MsgDedup, MsgReorder or DenomNormalizer middlewares if any, or all the tx
+// msgs otherwise.
+func routedMsgs(sdkCtx sdk.Context, sdkTx sdk.Tx) []sdk.Msg {
+	if msgs, ok := sdkCtx.Value(routedMsgsKey{}).([]sdk.Msg); ok {
+		return msgs
+	}
+
+	return sdkTx.GetMsgs()
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgDedupTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	ctx, err := txh.withDedupedMsgs(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgDedupTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	ctx, err := txh.withDedupedMsgs(ctx, sdkTx)
+	if err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgDedupTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	ctx, err := txh.withDedupedMsgs(ctx, sdkTx)
+	if err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
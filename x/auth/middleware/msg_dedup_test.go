@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMsgDedupMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	testdata.RegisterMsgServer(msr, testdata.MsgServerImpl{})
+
+	// two identical msgs and a distinct one
+	priv, _, _ := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(
+		&testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}},
+		&testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Rex"}},
+		&testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}},
+	))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name    string
+		mode    middleware.MsgDedupMode
+		expErr  bool
+		expMsgs []string
+	}{
+		{"off", middleware.MsgDedupOff, false, []string{"Spot", "Rex", "Spot"}},
+		{"reject", middleware.MsgDedupReject, true, nil},
+		{"collapse", middleware.MsgDedupCollapse, false, []string{"Spot", "Rex"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			txHandler := middleware.ComposeMiddlewares(
+				middleware.NewRunMsgsTxHandler(msr, nil),
+				middleware.NewMsgDedupMiddleware(tc.mode),
+			)
+
+			res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				return
+			}
+			s.Require().NoError(err)
+
+			var txMsgData sdk.TxMsgData
+			s.Require().NoError(s.clientCtx.Codec.Unmarshal(res.Data, &txMsgData))
+			names := make([]string, len(txMsgData.Data))
+			for i, data := range txMsgData.Data {
+				var msgRes testdata.MsgCreateDogResponse
+				s.Require().NoError(s.clientCtx.Codec.Unmarshal(data.Data, &msgRes))
+				names[i] = msgRes.Name
+			}
+			s.Require().Equal(tc.expMsgs, names)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// blockGasUsage tracks the gas accumulated within a single block.
+type blockGasUsage struct {
+	height int64
+	used   uint64
+}
+
+// resetOnNewBlock resets the usage if height is a new block.
+func (u *blockGasUsage) resetOnNewBlock(height int64) {
+	if height != u.height {
+		u.height, u.used = height, 0
+	}
+}
+
+// msgTypeGasBudget holds the gas accumulated by a msg type in the current
+// block. It is shared by all copies of the msgTypeGasBudgetTxHandler.
+type msgTypeGasBudget struct {
+	mtx sync.Mutex
+	// checked is the gas reserved by the txs admitted in CheckTx.
+	checked blockGasUsage
+	// delivered is the gas used by the msgs executed in DeliverTx.
+	delivered blockGasUsage
+}
+
+type msgTypeGasBudgetTxHandler struct {
+	typeURL        string
+	perBlockBudget uint64
+	budget         *msgTypeGasBudget
+	next           tx.Handler
+}
+
+// NewMsgTypeGasBudgetMiddleware returns a middleware that enforces a soft
+// per-block gas budget for the msgs with the given type URL, on top of the
+// consensus max block gas. The budget is reset on each new block height.
+//
+// In CheckTx (and not on ReCheckTx), as msgs are not executed, each tx
+// containing such a msg reserves its whole gas limit from the budget, and is
+// rejected with ErrOutOfGas if it doesn't fit in the remaining budget. The
+// reservation is released if the tx is then rejected by the inner handler.
+//
+// In DeliverTx, the block order is already fixed, so txs are always executed:
+// the gas actually used by these msgs is accumulated, and a budget overflow is
+// only logged. This requires the middleware to be placed inside of the Gas
+// middleware.
+//
+// A zero perBlockBudget disables the middleware.
+// CONTRACT: Tx must implement GasTx interface
+func NewMsgTypeGasBudgetMiddleware(typeURL string, perBlockBudget uint64) tx.Middleware {
+	budget := &msgTypeGasBudget{}
+
+	return func(txh tx.Handler) tx.Handler {
+		return msgTypeGasBudgetTxHandler{
+			typeURL:        typeURL,
+			perBlockBudget: perBlockBudget,
+			budget:         budget,
+			next:           txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgTypeGasBudgetTxHandler{}
+
+// hasMsgType reports whether the tx contains a msg of the budgeted type.
+func (txh msgTypeGasBudgetTxHandler) hasMsgType(sdkTx sdk.Tx) bool {
+	for _, msg := range sdkTx.GetMsgs() {
+		if sdk.MsgTypeURL(msg) == txh.typeURL {
+			return true
+		}
+	}
+
+	return false
+}
+
+// msgTypeGasRecorder is a GasTracer accumulating the gas consumed by the
+// msgs of a given type, and forwarding the traces to the next GasTracer, if
+// any.
+type msgTypeGasRecorder struct {
+	typeURL string
+	gas     sdk.Gas
+	next    GasTracer
+}
+
+var _ GasTracer = &msgTypeGasRecorder{}
+
+// TraceMsgGas implements GasTracer.TraceMsgGas.
+func (r *msgTypeGasRecorder) TraceMsgGas(msgIndex int, msgTypeURL string, gasBefore, gasAfter sdk.Gas) {
+	if msgTypeURL == r.typeURL {
+		r.gas += gasAfter - gasBefore
+	}
+
+	if r.next != nil {
+		r.next.TraceMsgGas(msgIndex, msgTypeURL, gasBefore, gasAfter)
+	}
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgTypeGasBudgetTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	// the tx has already been admitted once, don't charge it again on recheck
+	if txh.perBlockBudget == 0 || req.Type == abci.CheckTxType_Recheck || !txh.hasMsgType(sdkTx) {
+		return txh.next.CheckTx(ctx, sdkTx, req)
+	}
+
+	gasTx, ok := sdkTx.(GasTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be GasTx")
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if err := txh.reserve(sdkCtx.BlockHeight(), gasTx.GetGas()); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	// the txs rejected by the inner middlewares don't enter the mempool, so
+	// they don't keep their reservation
+	res, err := txh.next.CheckTx(ctx, sdkTx, req)
+	if err != nil {
+		txh.release(sdkCtx.BlockHeight(), gasTx.GetGas())
+		return res, err
+	}
+
+	return res, nil
+}
+
+// reserve reserves gas from the budget of the block at height, if it fits in
+// the remaining budget.
+func (txh msgTypeGasBudgetTxHandler) reserve(height int64, gas uint64) error {
+	txh.budget.mtx.Lock()
+	defer txh.budget.mtx.Unlock()
+
+	checked := &txh.budget.checked
+	checked.resetOnNewBlock(height)
+	// compare against the remaining budget to avoid overflowing used+gas
+	if gas > txh.perBlockBudget || checked.used > txh.perBlockBudget-gas {
+		return sdkerrors.Wrapf(sdkerrors.ErrOutOfGas,
+			"gas budget of %s exhausted for this block; used: %d, wanted: %d, budget: %d",
+			txh.typeURL, checked.used, gas, txh.perBlockBudget,
+		)
+	}
+
+	checked.used += gas
+	return nil
+}
+
+// release gives back gas reserved from the budget of the block at height. It
+// is a no-op if the budget was reset for a new block since.
+func (txh msgTypeGasBudgetTxHandler) release(height int64, gas uint64) {
+	txh.budget.mtx.Lock()
+	defer txh.budget.mtx.Unlock()
+
+	checked := &txh.budget.checked
+	if checked.height == height {
+		checked.used -= gas
+	}
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgTypeGasBudgetTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	// dry-run txs are not part of the block, so they don't use its budget
+	if txh.perBlockBudget == 0 || isDryRun(sdkCtx) || !txh.hasMsgType(sdkTx) {
+		return txh.next.DeliverTx(ctx, sdkTx, req)
+	}
+
+	recorder := &msgTypeGasRecorder{typeURL: txh.typeURL, next: gasTracerFromContext(sdkCtx)}
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(sdkCtx.WithValue(gasTracerKey{}, recorder)), sdkTx, req)
+
+	txh.budget.mtx.Lock()
+	delivered := &txh.budget.delivered
+	delivered.resetOnNewBlock(sdkCtx.BlockHeight())
+	delivered.used += recorder.gas
+	used := delivered.used
+	txh.budget.mtx.Unlock()
+
+	if used > txh.perBlockBudget {
+		sdkCtx.Logger().Error(
+			"msg type gas budget exceeded",
+			"type_url", txh.typeURL, "height", sdkCtx.BlockHeight(), "used", used, "budget", txh.perBlockBudget,
+		)
+	}
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgTypeGasBudgetTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
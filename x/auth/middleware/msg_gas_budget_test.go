@@ -0,0 +1,140 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+)
+
+func (s *MWTestSuite) TestMsgTypeGasBudgetCheckTx() {
+	ctx := s.SetupTest(true) // setup
+
+	typeURL := sdk.MsgTypeURL(&banktypes.MsgSend{})
+	budget := middleware.NewMsgTypeGasBudgetMiddleware(typeURL, 100000)
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, budget)
+	// rejectingTxHandler shares the budget of txHandler
+	rejectingTxHandler := middleware.ComposeMiddlewares(failingTxHandler{err: sdkerrors.ErrInsufficientFee}, budget)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	// newTx returns a tx with the given msg and gas limit.
+	newTx := func(msg sdk.Msg, gasLimit uint64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(msg))
+		txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+		txBuilder.SetGasLimit(gasLimit)
+		privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+		testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+		s.Require().NoError(err)
+
+		return testTx
+	}
+	sendTx := newTx(banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10))), 40000)
+	voteTx := newTx(govtypes.NewMsgVote(addr1, 1, govtypes.OptionYes), 40000)
+
+	ctx = ctx.WithBlockHeight(1)
+
+	// the budget fits two txs
+	for i := 0; i < 2; i++ {
+		_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{})
+		s.Require().NoError(err)
+	}
+
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+
+	// other msg types and rechecks are not budgeted
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), voteTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+	s.Require().NoError(err)
+
+	// the budget is reset on the next block
+	ctx = ctx.WithBlockHeight(2)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+
+	// a tx larger than the whole budget never fits
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10))), 200000), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrOutOfGas))
+
+	// the txs rejected by the inner middlewares release their reservation
+	ctx = ctx.WithBlockHeight(3)
+	for i := 0; i < 3; i++ {
+		_, err = rejectingTxHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{})
+		s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	}
+	for i := 0; i < 2; i++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), sendTx, abci.RequestCheckTx{})
+		s.Require().NoError(err)
+	}
+}
+
+// errorRecordingLogger is a test logger recording the messages logged at the
+// error level.
+type errorRecordingLogger struct {
+	log.Logger
+	errors *[]string
+}
+
+func (l errorRecordingLogger) Error(msg string, _ ...interface{}) {
+	*l.errors = append(*l.errors, msg)
+}
+
+func (l errorRecordingLogger) With(_ ...interface{}) log.Logger {
+	return l
+}
+
+func (s *MWTestSuite) TestMsgTypeGasBudgetDeliverTx() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	const bankGas = 60000
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute(banktypes.RouterKey, func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(bankGas, "test bank msg")
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	tracer := &recordingGasTracer{}
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewGasTxMiddleware(tracer),
+		middleware.NewMsgTypeGasBudgetMiddleware(sdk.MsgTypeURL(&banktypes.MsgSend{}), 100000),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	var logged []string
+	ctx = ctx.WithBlockHeight(1).WithLogger(errorRecordingLogger{Logger: log.NewNopLogger(), errors: &logged})
+
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+	s.Require().Empty(logged)
+
+	// the overflowing tx still executes, but the overflow is logged
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"msg type gas budget exceeded"}, logged)
+
+	// the Gas middleware tracer still receives the traces
+	s.Require().Len(tracer.entries, 2)
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type msgReorderTxHandler struct {
+	priorities map[string]int
+	next       tx.Handler
+}
+
+// NewMsgReorderMiddleware returns a middleware that stably reorders the msgs of
+// each tx before they are routed, by the position of their type URL in the
+// given order: the msgs of the first type URL are executed first, and the
+// msgs of unlisted types are executed last. Msgs of the same priority keep
+// their relative order in the tx. The other middlewares still see the msgs in
+// the tx order.
+//
+// As the msgs are executed in the new order, the message indices of the tx
+// response, e.g. in its logs and data, follow the execution order. This changes
+// the execution semantics of the txs, so an empty order returns the given
+// tx.Handler unchanged.
+func NewMsgReorderMiddleware(order []string) tx.Middleware {
+	priorities := make(map[string]int, len(order))
+	for i, typeURL := range order {
+		if _, ok := priorities[typeURL]; !ok {
+			priorities[typeURL] = i
+		}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		if len(priorities) == 0 {
+			return txh
+		}
+
+		return msgReorderTxHandler{
+			priorities: priorities,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgReorderTxHandler{}
+
+// priority returns the execution priority of the msg, the lowest first.
+func (txh msgReorderTxHandler) priority(msg sdk.Msg) int {
+	if p, ok := txh.priorities[sdk.MsgTypeURL(msg)]; ok {
+		return p
+	}
+
+	return len(txh.priorities)
+}
+
+// withReorderedMsgs returns a context holding the msgs to route in their
+// execution order.
+func (txh msgReorderTxHandler) withReorderedMsgs(ctx context.Context, sdkTx sdk.Tx) context.Context {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	msgs := routedMsgs(sdkCtx, sdkTx)
+
+	reordered := make([]sdk.Msg, len(msgs))
+	copy(reordered, msgs)
+	sort.SliceStable(reordered, func(i, j int) bool {
+		return txh.priority(reordered[i]) < txh.priority(reordered[j])
+	})
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(routedMsgsKey{}, reordered))
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgReorderTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(txh.withReorderedMsgs(ctx, sdkTx), sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgReorderTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(txh.withReorderedMsgs(ctx, sdkTx), sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgReorderTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(txh.withReorderedMsgs(ctx, sdkTx), sdkTx, req)
+}
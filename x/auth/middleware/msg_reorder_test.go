@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestMsgReorderMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	testdata.RegisterMsgServer(msr, testdata.MsgServerImpl{})
+	banktypes.RegisterMsgServer(msr, stubBankMsgServer{})
+
+	// the TestMsg handler records the order of its signers
+	var testMsgSigners []string
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		testMsgSigners = append(testMsgSigners, msg.GetSigners()[0].String())
+		return &sdk.Result{}, nil
+	}))
+
+	dogURL := sdk.MsgTypeURL(&testdata.MsgCreateDog{})
+	testMsgURL := sdk.MsgTypeURL(&testdata.TestMsg{})
+	sendURL := sdk.MsgTypeURL(&banktypes.MsgSend{})
+
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewMsgReorderMiddleware([]string{dogURL, testMsgURL}),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(
+		testdata.NewTestMsg(addr1),
+		banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 1))),
+		&testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Rex"}},
+		testdata.NewTestMsg(addr2),
+		&testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}},
+	))
+	testTx := txBuilder.GetTx()
+
+	// the reordering is the same on every execution
+	for i := 0; i < 2; i++ {
+		testMsgSigners = nil
+		res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+		s.Require().NoError(err)
+
+		var txMsgData sdk.TxMsgData
+		s.Require().NoError(s.clientCtx.Codec.Unmarshal(res.Data, &txMsgData))
+		typeURLs := make([]string, len(txMsgData.Data))
+		var dogs []string
+		for j, data := range txMsgData.Data {
+			typeURLs[j] = data.MsgType
+			if data.MsgType == dogURL {
+				var msgRes testdata.MsgCreateDogResponse
+				s.Require().NoError(s.clientCtx.Codec.Unmarshal(data.Data, &msgRes))
+				dogs = append(dogs, msgRes.Name)
+			}
+		}
+
+		s.Require().Equal([]string{dogURL, dogURL, testMsgURL, testMsgURL, sendURL}, typeURLs)
+		s.Require().Equal([]string{"Rex", "Spot"}, dogs)
+		s.Require().Equal([]string{addr1.String(), addr2.String()}, testMsgSigners)
+	}
+
+	// the other middlewares still see the tx order
+	s.Require().Equal(testMsgURL, sdk.MsgTypeURL(testTx.GetMsgs()[0]))
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// msgResultsKey is the context key of the msgResultsRecorder set by the
+// MsgResults middleware.
+type msgResultsKey struct{}
+
+// msgResultsRecorder collects the execution result of each msg of a tx, as
+// reported by the msg router.
+type msgResultsRecorder struct {
+	results []tx.MsgResult
+}
+
+// msgResultsFromContext returns the msgResultsRecorder set by the MsgResults
+// middleware, or nil if none is set.
+func msgResultsFromContext(sdkCtx sdk.Context) *msgResultsRecorder {
+	recorder, _ := sdkCtx.Value(msgResultsKey{}).(*msgResultsRecorder)
+	return recorder
+}
+
+// record records the result of the next msg. It is a no-op on a nil recorder.
+func (r *msgResultsRecorder) record(msg sdk.Msg, status tx.MsgStatus, log string) {
+	if r == nil {
+		return
+	}
+
+	r.results = append(r.results, tx.MsgResult{MsgType: sdk.MsgTypeURL(msg), Status: status, Log: log})
+}
+
+// abort records the failure of the msg at index i, which aborted the tx
+// execution, and marks the following msgs as skipped. It is a no-op on a nil
+// recorder.
+func (r *msgResultsRecorder) abort(msgs []sdk.Msg, i int, err error) {
+	if r == nil {
+		return
+	}
+
+	r.record(msgs[i], tx.MsgStatusFailed, err.Error())
+	for _, msg := range msgs[i+1:] {
+		r.record(msg, tx.MsgStatusSkipped, "")
+	}
+}
+
+type msgResultsTxHandler struct {
+	onDeliver func(ctx sdk.Context, results []tx.MsgResult)
+	next      tx.Handler
+}
+
+// NewMsgResultsMiddleware returns a middleware recording the execution result
+// of each msg of the txs, e.g. for analytics. In non-atomic mode, each msg
+// succeeds or fails independently. In atomic mode, the failing msg is marked
+// as failed and the following ones as skipped.
+//
+// The results of the simulations are returned in
+// ResponseSimulateTx.MsgResponses. The results of DeliverTx, including the
+// ones of failed txs, are passed to onDeliver, if set. No result is recorded
+// for the txs failing before their msgs are executed.
+func NewMsgResultsMiddleware(onDeliver func(ctx sdk.Context, results []tx.MsgResult)) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return msgResultsTxHandler{
+			onDeliver: onDeliver,
+			next:      txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgResultsTxHandler{}
+
+// withRecorder sets a new msgResultsRecorder on the context for the msg router
+// to pick up.
+func withRecorder(ctx context.Context) (context.Context, sdk.Context, *msgResultsRecorder) {
+	recorder := &msgResultsRecorder{}
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(msgResultsKey{}, recorder)), sdkCtx, recorder
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgResultsTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgResultsTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	ctx, sdkCtx, recorder := withRecorder(ctx)
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if txh.onDeliver != nil {
+		txh.onDeliver(sdkCtx, recorder.results)
+	}
+
+	return res, err
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgResultsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	ctx, _, recorder := withRecorder(ctx)
+	res, err := txh.next.SimulateTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	res.MsgResponses = recorder.results
+
+	return res, nil
+}
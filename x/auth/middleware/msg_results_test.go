@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestMsgResultsMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	priv, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, addr3 := testdata.KeyTestPubAddr()
+
+	// the msg signed by addr2 fails
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		if msg.GetSigners()[0].Equals(addr2) {
+			return nil, sdkerrors.ErrInvalidRequest
+		}
+
+		return &sdk.Result{Log: "ok"}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	newTx := func(signers ...sdk.AccAddress) (sdk.Tx, []byte) {
+		msgs := make([]sdk.Msg, len(signers))
+		for i, signer := range signers {
+			msgs[i] = testdata.NewTestMsg(signer)
+		}
+
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(msgs...))
+		privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+		testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+		s.Require().NoError(err)
+
+		return testTx, txBytes
+	}
+
+	typeURL := sdk.MsgTypeURL(&testdata.TestMsg{})
+	statuses := func(results []tx.MsgResult) []tx.MsgStatus {
+		res := make([]tx.MsgStatus, len(results))
+		for i, r := range results {
+			s.Require().Equal(typeURL, r.MsgType)
+			res[i] = r.Status
+		}
+
+		return res
+	}
+
+	testCases := []struct {
+		name      string
+		nonAtomic bool
+		signers   []sdk.AccAddress
+		expErr    bool
+		expStatus []tx.MsgStatus
+	}{
+		{
+			"all msgs succeed", false, []sdk.AccAddress{addr1, addr3}, false,
+			[]tx.MsgStatus{tx.MsgStatusSucceeded, tx.MsgStatusSucceeded},
+		},
+		{
+			"atomic execution skips the msgs after the failing one", false, []sdk.AccAddress{addr1, addr2, addr3}, true,
+			[]tx.MsgStatus{tx.MsgStatusSucceeded, tx.MsgStatusFailed, tx.MsgStatusSkipped},
+		},
+		{
+			"non-atomic execution reports each msg", true, []sdk.AccAddress{addr1, addr2, addr3}, false,
+			[]tx.MsgStatus{tx.MsgStatusSucceeded, tx.MsgStatusFailed, tx.MsgStatusSucceeded},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			var delivered []tx.MsgResult
+			txHandler := middleware.ComposeMiddlewares(
+				middleware.NewRunMsgsTxHandlerWithOptions(msr, legacyRouter, middleware.RunMsgsOptions{
+					NonAtomicMsgExecution: tc.nonAtomic,
+				}),
+				middleware.NewMsgResultsMiddleware(func(_ sdk.Context, results []tx.MsgResult) {
+					delivered = results
+				}),
+			)
+			testTx, txBytes := newTx(tc.signers...)
+
+			// DeliverTx reports the results, even if the tx fails
+			cacheCtx, _ := ctx.CacheContext()
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+			} else {
+				s.Require().NoError(err)
+			}
+			s.Require().Equal(tc.expStatus, statuses(delivered))
+
+			for i, r := range delivered {
+				switch r.Status {
+				case tx.MsgStatusSucceeded:
+					s.Require().Equal("ok", r.Log)
+				case tx.MsgStatusFailed:
+					s.Require().Contains(r.Log, "message index: 1")
+					s.Require().Equal(1, i)
+				case tx.MsgStatusSkipped:
+					s.Require().Empty(r.Log)
+				}
+			}
+
+			// successful simulations return the results in their response
+			cacheCtx, _ = ctx.CacheContext()
+			res, err := txHandler.SimulateTx(sdk.WrapSDKContext(cacheCtx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+			if tc.expErr {
+				s.Require().Error(err)
+				return
+			}
+			s.Require().NoError(err)
+			s.Require().Equal(tc.expStatus, statuses(res.MsgResponses))
+		})
+	}
+}
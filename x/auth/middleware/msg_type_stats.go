@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// MsgTypeCounter counts the msgs executed in DeliverTx by the msg-routing
+// tx.Handler, per msg type URL, see RunMsgsOptions.MsgTypeCounter. Only the
+// msgs which succeeded are counted, e.g. not the msgs failing under
+// NonAtomicMsgExecution in a tx whose other msgs succeeded. The counts
+// are reset on the first DeliverTx of each block. They are only meant for
+// observability and are kept in memory, outside of the state.
+type MsgTypeCounter struct {
+	mtx    sync.Mutex
+	height int64
+	counts map[string]uint64
+}
+
+// NewMsgTypeCounter returns an empty MsgTypeCounter.
+func NewMsgTypeCounter() *MsgTypeCounter {
+	return &MsgTypeCounter{counts: make(map[string]uint64)}
+}
+
+// add counts the given msgs, executed at the given block height.
+func (c *MsgTypeCounter) add(height int64, msgs []sdk.Msg) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if height != c.height {
+		c.height = height
+		c.counts = make(map[string]uint64)
+	}
+
+	for _, msg := range msgs {
+		c.counts[sdk.MsgTypeURL(msg)]++
+	}
+}
+
+// MsgTypeStats returns a snapshot of the number of msgs executed per type URL
+// in the last block with delivered txs.
+func (c *MsgTypeCounter) MsgTypeStats() map[string]uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := make(map[string]uint64, len(c.counts))
+	for typeURL, count := range c.counts {
+		stats[typeURL] = count
+	}
+
+	return stats
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// MsgValidator is a stateful pre-execution check of a msg, e.g. that the
+// recipient of a transfer is not blacklisted. It must not write to the state.
+type MsgValidator func(ctx sdk.Context, msg sdk.Msg) error
+
+// MsgValidatorRegistry holds the MsgValidators consulted by the MsgValidator
+// middleware, keyed by msg type URL. The validators must be registered when
+// wiring the app, before any tx is executed, as the registry is not safe for
+// concurrent use.
+type MsgValidatorRegistry struct {
+	validators map[string][]MsgValidator
+}
+
+// NewMsgValidatorRegistry returns an empty MsgValidatorRegistry.
+func NewMsgValidatorRegistry() *MsgValidatorRegistry {
+	return &MsgValidatorRegistry{validators: make(map[string][]MsgValidator)}
+}
+
+// RegisterMsgValidator registers a validator for the msgs with the given type
+// URL. Several validators can be registered for the same type URL, in which
+// case they are consulted in their registration order.
+func (r *MsgValidatorRegistry) RegisterMsgValidator(typeURL string, v MsgValidator) {
+	if typeURL == "" || v == nil {
+		panic(fmt.Errorf("invalid msg validator for type URL %q", typeURL))
+	}
+
+	r.validators[typeURL] = append(r.validators[typeURL], v)
+}
+
+// validate consults the validators registered for the type URL of the msg.
+func (r *MsgValidatorRegistry) validate(ctx sdk.Context, msg sdk.Msg) error {
+	for _, v := range r.validators[sdk.MsgTypeURL(msg)] {
+		if err := v(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type msgValidatorTxHandler struct {
+	registry *MsgValidatorRegistry
+	next     tx.Handler
+}
+
+// NewMsgValidatorMiddleware returns a middleware consulting, before the msgs of
+// the tx are routed, the validators registered in the given registry for each
+// msg, in the msgs order. The validated msgs are the ones to route, i.e. as
+// set by the MsgDedup, MsgReorder or DenomNormalizer middlewares placed before
+// this one. The tx is rejected with the error of the first failing validator,
+// in all modes.
+//
+// The validators run on a branch of the state which is discarded, so that they
+// can't alter it. They see the state changes of the middlewares placed before
+// this one, e.g. the fee deduction, but not the ones of the previous msgs,
+// which are not executed yet. A nil registry returns the given tx.Handler
+// unchanged.
+func NewMsgValidatorMiddleware(registry *MsgValidatorRegistry) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if registry == nil {
+			return txh
+		}
+
+		return msgValidatorTxHandler{
+			registry: registry,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = msgValidatorTxHandler{}
+
+// validateMsgs consults the registered validators of each msg to route.
+func (txh msgValidatorTxHandler) validateMsgs(ctx context.Context, sdkTx sdk.Tx) error {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	validateCtx, _ := sdkCtx.CacheContext()
+	for i, msg := range routedMsgs(sdkCtx, sdkTx) {
+		if err := txh.registry.validate(validateCtx, msg); err != nil {
+			return sdkerrors.Wrapf(err, "%s failed validation; message index: %d", sdk.MsgTypeURL(msg), i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh msgValidatorTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.validateMsgs(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh msgValidatorTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.validateMsgs(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh msgValidatorTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.validateMsgs(ctx, sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
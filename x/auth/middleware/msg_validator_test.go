@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (s *MWTestSuite) TestMsgValidatorMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, blacklisted := testdata.KeyTestPubAddr()
+
+	registry := middleware.NewMsgValidatorRegistry()
+	registry.RegisterMsgValidator(sdk.MsgTypeURL(&banktypes.MsgSend{}), func(ctx sdk.Context, msg sdk.Msg) error {
+		if msg.(*banktypes.MsgSend).ToAddress == blacklisted.String() {
+			return sdkerrors.Wrap(sdkerrors.ErrUnauthorized, "blacklisted recipient")
+		}
+		return nil
+	})
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewMsgValidatorMiddleware(registry))
+
+	coins := sdk.NewCoins(sdk.NewInt64Coin("atom", 10))
+	testCases := []struct {
+		name     string
+		msgs     []sdk.Msg
+		rejected bool
+	}{
+		{"allowed recipient", []sdk.Msg{banktypes.NewMsgSend(addr1, addr2, coins)}, false},
+		{"msg without validator", []sdk.Msg{testdata.NewTestMsg(addr1)}, false},
+		{"blacklisted recipient", []sdk.Msg{banktypes.NewMsgSend(addr1, blacklisted, coins)}, true},
+		{
+			"blacklisted recipient after an allowed msg",
+			[]sdk.Msg{banktypes.NewMsgSend(addr1, addr2, coins), banktypes.NewMsgSend(addr1, blacklisted, coins)},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			testTx := msgsTx(tc.msgs)
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			_, simulateErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, txtypes.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simulateErr} {
+				if tc.rejected {
+					s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
+
+func (s *MWTestSuite) TestMsgValidatorMiddlewareRoutedMsgs() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	// the validator only knows the canonical denom
+	registry := middleware.NewMsgValidatorRegistry()
+	registry.RegisterMsgValidator(sdk.MsgTypeURL(&banktypes.MsgSend{}), func(ctx sdk.Context, msg sdk.Msg) error {
+		if amount := msg.(*banktypes.MsgSend).Amount.AmountOf("uatom"); amount.GT(sdk.NewInt(100)) {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "%s uatom exceeds the limit", amount)
+		}
+		return nil
+	})
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewDenomNormalizerMiddleware(map[string]string{"uatom": "uatom"}),
+		middleware.NewMsgValidatorMiddleware(registry),
+	)
+
+	// the validator sees the normalized msg which is routed
+	msg := banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("UATOM", 1000)))
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), msgsTx{msg}, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+
+	msg = banktypes.NewMsgSend(addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("UATOM", 10)))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), msgsTx{msg}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// NegativeTxCache remembers, by tx hash, the txs which failed the
+// state-independent checks, i.e. ValidateBasic, along with their error, so
+// that the error middleware fast-fails them when they are submitted again in
+// CheckTx, see ErrorTxOptions.NegativeCache. It holds up to a fixed number of
+// txs, evicting the oldest ones first, and is safe for concurrent use.
+type NegativeTxCache struct {
+	mtx    sync.Mutex
+	size   int
+	errors map[string]error
+	order  []string
+}
+
+// NewNegativeTxCache returns an empty NegativeTxCache holding up to size txs.
+func NewNegativeTxCache(size int) *NegativeTxCache {
+	return &NegativeTxCache{
+		size:   size,
+		errors: make(map[string]error, size),
+	}
+}
+
+// add records the tx with the given hash, rejected with err.
+func (c *NegativeTxCache) add(hash []byte, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	key := string(hash)
+	if _, ok := c.errors[key]; ok || c.size <= 0 {
+		return
+	}
+
+	if len(c.order) >= c.size {
+		delete(c.errors, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	c.errors[key] = err
+	c.order = append(c.order, key)
+}
+
+// get returns the error of the tx with the given hash, if it is cached.
+func (c *NegativeTxCache) get(hash []byte) (error, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	err, ok := c.errors[string(hash)]
+	return err, ok
+}
+
+// Has reports whether the tx with the given hash is cached.
+func (c *NegativeTxCache) Has(hash []byte) bool {
+	_, ok := c.get(hash)
+	return ok
+}
+
+// Len returns the number of cached txs.
+func (c *NegativeTxCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return len(c.order)
+}
+
+// stateIndependentFailureKey is the sdk.Context key under which the error
+// middleware stores the stateIndependentFailure of the tx.
+type stateIndependentFailureKey struct{}
+
+// stateIndependentFailure records whether the tx was rejected by a
+// state-independent check.
+type stateIndependentFailure struct {
+	failed bool
+}
+
+// withStateIndependentFailure sets a new stateIndependentFailure on the
+// sdk.Context.
+func withStateIndependentFailure(ctx context.Context) (context.Context, *stateIndependentFailure) {
+	failure := &stateIndependentFailure{}
+	sdkCtx := sdk.UnwrapSDKContext(ctx).WithValue(stateIndependentFailureKey{}, failure)
+	return sdk.WrapSDKContext(sdkCtx), failure
+}
+
+// markStateIndependentFailure records that the tx was rejected by a
+// state-independent check, if the error middleware tracks it.
+func markStateIndependentFailure(ctx context.Context) {
+	failure, ok := sdk.UnwrapSDKContext(ctx).Value(stateIndependentFailureKey{}).(*stateIndependentFailure)
+	if ok {
+		failure.failed = true
+	}
+}
+
+// negativeCacheTxHash returns the hash of the tx bytes under which the tx is
+// cached, or nil if the tx bytes are unknown.
+func negativeCacheTxHash(txBytes []byte) []byte {
+	if len(txBytes) == 0 {
+		return nil
+	}
+
+	return tmhash.Sum(txBytes)
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+)
+
+func (s *MWTestSuite) TestNegativeTxCache() {
+	ctx := s.SetupTest(false) // setup
+
+	var calls []string
+	cache := middleware.NewNegativeTxCache(10)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewErrorTxMiddlewareWithOptions(middleware.ErrorTxOptions{NegativeCache: cache}),
+		recordingMiddleware("inner", &calls),
+		middleware.ValidateBasicMiddleware,
+		middleware.DeductFeeMiddleware(s.app.AccountKeeper, s.app.BankKeeper, s.app.FeeGrantKeeper),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	// a tx without signatures fails ValidateBasic
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	invalidTx := txBuilder.GetTx()
+	invalidTxBytes, err := s.clientCtx.TxConfig.TxEncoder()(invalidTx)
+	s.Require().NoError(err)
+
+	// a signed tx whose fee payer has insufficient funds fails in DeductFee
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 10))))
+	txBuilder = s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	unfundedTx, unfundedTxBytes, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}, ctx.ChainID())
+	s.Require().NoError(err)
+
+	// the ValidateBasic failure is cached, and fast-fails with the cached
+	// error in CheckTx, without calling the inner middlewares at all
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), invalidTx, abci.RequestCheckTx{Tx: invalidTxBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrNoSignatures))
+	s.Require().Equal([]string{"inner"}, calls)
+	s.Require().Equal(1, cache.Len())
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), invalidTx, abci.RequestCheckTx{Tx: invalidTxBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrNoSignatures))
+	s.Require().Equal([]string{"inner"}, calls)
+
+	// DeliverTx doesn't consult the node-local cache, all the middlewares run
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), invalidTx, abci.RequestDeliverTx{Tx: invalidTxBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrNoSignatures))
+	s.Require().Equal([]string{"inner", "inner"}, calls)
+	s.Require().Equal(1, cache.Len())
+
+	// the balance-dependent failure is not cached, and runs again
+	calls = nil
+	for i := 0; i < 2; i++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), unfundedTx, abci.RequestCheckTx{Tx: unfundedTxBytes})
+		s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+	}
+	s.Require().Equal([]string{"inner", "inner"}, calls)
+	s.Require().Equal(1, cache.Len())
+	s.Require().False(cache.Has(tmhash.Sum(unfundedTxBytes)))
+
+	// the ErrUnauthorized of a tx missing a signer is not cached either, as
+	// the signers may be resolved from the authz grants
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	txBuilder = s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1, addr2)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	unsignedTx, unsignedTxBytes, err := s.createTestTx(txBuilder, []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}, ctx.ChainID())
+	s.Require().NoError(err)
+
+	calls = nil
+	for i := 0; i < 2; i++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), unsignedTx, abci.RequestCheckTx{Tx: unsignedTxBytes})
+		s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+	}
+	s.Require().Equal([]string{"inner", "inner"}, calls)
+	s.Require().Equal(1, cache.Len())
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+type newAccountPolicyTxHandler struct {
+	ak              AccountKeeper
+	allowUnfunded   bool
+	allowedMsgTypes map[string]struct{}
+	next            tx.Handler
+}
+
+// NewNewAccountPolicyMiddleware returns a middleware restricting the txs of
+// signers without an on-chain account, e.g. to let new users claim funds from
+// a faucet. If allowUnfunded is set, such txs are only accepted if all of their
+// msgs have one of the allowed type URLs. Otherwise, or if any msg has another
+// type, they are rejected with ErrUnauthorized. The policy is enforced in all
+// modes, so it must be the same on all nodes.
+//
+// It must be placed before the SetPubKey middleware, which creates the signer
+// accounts.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewNewAccountPolicyMiddleware(ak AccountKeeper, allowUnfunded bool, allowedMsgTypes []string) tx.Middleware {
+	allowed := make(map[string]struct{}, len(allowedMsgTypes))
+	for _, typeURL := range allowedMsgTypes {
+		allowed[typeURL] = struct{}{}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return newAccountPolicyTxHandler{
+			ak:              ak,
+			allowUnfunded:   allowUnfunded,
+			allowedMsgTypes: allowed,
+			next:            txh,
+		}
+	}
+}
+
+var _ tx.Handler = newAccountPolicyTxHandler{}
+
+// checkNewAccounts checks the tx against the policy if any of its signers has
+// no account.
+func (txh newAccountPolicyTxHandler) checkNewAccounts(ctx context.Context, sdkTx sdk.Tx) error {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	var newSigner sdk.AccAddress
+	for _, signer := range sigTx.GetSigners() {
+		if txh.ak.GetAccount(sdkCtx, signer) == nil {
+			newSigner = signer
+			break
+		}
+	}
+
+	if newSigner == nil {
+		return nil
+	}
+
+	if !txh.allowUnfunded {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "signer %s has no account", newSigner)
+	}
+
+	for i, msg := range sdkTx.GetMsgs() {
+		if _, ok := txh.allowedMsgTypes[sdk.MsgTypeURL(msg)]; !ok {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized,
+				"signer %s has no account and can't send %s; message index: %d", newSigner, sdk.MsgTypeURL(msg), i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh newAccountPolicyTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkNewAccounts(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh newAccountPolicyTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkNewAccounts(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh newAccountPolicyTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkNewAccounts(ctx, sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
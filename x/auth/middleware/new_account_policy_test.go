@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestNewAccountPolicy() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, existing := testdata.KeyTestPubAddr()
+	s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, existing))
+	_, _, unfunded := testdata.KeyTestPubAddr()
+
+	allowedMsgs := []string{sdk.MsgTypeURL(&testdata.TestMsg{})}
+	testCases := []struct {
+		name          string
+		allowUnfunded bool
+		msgs          []sdk.Msg
+		expErr        bool
+	}{
+		{"existing signer", false, []sdk.Msg{testdata.NewTestMsg(existing), &testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}}}, false},
+		{"unfunded signer, unfunded not allowed", false, []sdk.Msg{testdata.NewTestMsg(unfunded)}, true},
+		{"unfunded signer, allowed msg", true, []sdk.Msg{testdata.NewTestMsg(unfunded)}, false},
+		{"unfunded signer, disallowed msg", true, []sdk.Msg{testdata.NewTestMsg(unfunded), &testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}}}, true},
+		{"unfunded cosigner, allowed msg", true, []sdk.Msg{testdata.NewTestMsg(existing, unfunded)}, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(tc.msgs...))
+
+			txHandler := middleware.ComposeMiddlewares(
+				noopTxHandler{},
+				middleware.NewNewAccountPolicyMiddleware(s.app.AccountKeeper, tc.allowUnfunded, allowedMsgs),
+			)
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txBuilder.GetTx(), abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+			} else {
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+)
+
+// NotBeforeTypeURL is the type URL of the extension option holding the time
+// before which a tx can't be executed.
+const NotBeforeTypeURL = "/cosmos.tx.ext.v1.NotBefore"
+
+type notBeforeTxHandler struct {
+	next tx.Handler
+}
+
+// NotBeforeMiddleware rejects in CheckTx and DeliverTx the txs carrying a
+// NotBefore extension option later than the block time, see NotBeforeTypeURL,
+// e.g. for time-locked operations. Once the block time reaches it, the
+// middleware is transparent. Simulations are not restricted, so that the gas
+// of a time-locked tx can be estimated in advance.
+//
+// The RejectExtensionOptions middleware must accept the NotBefore extension
+// option.
+func NotBeforeMiddleware(txh tx.Handler) tx.Handler {
+	return notBeforeTxHandler{
+		next: txh,
+	}
+}
+
+var _ tx.Handler = notBeforeTxHandler{}
+
+// notBefore returns the NotBefore time of the tx, or the zero time if the tx
+// has none.
+func notBefore(sdkTx sdk.Tx) (time.Time, error) {
+	extTx, ok := sdkTx.(HasExtensionOptionsTx)
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	var found *time.Time
+	for _, opt := range extTx.GetExtensionOptions() {
+		if opt.TypeUrl != NotBeforeTypeURL {
+			continue
+		}
+		if found != nil {
+			return time.Time{}, sdkerrors.Wrap(sdkerrors.ErrInvalidRequest, "tx holds more than one NotBefore time")
+		}
+
+		var nb ext.NotBefore
+		if err := nb.Unmarshal(opt.Value); err != nil {
+			return time.Time{}, sdkerrors.Wrapf(sdkerrors.ErrTxDecode, "invalid NotBefore time: %s", err)
+		}
+		found = &nb.Time
+	}
+
+	if found == nil {
+		return time.Time{}, nil
+	}
+
+	return *found, nil
+}
+
+// checkNotBefore checks that the block time has reached the NotBefore time of
+// the tx.
+func checkNotBefore(ctx context.Context, sdkTx sdk.Tx) error {
+	t, err := notBefore(sdkTx)
+	if err != nil {
+		return err
+	}
+
+	blockTime := sdk.UnwrapSDKContext(ctx).BlockTime()
+	if blockTime.Before(t) {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "tx can't be executed before %s; block time: %s", t, blockTime)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh notBeforeTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := checkNotBefore(ctx, sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh notBeforeTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := checkNotBefore(ctx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh notBeforeTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"errors"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/ext"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+)
+
+func (s *MWTestSuite) TestNotBeforeMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NotBeforeMiddleware)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	unlock := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	value, err := (&ext.NotBefore{Time: unlock}).Marshal()
+	s.Require().NoError(err)
+	txBuilder.(authtx.ExtensionOptionsTxBuilder).SetExtensionOptions(&codectypes.Any{
+		TypeUrl: middleware.NotBeforeTypeURL,
+		Value:   value,
+	})
+	lockedTx := txBuilder.GetTx()
+
+	testCases := []struct {
+		name      string
+		blockTime time.Time
+		expErr    bool
+	}{
+		{"before NotBefore", unlock.Add(-time.Second), true},
+		{"at NotBefore", unlock, false},
+		{"after NotBefore", unlock.Add(time.Hour), false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			ctx := ctx.WithBlockTime(tc.blockTime)
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), lockedTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), lockedTx, abci.RequestDeliverTx{})
+			for _, err := range []error{checkErr, deliverErr} {
+				if tc.expErr {
+					s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+
+			// simulations are never restricted
+			_, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), lockedTx, tx.RequestSimulateTx{})
+			s.Require().NoError(err)
+		})
+	}
+
+	// txs without NotBefore are not affected
+	txBuilder = s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithBlockTime(time.Time{})), txBuilder.GetTx(), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+}
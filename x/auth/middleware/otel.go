@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// Attribute keys of the spans recorded by the OTel middleware.
+const (
+	OTelAttributeKeyTxHash    = "tx.hash"
+	OTelAttributeKeySigners   = "tx.signers"
+	OTelAttributeKeyMsgCount  = "tx.msg_count"
+	OTelAttributeKeyGasWanted = "tx.gas_wanted"
+	OTelAttributeKeyGasUsed   = "tx.gas_used"
+	OTelAttributeKeyMsgIndex  = "msg.index"
+	OTelAttributeKeyMsgType   = "msg.type_url"
+)
+
+// otelTracerKey is the sdk.Context key under which the OTel middleware stores
+// its tracer, for the msg router to record the msg spans.
+type otelTracerKey struct{}
+
+// otelTracerFromContext returns the tracer set by the OTel middleware, or nil
+// if the middleware is not enabled.
+func otelTracerFromContext(sdkCtx sdk.Context) trace.Tracer {
+	tracer, _ := sdkCtx.Value(otelTracerKey{}).(trace.Tracer)
+	return tracer
+}
+
+type otelTxHandler struct {
+	tracer trace.Tracer
+	next   tx.Handler
+}
+
+// NewOTelMiddleware returns a middleware that records an OpenTelemetry span
+// for each tx, annotated with the tx hash, signers, msg count and gas, and a
+// child span for each msg routed by the msg router. The spans are ended even
+// if the tx panics, so the middleware is best placed outside of the recovery
+// middleware, and outside of the Gas middleware to report the gas used.
+//
+// If tracer is nil, the middleware is a no-op.
+func NewOTelMiddleware(tracer trace.Tracer) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if tracer == nil {
+			return txh
+		}
+
+		return otelTxHandler{
+			tracer: tracer,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = otelTxHandler{}
+
+// startSpan starts the span of the tx, and returns a context holding it for
+// the inner middlewares.
+func (txh otelTxHandler) startSpan(ctx context.Context, name string, sdkTx sdk.Tx, txBytes []byte) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String(OTelAttributeKeyTxHash, fmt.Sprintf("%X", tmhash.Sum(txBytes))),
+		attribute.Int(OTelAttributeKeyMsgCount, len(sdkTx.GetMsgs())),
+	}
+	if sigTx, ok := sdkTx.(authsigning.SigVerifiableTx); ok {
+		signers := sigTx.GetSigners()
+		addrs := make([]string, len(signers))
+		for i, signer := range signers {
+			addrs[i] = signer.String()
+		}
+		attrs = append(attrs, attribute.StringSlice(OTelAttributeKeySigners, addrs))
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	spanCtx, span := txh.tracer.Start(sdkCtx.Context(), name, trace.WithAttributes(attrs...))
+	sdkCtx = sdkCtx.WithContext(spanCtx).WithValue(otelTracerKey{}, txh.tracer)
+
+	return sdk.WrapSDKContext(sdkCtx), span
+}
+
+// endSpan annotates the span with the outcome of the tx and ends it. If the tx
+// panicked, i.e. recovered is not nil, the panic is propagated once the span
+// is ended.
+func endSpan(span trace.Span, recovered interface{}, gasWanted, gasUsed int64, err error) {
+	span.SetAttributes(
+		attribute.Int64(OTelAttributeKeyGasWanted, gasWanted),
+		attribute.Int64(OTelAttributeKeyGasUsed, gasUsed),
+	)
+
+	switch {
+	case recovered != nil:
+		span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", recovered))
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+
+	if recovered != nil {
+		panic(recovered)
+	}
+}
+
+// traceMsg executes the msg handler within a child span of the tx span, if the
+// OTel middleware is enabled.
+func traceMsg(sdkCtx sdk.Context, msgIndex int, msg sdk.Msg, handler func(sdk.Context, sdk.Msg) (*sdk.Result, error)) (res *sdk.Result, err error) {
+	tracer := otelTracerFromContext(sdkCtx)
+	if tracer == nil {
+		return handler(sdkCtx, msg)
+	}
+
+	spanCtx, span := tracer.Start(sdkCtx.Context(), sdk.MsgTypeURL(msg), trace.WithAttributes(
+		attribute.Int(OTelAttributeKeyMsgIndex, msgIndex),
+		attribute.String(OTelAttributeKeyMsgType, sdk.MsgTypeURL(msg)),
+	))
+	defer func() {
+		if r := recover(); r != nil {
+			span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", r))
+			span.End()
+			panic(r)
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	return handler(sdkCtx.WithContext(spanCtx), msg)
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh otelTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (res abci.ResponseCheckTx, err error) {
+	ctx, span := txh.startSpan(ctx, "CheckTx", sdkTx, req.Tx)
+	defer func() { endSpan(span, recover(), res.GasWanted, res.GasUsed, err) }()
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh otelTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (res abci.ResponseDeliverTx, err error) {
+	ctx, span := txh.startSpan(ctx, "DeliverTx", sdkTx, req.Tx)
+	defer func() { endSpan(span, recover(), res.GasWanted, res.GasUsed, err) }()
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh otelTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (res tx.ResponseSimulateTx, err error) {
+	ctx, span := txh.startSpan(ctx, "SimulateTx", sdkTx, req.TxBytes)
+	defer func() {
+		endSpan(span, recover(), int64(res.GasInfo.GasWanted), int64(res.GasInfo.GasUsed), err)
+	}()
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// spanAttribute returns the value of the span's attribute with the given key.
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func (s *MWTestSuite) TestOTelMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(1000, "test msg")
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewOTelMiddleware(tracer),
+		middleware.GasTxMiddleware,
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr2)))
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	testTx := txBuilder.GetTx()
+
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: []byte("tx")})
+	s.Require().NoError(err)
+
+	// the msg spans end before the tx span, and are its children
+	spans := recorder.Ended()
+	s.Require().Len(spans, 3)
+	txSpan := spans[2]
+	s.Require().Equal("DeliverTx", txSpan.Name())
+	s.Require().False(txSpan.Parent().IsValid())
+	s.Require().Equal(int64(2), spanAttribute(txSpan, middleware.OTelAttributeKeyMsgCount).AsInt64())
+	s.Require().Equal(res.GasUsed, spanAttribute(txSpan, middleware.OTelAttributeKeyGasUsed).AsInt64())
+	s.Require().Equal([]string{addr1.String(), addr2.String()}, spanAttribute(txSpan, middleware.OTelAttributeKeySigners).AsStringSlice())
+	s.Require().NotEmpty(spanAttribute(txSpan, middleware.OTelAttributeKeyTxHash).AsString())
+
+	for i, msgSpan := range spans[:2] {
+		s.Require().Equal(sdk.MsgTypeURL(&testdata.TestMsg{}), msgSpan.Name())
+		s.Require().Equal(txSpan.SpanContext().SpanID(), msgSpan.Parent().SpanID())
+		s.Require().Equal(int64(i), spanAttribute(msgSpan, middleware.OTelAttributeKeyMsgIndex).AsInt64())
+	}
+
+	// the tx span is ended on panic, and the panic is propagated
+	txHandler = middleware.ComposeMiddlewares(
+		panickingTxHandler{value: sentinelPanic{}},
+		middleware.NewOTelMiddleware(tracer),
+	)
+	s.Require().Panics(func() {
+		txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{}) //nolint:errcheck
+	})
+	spans = recorder.Ended()
+	s.Require().Len(spans, 4)
+	s.Require().Equal("CheckTx", spans[3].Name())
+	s.Require().Equal(codes.Error, spans[3].Status().Code)
+
+	// a nil tracer leaves the stack untouched
+	s.Require().Equal(noopTxHandler{}, middleware.NewOTelMiddleware(nil)(noopTxHandler{}))
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// InvariantCheck is an invariant checked after the execution of a tx, see
+// NewPostExecInvariantMiddleware.
+type InvariantCheck struct {
+	// Name identifies the invariant in the error of the txs breaking it.
+	Name string
+	// Check returns an error if the state resulting from the execution of the
+	// given tx breaks the invariant. It is given the context of the state before
+	// the execution, and the one of the state resulting from it, so that it can
+	// check how the tx changed the state. It must be deterministic.
+	Check func(preCtx, postCtx sdk.Context, tx sdk.Tx) error
+}
+
+type postExecInvariantTxHandler struct {
+	checks []InvariantCheck
+	next   tx.Handler
+}
+
+// NewPostExecInvariantMiddleware returns a middleware that runs the given
+// checks against the state resulting from the execution of each tx by the
+// inner middlewares, e.g. to check that some txs don't change the total
+// supply. The inner middlewares are run on a branch of the multistore, which is
+// only written if they succeed and no check fails; otherwise the tx fails with
+// ErrLogic. The checks are run in DeliverTx and SimulateTx, and CheckTx is left
+// untouched, as it doesn't execute msgs.
+func NewPostExecInvariantMiddleware(checks []InvariantCheck) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if len(checks) == 0 {
+			return txh
+		}
+
+		return postExecInvariantTxHandler{
+			checks: checks,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = postExecInvariantTxHandler{}
+
+// checkInvariants runs all the checks against the states before and after the
+// execution of the tx.
+func (txh postExecInvariantTxHandler) checkInvariants(preCtx, postCtx sdk.Context, sdkTx sdk.Tx) error {
+	for _, check := range txh.checks {
+		if err := check.Check(preCtx, postCtx, sdkTx); err != nil {
+			return sdkerrors.Wrapf(sdkerrors.ErrLogic, "invariant %s broken: %s", check.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh postExecInvariantTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh postExecInvariantTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	branchCtx, msCache := cacheTxContext(sdkCtx, req.Tx)
+	res, err := txh.next.DeliverTx(sdk.WrapSDKContext(branchCtx), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	if err := txh.checkInvariants(sdkCtx, branchCtx, sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh postExecInvariantTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	branchCtx, msCache := cacheTxContext(sdkCtx, req.TxBytes)
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(branchCtx), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	if err := txh.checkInvariants(sdkCtx, branchCtx, sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	msCache.Write()
+
+	return res, nil
+}
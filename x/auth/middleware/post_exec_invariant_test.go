@@ -0,0 +1,81 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+)
+
+func (s *MWTestSuite) TestPostExecInvariantMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("atom", 100))))
+
+	// each TestMsg sends 10atom from addr1 to addr2
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		return &sdk.Result{}, s.app.BankKeeper.SendCoins(ctx, addr1, addr2, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))
+	}))
+	maxBalance := middleware.InvariantCheck{
+		Name: "max balance",
+		Check: func(_, postCtx sdk.Context, _ sdk.Tx) error {
+			if balance := s.app.BankKeeper.GetBalance(postCtx, addr2, "atom"); balance.Amount.GT(sdk.NewInt(25)) {
+				return fmt.Errorf("balance %s of %s is over 25atom", balance, addr2)
+			}
+			return nil
+		},
+	}
+	maxIncrease := middleware.InvariantCheck{
+		Name: "max increase",
+		Check: func(preCtx, postCtx sdk.Context, _ sdk.Tx) error {
+			pre := s.app.BankKeeper.GetBalance(preCtx, addr2, "atom")
+			if post := s.app.BankKeeper.GetBalance(postCtx, addr2, "atom"); post.Sub(pre).Amount.GT(sdk.NewInt(15)) {
+				return fmt.Errorf("balance of %s increased by %s, over 15atom", addr2, post.Sub(pre))
+			}
+			return nil
+		},
+	}
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry), legacyRouter),
+		middleware.NewPostExecInvariantMiddleware([]middleware.InvariantCheck{maxIncrease, maxBalance}),
+	)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	testTx := txBuilder.GetTx()
+	txBuilder = s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr1)))
+	doubleTx := txBuilder.GetTx()
+
+	// the first send keeps the invariants
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdk.NewInt(10), s.app.BankKeeper.GetBalance(ctx, addr2, "atom").Amount)
+
+	// a double send breaks the increase invariant, and is rolled back
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), doubleTx, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+	s.Require().Contains(err.Error(), "invariant max increase broken")
+	s.Require().Equal(sdk.NewInt(10), s.app.BankKeeper.GetBalance(ctx, addr2, "atom").Amount)
+
+	// the second send keeps them
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(sdk.NewInt(20), s.app.BankKeeper.GetBalance(ctx, addr2, "atom").Amount)
+
+	// the third one breaks the balance invariant, and is rolled back
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+	s.Require().Contains(err.Error(), "invariant max balance broken")
+	s.Require().Equal(sdk.NewInt(20), s.app.BankKeeper.GetBalance(ctx, addr2, "atom").Amount)
+	s.Require().Equal(sdk.NewInt(80), s.app.BankKeeper.GetBalance(ctx, addr1, "atom").Amount)
+}
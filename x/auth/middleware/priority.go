@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"math"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// TxPriorityScorer computes the mempool priority of a tx, a higher score
+// meaning a higher priority. It must be deterministic for a given tx and state.
+type TxPriorityScorer func(ctx sdk.Context, tx sdk.Tx) int64
+
+type priorityTxHandler struct {
+	scorer TxPriorityScorer
+	next   tx.Handler
+}
+
+// NewPriorityMiddleware returns a middleware that sets the priority computed
+// by the given scorer on ResponseCheckTx.Priority, to be used by a priority
+// mempool. A nil scorer defaults to DefaultTxPriority.
+//
+// The priority is only set on CheckTx, DeliverTx is not affected.
+func NewPriorityMiddleware(scorer TxPriorityScorer) tx.Middleware {
+	if scorer == nil {
+		scorer = DefaultTxPriority
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return priorityTxHandler{
+			scorer: scorer,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = priorityTxHandler{}
+
+// DefaultTxPriority is the default TxPriorityScorer, which derives the priority
+// from the fee per gas of the tx, i.e. its fee amount divided by its gas limit.
+// For fees in multiple denoms, the lowest fee per gas is used. Txs which are
+// not a FeeTx, or have a zero gas limit, get a zero priority.
+func DefaultTxPriority(_ sdk.Context, sdkTx sdk.Tx) int64 {
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok || feeTx.GetGas() == 0 {
+		return 0
+	}
+
+	gas := sdk.NewIntFromUint64(feeTx.GetGas())
+
+	var (
+		priority int64
+		set      bool
+	)
+	for _, coin := range feeTx.GetFee() {
+		p := int64(math.MaxInt64)
+		if gasPrice := coin.Amount.Quo(gas); gasPrice.IsInt64() {
+			p = gasPrice.Int64()
+		}
+
+		if !set || p < priority {
+			priority, set = p, true
+		}
+	}
+
+	return priority
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh priorityTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	res, err := txh.next.CheckTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	res.Priority = txh.scorer(sdk.UnwrapSDKContext(ctx), sdkTx)
+
+	return res, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh priorityTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh priorityTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
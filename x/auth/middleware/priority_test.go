@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestPriorityMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewPriorityMiddleware(nil))
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+
+	// newTx returns a tx with the given fee and gas limit.
+	newTx := func(fee sdk.Coins, gasLimit uint64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetFeeAmount(fee)
+		txBuilder.SetGasLimit(gasLimit)
+
+		return txBuilder.GetTx()
+	}
+
+	testCases := []struct {
+		name        string
+		tx          sdk.Tx
+		expPriority int64
+	}{
+		{"fee per gas", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 200000)), 100000), 2},
+		{"higher fee", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 500000)), 100000), 5},
+		{"lowest fee per gas across denoms", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 500000), sdk.NewInt64Coin("stake", 300000)), 100000), 3},
+		{"zero fee per gas in the first denom", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 50000), sdk.NewInt64Coin("stake", 300000)), 100000), 0},
+		{"zero fee per gas in the last denom", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 300000), sdk.NewInt64Coin("stake", 50000)), 100000), 0},
+		{"zero gas limit", newTx(sdk.NewCoins(sdk.NewInt64Coin("atom", 500000)), 0), 0},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			res, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestCheckTx{})
+			s.Require().NoError(err)
+			s.Require().Equal(tc.expPriority, res.Priority)
+		})
+	}
+
+	// a custom scorer
+	txHandler = middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewPriorityMiddleware(func(_ sdk.Context, sdkTx sdk.Tx) int64 {
+		return int64(len(sdkTx.GetMsgs())) * 10
+	}))
+	res, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testCases[0].tx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(int64(10), res.Priority)
+
+	// DeliverTx is not affected
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testCases[0].tx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+}
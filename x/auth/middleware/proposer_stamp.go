@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// AttributeKeyProposer is the key of the `tx` event attribute holding the
+// consensus address of the block proposer.
+const AttributeKeyProposer = "proposer"
+
+type proposerStampTxHandler struct {
+	next tx.Handler
+}
+
+// NewProposerStampMiddleware returns a middleware that adds to the DeliverTx
+// result a `tx` event with a `proposer` attribute, holding the consensus
+// address of the proposer of the block including the tx, as read from the
+// block header. It is a no-op in CheckTx and SimulateTx.
+func NewProposerStampMiddleware() tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return proposerStampTxHandler{next: txh}
+	}
+}
+
+var _ tx.Handler = proposerStampTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh proposerStampTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh proposerStampTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	proposer := sdk.UnwrapSDKContext(ctx).BlockHeader().ProposerAddress
+	if len(proposer) == 0 {
+		return res, nil
+	}
+
+	res.Events = append(res.Events, abci.Event(sdk.NewEvent(sdk.EventTypeTx,
+		sdk.NewAttribute(AttributeKeyProposer, sdk.ConsAddress(proposer).String()),
+	)))
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh proposerStampTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
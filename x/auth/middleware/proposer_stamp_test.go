@@ -0,0 +1,40 @@
+package middleware_test
+
+import (
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestProposerStampMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.NewProposerStampMiddleware()(eventsTxHandler{})
+
+	_, pubKey, _ := testdata.KeyTestPubAddr()
+	proposer := sdk.ConsAddress(pubKey.Address())
+	ctx = ctx.WithBlockHeader(tmproto.Header{ProposerAddress: proposer})
+
+	// the proposer is appended to the DeliverTx events
+	res, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Len(res.Events, 3)
+	s.Require().Equal(sdk.EventTypeTx, res.Events[2].Type)
+	s.Require().Equal([]abci.EventAttribute{{Key: middleware.AttributeKeyProposer, Value: proposer.String()}}, res.Events[2].Attributes)
+
+	// CheckTx and SimulateTx are left untouched
+	checkRes, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Len(checkRes.Events, 2)
+	simRes, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), txTest{}, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+	s.Require().Len(simRes.Result.Events, 2)
+
+	// no event is added without a proposer
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx.WithBlockHeader(tmproto.Header{})), txTest{}, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Len(res.Events, 2)
+}
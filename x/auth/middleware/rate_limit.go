@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// RateLimitConfig defines the parameters of the per-signer token buckets used
+// by the rate limit middleware.
+type RateLimitConfig struct {
+	// BucketSize is the maximum number of tokens a signer's bucket can hold,
+	// i.e. the maximum burst of txs a signer can submit at once.
+	BucketSize uint64
+	// RefillPerBlock is the number of tokens added back to a signer's bucket
+	// for each block elapsed since the bucket was last refilled.
+	RefillPerBlock uint64
+}
+
+// tokenBucket tracks the remaining tokens of a single signer.
+type tokenBucket struct {
+	tokens     uint64
+	lastHeight int64
+}
+
+// rateLimiter holds the in-memory token buckets, keyed by signer address. It
+// is shared by all copies of the rateLimitTxHandler.
+//
+// As a full bucket is equivalent to a missing one, the buckets which refilled
+// to full are evicted once per block, so that the map only holds the signers
+// which recently submitted txs.
+type rateLimiter struct {
+	mtx         sync.Mutex
+	cfg         RateLimitConfig
+	buckets     map[string]*tokenBucket
+	sweptHeight int64
+}
+
+type rateLimitTxHandler struct {
+	limiter *rateLimiter
+	next    tx.Handler
+}
+
+// NewRateLimitTxMiddleware returns a middleware that limits the number of txs
+// each signer can submit to the mempool, using an in-memory token bucket per
+// signer address refilled on every new block. Every signer of a tx must have
+// a token available, otherwise the tx is rejected with ErrTooManyRequests.
+//
+// The rate limit is only applied on CheckTx (and not on ReCheckTx), as it is a
+// local mempool protection and must not affect consensus.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewRateLimitTxMiddleware(cfg RateLimitConfig) tx.Middleware {
+	limiter := &rateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return rateLimitTxHandler{
+			limiter: limiter,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = rateLimitTxHandler{}
+
+// take removes one token from the bucket of each given signer. If any signer
+// has an empty bucket, no token is consumed and an error is returned.
+func (rl *rateLimiter) take(height int64, signers []sdk.AccAddress) error {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	rl.evictFull(height)
+
+	buckets := make([]*tokenBucket, 0, len(signers))
+	for _, signer := range signers {
+		bucket := rl.refill(signer.String(), height)
+		if bucket.tokens == 0 {
+			return sdkerrors.Wrapf(sdkerrors.ErrTooManyRequests, "rate limit exceeded for signer %s", signer)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+
+	return nil
+}
+
+// evictFull removes the buckets which refilled to full, at most once per
+// height.
+func (rl *rateLimiter) evictFull(height int64) {
+	if height <= rl.sweptHeight {
+		return
+	}
+
+	for addr, bucket := range rl.buckets {
+		rl.topUp(bucket, height)
+		if bucket.tokens >= rl.cfg.BucketSize {
+			delete(rl.buckets, addr)
+		}
+	}
+	rl.sweptHeight = height
+}
+
+// refill returns the bucket of the given address, topped up with the tokens
+// accrued since its last refill.
+func (rl *rateLimiter) refill(addr string, height int64) *tokenBucket {
+	bucket, ok := rl.buckets[addr]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.cfg.BucketSize, lastHeight: height}
+		rl.buckets[addr] = bucket
+
+		return bucket
+	}
+
+	rl.topUp(bucket, height)
+	return bucket
+}
+
+// topUp adds to the bucket the tokens accrued since its last refill.
+func (rl *rateLimiter) topUp(bucket *tokenBucket, height int64) {
+	if height > bucket.lastHeight && rl.cfg.RefillPerBlock > 0 {
+		elapsed := uint64(height - bucket.lastHeight)
+		missing := rl.cfg.BucketSize - bucket.tokens
+		// compare against missing/RefillPerBlock first to avoid overflowing
+		// elapsed*RefillPerBlock after a long idle period
+		if elapsed > missing/rl.cfg.RefillPerBlock {
+			bucket.tokens = rl.cfg.BucketSize
+		} else {
+			bucket.tokens += elapsed * rl.cfg.RefillPerBlock
+		}
+		bucket.lastHeight = height
+	}
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh rateLimitTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	// the tx has already been admitted once, don't charge it again on recheck
+	if req.Type == abci.CheckTxType_Recheck {
+		return txh.next.CheckTx(ctx, sdkTx, req)
+	}
+
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	if err := txh.limiter.take(sdkCtx.BlockHeight(), sigTx.GetSigners()); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh rateLimitTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh rateLimitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
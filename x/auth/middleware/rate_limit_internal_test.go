@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestRateLimiterEvictsFullBuckets(t *testing.T) {
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	rl := &rateLimiter{
+		cfg:     RateLimitConfig{BucketSize: 4, RefillPerBlock: 1},
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rl.take(1, []sdk.AccAddress{addr1}))
+	}
+	require.NoError(t, rl.take(2, []sdk.AccAddress{addr2}))
+	require.Len(t, rl.buckets, 2)
+
+	// addr1 is back to 3 tokens, addr2 to 4, and evicted
+	require.NoError(t, rl.take(3, nil))
+	require.Len(t, rl.buckets, 1)
+	require.Contains(t, rl.buckets, addr1.String())
+	require.Equal(t, uint64(3), rl.buckets[addr1.String()].tokens)
+
+	// addr1 is back to 4 tokens, and evicted
+	require.NoError(t, rl.take(4, nil))
+	require.Empty(t, rl.buckets)
+
+	// an evicted signer starts over with a full bucket
+	for i := 0; i < 4; i++ {
+		require.NoError(t, rl.take(4, []sdk.AccAddress{addr1}))
+	}
+	require.Error(t, rl.take(4, []sdk.AccAddress{addr1}))
+}
@@ -0,0 +1,75 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestRateLimitTxMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewRateLimitTxMiddleware(middleware.RateLimitConfig{
+			BucketSize:     2,
+			RefillPerBlock: 1,
+		}),
+	)
+
+	// keys and addresses
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	// msg and signatures
+	msg := testdata.NewTestMsg(addr1)
+	s.Require().NoError(txBuilder.SetMsgs(msg))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	ctx = ctx.WithBlockHeight(1)
+
+	// the bucket is exactly exhausted after BucketSize txs
+	for i := 0; i < 2; i++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+		s.Require().NoError(err)
+	}
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// recheck, DeliverTx and SimulateTx are not rate limited
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+	s.Require().NoError(err)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+
+	// one token is refilled on the next block
+	ctx = ctx.WithBlockHeight(2)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+
+	// the refill is capped at BucketSize
+	ctx = ctx.WithBlockHeight(100)
+	for i := 0; i < 2; i++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+		s.Require().NoError(err)
+	}
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTooManyRequests))
+}
@@ -11,8 +11,45 @@ import (
 	"github.com/cosmos/cosmos-sdk/types/tx"
 )
 
+// RecoveryHandler handles a value recovered from a panic in the Recovery
+// middleware. It returns the error the panic is converted to, or nil if it
+// doesn't handle this value, in which case the next handler is tried.
+type RecoveryHandler func(recoveryObj interface{}, sdkCtx sdk.Context) error
+
+// RecoveryMiddleware builds a Recovery middleware with custom recovery
+// handlers.
+type RecoveryMiddleware struct {
+	handlers []RecoveryHandler
+}
+
+// NewRecoveryMiddleware returns a RecoveryMiddleware without any custom
+// recovery handler, behaving like RecoveryTxMiddleware.
+func NewRecoveryMiddleware() *RecoveryMiddleware {
+	return &RecoveryMiddleware{}
+}
+
+// AddRecoveryHandler adds a custom recovery handler. The custom handlers are
+// tried in the order they are added, before the default handlers converting
+// out-of-gas panics to ErrOutOfGas, and any other panic to ErrPanic.
+//
+// Handlers added after the middleware is applied to a tx.Handler don't apply to
+// that tx.Handler.
+func (m *RecoveryMiddleware) AddRecoveryHandler(h RecoveryHandler) {
+	m.handlers = append(m.handlers, h)
+}
+
+// Middleware is the tx.Middleware catching all panics that happen in inner
+// middlewares, see RecoveryTxMiddleware.
+func (m *RecoveryMiddleware) Middleware(txh tx.Handler) tx.Handler {
+	handlers := make([]RecoveryHandler, len(m.handlers))
+	copy(handlers, m.handlers)
+
+	return recoveryTxHandler{handlers: handlers, next: txh}
+}
+
 type recoveryTxHandler struct {
-	next tx.Handler
+	handlers []RecoveryHandler
+	next     tx.Handler
 }
 
 // RecoveryTxMiddleware defines a middleware that catches all panics that
@@ -31,7 +68,7 @@ func (txh recoveryTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.Re
 	// Panic recovery.
 	defer func() {
 		if r := recover(); r != nil {
-			err = handleRecovery(r, sdkCtx)
+			err = txh.handleRecovery(r, sdkCtx)
 		}
 	}()
 
@@ -52,7 +89,7 @@ func (txh recoveryTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.
 	// Panic recovery.
 	defer func() {
 		if r := recover(); r != nil {
-			err = handleRecovery(r, sdkCtx)
+			err = txh.handleRecovery(r, sdkCtx)
 		}
 	}()
 
@@ -80,19 +117,25 @@ func (txh recoveryTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req t
 	// Panic recovery.
 	defer func() {
 		if r := recover(); r != nil {
-			err = handleRecovery(r, sdkCtx)
+			err = txh.handleRecovery(r, sdkCtx)
 		}
 	}()
 
 	return txh.next.SimulateTx(ctx, sdkTx, req)
 }
 
-func handleRecovery(r interface{}, sdkCtx sdk.Context) error {
+func (txh recoveryTxHandler) handleRecovery(r interface{}, sdkCtx sdk.Context) error {
+	for _, h := range txh.handlers {
+		if err := h(r, sdkCtx); err != nil {
+			return err
+		}
+	}
+
 	switch r := r.(type) {
 	case sdk.ErrorOutOfGas:
 		return sdkerrors.Wrapf(sdkerrors.ErrOutOfGas,
-			"out of gas in location: %v; gasWanted: %d, gasUsed: %d",
-			r.Descriptor, sdkCtx.GasMeter().Limit(), sdkCtx.GasMeter().GasConsumed(),
+			"out of gas in location: %v; gasWanted: %d, gasUsed: %d%s",
+			r.Descriptor, sdkCtx.GasMeter().Limit(), sdkCtx.GasMeter().GasConsumed(), gasConsumersLog(sdkCtx),
 		)
 
 	default:
@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+var errSentinelPanic = sdkerrors.Register("recovery_test", 2, "sentinel panic")
+
+// sentinelPanic is the value panicked with by the panickingTxHandler.
+type sentinelPanic struct{}
+
+// panickingTxHandler is a test middleware that panics with the given value.
+type panickingTxHandler struct {
+	value interface{}
+}
+
+var _ tx.Handler = panickingTxHandler{}
+
+func (txh panickingTxHandler) CheckTx(_ context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	panic(txh.value)
+}
+func (txh panickingTxHandler) DeliverTx(_ context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	panic(txh.value)
+}
+func (txh panickingTxHandler) SimulateTx(_ context.Context, _ sdk.Tx, _ tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	panic(txh.value)
+}
+
+func (s *MWTestSuite) TestRecoveryHandlers() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithGasMeter(sdk.NewGasMeter(10))
+
+	var calls []string
+	recovery := middleware.NewRecoveryMiddleware()
+	recovery.AddRecoveryHandler(func(recoveryObj interface{}, _ sdk.Context) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	recovery.AddRecoveryHandler(func(recoveryObj interface{}, _ sdk.Context) error {
+		calls = append(calls, "second")
+		if _, ok := recoveryObj.(sentinelPanic); ok {
+			return errSentinelPanic
+		}
+
+		return nil
+	})
+
+	testCases := []struct {
+		name   string
+		next   tx.Handler
+		expErr *sdkerrors.Error
+	}{
+		{"sentinel panic is handled by the custom handler", panickingTxHandler{value: sentinelPanic{}}, errSentinelPanic},
+		{"out of gas is handled by the default handler", outOfGasTxHandler{}, sdkerrors.ErrOutOfGas},
+		{"other panics are handled by the default handler", panickingTxHandler{value: "boom"}, sdkerrors.ErrPanic},
+	}
+
+	// requireCode checks the error code returned to the client
+	requireCode := func(err error, expErr *sdkerrors.Error) {
+		codespace, code, _ := sdkerrors.ABCIInfo(err, false)
+		s.Require().Equal(expErr.Codespace(), codespace, err)
+		s.Require().Equal(expErr.ABCICode(), code, err)
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txHandler := middleware.ComposeMiddlewares(tc.next, recovery.Middleware)
+
+			calls = nil
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{})
+			requireCode(err, tc.expErr)
+			// the handlers are tried in order
+			s.Require().Equal([]string{"first", "second"}, calls)
+
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{})
+			requireCode(err, tc.expErr)
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), txTest{}, tx.RequestSimulateTx{})
+			requireCode(err, tc.expErr)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+type requiredExtensionTxHandler struct {
+	typeURL  string
+	msgType  reflect.Type
+	validate func(proto.Message) error
+	next     tx.Handler
+}
+
+// NewRequiredExtensionMiddleware returns a middleware rejecting the txs which
+// don't carry an extension option of the given type URL, or whose extension
+// option fails the given validation, with ErrInvalidRequest. It is enforced in
+// all modes, e.g. for chains requiring all txs to embed some metadata.
+//
+// The extension option is decoded into a new instance of the protobuf message
+// registered for the type URL, and passed to validate. If validate is nil, the
+// extension option only has to be decodable. The function panics if no
+// protobuf message is registered for the type URL.
+//
+// The RejectExtensionOptions middleware must accept the required extension
+// option.
+func NewRequiredExtensionMiddleware(typeURL string, validate func(proto.Message) error) tx.Middleware {
+	msgType := proto.MessageType(strings.TrimPrefix(typeURL, "/"))
+	if msgType == nil {
+		panic("no protobuf message registered for type URL " + typeURL)
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return requiredExtensionTxHandler{
+			typeURL:  typeURL,
+			msgType:  msgType,
+			validate: validate,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = requiredExtensionTxHandler{}
+
+// checkRequiredExtension checks that the tx carries the required extension
+// option, and that all its occurrences are valid.
+func (txh requiredExtensionTxHandler) checkRequiredExtension(sdkTx sdk.Tx) error {
+	extTx, ok := sdkTx.(HasExtensionOptionsTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	found := false
+	for _, opt := range extTx.GetExtensionOptions() {
+		if opt.TypeUrl != txh.typeURL {
+			continue
+		}
+
+		msg := reflect.New(txh.msgType.Elem()).Interface().(proto.Message)
+		if err := proto.Unmarshal(opt.Value, msg); err != nil {
+			return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "invalid extension option %s: %s", txh.typeURL, err)
+		}
+
+		if txh.validate != nil {
+			if err := txh.validate(msg); err != nil {
+				return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "invalid extension option %s: %s", txh.typeURL, err)
+			}
+		}
+
+		found = true
+	}
+
+	if !found {
+		return sdkerrors.Wrapf(sdkerrors.ErrInvalidRequest, "missing required extension option %s", txh.typeURL)
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh requiredExtensionTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkRequiredExtension(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh requiredExtensionTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkRequiredExtension(sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh requiredExtensionTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkRequiredExtension(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	gogotypes "github.com/gogo/protobuf/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+)
+
+func (s *MWTestSuite) TestRequiredExtensionMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	// the origin chain is required as a StringValue extension option
+	typeURL := "/google.protobuf.StringValue"
+	validate := func(msg proto.Message) error {
+		if origin := msg.(*gogotypes.StringValue).Value; origin != "origin-chain" {
+			return fmt.Errorf("unknown origin %q", origin)
+		}
+		return nil
+	}
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewRequiredExtensionMiddleware(typeURL, validate),
+	)
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	newTx := func(origin *string) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		if origin != nil {
+			value, err := (&gogotypes.StringValue{Value: *origin}).Marshal()
+			s.Require().NoError(err)
+			txBuilder.(authtx.ExtensionOptionsTxBuilder).SetExtensionOptions(&codectypes.Any{TypeUrl: typeURL, Value: value})
+		}
+		return txBuilder.GetTx()
+	}
+
+	valid, invalid := "origin-chain", "other-chain"
+	testCases := []struct {
+		name   string
+		tx     sdk.Tx
+		expErr bool
+	}{
+		{"present and valid", newTx(&valid), false},
+		{"present and invalid", newTx(&invalid), true},
+		{"missing", newTx(nil), true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		s.Run(tc.name, func() {
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestDeliverTx{})
+			_, simErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), tc.tx, tx.RequestSimulateTx{})
+			for _, err := range []error{checkErr, deliverErr, simErr} {
+				if tc.expErr {
+					s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
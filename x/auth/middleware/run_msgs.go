@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -18,17 +19,52 @@ import (
 type runMsgsTxHandler struct {
 	legacyRouter     sdk.Router        // router for redirecting legacy Msgs
 	msgServiceRouter *MsgServiceRouter // router for redirecting Msg service messages
+	opts             RunMsgsOptions
+}
+
+// RunMsgsOptions are the options of the msg-routing tx.Handler.
+type RunMsgsOptions struct {
+	// NonAtomicMsgExecution defines whether each message is executed in its
+	// own store branch, so that a failing message does not revert the state
+	// changes of the other messages of the tx. The failed messages are
+	// reported in the response log. The tx itself only fails if all of its
+	// messages fail.
+	//
+	// By default, the messages of a tx are executed atomically: if any message
+	// fails, no state change is written.
+	NonAtomicMsgExecution bool
+	// MsgTypeCounter, if set, counts the msgs successfully executed in
+	// DeliverTx, per msg type URL. Dry runs are not counted.
+	MsgTypeCounter *MsgTypeCounter
+	// RetryableErrors defines the errors on which a msg execution is retried
+	// once before failing, e.g. the transient errors of keepers initialized
+	// lazily. An error is retryable if it wraps one of them, so that retries
+	// are deterministic. The state changes of the failed attempt are
+	// discarded, but the gas it consumed is not refunded.
+	RetryableErrors []error
 }
 
 func NewRunMsgsTxHandler(msr *MsgServiceRouter, legacyRouter sdk.Router) tx.Handler {
+	return NewRunMsgsTxHandlerWithOptions(msr, legacyRouter, RunMsgsOptions{})
+}
+
+// NewRunMsgsTxHandlerWithOptions returns the msg-routing tx.Handler, configured
+// with the given options.
+func NewRunMsgsTxHandlerWithOptions(msr *MsgServiceRouter, legacyRouter sdk.Router, opts RunMsgsOptions) tx.Handler {
 	return runMsgsTxHandler{
 		legacyRouter:     legacyRouter,
 		msgServiceRouter: msr,
+		opts:             opts,
 	}
 }
 
 var _ tx.Handler = runMsgsTxHandler{}
 
+// Named implements NamedTxHandler.Named.
+func (txh runMsgsTxHandler) Named() string {
+	return "run_msgs"
+}
+
 // CheckTx implements tx.Handler.CheckTx method.
 func (txh runMsgsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	// Don't run Msgs during CheckTx.
@@ -37,11 +73,17 @@ func (txh runMsgsTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.Req
 
 // DeliverTx implements tx.Handler.DeliverTx method.
 func (txh runMsgsTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
-	res, err := txh.runMsgs(sdk.UnwrapSDKContext(ctx), tx.GetMsgs(), req.Tx)
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	msgs := routedMsgs(sdkCtx, tx)
+	res, msgEvents, err := txh.runMsgs(sdkCtx, msgs, req.Tx)
 	if err != nil {
 		return abci.ResponseDeliverTx{}, err
 	}
 
+	if txh.opts.MsgTypeCounter != nil && !isDryRun(sdkCtx) {
+		txh.opts.MsgTypeCounter.add(sdkCtx.BlockHeight(), succeededMsgs(msgs, msgEvents))
+	}
+
 	return abci.ResponseDeliverTx{
 		// GasInfo will be populated by the Gas middleware.
 		Log:    res.Log,
@@ -52,14 +94,29 @@ func (txh runMsgsTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.R
 
 // SimulateTx implements tx.Handler.SimulateTx method.
 func (txh runMsgsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
-	res, err := txh.runMsgs(sdk.UnwrapSDKContext(ctx), sdkTx.GetMsgs(), req.TxBytes)
+	if req.SimulateOptions.StopAfterAnte {
+		// GasInfo will be populated by the Gas middleware.
+		return tx.ResponseSimulateTx{Result: &sdk.Result{}}, nil
+	}
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	res, msgEvents, err := txh.runMsgs(sdkCtx, routedMsgs(sdkCtx, sdkTx), req.TxBytes)
 	if err != nil {
 		return tx.ResponseSimulateTx{}, err
 	}
 
+	var groupedEvents [][]abci.Event
+	if req.SimulateOptions.GroupEvents {
+		groupedEvents = make([][]abci.Event, len(msgEvents))
+		for i, events := range msgEvents {
+			groupedEvents[i] = events.ToABCIEvents()
+		}
+	}
+
 	return tx.ResponseSimulateTx{
 		// GasInfo will be populated by the Gas middleware.
-		Result: res,
+		Result:        res,
+		GroupedEvents: groupedEvents,
 	}, nil
 }
 
@@ -67,8 +124,17 @@ func (txh runMsgsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx
 // Context and execution mode. Messages will only be executed during simulation
 // and DeliverTx. An error is returned if any single message fails or if a
 // Handler does not exist for a given message route. Otherwise, a reference to a
-// Result is returned. The caller must not commit state if an error is returned.
-func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes []byte) (*sdk.Result, error) {
+// Result is returned, along with the events emitted by each message. The caller
+// must not commit state if an error is returned.
+//
+// If NonAtomicMsgExecution is enabled, a failing message does not abort the
+// execution: its state changes are discarded, the failure is recorded in its
+// message log, and the next message is executed. An error is then only
+// returned if all messages fail.
+func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes []byte) (*sdk.Result, []sdk.Events, error) {
+	// Everything consumed so far is ante gas, let the Gas middleware know.
+	markAnteGasBoundary(sdkCtx)
+
 	// Create a new Context based off of the existing Context with a MultiStore branch
 	// in case message processing fails. At this point, the MultiStore
 	// is a branch of a branch.
@@ -78,11 +144,18 @@ func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes
 	// and we're in DeliverTx. Note, runMsgs will never return a reference to a
 	// Result if any single message fails or does not have a registered Handler.
 	msgLogs := make(sdk.ABCIMessageLogs, 0, len(msgs))
+	allMsgEvents := make([]sdk.Events, 0, len(msgs))
 	events := sdkCtx.EventManager().Events()
 	txMsgData := &sdk.TxMsgData{
 		Data: make([]*sdk.MsgData, 0, len(msgs)),
 	}
 
+	gasTracer := gasTracerFromContext(sdkCtx)
+	gasMeters := msgGasMetersFromContext(sdkCtx)
+	results := msgResultsFromContext(sdkCtx)
+	var firstErr error
+	failedMsgs := 0
+
 	// NOTE: GasWanted is determined by the Gas TxHandler and GasUsed by the GasMeter.
 	for i, msg := range msgs {
 		var (
@@ -91,9 +164,37 @@ func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes
 			err          error
 		)
 
+		// Abort if the context was cancelled, e.g. when a simulation exceeds
+		// its deadline.
+		if err := sdkCtx.Context().Err(); err != nil {
+			err = newAbortedMsgError(err, i)
+			results.abort(msgs, i, err)
+			return nil, nil, err
+		}
+
+		// In non-atomic mode, branch the store once more for each message, so
+		// that each message's state changes can be written independently.
+		msgCtx := runMsgCtx
+		var msgCache sdk.CacheMultiStore
+		if txh.opts.NonAtomicMsgExecution {
+			msgCtx, msgCache = cacheTxContext(runMsgCtx, txBytes)
+		}
+
+		// Execute the message with its own GasMeter if one is selected for it.
+		gasMeter := sdkCtx.GasMeter()
+		selectedMeter := false
+		if gasMeters != nil {
+			if meter := gasMeters.selector(msg); meter != nil {
+				gasMeter, selectedMeter = meter, true
+				msgCtx = msgCtx.WithGasMeter(meter)
+			}
+		}
+
+		gasBefore := gasMeter.GasConsumed()
+
 		if handler := txh.msgServiceRouter.Handler(msg); handler != nil {
 			// ADR 031 request type routing
-			msgResult, err = handler(runMsgCtx, msg)
+			msgResult, err = txh.executeMsg(msgCtx, i, msg, handler, txBytes)
 			eventMsgName = sdk.MsgTypeURL(msg)
 		} else if legacyMsg, ok := msg.(legacytx.LegacyMsg); ok {
 			// legacy sdk.Msg routing
@@ -105,16 +206,48 @@ func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes
 			eventMsgName = legacyMsg.Type()
 			handler := txh.legacyRouter.Route(sdkCtx, msgRoute)
 			if handler == nil {
-				return nil, sdkerrors.Wrapf(sdkerrors.ErrUnknownRequest, "unrecognized message route: %s; message index: %d", msgRoute, i)
+				err = sdkerrors.Wrapf(sdkerrors.ErrUnknownRequest, "unrecognized message route: %s; message index: %d", msgRoute, i)
+				results.abort(msgs, i, err)
+				return nil, nil, err
 			}
 
-			msgResult, err = handler(sdkCtx, msg)
+			msgResult, err = txh.executeMsg(msgCtx, i, msg, handler, txBytes)
 		} else {
-			return nil, sdkerrors.Wrapf(sdkerrors.ErrUnknownRequest, "can't route message %+v", msg)
+			err = sdkerrors.Wrapf(sdkerrors.ErrUnknownRequest, "can't route message %+v", msg)
+			results.abort(msgs, i, err)
+			return nil, nil, err
+		}
+
+		if selectedMeter {
+			gasMeters.consumed += gasMeter.GasConsumed() - gasBefore
+		}
+
+		if gasTracer != nil {
+			gasTracer.TraceMsgGas(i, sdk.MsgTypeURL(msg), gasBefore, gasMeter.GasConsumed())
 		}
 
 		if err != nil {
-			return nil, sdkerrors.Wrapf(err, "failed to execute message; message index: %d", i)
+			err = sdkerrors.Wrapf(err, "failed to execute message; message index: %d", i)
+			if !txh.opts.NonAtomicMsgExecution {
+				results.abort(msgs, i, err)
+				return nil, nil, err
+			}
+
+			// Record the failure, keeping the message logs, data and events
+			// aligned with the message indices.
+			if firstErr == nil {
+				firstErr = err
+			}
+			failedMsgs++
+			allMsgEvents = append(allMsgEvents, sdk.Events{})
+			txMsgData.Data = append(txMsgData.Data, &sdk.MsgData{MsgType: sdk.MsgTypeURL(msg)})
+			msgLogs = append(msgLogs, sdk.NewABCIMessageLog(uint32(i), err.Error(), nil))
+			results.record(msg, tx.MsgStatusFailed, err.Error())
+			continue
+		}
+
+		if msgCache != nil {
+			msgCache.Write()
 		}
 
 		msgEvents := sdk.Events{
@@ -127,22 +260,77 @@ func (txh runMsgsTxHandler) runMsgs(sdkCtx sdk.Context, msgs []sdk.Msg, txBytes
 		// Note: Each message result's data must be length-prefixed in order to
 		// separate each result.
 		events = events.AppendEvents(msgEvents)
+		allMsgEvents = append(allMsgEvents, msgEvents)
 
 		txMsgData.Data = append(txMsgData.Data, &sdk.MsgData{MsgType: sdk.MsgTypeURL(msg), Data: msgResult.Data})
 		msgLogs = append(msgLogs, sdk.NewABCIMessageLog(uint32(i), msgResult.Log, msgEvents))
+		results.record(msg, tx.MsgStatusSucceeded, msgResult.Log)
+	}
+
+	if failedMsgs > 0 && failedMsgs == len(msgs) {
+		return nil, nil, firstErr
 	}
 
 	msCache.Write()
 	data, err := proto.Marshal(txMsgData)
 	if err != nil {
-		return nil, sdkerrors.Wrap(err, "failed to marshal tx data")
+		return nil, nil, sdkerrors.Wrap(err, "failed to marshal tx data")
 	}
 
 	return &sdk.Result{
 		Data:   data,
 		Log:    strings.TrimSpace(msgLogs.String()),
 		Events: events.ToABCIEvents(),
-	}, nil
+	}, allMsgEvents, nil
+}
+
+// succeededMsgs returns the msgs which succeeded, given the events returned by
+// runMsgs for each of them: the msgs failing under NonAtomicMsgExecution have
+// no events, while the other ones have at least their `message` event.
+func succeededMsgs(msgs []sdk.Msg, msgEvents []sdk.Events) []sdk.Msg {
+	succeeded := make([]sdk.Msg, 0, len(msgs))
+	for i, msg := range msgs {
+		if len(msgEvents[i]) > 0 {
+			succeeded = append(succeeded, msg)
+		}
+	}
+
+	return succeeded
+}
+
+// executeMsg executes the msg with the given handler. If the execution fails
+// with one of the RetryableErrors, the msg is executed once more, on a new
+// store branch.
+func (txh runMsgsTxHandler) executeMsg(msgCtx sdk.Context, i int, msg sdk.Msg, handler func(sdk.Context, sdk.Msg) (*sdk.Result, error), txBytes []byte) (*sdk.Result, error) {
+	if len(txh.opts.RetryableErrors) == 0 {
+		return traceMsg(msgCtx, i, msg, handler)
+	}
+
+	attemptCtx, attemptCache := cacheTxContext(msgCtx, txBytes)
+	res, err := traceMsg(attemptCtx, i, msg, handler)
+	if err != nil && txh.isRetryable(err) {
+		attemptCtx, attemptCache = cacheTxContext(msgCtx, txBytes)
+		res, err = traceMsg(attemptCtx, i, msg, handler)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attemptCache.Write()
+
+	return res, nil
+}
+
+// isRetryable reports whether the msg execution error is one of the
+// RetryableErrors.
+func (txh runMsgsTxHandler) isRetryable(err error) bool {
+	for _, retryable := range txh.opts.RetryableErrors {
+		if errors.Is(err, retryable) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // cacheTxContext returns a new context based off of the provided context with
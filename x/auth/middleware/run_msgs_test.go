@@ -1,12 +1,20 @@
 package middleware_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/tendermint/tendermint/abci/types"
 
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 )
 
 func (s *MWTestSuite) TestRunMsgs() {
@@ -34,3 +42,304 @@ func (s *MWTestSuite) TestRunMsgs() {
 	s.Require().Len(txMsgData.Data, 1)
 	s.Require().Equal(sdk.MsgTypeURL(&testdata.MsgCreateDog{}), txMsgData.Data[0].MsgType)
 }
+
+func (s *MWTestSuite) TestSimulateStopAfterAnte() {
+	ctx := s.SetupTest(false) // setup
+
+	// each TestMsg consumes 1000 gas
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		ctx.GasMeter().ConsumeGas(1000, "test msg")
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.GasTxMiddleware,
+		middleware.ConsumeTxSizeGasMiddleware(s.app.AccountKeeper),
+	)
+
+	priv, _, addr := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr)))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	res, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+
+	anteRes, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{
+		TxBytes:         txBytes,
+		SimulateOptions: tx.SimulateOptions{StopAfterAnte: true},
+	})
+	s.Require().NoError(err)
+
+	// the tx size gas is still reported, but not the msg execution gas
+	s.Require().NotZero(anteRes.GasInfo.GasUsed)
+	s.Require().Equal(res.GasInfo.GasUsed-1000, anteRes.GasInfo.GasUsed)
+}
+
+func (s *MWTestSuite) TestSimulateGroupEvents() {
+	ctx := s.SetupTest(false) // setup
+
+	// each TestMsg emits an event with its first signer
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		event := sdk.NewEvent("test", sdk.NewAttribute("signer", msg.GetSigners()[0].String()))
+		return &sdk.Result{Events: []types.Event{types.Event(event)}}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	txHandler := middleware.NewRunMsgsTxHandler(msr, legacyRouter)
+
+	priv, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	msgs := []sdk.Msg{testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr2)}
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(msgs...))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	res, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+	s.Require().Nil(res.GroupedEvents)
+
+	res, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{
+		TxBytes:         txBytes,
+		SimulateOptions: tx.SimulateOptions{GroupEvents: true},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(res.GroupedEvents, len(msgs))
+
+	var flattened []types.Event
+	for i, events := range res.GroupedEvents {
+		s.Require().Equal(
+			sdk.Events{
+				sdk.NewEvent(sdk.EventTypeMessage, sdk.NewAttribute(sdk.AttributeKeyAction, (&testdata.TestMsg{}).Type())),
+				sdk.NewEvent("test", sdk.NewAttribute("signer", msgs[i].GetSigners()[0].String())),
+			}.ToABCIEvents(),
+			events,
+		)
+		flattened = append(flattened, events...)
+	}
+
+	// the flattened events are left intact
+	s.Require().Equal(flattened, res.Result.Events)
+}
+
+func (s *MWTestSuite) TestRunMsgsNonAtomic() {
+	ctx := s.SetupTest(false) // setup
+
+	priv, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	_, _, addr3 := testdata.KeyTestPubAddr()
+
+	// each TestMsg creates an account for its first signer, the msg signed by
+	// addr2 fails after writing its account
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		signer := msg.GetSigners()[0]
+		s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, signer))
+		if signer.Equals(addr2) {
+			return nil, sdkerrors.ErrInvalidRequest
+		}
+
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1), testdata.NewTestMsg(addr2), testdata.NewTestMsg(addr3)))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name      string
+		nonAtomic bool
+	}{
+		{"atomic execution reverts all msgs", false},
+		{"non-atomic execution only reverts the failed msg", true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			cacheCtx, _ := ctx.CacheContext()
+			txHandler := middleware.NewRunMsgsTxHandlerWithOptions(msr, legacyRouter, middleware.RunMsgsOptions{
+				NonAtomicMsgExecution: tc.nonAtomic,
+			})
+
+			res, err := txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, types.RequestDeliverTx{Tx: txBytes})
+			if !tc.nonAtomic {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+				s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr1))
+				s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr2))
+				s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr3))
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().True(s.app.AccountKeeper.HasAccount(cacheCtx, addr1))
+			s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr2))
+			s.Require().True(s.app.AccountKeeper.HasAccount(cacheCtx, addr3))
+
+			// the failed msg is reported at its index in the log
+			logs, err := sdk.ParseABCILogs(res.Log)
+			s.Require().NoError(err)
+			s.Require().Len(logs, 3)
+			s.Require().Equal(uint32(1), logs[1].MsgIndex)
+			s.Require().Contains(logs[1].Log, fmt.Sprintf("message index: %d", 1))
+			s.Require().Empty(logs[0].Log)
+			s.Require().Empty(logs[2].Log)
+		})
+	}
+}
+
+// stubBankMsgServer and stubStakingMsgServer accept the bank sends and the
+// delegations without touching the state.
+type stubBankMsgServer struct{ banktypes.MsgServer }
+
+func (stubBankMsgServer) Send(context.Context, *banktypes.MsgSend) (*banktypes.MsgSendResponse, error) {
+	return &banktypes.MsgSendResponse{}, nil
+}
+
+type stubStakingMsgServer struct{ stakingtypes.MsgServer }
+
+func (stubStakingMsgServer) Delegate(context.Context, *stakingtypes.MsgDelegate) (*stakingtypes.MsgDelegateResponse, error) {
+	return &stakingtypes.MsgDelegateResponse{}, nil
+}
+
+// failingStakingMsgServer rejects the delegations.
+type failingStakingMsgServer struct{ stakingtypes.MsgServer }
+
+func (failingStakingMsgServer) Delegate(context.Context, *stakingtypes.MsgDelegate) (*stakingtypes.MsgDelegateResponse, error) {
+	return nil, sdkerrors.ErrInvalidRequest
+}
+
+func (s *MWTestSuite) TestRunMsgsMsgTypeCounter() {
+	ctx := s.SetupTest(true) // setup
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	banktypes.RegisterMsgServer(msr, stubBankMsgServer{})
+	stakingtypes.RegisterMsgServer(msr, stubStakingMsgServer{})
+	counter := middleware.NewMsgTypeCounter()
+	txHandler := middleware.NewRunMsgsTxHandlerWithOptions(msr, nil, middleware.RunMsgsOptions{MsgTypeCounter: counter})
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+	coins := sdk.NewCoins(sdk.NewInt64Coin("atom", 10))
+	send := banktypes.NewMsgSend(addr1, addr2, coins)
+	delegate := stakingtypes.NewMsgDelegate(addr1, sdk.ValAddress(addr2), coins[0])
+
+	newTx := func(msgs ...sdk.Msg) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(msgs...))
+		return txBuilder.GetTx()
+	}
+
+	ctx = ctx.WithBlockHeight(1)
+	for _, testTx := range []sdk.Tx{newTx(send, send), newTx(delegate)} {
+		_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, types.RequestDeliverTx{})
+		s.Require().NoError(err)
+	}
+
+	// simulations are not counted
+	_, err := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), newTx(delegate), tx.RequestSimulateTx{})
+	s.Require().NoError(err)
+
+	s.Require().Equal(map[string]uint64{
+		sdk.MsgTypeURL(send):     2,
+		sdk.MsgTypeURL(delegate): 1,
+	}, counter.MsgTypeStats())
+
+	// the counts are reset on the next block
+	ctx = ctx.WithBlockHeight(2)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(delegate), types.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(map[string]uint64{sdk.MsgTypeURL(delegate): 1}, counter.MsgTypeStats())
+
+	// the msgs failing under NonAtomicMsgExecution are not counted
+	msr = middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	banktypes.RegisterMsgServer(msr, stubBankMsgServer{})
+	stakingtypes.RegisterMsgServer(msr, failingStakingMsgServer{})
+	counter = middleware.NewMsgTypeCounter()
+	txHandler = middleware.NewRunMsgsTxHandlerWithOptions(msr, nil, middleware.RunMsgsOptions{
+		NonAtomicMsgExecution: true,
+		MsgTypeCounter:        counter,
+	})
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(send, delegate), types.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(map[string]uint64{sdk.MsgTypeURL(send): 1}, counter.MsgTypeStats())
+}
+
+func (s *MWTestSuite) TestRunMsgsRetry() {
+	ctx := s.SetupTest(false) // setup
+
+	priv, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	// the stub keeper fails once with failErr after creating an account for
+	// addr2, then creates an account for addr1
+	var calls int
+	var failErr error
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		calls++
+		if calls == 1 {
+			s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, addr2))
+			return nil, sdkerrors.Wrap(failErr, "not initialized yet")
+		}
+
+		s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1))
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	txHandler := middleware.NewRunMsgsTxHandlerWithOptions(msr, legacyRouter, middleware.RunMsgsOptions{
+		RetryableErrors: []error{sdkerrors.ErrConflict},
+	})
+
+	testCases := []struct {
+		name     string
+		failErr  error
+		expErr   error
+		expCalls int
+	}{
+		{"retryable error is retried once", sdkerrors.ErrConflict, nil, 2},
+		{"other errors are not retried", sdkerrors.ErrInvalidRequest, sdkerrors.ErrInvalidRequest, 1},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			calls, failErr = 0, tc.failErr
+			cacheCtx, _ := ctx.CacheContext()
+
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, types.RequestDeliverTx{Tx: txBytes})
+			s.Require().Equal(tc.expCalls, calls)
+			if tc.expErr != nil {
+				s.Require().True(errors.Is(err, tc.expErr))
+				s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr1))
+				return
+			}
+
+			// the state changes of the failed attempt are discarded
+			s.Require().NoError(err)
+			s.Require().True(s.app.AccountKeeper.HasAccount(cacheCtx, addr1))
+			s.Require().False(s.app.AccountKeeper.HasAccount(cacheCtx, addr2))
+		})
+	}
+
+	// without retryable errors, the first failure is final
+	calls, failErr = 0, sdkerrors.ErrConflict
+	cacheCtx, _ := ctx.CacheContext()
+	_, err = middleware.NewRunMsgsTxHandler(msr, legacyRouter).DeliverTx(sdk.WrapSDKContext(cacheCtx), testTx, types.RequestDeliverTx{Tx: txBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrConflict))
+	s.Require().Equal(1, calls)
+}
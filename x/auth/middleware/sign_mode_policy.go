@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+type signModePolicyTxHandler struct {
+	disallowed map[signing.SignMode]struct{}
+	next       tx.Handler
+}
+
+// NewSignModePolicyMiddleware returns a middleware rejecting, with
+// ErrUnauthorized, the txs with a signature using one of the disallowed sign
+// modes, e.g. to phase out SIGN_MODE_LEGACY_AMINO_JSON. The sign mode of each
+// signer is checked, including the ones of the multisig sub-signatures. The
+// policy is enforced in CheckTx and DeliverTx; simulations, whose signatures
+// are not verified, are not affected.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewSignModePolicyMiddleware(disallowed []signing.SignMode) tx.Middleware {
+	modes := make(map[signing.SignMode]struct{}, len(disallowed))
+	for _, mode := range disallowed {
+		modes[mode] = struct{}{}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		if len(modes) == 0 {
+			return txh
+		}
+
+		return signModePolicyTxHandler{
+			disallowed: modes,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = signModePolicyTxHandler{}
+
+// disallowedSignMode returns the first disallowed sign mode used in the given
+// signature data, if any.
+func (txh signModePolicyTxHandler) disallowedSignMode(data signing.SignatureData) (signing.SignMode, bool) {
+	switch data := data.(type) {
+	case *signing.SingleSignatureData:
+		_, disallowed := txh.disallowed[data.SignMode]
+		return data.SignMode, disallowed
+	case *signing.MultiSignatureData:
+		for _, sig := range data.Signatures {
+			if mode, disallowed := txh.disallowedSignMode(sig); disallowed {
+				return mode, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// checkSignModes checks that none of the tx signatures uses a disallowed sign
+// mode.
+func (txh signModePolicyTxHandler) checkSignModes(sdkTx sdk.Tx) error {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	sigs, err := sigTx.GetSignaturesV2()
+	if err != nil {
+		return err
+	}
+
+	for i, sig := range sigs {
+		if mode, disallowed := txh.disallowedSignMode(sig.Data); disallowed {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "signature %d uses the disallowed sign mode %s", i, mode)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh signModePolicyTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkSignModes(sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh signModePolicyTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkSignModes(sdkTx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh signModePolicyTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestSignModePolicyMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewSignModePolicyMiddleware([]signing.SignMode{signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON}),
+	)
+
+	_, pub1, addr1 := testdata.KeyTestPubAddr()
+	_, pub2, _ := testdata.KeyTestPubAddr()
+	multisigKey := multisig.NewLegacyAminoPubKey(1, []cryptotypes.PubKey{pub1, pub2})
+
+	single := func(mode signing.SignMode) signing.SignatureData {
+		return &signing.SingleSignatureData{SignMode: mode, Signature: []byte("sig")}
+	}
+
+	testCases := []struct {
+		name     string
+		sig      signing.SignatureV2
+		rejected bool
+	}{
+		{
+			"allowed sign mode",
+			signing.SignatureV2{PubKey: pub1, Data: single(signing.SignMode_SIGN_MODE_DIRECT)},
+			false,
+		},
+		{
+			"disallowed sign mode",
+			signing.SignatureV2{PubKey: pub1, Data: single(signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON)},
+			true,
+		},
+		{
+			"disallowed sign mode in a multisig",
+			signing.SignatureV2{PubKey: multisigKey, Data: &signing.MultiSignatureData{
+				Signatures: []signing.SignatureData{single(signing.SignMode_SIGN_MODE_DIRECT), single(signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON)},
+			}},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			s.Require().NoError(txBuilder.SetSignatures(tc.sig))
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			for _, err := range []error{checkErr, deliverErr} {
+				if tc.rejected {
+					s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+type signerAllowlistTxHandler struct {
+	allowed map[string]struct{}
+	next    tx.Handler
+}
+
+// NewSignerAllowlistMiddleware returns a middleware for permissioned chains,
+// rejecting any tx with a signer whose bech32 address is not in the allowed
+// set. Note that an empty allowlist denies all txs.
+//
+// It should be placed after the signature verification middlewares, so that
+// the signers are trustworthy.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewSignerAllowlistMiddleware(allowed map[string]struct{}) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return signerAllowlistTxHandler{
+			allowed: allowed,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = signerAllowlistTxHandler{}
+
+func (txh signerAllowlistTxHandler) checkSigners(tx sdk.Tx) error {
+	sigTx, ok := tx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	for _, signer := range sigTx.GetSigners() {
+		if _, ok := txh.allowed[signer.String()]; !ok {
+			return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "signer %s is not allowed", signer)
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh signerAllowlistTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkSigners(tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh signerAllowlistTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkSigners(tx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh signerAllowlistTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkSigners(sdkTx); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestSignerAllowlistMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	_, _, addr2 := testdata.KeyTestPubAddr()
+
+	testCases := []struct {
+		desc    string
+		allowed map[string]struct{}
+		signers []sdk.AccAddress
+		expErr  error
+	}{
+		{"allowed signer", map[string]struct{}{addr1.String(): {}}, []sdk.AccAddress{addr1}, nil},
+		{"denied signer", map[string]struct{}{addr1.String(): {}}, []sdk.AccAddress{addr2}, sdkerrors.ErrUnauthorized},
+		{"one denied signer among allowed ones", map[string]struct{}{addr1.String(): {}}, []sdk.AccAddress{addr1, addr2}, sdkerrors.ErrUnauthorized},
+		{"empty allowlist denies all", map[string]struct{}{}, []sdk.AccAddress{addr1}, sdkerrors.ErrUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewSignerAllowlistMiddleware(tc.allowed))
+
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(tc.signers...)))
+			testTx := txBuilder.GetTx()
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			for _, err := range []error{checkErr, deliverErr} {
+				if tc.expErr != nil {
+					s.Require().True(errors.Is(err, tc.expErr))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+type signerFundedCheckTxHandler struct {
+	bankKeeper BalanceKeeper
+	minBalance sdk.Coins
+	next       tx.Handler
+}
+
+// NewSignerFundedCheckMiddleware returns a middleware that rejects, with
+// ErrInsufficientFunds, the txs which have a signer whose balance is below
+// minBalance in any of its denoms. An empty minBalance disables the check.
+//
+// This is a mempool heuristic, so the check is only applied on CheckTx (and
+// ReCheckTx), and must not affect consensus. As such, it is not applied on
+// DeliverTx.
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewSignerFundedCheckMiddleware(bankKeeper BalanceKeeper, minBalance sdk.Coins) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return signerFundedCheckTxHandler{
+			bankKeeper: bankKeeper,
+			minBalance: minBalance,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = signerFundedCheckTxHandler{}
+
+// checkSignersFunded checks that all signers hold at least minBalance.
+func (txh signerFundedCheckTxHandler) checkSignersFunded(sdkCtx sdk.Context, sdkTx sdk.Tx) error {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	for _, signer := range sigTx.GetSigners() {
+		for _, min := range txh.minBalance {
+			balance := txh.bankKeeper.GetBalance(sdkCtx, signer, min.Denom)
+			if balance.IsLT(min) {
+				return sdkerrors.Wrapf(sdkerrors.ErrInsufficientFunds,
+					"signer %s balance %s is below the minimum balance %s", signer, balance, min,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh signerFundedCheckTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkSignersFunded(sdk.UnwrapSDKContext(ctx), sdkTx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh signerFundedCheckTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh signerFundedCheckTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
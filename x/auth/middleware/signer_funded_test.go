@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+)
+
+func (s *MWTestSuite) TestSignerFundedCheckMiddleware() {
+	ctx := s.SetupTest(true) // setup
+
+	minBalance := sdk.NewCoins(sdk.NewInt64Coin("atom", 100))
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewSignerFundedCheckMiddleware(s.app.BankKeeper, minBalance),
+	)
+
+	_, _, funded := testdata.KeyTestPubAddr()
+	_, _, underfunded := testdata.KeyTestPubAddr()
+	_, _, unfunded := testdata.KeyTestPubAddr()
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, funded, sdk.NewCoins(sdk.NewInt64Coin("atom", 100))))
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, underfunded, sdk.NewCoins(sdk.NewInt64Coin("atom", 99), sdk.NewInt64Coin("stake", 1000))))
+
+	// newTx returns a tx signed by the given signers.
+	newTx := func(signers ...sdk.AccAddress) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(signers...)))
+
+		return txBuilder.GetTx()
+	}
+
+	testCases := []struct {
+		name    string
+		tx      sdk.Tx
+		expPass bool
+	}{
+		{"funded signer", newTx(funded), true},
+		{"underfunded signer", newTx(underfunded), false},
+		{"signer without balance", newTx(unfunded), false},
+		{"funded and underfunded signers", newTx(funded, underfunded), false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestCheckTx{})
+			if tc.expPass {
+				s.Require().NoError(err)
+			} else {
+				s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+			}
+
+			// the check is not applied outside of the mempool
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), tc.tx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), tc.tx, tx.RequestSimulateTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
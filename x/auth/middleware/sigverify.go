@@ -63,7 +63,7 @@ func (spkm setPubKeyTxHandler) setPubKey(ctx context.Context, tx sdk.Tx, simulat
 	if err != nil {
 		return err
 	}
-	signers := sigTx.GetSigners()
+	signers := txSigners(sdkCtx, sigTx)
 
 	for i, pk := range pubkeys {
 		// PublicKey was omitted from slice since it has already been set in context
@@ -125,6 +125,11 @@ func (spkm setPubKeyTxHandler) setPubKey(ctx context.Context, tx sdk.Tx, simulat
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (spkm setPubKeyTxHandler) Named() string {
+	return "set_pubkey"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (spkm setPubKeyTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := spkm.setPubKey(ctx, tx, false); err != nil {
@@ -195,6 +200,11 @@ func (vscd validateSigCountTxHandler) checkSigCount(ctx context.Context, tx sdk.
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (vscd validateSigCountTxHandler) Named() string {
+	return "validate_sig_count"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (vscd validateSigCountTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := vscd.checkSigCount(ctx, tx); err != nil {
@@ -227,19 +237,49 @@ func (vscd validateSigCountTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx,
 // by the concrete type.
 func DefaultSigVerificationGasConsumer(
 	meter sdk.GasMeter, sig signing.SignatureV2, params types.Params,
+) error {
+	return consumeSigVerificationGas(meter, sig, params, nil)
+}
+
+// NewSigVerificationGasConsumer returns a SignatureVerificationGasConsumer
+// behaving like DefaultSigVerificationGasConsumer, except that the gas consumed
+// for the public key types in costs, as returned by PubKey.Type() (e.g.
+// "secp256k1"), is read from costs instead of the params. Multisig public keys
+// consume the gas of each of their sub-signatures. The accepted public key types
+// are the same as DefaultSigVerificationGasConsumer.
+func NewSigVerificationGasConsumer(costs map[string]uint64) SignatureVerificationGasConsumer {
+	return func(meter sdk.GasMeter, sig signing.SignatureV2, params types.Params) error {
+		return consumeSigVerificationGas(meter, sig, params, costs)
+	}
+}
+
+// sigVerifyCost returns the signature verification cost of the given public
+// key, as overridden in costs, or defaultCost.
+func sigVerifyCost(costs map[string]uint64, pubkey cryptotypes.PubKey, defaultCost uint64) uint64 {
+	if cost, ok := costs[pubkey.Type()]; ok {
+		return cost
+	}
+
+	return defaultCost
+}
+
+// consumeSigVerificationGas consumes the signature verification gas of the
+// given signature, see NewSigVerificationGasConsumer.
+func consumeSigVerificationGas(
+	meter sdk.GasMeter, sig signing.SignatureV2, params types.Params, costs map[string]uint64,
 ) error {
 	pubkey := sig.PubKey
 	switch pubkey := pubkey.(type) {
 	case *ed25519.PubKey:
-		meter.ConsumeGas(params.SigVerifyCostED25519, "ante verify: ed25519")
+		meter.ConsumeGas(sigVerifyCost(costs, pubkey, params.SigVerifyCostED25519), "ante verify: ed25519")
 		return sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "ED25519 public keys are unsupported")
 
 	case *secp256k1.PubKey:
-		meter.ConsumeGas(params.SigVerifyCostSecp256k1, "ante verify: secp256k1")
+		meter.ConsumeGas(sigVerifyCost(costs, pubkey, params.SigVerifyCostSecp256k1), "ante verify: secp256k1")
 		return nil
 
 	case *secp256r1.PubKey:
-		meter.ConsumeGas(params.SigVerifyCostSecp256r1(), "ante verify: secp256r1")
+		meter.ConsumeGas(sigVerifyCost(costs, pubkey, params.SigVerifyCostSecp256r1()), "ante verify: secp256r1")
 		return nil
 
 	case multisig.PubKey:
@@ -247,7 +287,7 @@ func DefaultSigVerificationGasConsumer(
 		if !ok {
 			return fmt.Errorf("expected %T, got, %T", &signing.MultiSignatureData{}, sig.Data)
 		}
-		err := ConsumeMultisignatureVerificationGas(meter, multisignature, pubkey, params, sig.Sequence)
+		err := consumeMultisignatureVerificationGas(meter, multisignature, pubkey, params, sig.Sequence, costs)
 		if err != nil {
 			return err
 		}
@@ -258,13 +298,33 @@ func DefaultSigVerificationGasConsumer(
 	}
 }
 
-// ConsumeMultisignatureVerificationGas consumes gas from a GasMeter for verifying a multisig pubkey signature
+// ConsumeMultisignatureVerificationGas consumes gas from a GasMeter for verifying a multisig pubkey signature.
+// Only the sub-signatures present in the multisignature are charged, so that an
+// M-of-N multisig signed by M keys costs the same as M single signatures. The
+// multisignature is rejected if its bit array doesn't match the pubkey or the
+// number of sub-signatures.
 func ConsumeMultisignatureVerificationGas(
 	meter sdk.GasMeter, sig *signing.MultiSignatureData, pubkey multisig.PubKey,
 	params types.Params, accSeq uint64,
 ) error {
+	return consumeMultisignatureVerificationGas(meter, sig, pubkey, params, accSeq, nil)
+}
+
+func consumeMultisignatureVerificationGas(
+	meter sdk.GasMeter, sig *signing.MultiSignatureData, pubkey multisig.PubKey,
+	params types.Params, accSeq uint64, costs map[string]uint64,
+) error {
+	pubkeys := pubkey.GetPubKeys()
+	if sig.BitArray == nil || sig.BitArray.Count() != len(pubkeys) {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "multisignature bit array doesn't match the %d multisig pubkeys", len(pubkeys))
+	}
 
 	size := sig.BitArray.Count()
+	if present := sig.BitArray.NumTrueBitsBefore(size); present != len(sig.Signatures) {
+		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized,
+			"multisignature has %d sub-signatures, but %d are marked as present", len(sig.Signatures), present)
+	}
+
 	sigIndex := 0
 
 	for i := 0; i < size; i++ {
@@ -272,11 +332,11 @@ func ConsumeMultisignatureVerificationGas(
 			continue
 		}
 		sigV2 := signing.SignatureV2{
-			PubKey:   pubkey.GetPubKeys()[i],
+			PubKey:   pubkeys[i],
 			Data:     sig.Signatures[sigIndex],
 			Sequence: accSeq,
 		}
-		err := DefaultSigVerificationGasConsumer(meter, sigV2, params)
+		err := consumeSigVerificationGas(meter, sigV2, params, costs)
 		if err != nil {
 			return err
 		}
@@ -324,7 +384,7 @@ func (sgcm sigGasConsumeTxHandler) sigGasConsume(ctx context.Context, tx sdk.Tx,
 
 	// stdSigs contains the sequence number, account number, and signatures.
 	// When simulating, this would just be a 0-length slice.
-	signerAddrs := sigTx.GetSigners()
+	signerAddrs := txSigners(sdkCtx, sigTx)
 
 	for i, sig := range sigs {
 		signerAcc, err := GetSignerAcc(sdkCtx, sgcm.ak, signerAddrs[i])
@@ -358,6 +418,11 @@ func (sgcm sigGasConsumeTxHandler) sigGasConsume(ctx context.Context, tx sdk.Tx,
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (sgcm sigGasConsumeTxHandler) Named() string {
+	return "sig_gas_consume"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (sgcm sigGasConsumeTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := sgcm.sigGasConsume(ctx, tx, false); err != nil {
@@ -390,24 +455,82 @@ var _ tx.Handler = sigVerificationTxHandler{}
 type sigVerificationTxHandler struct {
 	ak              AccountKeeper
 	signModeHandler authsigning.SignModeHandler
+	opts            SigVerificationOptions
 	next            tx.Handler
 }
 
+// SigVerificationOptions defines the optional behaviors of the
+// SigVerification middleware.
+type SigVerificationOptions struct {
+	// SimulateSequenceCheck makes simulations check the signature sequences
+	// against the signers' account sequences, as DeliverTx does, so that
+	// clients learn about stale sequences before broadcasting their txs. By
+	// default, the sequences are not checked in simulations, as they are only
+	// verified along with the signatures. A simulation can bypass the check
+	// with SimulateOptions.SkipSequenceCheck.
+	SimulateSequenceCheck bool
+	// SequenceGapTolerance is the number of sequences ahead of a signer's
+	// account sequence that CheckTx accepts, e.g. for wallets submitting txs
+	// slightly out of order. A tx signed with such a sequence is admitted to
+	// the mempool as pending: the signer's sequence is not incremented in the
+	// CheckTx state, so that the missing txs can still be admitted. Pending txs
+	// are not rejected on recheck, and their signers' sequence is only
+	// incremented once the gap is filled. They fail in DeliverTx if they are
+	// included in a block before the txs filling the gap, in which case none
+	// of their state changes, fees included, are applied.
+	//
+	// It only applies to CheckTx: DeliverTx always requires the exact account
+	// sequence. Legacy amino signatures, whose sequence is only part of their
+	// sign bytes, are not tolerated any gap.
+	SequenceGapTolerance uint64
+	// BatchVerify makes CheckTx verify the single signatures of each tx
+	// together with a SigBatchVerifier, which batches the ed25519 ones, e.g.
+	// for the txs of several ed25519 signers. When the batch is invalid, the
+	// signatures are verified one by one to report the offending one. The
+	// rechecked txs are not concerned, as their signatures are not verified
+	// again. DeliverTx always verifies the signatures one by one, so that the
+	// batch verification rules never affect consensus.
+	BatchVerify bool
+}
+
 // SigVerificationMiddleware verifies all signatures for a tx and return an error if any are invalid. Note,
 // the sigVerificationTxHandler middleware will not get executed on ReCheck.
 //
 // CONTRACT: Pubkeys are set in context for all signers before this middleware runs
 // CONTRACT: Tx must implement SigVerifiableTx interface
 func SigVerificationMiddleware(ak AccountKeeper, signModeHandler authsigning.SignModeHandler) tx.Middleware {
+	return NewSigVerificationMiddleware(ak, signModeHandler, SigVerificationOptions{})
+}
+
+// NewSigVerificationMiddleware is the same as SigVerificationMiddleware, with
+// additional behaviors configured by the given SigVerificationOptions.
+//
+// CONTRACT: Pubkeys are set in context for all signers before this middleware runs
+// CONTRACT: Tx must implement SigVerifiableTx interface
+func NewSigVerificationMiddleware(ak AccountKeeper, signModeHandler authsigning.SignModeHandler, opts SigVerificationOptions) tx.Middleware {
 	return func(h tx.Handler) tx.Handler {
 		return sigVerificationTxHandler{
 			ak:              ak,
 			signModeHandler: signModeHandler,
+			opts:            opts,
 			next:            h,
 		}
 	}
 }
 
+// pendingSignersKey is the sdk.Context key under which the SigVerification
+// middleware stores the signers whose sequence was accepted within the
+// SequenceGapTolerance.
+type pendingSignersKey struct{}
+
+// isPendingSigner reports whether the signer's sequence was accepted within
+// the SequenceGapTolerance, in which case its sequence must not be
+// incremented.
+func isPendingSigner(sdkCtx sdk.Context, signer sdk.AccAddress) bool {
+	pending, _ := sdkCtx.Value(pendingSignersKey{}).(map[string]bool)
+	return pending[signer.String()]
+}
+
 // OnlyLegacyAminoSigners checks SignatureData to see if all
 // signers are using SIGN_MODE_LEGACY_AMINO_JSON. If this is the case
 // then the corresponding SignatureV2 struct will not have account sequence
@@ -429,41 +552,61 @@ func OnlyLegacyAminoSigners(sigData signing.SignatureData) bool {
 	}
 }
 
-func (svd sigVerificationTxHandler) sigVerify(ctx context.Context, tx sdk.Tx, isReCheckTx, simulate bool) error {
+// sigVerify verifies the signatures of the tx, and checks their sequences
+// against the signers' account sequences, unless skipSequenceCheck is set. In
+// simulate mode, the signatures themselves are not verified.
+// Signatures whose sequence is at most gapTolerance ahead of the account
+// sequence are accepted, and their signers are returned as pending. If
+// batchVerify is set, the single signatures are verified together with a
+// SigBatchVerifier once all of them are collected.
+func (svd sigVerificationTxHandler) sigVerify(ctx context.Context, tx sdk.Tx, isReCheckTx, simulate, skipSequenceCheck, batchVerify bool, gapTolerance uint64) ([]sdk.AccAddress, error) {
 	sdkCtx := sdk.UnwrapSDKContext(ctx)
-	// no need to verify signatures on recheck tx
+	// no need to verify signatures on recheck tx, the pending signers are
+	// still needed for their sequence not to be incremented
 	if isReCheckTx {
-		return nil
+		if gapTolerance == 0 {
+			return nil, nil
+		}
+		return svd.recheckPendingSigners(sdkCtx, tx)
 	}
 	sigTx, ok := tx.(authsigning.SigVerifiableTx)
 	if !ok {
-		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid transaction type")
+		return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid transaction type")
 	}
 
 	// stdSigs contains the sequence number, account number, and signatures.
 	// When simulating, this would just be a 0-length slice.
 	sigs, err := sigTx.GetSignaturesV2()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	signerAddrs := sigTx.GetSigners()
+	signerAddrs := txSigners(sdkCtx, sigTx)
 
 	// check that signer length and signature length are the same
 	if len(sigs) != len(signerAddrs) {
-		return sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "invalid number of signer;  expected: %d, got %d", len(signerAddrs), len(sigs))
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "invalid number of signer;  expected: %d, got %d", len(signerAddrs), len(sigs))
+	}
+
+	var (
+		batch     *SigBatchVerifier
+		batchErrs []error
+	)
+	if batchVerify && !simulate {
+		batch = NewSigBatchVerifier()
 	}
 
+	var pendingSigners []sdk.AccAddress
 	for i, sig := range sigs {
 		acc, err := GetSignerAcc(sdkCtx, svd.ak, signerAddrs[i])
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// retrieve pubkey
 		pubKey := acc.GetPubKey()
 		if !simulate && pubKey == nil {
-			return sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "pubkey on account is not set")
+			return nil, sdkerrors.Wrap(sdkerrors.ErrInvalidPubKey, "pubkey on account is not set")
 		}
 
 		// retrieve signer data
@@ -482,38 +625,119 @@ func (svd sigVerificationTxHandler) sigVerify(ctx context.Context, tx sdk.Tx, is
 			SignerIndex:   i,
 		}
 
+		// Check the account sequence. The SIGN_MODE_DIRECT sign bytes only
+		// hold the sequence of the signature itself, so it must be checked
+		// explicitly for the signature not to be replayed. Legacy amino
+		// signatures don't carry their sequence, it is verified along with
+		// their sign bytes instead. A sequence slightly ahead of the account
+		// sequence is accepted within gapTolerance, the signature then being
+		// verified against it.
+		if !skipSequenceCheck && !OnlyLegacyAminoSigners(sig.Data) && sig.Sequence != acc.GetSequence() {
+			if gapTolerance == 0 {
+				return nil, sdkerrors.Wrapf(sdkerrors.ErrWrongSequence, "account sequence mismatch, expected %d, got %d", acc.GetSequence(), sig.Sequence)
+			}
+			if sig.Sequence < acc.GetSequence() || sig.Sequence-acc.GetSequence() > gapTolerance {
+				return nil, sdkerrors.Wrapf(sdkerrors.ErrWrongSequence,
+					"account sequence mismatch, expected %d up to %d, got %d", acc.GetSequence(), acc.GetSequence()+gapTolerance, sig.Sequence,
+				)
+			}
+
+			signerData.Sequence = sig.Sequence
+			pendingSigners = append(pendingSigners, signerAddrs[i])
+		}
+
 		if !simulate {
-			err := authsigning.VerifySignature(pubKey, signerData, sig.Data, svd.signModeHandler, tx)
-			if err != nil {
-				var errMsg string
-				if OnlyLegacyAminoSigners(sig.Data) {
-					// If all signers are using SIGN_MODE_LEGACY_AMINO, we rely on VerifySignature to check account sequence number,
-					// and therefore communicate sequence number as a potential cause of error.
-					errMsg = fmt.Sprintf("signature verification failed; please verify account number (%d), sequence (%d) and chain-id (%s)", accNum, acc.GetSequence(), chainID)
-				} else {
-					errMsg = fmt.Sprintf("signature verification failed; please verify account number (%d) and chain-id (%s)", accNum, chainID)
-				}
-				return sdkerrors.Wrap(sdkerrors.ErrUnauthorized, errMsg)
+			var errMsg string
+			if OnlyLegacyAminoSigners(sig.Data) {
+				// If all signers are using SIGN_MODE_LEGACY_AMINO, we rely on VerifySignature to check account sequence number,
+				// and therefore communicate sequence number as a potential cause of error.
+				errMsg = fmt.Sprintf("signature verification failed; please verify account number (%d), sequence (%d) and chain-id (%s)", accNum, acc.GetSequence(), chainID)
+			} else {
+				errMsg = fmt.Sprintf("signature verification failed; please verify account number (%d) and chain-id (%s)", accNum, chainID)
+			}
 
+			if single, ok := sig.Data.(*signing.SingleSignatureData); ok && batch != nil {
+				signBytes, err := svd.signModeHandler.GetSignBytes(single.SignMode, signerData, tx)
+				if err != nil {
+					return nil, sdkerrors.Wrap(sdkerrors.ErrUnauthorized, errMsg)
+				}
+				batch.Add(pubKey, signBytes, single.Signature)
+				batchErrs = append(batchErrs, sdkerrors.Wrap(sdkerrors.ErrUnauthorized, errMsg))
+			} else if err := authsigning.VerifySignature(pubKey, signerData, sig.Data, svd.signModeHandler, tx); err != nil {
+				return nil, sdkerrors.Wrap(sdkerrors.ErrUnauthorized, errMsg)
 			}
 		}
 	}
 
-	return nil
+	if batch != nil {
+		if invalid := batch.Flush(); len(invalid) > 0 {
+			return nil, batchErrs[invalid[0]]
+		}
+	}
+
+	return pendingSigners, nil
+}
+
+// recheckPendingSigners returns the signers of the rechecked tx whose
+// signature sequence is still ahead of their account sequence, without
+// verifying the signatures nor rejecting the tx.
+func (svd sigVerificationTxHandler) recheckPendingSigners(sdkCtx sdk.Context, tx sdk.Tx) ([]sdk.AccAddress, error) {
+	sigTx, ok := tx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid transaction type")
+	}
+
+	sigs, err := sigTx.GetSignaturesV2()
+	if err != nil {
+		return nil, err
+	}
+
+	signerAddrs := txSigners(sdkCtx, sigTx)
+	if len(sigs) != len(signerAddrs) {
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "invalid number of signer;  expected: %d, got %d", len(signerAddrs), len(sigs))
+	}
+
+	var pendingSigners []sdk.AccAddress
+	for i, sig := range sigs {
+		acc, err := GetSignerAcc(sdkCtx, svd.ak, signerAddrs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !OnlyLegacyAminoSigners(sig.Data) && sig.Sequence > acc.GetSequence() {
+			pendingSigners = append(pendingSigners, signerAddrs[i])
+		}
+	}
+
+	return pendingSigners, nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (svd sigVerificationTxHandler) Named() string {
+	return "sig_verification"
 }
 
 // CheckTx implements tx.Handler.CheckTx.
 func (svd sigVerificationTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
-	if err := svd.sigVerify(ctx, tx, req.Type == abci.CheckTxType_Recheck, false); err != nil {
+	pendingSigners, err := svd.sigVerify(ctx, tx, req.Type == abci.CheckTxType_Recheck, false, false, svd.opts.BatchVerify, svd.opts.SequenceGapTolerance)
+	if err != nil {
 		return abci.ResponseCheckTx{}, err
 	}
 
+	if len(pendingSigners) > 0 {
+		pending := make(map[string]bool, len(pendingSigners))
+		for _, signer := range pendingSigners {
+			pending[signer.String()] = true
+		}
+		ctx = sdk.WrapSDKContext(sdk.UnwrapSDKContext(ctx).WithValue(pendingSignersKey{}, pending))
+	}
+
 	return svd.next.CheckTx(ctx, tx, req)
 }
 
 // DeliverTx implements tx.Handler.DeliverTx.
 func (svd sigVerificationTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
-	if err := svd.sigVerify(ctx, tx, false, false); err != nil {
+	if _, err := svd.sigVerify(ctx, tx, false, false, false, false, 0); err != nil {
 		return abci.ResponseDeliverTx{}, err
 	}
 
@@ -522,7 +746,8 @@ func (svd sigVerificationTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, re
 
 // SimulateTx implements tx.Handler.SimulateTx.
 func (svd sigVerificationTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
-	if err := svd.sigVerify(ctx, sdkTx, false, true); err != nil {
+	skipSequenceCheck := !svd.opts.SimulateSequenceCheck || req.SimulateOptions.SkipSequenceCheck
+	if _, err := svd.sigVerify(ctx, sdkTx, false, true, skipSequenceCheck, false, 0); err != nil {
 		return tx.ResponseSimulateTx{}, err
 	}
 
@@ -561,8 +786,13 @@ func (isd incrementSequenceTxHandler) incrementSeq(ctx context.Context, tx sdk.T
 		return sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid transaction type")
 	}
 
-	// increment sequence of all signers
-	for _, addr := range sigTx.GetSigners() {
+	// increment sequence of all signers, but the pending ones whose sequence
+	// is ahead of their account sequence, see SequenceGapTolerance
+	for _, addr := range txSigners(sdkCtx, sigTx) {
+		if isPendingSigner(sdkCtx, addr) {
+			continue
+		}
+
 		acc := isd.ak.GetAccount(sdkCtx, addr)
 		if err := acc.SetSequence(acc.GetSequence() + 1); err != nil {
 			panic(err)
@@ -574,6 +804,11 @@ func (isd incrementSequenceTxHandler) incrementSeq(ctx context.Context, tx sdk.T
 	return nil
 }
 
+// Named implements NamedTxHandler.Named.
+func (isd incrementSequenceTxHandler) Named() string {
+	return "increment_sequence"
+}
+
 // CheckTx implements tx.Handler.CheckTx.
 func (isd incrementSequenceTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
 	if err := isd.incrementSeq(ctx, tx); err != nil {
@@ -1,6 +1,7 @@
 package middleware_test
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/cosmos/cosmos-sdk/client"
@@ -14,11 +15,13 @@ import (
 	"github.com/cosmos/cosmos-sdk/simapp"
 	"github.com/cosmos/cosmos-sdk/testutil/testdata"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	"github.com/cosmos/cosmos-sdk/types/tx"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
 	"github.com/cosmos/cosmos-sdk/x/auth/migrations/legacytx"
 	"github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
 	abci "github.com/tendermint/tendermint/abci/types"
 )
 
@@ -121,6 +124,80 @@ func (s *MWTestSuite) TestConsumeSignatureVerificationGas() {
 	}
 }
 
+func (s *MWTestSuite) TestSigVerificationGasCosts() {
+	params := types.DefaultParams()
+	msg := []byte{1, 2, 3, 4}
+	cdc := simapp.MakeTestEncodingConfig().Amino
+	consumer := middleware.NewSigVerificationGasConsumer(map[string]uint64{"secp256k1": 2000, "secp256r1": 3000})
+
+	consumeGas := func(sig signing.SignatureV2) (sdk.Gas, error) {
+		meter := sdk.NewInfiniteGasMeter()
+		err := consumer(meter, sig, params)
+		return meter.GasConsumed(), err
+	}
+
+	// the gas differs by key type
+	gas, err := consumeGas(signing.SignatureV2{PubKey: secp256k1.GenPrivKey().PubKey()})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2000), gas)
+	skR1, _ := secp256r1.GenPrivKey()
+	gas, err = consumeGas(signing.SignatureV2{PubKey: skR1.PubKey()})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(3000), gas)
+
+	// key types without an overridden cost use the params, and the same key
+	// types are rejected
+	gas, err = consumeGas(signing.SignatureV2{PubKey: ed25519.GenPrivKey().PubKey()})
+	s.Require().Error(err)
+	s.Require().Equal(params.SigVerifyCostED25519, gas)
+
+	// multisigs consume the gas of each sub-signature
+	pkSet, sigSet := generatePubKeysAndSignatures(5, msg, false)
+	for _, signers := range []int{2, 3, 5} {
+		multisignature := multisig.NewMultisig(len(pkSet))
+		for i := 0; i < signers; i++ {
+			sigV2, err := legacytx.StdSignatureToSignatureV2(cdc, legacytx.StdSignature{PubKey: pkSet[i], Signature: sigSet[i]})
+			s.Require().NoError(err)
+			s.Require().NoError(multisig.AddSignatureV2(multisignature, sigV2, pkSet))
+		}
+
+		gas, err = consumeGas(signing.SignatureV2{PubKey: kmultisig.NewLegacyAminoPubKey(2, pkSet), Data: multisignature})
+		s.Require().NoError(err)
+		s.Require().Equal(uint64(signers*2000), gas)
+	}
+}
+
+func (s *MWTestSuite) TestMultisigVerificationGasPerPresentSignature() {
+	params := types.DefaultParams()
+	msg := []byte{1, 2, 3, 4}
+	cdc := simapp.MakeTestEncodingConfig().Amino
+
+	// a 3-of-5 multisig signed by exactly 3 non-contiguous keys
+	pkSet, sigSet := generatePubKeysAndSignatures(5, msg, false)
+	multisigKey := kmultisig.NewLegacyAminoPubKey(3, pkSet)
+	multisignature := multisig.NewMultisig(len(pkSet))
+	for _, i := range []int{0, 2, 4} {
+		sigV2, err := legacytx.StdSignatureToSignatureV2(cdc, legacytx.StdSignature{PubKey: pkSet[i], Signature: sigSet[i]})
+		s.Require().NoError(err)
+		s.Require().NoError(multisig.AddSignatureV2(multisignature, sigV2, pkSet))
+	}
+
+	meter := sdk.NewInfiniteGasMeter()
+	err := middleware.ConsumeMultisignatureVerificationGas(meter, multisignature, multisigKey, params, 0)
+	s.Require().NoError(err)
+	s.Require().Equal(3*params.SigVerifyCostSecp256k1, meter.GasConsumed())
+
+	// malformed multisignatures are rejected before consuming any gas
+	tooFewSigs := &signing.MultiSignatureData{BitArray: multisignature.BitArray, Signatures: multisignature.Signatures[:2]}
+	wrongSize := &signing.MultiSignatureData{BitArray: cryptotypes.NewCompactBitArray(4), Signatures: nil}
+	for _, sig := range []*signing.MultiSignatureData{tooFewSigs, wrongSize, {}} {
+		meter := sdk.NewInfiniteGasMeter()
+		err := middleware.ConsumeMultisignatureVerificationGas(meter, sig, multisigKey, params, 0)
+		s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized))
+		s.Require().Zero(meter.GasConsumed())
+	}
+}
+
 func (s *MWTestSuite) TestSigVerification() {
 	ctx := s.SetupTest(true) // setup
 
@@ -290,6 +367,258 @@ func (s *MWTestSuite) TestSigVerification_ExplicitAmino() {
 	}
 }
 
+func (s *MWTestSuite) TestSimulateSkipSequenceCheck() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.NewSigVerificationMiddleware(
+			s.app.AccountKeeper,
+			s.clientCtx.TxConfig.SignModeHandler(),
+			middleware.SigVerificationOptions{SimulateSequenceCheck: true},
+		),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+
+	testCases := []struct {
+		name              string
+		accSeq            uint64
+		skipSequenceCheck bool
+		shouldErr         bool
+	}{
+		{"current sequence", 0, false, false},
+		{"future sequence", 3, false, true},
+		{"future sequence with SkipSequenceCheck", 3, true, false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+			txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+			txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+
+			privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{acc.GetAccountNumber()}, []uint64{tc.accSeq}
+			testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+			s.Require().NoError(err)
+
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{
+				TxBytes:         txBytes,
+				SimulateOptions: tx.SimulateOptions{SkipSequenceCheck: tc.skipSequenceCheck},
+			})
+			if tc.shouldErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+			} else {
+				s.Require().NoError(err)
+			}
+		})
+	}
+}
+
+// TestSimulateSequenceCheck checks that the default tx handler doesn't check
+// the sequences in simulations, as SimulateSequenceCheck is opt-in.
+func (s *MWTestSuite) TestSimulateSequenceCheck() {
+	ctx := s.SetupTest(false) // setup
+	ctx = ctx.WithBlockHeight(1)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.Require().NoError(acc.SetSequence(1))
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, addr1, testdata.NewTestFeeAmount()))
+
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{acc.GetAccountNumber()}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	_, err = s.txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+}
+
+func (s *MWTestSuite) TestSigVerificationSequenceGapTolerance() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.SetPubKeyMiddleware(s.app.AccountKeeper),
+		middleware.NewSigVerificationMiddleware(
+			s.app.AccountKeeper,
+			s.clientCtx.TxConfig.SignModeHandler(),
+			middleware.SigVerificationOptions{SequenceGapTolerance: 2},
+		),
+		middleware.IncrementSequenceMiddleware(s.app.AccountKeeper),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+
+	newTx := func(accSeq uint64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+		txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+
+		privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{acc.GetAccountNumber()}, []uint64{accSeq}
+		testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+		s.Require().NoError(err)
+
+		return testTx
+	}
+	sequence := func() uint64 {
+		return s.app.AccountKeeper.GetAccount(ctx, addr1).GetSequence()
+	}
+
+	// a sequence within the gap is accepted as pending, without incrementing
+	// the account sequence
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(2), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(0), sequence())
+
+	// a sequence beyond the gap, or behind the account sequence, is rejected
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(3), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+
+	// the txs filling the gap are still accepted, and increment the sequence
+	for seq := uint64(0); seq < 2; seq++ {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(seq), abci.RequestCheckTx{})
+		s.Require().NoError(err)
+	}
+	s.Require().Equal(uint64(2), sequence())
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(1), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+
+	// DeliverTx only accepts the exact account sequence
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(3), abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), newTx(2), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(3), sequence())
+
+	// a pending tx rechecked after a block keeps the account sequence, so that
+	// the tx filling the gap is still accepted
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(4), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(4), abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(3), sequence())
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(3), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(4), sequence())
+
+	// once the gap is filled, the rechecked tx increments the sequence
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(4), abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(5), sequence())
+}
+
+// TestSigVerificationReplay checks that the SIGN_MODE_DIRECT signatures,
+// whose sign bytes don't hold the account sequence, are bound to it.
+func (s *MWTestSuite) TestSigVerificationBatchVerify() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.SetPubKeyMiddleware(s.app.AccountKeeper),
+		middleware.NewSigVerificationMiddleware(
+			s.app.AccountKeeper,
+			s.clientCtx.TxConfig.SignModeHandler(),
+			middleware.SigVerificationOptions{BatchVerify: true},
+		),
+	)
+
+	// two ed25519 signers, batched, and a secp256k1 one, which isn't
+	privs := []cryptotypes.PrivKey{ed25519.GenPrivKey(), ed25519.GenPrivKey(), secp256k1.GenPrivKey()}
+	msgs := make([]sdk.Msg, len(privs))
+	for i, priv := range privs {
+		addr := sdk.AccAddress(priv.PubKey().Address())
+		acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr)
+		s.Require().NoError(acc.SetAccountNumber(uint64(i)))
+		s.app.AccountKeeper.SetAccount(ctx, acc)
+		msgs[i] = testdata.NewTestMsg(addr)
+	}
+
+	newTx := func(invalidSig int) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(msgs...))
+		txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+		txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+		testTx, _, err := s.createTestTx(txBuilder, privs, []uint64{0, 1, 2}, []uint64{0, 0, 0}, ctx.ChainID())
+		s.Require().NoError(err)
+		if invalidSig < 0 {
+			return testTx
+		}
+
+		sigs, err := testTx.GetSignaturesV2()
+		s.Require().NoError(err)
+		data := sigs[invalidSig].Data.(*signing.SingleSignatureData)
+		data.Signature = append([]byte{}, data.Signature...)
+		data.Signature[0] ^= 0xff
+		s.Require().NoError(txBuilder.SetSignatures(sigs...))
+		return txBuilder.GetTx()
+	}
+
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(-1), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+
+	// an invalid signature is found, whether it was batched or not
+	for _, invalidSig := range []int{1, 2} {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(invalidSig), abci.RequestCheckTx{})
+		s.Require().True(errors.Is(err, sdkerrors.ErrUnauthorized), "invalid signature %d", invalidSig)
+		s.Require().Contains(err.Error(), fmt.Sprintf("account number (%d)", invalidSig))
+	}
+}
+
+func (s *MWTestSuite) TestSigVerificationReplay() {
+	ctx := s.SetupTest(true) // setup
+	ctx = ctx.WithBlockHeight(1)
+	txHandler := middleware.ComposeMiddlewares(
+		noopTxHandler{},
+		middleware.SetPubKeyMiddleware(s.app.AccountKeeper),
+		middleware.SigVerificationMiddleware(s.app.AccountKeeper, s.clientCtx.TxConfig.SignModeHandler()),
+		middleware.IncrementSequenceMiddleware(s.app.AccountKeeper),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	acc := s.app.AccountKeeper.NewAccountWithAddress(ctx, addr1)
+	s.app.AccountKeeper.SetAccount(ctx, acc)
+
+	newTx := func(accSeq uint64) sdk.Tx {
+		txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+		s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+		txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+		txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+
+		privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{acc.GetAccountNumber()}, []uint64{accSeq}
+		testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+		s.Require().NoError(err)
+
+		return testTx
+	}
+
+	replayed := newTx(0)
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), replayed, abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+
+	// the signatures are valid, but their sequence is stale or ahead of the
+	// account sequence
+	for _, testTx := range []sdk.Tx{replayed, newTx(2)} {
+		_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+		s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+		_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+		s.Require().True(errors.Is(err, sdkerrors.ErrWrongSequence))
+	}
+	s.Require().Equal(uint64(1), s.app.AccountKeeper.GetAccount(ctx, addr1).GetSequence())
+}
+
 func (s *MWTestSuite) TestSigIntegration() {
 	// generate private keys
 	privs := []cryptotypes.PrivKey{
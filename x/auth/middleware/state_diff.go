@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store/cachemulti"
+	"github.com/cosmos/cosmos-sdk/store/dbadapter"
+	"github.com/cosmos/cosmos-sdk/store/tracekv"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// stateDiffTracer is the trace writer of a traced store, recording the
+// entries written to it along with their previous value.
+type stateDiffTracer struct {
+	storeKey string
+	parent   storetypes.KVStore
+	changes  *[]tx.StateChange
+}
+
+// Write implements io.Writer. tracekv writes each operation in a single call,
+// before applying it, so that the previous value can still be read from the
+// parent store.
+func (t stateDiffTracer) Write(p []byte) (int, error) {
+	var op traceOperation
+	if len(bytes.TrimSpace(p)) == 0 || json.Unmarshal(p, &op) != nil {
+		return len(p), nil
+	}
+	if op.Operation != "write" && op.Operation != "delete" {
+		return len(p), nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(op.Key)
+	if err != nil {
+		return len(p), nil
+	}
+
+	var newValue []byte
+	if op.Operation == "write" {
+		if newValue, err = base64.StdEncoding.DecodeString(op.Value); err != nil {
+			return len(p), nil
+		}
+	}
+
+	oldValue := t.parent.Get(key)
+	if (oldValue == nil) == (newValue == nil) && bytes.Equal(oldValue, newValue) {
+		return len(p), nil
+	}
+
+	*t.changes = append(*t.changes, tx.StateChange{
+		StoreKey: t.storeKey,
+		Key:      key,
+		OldValue: oldValue,
+		NewValue: newValue,
+	})
+
+	return len(p), nil
+}
+
+type stateDiffTxHandler struct {
+	keys       []storetypes.StoreKey
+	maxEntries int
+	next       tx.Handler
+}
+
+// NewStateDiffMiddleware returns a middleware returning, in the simulations
+// with SimulateOptions.ReturnStateDiff set, the store entries changed by the
+// middlewares it wraps and the msgs, e.g. for explorers to preview the
+// balance changes of a tx. The changes are captured by tracing the writes to
+// the given stores, which must be all the stores of the app. At most
+// maxEntries changes are returned.
+//
+// It has no effect on CheckTx and DeliverTx, and a maxEntries not positive
+// disables it.
+func NewStateDiffMiddleware(keys []storetypes.StoreKey, maxEntries int) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if maxEntries <= 0 {
+			return txh
+		}
+
+		return stateDiffTxHandler{
+			keys:       keys,
+			maxEntries: maxEntries,
+			next:       txh,
+		}
+	}
+}
+
+var _ tx.Handler = stateDiffTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh stateDiffTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh stateDiffTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh stateDiffTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if !req.SimulateOptions.ReturnStateDiff {
+		return txh.next.SimulateTx(ctx, sdkTx, req)
+	}
+
+	// Branch the stores on top of traced stores, so that the writes of the
+	// simulation are traced when the branch is written.
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	var changes []tx.StateChange
+	stores := make(map[storetypes.StoreKey]storetypes.CacheWrapper, len(txh.keys))
+	for _, key := range txh.keys {
+		parent := sdkCtx.MultiStore().GetKVStore(key)
+		stores[key] = tracekv.NewStore(parent, stateDiffTracer{storeKey: key.Name(), parent: parent, changes: &changes}, nil)
+	}
+	msCache := cachemulti.NewFromKVStore(dbadapter.Store{DB: dbm.NewMemDB()}, stores, nil, nil, nil, nil)
+
+	res, err := txh.next.SimulateTx(sdk.WrapSDKContext(sdkCtx.WithMultiStore(msCache)), sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	msCache.Write()
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].StoreKey != changes[j].StoreKey {
+			return changes[i].StoreKey < changes[j].StoreKey
+		}
+
+		return bytes.Compare(changes[i].Key, changes[j].Key) < 0
+	})
+	if len(changes) > txh.maxEntries {
+		changes, res.StateDiffTruncated = changes[:txh.maxEntries], true
+	}
+	res.StateDiff = changes
+
+	return res, nil
+}
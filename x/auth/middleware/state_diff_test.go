@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"bytes"
+
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	paramstypes "github.com/cosmos/cosmos-sdk/x/params/types"
+)
+
+func (s *MWTestSuite) TestStateDiffMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	_, _, sender := testdata.KeyTestPubAddr()
+	_, _, recipient := testdata.KeyTestPubAddr()
+	s.app.AccountKeeper.SetAccount(ctx, s.app.AccountKeeper.NewAccountWithAddress(ctx, sender))
+	s.Require().NoError(testutil.FundAccount(s.app.BankKeeper, ctx, sender, sdk.NewCoins(sdk.NewInt64Coin("atom", 100))))
+
+	// the TestMsg sends 10atom from the sender to the recipient
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		return &sdk.Result{}, s.app.BankKeeper.SendCoins(ctx, sender, recipient, sdk.NewCoins(sdk.NewInt64Coin("atom", 10)))
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	keys := []storetypes.StoreKey{
+		s.app.GetKey(authtypes.StoreKey),
+		s.app.GetKey(banktypes.StoreKey),
+		s.app.GetKey(paramstypes.StoreKey),
+		s.app.GetTKey(paramstypes.TStoreKey),
+	}
+	newTxHandler := func(maxEntries int) tx.Handler {
+		return middleware.ComposeMiddlewares(
+			middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+			middleware.NewStateDiffMiddleware(keys, maxEntries),
+		)
+	}
+
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(sender)))
+	testTx := txBuilder.GetTx()
+	simulate := func(maxEntries int, returnDiff bool) tx.ResponseSimulateTx {
+		cacheCtx, _ := ctx.CacheContext()
+		res, err := newTxHandler(maxEntries).SimulateTx(sdk.WrapSDKContext(cacheCtx), testTx, tx.RequestSimulateTx{
+			SimulateOptions: tx.SimulateOptions{ReturnStateDiff: returnDiff},
+		})
+		s.Require().NoError(err)
+
+		return res
+	}
+
+	// no diff is returned unless requested
+	res := simulate(100, false)
+	s.Require().Empty(res.StateDiff)
+
+	res = simulate(100, true)
+	s.Require().False(res.StateDiffTruncated)
+	findChange := func(storeKey string, key []byte) *tx.StateChange {
+		for _, change := range res.StateDiff {
+			if change.StoreKey == storeKey && bytes.Equal(change.Key, key) {
+				return &change
+			}
+		}
+
+		return nil
+	}
+	balance := func(bz []byte) int64 {
+		if bz == nil {
+			return 0
+		}
+
+		var amount sdk.Int
+		s.Require().NoError(amount.Unmarshal(bz))
+		return amount.Int64()
+	}
+
+	// both balances change, and the recipient account is created
+	senderChange := findChange(banktypes.StoreKey, append(banktypes.CreateAccountBalancesPrefix(sender), []byte("atom")...))
+	s.Require().NotNil(senderChange)
+	s.Require().Equal(int64(100), balance(senderChange.OldValue))
+	s.Require().Equal(int64(90), balance(senderChange.NewValue))
+
+	recipientChange := findChange(banktypes.StoreKey, append(banktypes.CreateAccountBalancesPrefix(recipient), []byte("atom")...))
+	s.Require().NotNil(recipientChange)
+	s.Require().Nil(recipientChange.OldValue)
+	s.Require().Equal(int64(10), balance(recipientChange.NewValue))
+
+	accountChange := findChange(authtypes.StoreKey, authtypes.AddressStoreKey(recipient))
+	s.Require().NotNil(accountChange)
+	s.Require().Nil(accountChange.OldValue)
+	s.Require().Nil(findChange(authtypes.StoreKey, authtypes.AddressStoreKey(sender)))
+
+	// the diff is bounded
+	full := res.StateDiff
+	res = simulate(1, true)
+	s.Require().True(res.StateDiffTruncated)
+	s.Require().Equal(full[:1], res.StateDiff)
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// abortedMsgError is the error of the msg router when the context of the tx is
+// done before the execution of a msg. It is an ErrLogic, which gives its ABCI
+// code, and it also matches the context error with errors.Is, e.g.
+// context.DeadlineExceeded.
+type abortedMsgError struct {
+	// err is the ErrLogic describing the aborted msg.
+	err error
+	// ctxErr is the error of the context.
+	ctxErr error
+}
+
+// newAbortedMsgError returns the error of the msg at the given index, aborted
+// with the given context error.
+func newAbortedMsgError(ctxErr error, msgIndex int) error {
+	return &abortedMsgError{
+		err:    sdkerrors.Wrap(sdkerrors.ErrLogic, fmt.Sprintf("%s; message index: %d", ctxErr, msgIndex)),
+		ctxErr: ctxErr,
+	}
+}
+
+func (e *abortedMsgError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the ErrLogic, so that the ABCI code of the error is found.
+func (e *abortedMsgError) Cause() error {
+	return e.err
+}
+
+// Unwrap implements the built-in errors.Unwrap.
+func (e *abortedMsgError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the context error matches the target, the ErrLogic is
+// matched through Unwrap.
+func (e *abortedMsgError) Is(target error) bool {
+	return errors.Is(e.ctxErr, target)
+}
+
+type simulateTimeoutTxHandler struct {
+	timeout time.Duration
+	next    tx.Handler
+}
+
+// NewSimulateTimeoutMiddleware returns a middleware that bounds the wall-clock
+// time a SimulateTx call can spend, by setting a deadline on the context passed
+// to the inner middlewares. The msg router checks the context between each msg,
+// and aborts the simulation once the deadline is exceeded. A non-positive
+// timeout disables the deadline.
+//
+// CheckTx and DeliverTx are never bounded, as aborting them depending on the
+// node's speed would be non-deterministic.
+func NewSimulateTimeoutMiddleware(timeout time.Duration) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return simulateTimeoutTxHandler{
+			timeout: timeout,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = simulateTimeoutTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx method.
+func (txh simulateTimeoutTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx method.
+func (txh simulateTimeoutTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx method.
+func (txh simulateTimeoutTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if txh.timeout <= 0 {
+		return txh.next.SimulateTx(ctx, sdkTx, req)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, txh.timeout)
+	defer cancel()
+
+	sdkCtx := sdk.UnwrapSDKContext(ctx).WithContext(timeoutCtx)
+
+	return txh.next.SimulateTx(sdk.WrapSDKContext(sdkCtx), sdkTx, req)
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+// Event type and attribute keys of the event emitted by the TimeoutHeightOrder
+// middleware in TimeoutOrderWarn mode.
+const (
+	EventTypeTimeoutHeightMisordered = "timeout_height_misordered"
+
+	AttributeKeyTimeoutHeight        = "timeout_height"
+	AttributeKeyPendingTimeoutHeight = "pending_timeout_height"
+)
+
+// TimeoutOrderMode defines how the TimeoutHeightOrder middleware handles the
+// txs whose timeout height is misordered.
+type TimeoutOrderMode int
+
+const (
+	// TimeoutOrderWarn accepts the misordered txs, with a
+	// `timeout_height_misordered` event in their CheckTx response.
+	TimeoutOrderWarn TimeoutOrderMode = iota
+	// TimeoutOrderReject rejects the misordered txs with ErrTxTimeoutHeight.
+	TimeoutOrderReject
+)
+
+// pendingTimeouts holds the in-memory timeout heights of the txs accepted in
+// the mempool but not yet committed, keyed by signer address and then by the
+// signer's sequence. A zero timeout height means no timeout. It is shared by
+// all copies of the timeoutHeightOrderTxHandler.
+type pendingTimeouts struct {
+	mtx     sync.Mutex
+	pending map[string]map[uint64]uint64
+}
+
+// signerSequence is the sequence of a signer of a tx.
+type signerSequence struct {
+	signer   sdk.AccAddress
+	sequence uint64
+}
+
+type timeoutHeightOrderTxHandler struct {
+	mode     TimeoutOrderMode
+	timeouts *pendingTimeouts
+	next     tx.Handler
+}
+
+// NewTimeoutHeightOrderMiddleware returns a mempool heuristic helping wallets
+// avoid stuck sequences: it tracks the timeout heights of each signer's txs
+// pending in the mempool, and detects the new txs timing out earlier than a
+// pending tx of the same signer with a lower sequence, which handles them
+// according to mode. A tx without timeout height never times out.
+//
+// A tx is pending from its acceptance in CheckTx until a tx of the same signer
+// with the same or a higher sequence is delivered, or it is evicted on
+// ReCheckTx. The heuristic only applies to CheckTx and never fails DeliverTx.
+// CONTRACT: Tx must implement SigVerifiableTx and TxWithTimeoutHeight interfaces
+func NewTimeoutHeightOrderMiddleware(mode TimeoutOrderMode) tx.Middleware {
+	timeouts := &pendingTimeouts{pending: make(map[string]map[uint64]uint64)}
+
+	return func(txh tx.Handler) tx.Handler {
+		return timeoutHeightOrderTxHandler{
+			mode:     mode,
+			timeouts: timeouts,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = timeoutHeightOrderTxHandler{}
+
+// timesOutBefore reports whether timeout height a is earlier than b.
+func timesOutBefore(a, b uint64) bool {
+	return a != 0 && (b == 0 || a < b)
+}
+
+// signerSequences returns the sequence of each signature of the tx.
+func signerSequences(sigTx authsigning.SigVerifiableTx) ([]signerSequence, error) {
+	sigs, err := sigTx.GetSignaturesV2()
+	if err != nil {
+		return nil, err
+	}
+
+	signers := sigTx.GetSigners()
+	if len(sigs) != len(signers) {
+		return nil, sdkerrors.Wrapf(sdkerrors.ErrUnauthorized, "invalid number of signer; expected: %d, got %d", len(signers), len(sigs))
+	}
+
+	seqs := make([]signerSequence, len(sigs))
+	for i, sig := range sigs {
+		seqs[i] = signerSequence{signer: signers[i], sequence: sig.Sequence}
+	}
+
+	return seqs, nil
+}
+
+// misordered returns the timeout height of a pending tx of the signer with a
+// lower sequence, which times out after timeoutHeight, if any.
+func (p *pendingTimeouts) misordered(seq signerSequence, timeoutHeight uint64) (uint64, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for sequence, pendingHeight := range p.pending[seq.signer.String()] {
+		if sequence < seq.sequence && timesOutBefore(timeoutHeight, pendingHeight) {
+			return pendingHeight, true
+		}
+	}
+
+	return 0, false
+}
+
+// add records the timeout height of the tx with the given signer sequences.
+func (p *pendingTimeouts) add(seqs []signerSequence, timeoutHeight uint64) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, seq := range seqs {
+		addr := seq.signer.String()
+		if p.pending[addr] == nil {
+			p.pending[addr] = make(map[uint64]uint64)
+		}
+		p.pending[addr][seq.sequence] = timeoutHeight
+	}
+}
+
+// remove forgets the txs with the given signer sequences. If committed is set,
+// the txs of the same signers with lower sequences are forgotten too, as they
+// can't be included anymore.
+func (p *pendingTimeouts) remove(seqs []signerSequence, committed bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, seq := range seqs {
+		addr := seq.signer.String()
+		for sequence := range p.pending[addr] {
+			if sequence == seq.sequence || committed && sequence < seq.sequence {
+				delete(p.pending[addr], sequence)
+			}
+		}
+		if len(p.pending[addr]) == 0 {
+			delete(p.pending, addr)
+		}
+	}
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh timeoutHeightOrderTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sigTx, ok := sdkTx.(authsigning.SigVerifiableTx)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "invalid tx type")
+	}
+
+	seqs, err := signerSequences(sigTx)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	// the tx is already pending, it is only forgotten if it gets evicted
+	if req.Type == abci.CheckTxType_Recheck {
+		res, err := txh.next.CheckTx(ctx, sdkTx, req)
+		if err != nil {
+			txh.timeouts.remove(seqs, false)
+		}
+
+		return res, err
+	}
+
+	timeoutTx, ok := sdkTx.(sdk.TxWithTimeoutHeight)
+	if !ok {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "expected tx to implement TxWithTimeoutHeight")
+	}
+
+	timeoutHeight := timeoutTx.GetTimeoutHeight()
+	var events []abci.Event
+	for _, seq := range seqs {
+		pendingHeight, misordered := txh.timeouts.misordered(seq, timeoutHeight)
+		if !misordered {
+			continue
+		}
+
+		if txh.mode == TimeoutOrderReject {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrTxTimeoutHeight,
+				"timeout height %d of signer %s is earlier than the timeout height %d of a pending tx with a lower sequence",
+				timeoutHeight, seq.signer, pendingHeight)
+		}
+
+		events = append(events, abci.Event(sdk.NewEvent(EventTypeTimeoutHeightMisordered,
+			sdk.NewAttribute(AttributeKeySigner, seq.signer.String()),
+			sdk.NewAttribute(sdk.AttributeKeyAccountSequence, strconv.FormatUint(seq.sequence, 10)),
+			sdk.NewAttribute(AttributeKeyTimeoutHeight, strconv.FormatUint(timeoutHeight, 10)),
+			sdk.NewAttribute(AttributeKeyPendingTimeoutHeight, strconv.FormatUint(pendingHeight, 10)),
+		)))
+	}
+
+	res, err := txh.next.CheckTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	txh.timeouts.add(seqs, timeoutHeight)
+	res.Events = append(res.Events, events...)
+
+	return res, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh timeoutHeightOrderTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	// the tx is committed in the block and leaves the mempool, whatever its
+	// result, unless it's a dry-run tx, which isn't part of the block
+	if sigTx, ok := sdkTx.(authsigning.SigVerifiableTx); ok && !isDryRun(sdk.UnwrapSDKContext(ctx)) {
+		if seqs, err := signerSequences(sigTx); err == nil {
+			defer txh.timeouts.remove(seqs, true)
+		}
+	}
+
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh timeoutHeightOrderTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
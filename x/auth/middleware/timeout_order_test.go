@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// sequencedTx returns a tx of the account of pub with the given sequence and
+// timeout height, and an unverified signature.
+func (s *MWTestSuite) sequencedTx(pub cryptotypes.PubKey, sequence, timeoutHeight uint64) sdk.Tx {
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(sdk.AccAddress(pub.Address()))))
+	txBuilder.SetTimeoutHeight(timeoutHeight)
+	s.Require().NoError(txBuilder.SetSignatures(signing.SignatureV2{
+		PubKey:   pub,
+		Data:     &signing.SingleSignatureData{SignMode: signing.SignMode_SIGN_MODE_DIRECT, Signature: []byte("sig")},
+		Sequence: sequence,
+	}))
+	return txBuilder.GetTx()
+}
+
+func (s *MWTestSuite) TestTimeoutHeightOrderMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	_, pub1, _ := testdata.KeyTestPubAddr()
+
+	newTx := func(sequence, timeoutHeight uint64) sdk.Tx {
+		return s.sequencedTx(pub1, sequence, timeoutHeight)
+	}
+
+	testCases := []struct {
+		name       string
+		mode       middleware.TimeoutOrderMode
+		timeouts   []uint64
+		misordered bool
+	}{
+		{"increasing timeout heights", middleware.TimeoutOrderReject, []uint64{10, 20, 20}, false},
+		{"no timeout after a timeout", middleware.TimeoutOrderReject, []uint64{10, 0}, false},
+		{"decreasing timeout heights warn", middleware.TimeoutOrderWarn, []uint64{20, 10}, true},
+		{"decreasing timeout heights reject", middleware.TimeoutOrderReject, []uint64{20, 10}, true},
+		{"timeout after no timeout reject", middleware.TimeoutOrderReject, []uint64{0, 10}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewTimeoutHeightOrderMiddleware(tc.mode))
+
+			last := len(tc.timeouts) - 1
+			for i, timeoutHeight := range tc.timeouts[:last] {
+				_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(uint64(i), timeoutHeight), abci.RequestCheckTx{})
+				s.Require().NoError(err)
+			}
+
+			res, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), newTx(uint64(last), tc.timeouts[last]), abci.RequestCheckTx{})
+			switch {
+			case !tc.misordered:
+				s.Require().NoError(err)
+				s.Require().Empty(res.Events)
+			case tc.mode == middleware.TimeoutOrderReject:
+				s.Require().True(errors.Is(err, sdkerrors.ErrTxTimeoutHeight))
+			default:
+				s.Require().NoError(err)
+				s.Require().Len(res.Events, 1)
+				s.Require().Equal(middleware.EventTypeTimeoutHeightMisordered, res.Events[0].Type)
+			}
+		})
+	}
+}
+
+func (s *MWTestSuite) TestTimeoutHeightOrderMiddlewarePendingTxs() {
+	ctx := s.SetupTest(true) // setup
+	_, pub1, _ := testdata.KeyTestPubAddr()
+
+	newTx := func(sequence, timeoutHeight uint64) sdk.Tx {
+		return s.sequencedTx(pub1, sequence, timeoutHeight)
+	}
+
+	order := middleware.NewTimeoutHeightOrderMiddleware(middleware.TimeoutOrderReject)
+	passing := order(noopTxHandler{})
+	failing := order(failingTxHandler{sdkerrors.ErrInsufficientFee})
+
+	// txs rejected by the next handlers are not pending
+	_, err := failing.CheckTx(sdk.WrapSDKContext(ctx), newTx(0, 20), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), newTx(1, 10), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+
+	// a tx evicted on recheck is not pending anymore
+	_, err = failing.CheckTx(sdk.WrapSDKContext(ctx), newTx(1, 10), abci.RequestCheckTx{Type: abci.CheckTxType_Recheck})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFee))
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), newTx(2, 5), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+
+	// a committed tx is not pending anymore, nor the ones with lower sequences
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), newTx(0, 30), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), newTx(3, 15), abci.RequestCheckTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrTxTimeoutHeight))
+
+	_, err = passing.DeliverTx(sdk.WrapSDKContext(ctx), newTx(2, 5), abci.RequestDeliverTx{})
+	s.Require().NoError(err)
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), newTx(3, 1), abci.RequestCheckTx{})
+	s.Require().NoError(err)
+}
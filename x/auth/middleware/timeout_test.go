@@ -0,0 +1,57 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestSimulateTimeoutMiddleware() {
+	ctx := s.SetupTest(false) // setup
+
+	// each TestMsg takes 50ms to execute
+	var executed int
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) {
+		time.Sleep(50 * time.Millisecond)
+		executed++
+		return &sdk.Result{}, nil
+	}))
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+
+	priv, _, addr := testdata.KeyTestPubAddr()
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr), testdata.NewTestMsg(addr), testdata.NewTestMsg(addr)))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	// the deadline is exceeded while executing the first msg
+	txHandler := middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewSimulateTimeoutMiddleware(10*time.Millisecond),
+	)
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().True(errors.Is(err, sdkerrors.ErrLogic))
+	s.Require().True(errors.Is(err, context.DeadlineExceeded))
+	_, code, _ := sdkerrors.ABCIInfo(err, false)
+	s.Require().Equal(sdkerrors.ErrLogic.ABCICode(), code)
+	s.Require().Equal(1, executed)
+
+	// no timeout
+	executed = 0
+	txHandler = middleware.ComposeMiddlewares(
+		middleware.NewRunMsgsTxHandler(msr, legacyRouter),
+		middleware.NewSimulateTimeoutMiddleware(0),
+	)
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+	s.Require().Equal(3, executed)
+}
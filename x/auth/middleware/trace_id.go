@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// traceIDKey is the sdk.Context key under which the tx trace ID is stored.
+type traceIDKey struct{}
+
+// AttributeKeyTraceID is the logger key of the tx trace ID.
+const AttributeKeyTraceID = "tx_id"
+
+type traceIDTxHandler struct {
+	next tx.Handler
+}
+
+// NewTraceIDMiddleware returns a middleware that tags each tx with a trace ID,
+// to correlate the logs of all middlewares and msg handlers processing it. The
+// trace ID is the hash of the tx bytes, so it is identical in CheckTx,
+// DeliverTx and SimulateTx for the same tx, and matches the tx hash reported
+// by Tendermint.
+//
+// The trace ID is stored in the sdk.Context, see TraceIDFromContext, and added
+// to the sdk.Context logger under the `tx_id` key.
+func NewTraceIDMiddleware() tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return traceIDTxHandler{next: txh}
+	}
+}
+
+var _ tx.Handler = traceIDTxHandler{}
+
+// TraceIDFromContext returns the trace ID set by the TraceID middleware, and
+// whether one is set.
+func TraceIDFromContext(sdkCtx sdk.Context) (tmbytes.HexBytes, bool) {
+	traceID, ok := sdkCtx.Value(traceIDKey{}).(tmbytes.HexBytes)
+	return traceID, ok
+}
+
+// withTraceID sets the trace ID of the given tx bytes on the sdk.Context and
+// its logger.
+func withTraceID(ctx context.Context, txBytes []byte) context.Context {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	traceID := tmbytes.HexBytes(tmhash.Sum(txBytes))
+
+	sdkCtx = sdkCtx.
+		WithValue(traceIDKey{}, traceID).
+		WithLogger(sdkCtx.Logger().With(AttributeKeyTraceID, traceID.String()))
+
+	return sdk.WrapSDKContext(sdkCtx)
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh traceIDTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(withTraceID(ctx, req.Tx), tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh traceIDTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(withTraceID(ctx, req.Tx), tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh traceIDTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(withTraceID(ctx, req.TxBytes), sdkTx, req)
+}
@@ -0,0 +1,75 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// traceIDRecordingTxHandler is a test tx.Handler recording the trace IDs found
+// in the sdk.Context.
+type traceIDRecordingTxHandler struct {
+	traceIDs *[]tmbytes.HexBytes
+}
+
+var _ tx.Handler = traceIDRecordingTxHandler{}
+
+func (txh traceIDRecordingTxHandler) record(ctx context.Context) {
+	traceID, ok := middleware.TraceIDFromContext(sdk.UnwrapSDKContext(ctx))
+	if ok {
+		*txh.traceIDs = append(*txh.traceIDs, traceID)
+	}
+}
+
+func (txh traceIDRecordingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	txh.record(ctx)
+	return abci.ResponseCheckTx{}, nil
+}
+func (txh traceIDRecordingTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.record(ctx)
+	return abci.ResponseDeliverTx{}, nil
+}
+func (txh traceIDRecordingTxHandler) SimulateTx(ctx context.Context, _ sdk.Tx, _ tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	txh.record(ctx)
+	return tx.ResponseSimulateTx{}, nil
+}
+
+func (s *MWTestSuite) TestTraceIDMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	var traceIDs []tmbytes.HexBytes
+	txHandler := middleware.ComposeMiddlewares(
+		traceIDRecordingTxHandler{traceIDs: &traceIDs},
+		middleware.NewTraceIDMiddleware(),
+	)
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	// no trace ID is set outside of the middleware
+	_, ok := middleware.TraceIDFromContext(ctx)
+	s.Require().False(ok)
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: txBytes})
+	s.Require().NoError(err)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+	s.Require().NoError(err)
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+	s.Require().NoError(err)
+
+	// the trace ID is the tx hash, identical in all modes
+	expected := tmbytes.HexBytes(tmhash.Sum(txBytes))
+	s.Require().Equal([]tmbytes.HexBytes{expected, expected, expected}, traceIDs)
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// recentTxHashes is a fixed-size set of the most recently seen tx hashes. Once
+// full, the oldest hash is evicted for each new one. It is shared by all
+// copies of the txDedupTxHandler.
+type recentTxHashes struct {
+	mtx    sync.Mutex
+	hashes map[string]struct{}
+	// ring holds the hashes in insertion order, next is the index of the
+	// oldest entry, to be overwritten by the next insertion.
+	ring []string
+	next int
+}
+
+type txDedupTxHandler struct {
+	recent *recentTxHashes
+	next   tx.Handler
+}
+
+// NewTxDedupMiddleware returns a middleware that keeps the hashes of the last
+// window txs accepted in CheckTx, and rejects any tx whose hash is already in
+// the set with ErrTxInMempoolCache. The txs rejected by the next handlers are
+// not recorded, so that they can be resubmitted right away, e.g. once their
+// signer is funded. It is a defense-in-depth against accidental
+// tx rebroadcast, on top of the account sequence replay protection.
+//
+// Only new txs are checked on CheckTx: ReCheckTx, DeliverTx and SimulateTx are
+// not affected. A non-positive window disables the middleware.
+func NewTxDedupMiddleware(window int) tx.Middleware {
+	var recent *recentTxHashes
+	if window > 0 {
+		recent = &recentTxHashes{
+			hashes: make(map[string]struct{}, window),
+			ring:   make([]string, 0, window),
+		}
+	}
+
+	return func(txh tx.Handler) tx.Handler {
+		return txDedupTxHandler{
+			recent: recent,
+			next:   txh,
+		}
+	}
+}
+
+var _ tx.Handler = txDedupTxHandler{}
+
+// has reports whether the hash is in the set.
+func (r *recentTxHashes) has(hash string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	_, ok := r.hashes[hash]
+	return ok
+}
+
+// add inserts the hash in the set, evicting the oldest one if the set is full.
+func (r *recentTxHashes) add(hash string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.hashes[hash]; ok {
+		return
+	}
+
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, hash)
+	} else {
+		delete(r.hashes, r.ring[r.next])
+		r.ring[r.next] = hash
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.hashes[hash] = struct{}{}
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh txDedupTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if txh.recent == nil || req.Type == abci.CheckTxType_Recheck {
+		return txh.next.CheckTx(ctx, tx, req)
+	}
+
+	hash := tmhash.Sum(req.Tx)
+	if txh.recent.has(string(hash)) {
+		return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrTxInMempoolCache, "tx %X", hash)
+	}
+
+	res, err := txh.next.CheckTx(ctx, tx, req)
+	if err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	txh.recent.add(string(hash))
+
+	return res, nil
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh txDedupTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh txDedupTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// failingTxHandler is a test tx.Handler failing with err.
+type failingTxHandler struct {
+	err error
+}
+
+var _ tx.Handler = failingTxHandler{}
+
+func (txh failingTxHandler) CheckTx(_ context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return abci.ResponseCheckTx{}, txh.err
+}
+func (txh failingTxHandler) DeliverTx(_ context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return abci.ResponseDeliverTx{}, txh.err
+}
+func (txh failingTxHandler) SimulateTx(_ context.Context, _ sdk.Tx, _ tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return tx.ResponseSimulateTx{}, txh.err
+}
+
+func (s *MWTestSuite) TestTxDedupMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewTxDedupMiddleware(2))
+
+	checkTx := func(txBytes string) error {
+		_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{Tx: []byte(txBytes)})
+		return err
+	}
+
+	s.Require().NoError(checkTx("tx1"))
+	s.Require().NoError(checkTx("tx2"))
+
+	// duplicates are rejected on CheckTx, but not on ReCheckTx nor DeliverTx
+	s.Require().True(errors.Is(checkTx("tx1"), sdkerrors.ErrTxInMempoolCache))
+	_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestCheckTx{Tx: []byte("tx1"), Type: abci.CheckTxType_Recheck})
+	s.Require().NoError(err)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), txTest{}, abci.RequestDeliverTx{Tx: []byte("tx1")})
+	s.Require().NoError(err)
+
+	// tx3 evicts tx1, the oldest entry
+	s.Require().NoError(checkTx("tx3"))
+	s.Require().True(errors.Is(checkTx("tx2"), sdkerrors.ErrTxInMempoolCache))
+	s.Require().True(errors.Is(checkTx("tx3"), sdkerrors.ErrTxInMempoolCache))
+
+	// a retried tx1 is accepted after eviction, and now evicts tx2
+	s.Require().NoError(checkTx("tx1"))
+	s.Require().NoError(checkTx("tx2"))
+}
+
+func (s *MWTestSuite) TestTxDedupMiddlewareRejectedTx() {
+	ctx := s.SetupTest(true) // setup
+	dedup := middleware.NewTxDedupMiddleware(2)
+	failing := middleware.ComposeMiddlewares(failingTxHandler{sdkerrors.ErrInsufficientFunds}, dedup)
+	passing := middleware.ComposeMiddlewares(noopTxHandler{}, dedup)
+	req := abci.RequestCheckTx{Tx: []byte("tx1")}
+
+	// a tx rejected by the next handlers is not recorded, and can be retried
+	_, err := failing.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, req)
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+	_, err = failing.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, req)
+	s.Require().True(errors.Is(err, sdkerrors.ErrInsufficientFunds))
+
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, req)
+	s.Require().NoError(err)
+	_, err = passing.CheckTx(sdk.WrapSDKContext(ctx), txTest{}, req)
+	s.Require().True(errors.Is(err, sdkerrors.ErrTxInMempoolCache))
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// txSeedKey is the sdk.Context key under which the tx seed is stored.
+type txSeedKey struct{}
+
+// txIndexCounter counts the txs delivered in the current block, to derive the
+// index of each tx. It is shared by all copies of the txSeedTxHandler.
+type txIndexCounter struct {
+	mtx    sync.Mutex
+	height int64
+	next   uint64
+}
+
+// index returns the index of the next tx delivered at the given height. If
+// consume is set, the index is consumed, and the next call returns the
+// following one.
+func (c *txIndexCounter) index(height int64, consume bool) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if height != c.height {
+		c.height = height
+		c.next = 0
+	}
+
+	index := c.next
+	if consume {
+		c.next++
+	}
+
+	return index
+}
+
+type txSeedTxHandler struct {
+	counter *txIndexCounter
+	next    tx.Handler
+}
+
+// NewTxSeedMiddleware returns a middleware deriving a pseudo-random seed for
+// each tx, e.g. for on-chain games and lotteries, and storing it in the
+// sdk.Context of the next handlers, see GetTxSeed. The seed is the SHA-256
+// hash of the block hash, the big-endian index of the tx in the block and the
+// tx hash, so that it is identical on all the honest nodes, and differs across
+// the txs of a block, including the identical ones.
+//
+// The tx index counts the txs delivered to this middleware since the start of
+// the block, so the middleware must be placed where every tx of the block
+// reaches it. The block proposer knows the seeds of the txs it includes, so
+// they must not be used where the proposer could profit from them. In
+// SimulateTx, the seed is derived from the index the tx would have if it was
+// delivered next, and it is not consumed. No seed is set in CheckTx, as the
+// msgs are not executed then.
+func NewTxSeedMiddleware() tx.Middleware {
+	counter := &txIndexCounter{}
+
+	return func(txh tx.Handler) tx.Handler {
+		return txSeedTxHandler{
+			counter: counter,
+			next:    txh,
+		}
+	}
+}
+
+var _ tx.Handler = txSeedTxHandler{}
+
+// GetTxSeed returns the seed of the tx set by the TxSeed middleware, and
+// whether one is set.
+func GetTxSeed(sdkCtx sdk.Context) ([]byte, bool) {
+	seed, ok := sdkCtx.Value(txSeedKey{}).([]byte)
+	return seed, ok
+}
+
+// TxSeed returns the seed of the tx with the given hash, at the given index of
+// the block with the given hash.
+func TxSeed(blockHash []byte, txIndex uint64, txHash []byte) []byte {
+	h := sha256.New()
+	h.Write(blockHash)
+	index := make([]byte, 8)
+	binary.BigEndian.PutUint64(index, txIndex)
+	h.Write(index)
+	h.Write(txHash)
+
+	return h.Sum(nil)
+}
+
+// withTxSeed sets the seed of the tx on the sdk.Context. If consume is set,
+// the tx index is consumed.
+func (txh txSeedTxHandler) withTxSeed(ctx context.Context, txBytes []byte, consume bool) context.Context {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	index := txh.counter.index(sdkCtx.BlockHeight(), consume)
+	seed := TxSeed(sdkCtx.HeaderHash(), index, tmhash.Sum(txBytes))
+
+	return sdk.WrapSDKContext(sdkCtx.WithValue(txSeedKey{}, seed))
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh txSeedTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh txSeedTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	// dry-run txs are not part of the block
+	consume := !isDryRun(sdk.UnwrapSDKContext(ctx))
+	return txh.next.DeliverTx(txh.withTxSeed(ctx, req.Tx, consume), sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh txSeedTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(txh.withTxSeed(ctx, req.TxBytes, false), sdkTx, req)
+}
@@ -0,0 +1,103 @@
+package middleware_test
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+// txSeedRecordingTxHandler is a test tx.Handler recording the tx seeds found
+// in the sdk.Context.
+type txSeedRecordingTxHandler struct {
+	seeds *[][]byte
+}
+
+var _ tx.Handler = txSeedRecordingTxHandler{}
+
+func (txh txSeedRecordingTxHandler) record(ctx context.Context) {
+	seed, ok := middleware.GetTxSeed(sdk.UnwrapSDKContext(ctx))
+	if ok {
+		*txh.seeds = append(*txh.seeds, seed)
+	}
+}
+
+func (txh txSeedRecordingTxHandler) CheckTx(ctx context.Context, _ sdk.Tx, _ abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	txh.record(ctx)
+	return abci.ResponseCheckTx{}, nil
+}
+func (txh txSeedRecordingTxHandler) DeliverTx(ctx context.Context, _ sdk.Tx, _ abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	txh.record(ctx)
+	return abci.ResponseDeliverTx{}, nil
+}
+func (txh txSeedRecordingTxHandler) SimulateTx(ctx context.Context, _ sdk.Tx, _ tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	txh.record(ctx)
+	return tx.ResponseSimulateTx{}, nil
+}
+
+func (s *MWTestSuite) TestTxSeedMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	blockHash := tmhash.Sum([]byte("block"))
+	ctx = ctx.WithBlockHeight(10).WithHeaderHash(blockHash)
+
+	// deliverBlock delivers the given txs in a block through a new node's
+	// tx.Handler, and returns their seeds.
+	deliverBlock := func(sdkCtx sdk.Context, txs ...[]byte) [][]byte {
+		var seeds [][]byte
+		txHandler := middleware.ComposeMiddlewares(
+			txSeedRecordingTxHandler{seeds: &seeds},
+			middleware.NewTxSeedMiddleware(),
+		)
+		for _, txBytes := range txs {
+			_, err := txHandler.DeliverTx(sdk.WrapSDKContext(sdkCtx), msgsTx{}, abci.RequestDeliverTx{Tx: txBytes})
+			s.Require().NoError(err)
+		}
+
+		return seeds
+	}
+
+	tx1, tx2 := []byte("tx1"), []byte("tx2")
+	seeds := deliverBlock(ctx, tx1, tx2, tx1)
+	s.Require().Len(seeds, 3)
+
+	// the seed is derived from the block hash, the tx index and the tx hash
+	s.Require().Equal(middleware.TxSeed(blockHash, 0, tmhash.Sum(tx1)), seeds[0])
+	s.Require().Equal(middleware.TxSeed(blockHash, 1, tmhash.Sum(tx2)), seeds[1])
+	s.Require().Equal(middleware.TxSeed(blockHash, 2, tmhash.Sum(tx1)), seeds[2])
+
+	// the seeds differ across txs, including identical ones
+	s.Require().NotEqual(seeds[0], seeds[1])
+	s.Require().NotEqual(seeds[0], seeds[2])
+
+	// another node delivering the same block derives the same seeds
+	s.Require().Equal(seeds, deliverBlock(ctx, tx1, tx2, tx1))
+
+	// the seeds differ in another block
+	otherBlock := ctx.WithBlockHeight(11).WithHeaderHash(tmhash.Sum([]byte("other block")))
+	s.Require().NotEqual(seeds[0], deliverBlock(otherBlock, tx1)[0])
+
+	// the tx index restarts at each block, and simulations don't consume it
+	var recorded [][]byte
+	txHandler := middleware.ComposeMiddlewares(
+		txSeedRecordingTxHandler{seeds: &recorded},
+		middleware.NewTxSeedMiddleware(),
+	)
+	_, err := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), msgsTx{}, abci.RequestDeliverTx{Tx: tx1})
+	s.Require().NoError(err)
+	_, err = txHandler.SimulateTx(sdk.WrapSDKContext(otherBlock), msgsTx{}, tx.RequestSimulateTx{TxBytes: tx2})
+	s.Require().NoError(err)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(otherBlock), msgsTx{}, abci.RequestDeliverTx{Tx: tx2})
+	s.Require().NoError(err)
+	s.Require().Len(recorded, 3)
+	s.Require().Equal(recorded[1], recorded[2])
+	s.Require().Equal(middleware.TxSeed(otherBlock.HeaderHash(), 0, tmhash.Sum(tx2)), recorded[2])
+
+	// no seed is set in CheckTx
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), msgsTx{}, abci.RequestCheckTx{Tx: tx1})
+	s.Require().NoError(err)
+	s.Require().Len(recorded, 3)
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// DecompressedTx is implemented by the txs decoded from a compressed tx
+// envelope. Their size is the one of their decompressed encoding, rather than
+// the one of the received bytes, so that compression doesn't lower the size
+// limits and the size gas of a tx.
+type DecompressedTx interface {
+	GetDecompressedSize() int
+}
+
+// txSize returns the size of the given tx, which is the length of its encoding
+// unless it was decompressed.
+func txSize(sdkTx sdk.Tx, txBytes []byte) int {
+	if dtx, ok := sdkTx.(DecompressedTx); ok && dtx.GetDecompressedSize() > 0 {
+		return dtx.GetDecompressedSize()
+	}
+
+	return len(txBytes)
+}
+
+type txSizeLimitTxHandler struct {
+	maxBytes int
+	next     tx.Handler
+}
+
+// NewTxSizeLimitMiddleware returns a middleware that rejects txs whose encoded
+// size, decompressed for a DecompressedTx, is larger than maxBytes. It should be placed before the signature
+// verification middlewares, so that oversized txs fail fast. A non-positive
+// maxBytes disables the limit.
+func NewTxSizeLimitMiddleware(maxBytes int) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return txSizeLimitTxHandler{
+			maxBytes: maxBytes,
+			next:     txh,
+		}
+	}
+}
+
+var _ tx.Handler = txSizeLimitTxHandler{}
+
+func (txh txSizeLimitTxHandler) checkTxSize(sdkTx sdk.Tx, txBytes []byte) error {
+	if size := txSize(sdkTx, txBytes); txh.maxBytes > 0 && size > txh.maxBytes {
+		return sdkerrors.Wrapf(sdkerrors.ErrTxTooLarge, "tx size is %d bytes, max is %d bytes", size, txh.maxBytes)
+	}
+
+	return nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh txSizeLimitTxHandler) Named() string {
+	return "tx_size_limit"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh txSizeLimitTxHandler) CheckTx(ctx context.Context, tx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	if err := txh.checkTxSize(tx, req.Tx); err != nil {
+		return abci.ResponseCheckTx{}, err
+	}
+
+	return txh.next.CheckTx(ctx, tx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh txSizeLimitTxHandler) DeliverTx(ctx context.Context, tx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	if err := txh.checkTxSize(tx, req.Tx); err != nil {
+		return abci.ResponseDeliverTx{}, err
+	}
+
+	return txh.next.DeliverTx(ctx, tx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh txSizeLimitTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	if err := txh.checkTxSize(sdkTx, req.TxBytes); err != nil {
+		return tx.ResponseSimulateTx{}, err
+	}
+
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
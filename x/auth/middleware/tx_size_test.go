@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"errors"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+)
+
+func (s *MWTestSuite) TestTxSizeLimitMiddleware() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	// keys and addresses
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	// msg and signatures
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		desc     string
+		maxBytes int
+		expErr   error
+	}{
+		{"no limit", 0, nil},
+		{"tx exactly at the limit", len(txBytes), nil},
+		{"tx one byte over the limit", len(txBytes) - 1, sdkerrors.ErrTxTooLarge},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.desc, func() {
+			txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewTxSizeLimitMiddleware(tc.maxBytes))
+
+			_, checkErr := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{Tx: txBytes})
+			_, deliverErr := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{Tx: txBytes})
+			_, simulateErr := txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{TxBytes: txBytes})
+
+			for _, err := range []error{checkErr, deliverErr, simulateErr} {
+				if tc.expErr != nil {
+					s.Require().True(errors.Is(err, tc.expErr))
+				} else {
+					s.Require().NoError(err)
+				}
+			}
+		})
+	}
+}
+
+func (s *MWTestSuite) TestTxSizeLimitMiddlewareCompressedTx() {
+	ctx := s.SetupTest(true) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	// keys and addresses
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	txBuilder.SetMemo(strings.Repeat("a", 500))
+
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	_, txBytes, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	compressedBytes, err := authtx.CompressTxBytes(txBytes)
+	s.Require().NoError(err)
+	compressedTx, err := authtx.CompressedTxDecoder(s.clientCtx.TxConfig.TxDecoder(), len(txBytes))(compressedBytes)
+	s.Require().NoError(err)
+
+	// the limit applies to the decompressed size, not to the compressed bytes
+	s.Require().Less(len(compressedBytes), len(txBytes)-1)
+	txHandler := middleware.ComposeMiddlewares(noopTxHandler{}, middleware.NewTxSizeLimitMiddleware(len(txBytes)-1))
+
+	_, err = txHandler.CheckTx(sdk.WrapSDKContext(ctx), compressedTx, abci.RequestCheckTx{Tx: compressedBytes})
+	s.Require().ErrorIs(err, sdkerrors.ErrTxTooLarge)
+	_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), compressedTx, abci.RequestDeliverTx{Tx: compressedBytes})
+	s.Require().ErrorIs(err, sdkerrors.ErrTxTooLarge)
+}
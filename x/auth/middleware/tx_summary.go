@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Event type and attribute keys of the event emitted by the TxSummary
+// middleware.
+const (
+	EventTypeTxSummary = "tx_summary"
+
+	AttributeKeyGasWanted = "gas_wanted"
+	AttributeKeyGasUsed   = "gas_used"
+	AttributeKeySigner    = "signer"
+)
+
+type txSummaryTxHandler struct {
+	next tx.Handler
+}
+
+// NewTxSummaryMiddleware returns a middleware that, if emitSummary is set,
+// appends a `tx_summary` event to the successful DeliverTx responses, holding
+// the tx fee, the gas wanted and used, and the fee payer as signer, so that
+// indexers find them on a single event. It must be placed outside of the Gas
+// middleware, for the gas used to be final.
+//
+// The event is appended outside of the IndexEvents middleware, so it is always
+// indexed.
+// CONTRACT: Tx must implement FeeTx interface
+func NewTxSummaryMiddleware(emitSummary bool) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		if !emitSummary {
+			return txh
+		}
+
+		return txSummaryTxHandler{next: txh}
+	}
+}
+
+var _ tx.Handler = txSummaryTxHandler{}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh txSummaryTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh txSummaryTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	feeTx, ok := sdkTx.(sdk.FeeTx)
+	if !ok {
+		return abci.ResponseDeliverTx{}, sdkerrors.Wrap(sdkerrors.ErrTxDecode, "Tx must be a FeeTx")
+	}
+
+	res, err := txh.next.DeliverTx(ctx, sdkTx, req)
+	if err != nil {
+		return res, err
+	}
+
+	event := abci.Event(sdk.NewEvent(EventTypeTxSummary,
+		sdk.NewAttribute(sdk.AttributeKeyFee, feeTx.GetFee().String()),
+		sdk.NewAttribute(AttributeKeyGasWanted, strconv.FormatInt(res.GasWanted, 10)),
+		sdk.NewAttribute(AttributeKeyGasUsed, strconv.FormatInt(res.GasUsed, 10)),
+		sdk.NewAttribute(AttributeKeySigner, feeTx.FeePayer().String()),
+	))
+	for i := range event.Attributes {
+		event.Attributes[i].Index = true
+	}
+	res.Events = append(res.Events, event)
+
+	return res, nil
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh txSummaryTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
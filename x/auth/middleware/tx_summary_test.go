@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"errors"
+	"strconv"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestTxSummaryMiddleware() {
+	ctx := s.SetupTest(false) // setup
+	txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+
+	priv1, _, addr1 := testdata.KeyTestPubAddr()
+	s.Require().NoError(txBuilder.SetMsgs(testdata.NewTestMsg(addr1)))
+	txBuilder.SetFeeAmount(testdata.NewTestFeeAmount())
+	txBuilder.SetGasLimit(testdata.NewTestGasLimit())
+	privs, accNums, accSeqs := []cryptotypes.PrivKey{priv1}, []uint64{0}, []uint64{0}
+	testTx, _, err := s.createTestTx(txBuilder, privs, accNums, accSeqs, ctx.ChainID())
+	s.Require().NoError(err)
+
+	deliver := func(base gasUsingTxHandler, emitSummary bool) abci.ResponseDeliverTx {
+		txHandler := middleware.ComposeMiddlewares(base, middleware.NewTxSummaryMiddleware(emitSummary), middleware.GasTxMiddleware)
+		res, _ := txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+		return res
+	}
+
+	// the summary matches the final response gas and the tx fee
+	res := deliver(gasUsingTxHandler{gasUsed: 12345}, true)
+	s.Require().Len(res.Events, 1)
+	event := res.Events[0]
+	s.Require().Equal(middleware.EventTypeTxSummary, event.Type)
+
+	attrs := make(map[string]string)
+	for _, attr := range event.Attributes {
+		s.Require().True(attr.Index)
+		attrs[string(attr.Key)] = string(attr.Value)
+	}
+	s.Require().Equal(map[string]string{
+		sdk.AttributeKeyFee:              testdata.NewTestFeeAmount().String(),
+		middleware.AttributeKeyGasWanted: strconv.FormatInt(res.GasWanted, 10),
+		middleware.AttributeKeyGasUsed:   strconv.FormatInt(res.GasUsed, 10),
+		middleware.AttributeKeySigner:    addr1.String(),
+	}, attrs)
+	s.Require().Equal(int64(testdata.NewTestGasLimit()), res.GasWanted)
+	s.Require().Equal(int64(12345), res.GasUsed)
+
+	// no summary is emitted when disabled, or for failed txs
+	s.Require().Empty(deliver(gasUsingTxHandler{gasUsed: 12345}, false).Events)
+
+	txHandler := middleware.ComposeMiddlewares(
+		gasUsingTxHandler{gasUsed: 12345, err: sdkerrors.ErrInvalidRequest},
+		middleware.NewTxSummaryMiddleware(true),
+		middleware.GasTxMiddleware,
+	)
+	res, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+	s.Require().True(errors.Is(err, sdkerrors.ErrInvalidRequest))
+	s.Require().Empty(res.Events)
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/migrations/legacytx"
+)
+
+type rejectUnknownMsgsTxHandler struct {
+	msgServiceRouter *MsgServiceRouter
+	legacyRouter     sdk.Router
+	next             tx.Handler
+}
+
+// NewRejectUnknownMsgsMiddleware returns a middleware that rejects, with
+// ErrUnknownRequest, the txs containing a msg which has no route in the given
+// routers, i.e. which the msg router would fail to route. It should be given
+// the same routers as NewRunMsgsTxHandler.
+//
+// The check is only applied on CheckTx, and should be placed before the
+// signature verification middlewares, to reject such txs before doing any
+// expensive work on them.
+func NewRejectUnknownMsgsMiddleware(msr *MsgServiceRouter, legacyRouter sdk.Router) tx.Middleware {
+	return func(txh tx.Handler) tx.Handler {
+		return rejectUnknownMsgsTxHandler{
+			msgServiceRouter: msr,
+			legacyRouter:     legacyRouter,
+			next:             txh,
+		}
+	}
+}
+
+var _ tx.Handler = rejectUnknownMsgsTxHandler{}
+
+// isRoutable reports whether the msg can be routed, using the same routing as
+// the msg router.
+func (txh rejectUnknownMsgsTxHandler) isRoutable(sdkCtx sdk.Context, msg sdk.Msg) bool {
+	if txh.msgServiceRouter != nil && txh.msgServiceRouter.Handler(msg) != nil {
+		return true
+	}
+
+	legacyMsg, ok := msg.(legacytx.LegacyMsg)
+	return ok && txh.legacyRouter != nil && txh.legacyRouter.Route(sdkCtx, legacyMsg.Route()) != nil
+}
+
+// Named implements NamedTxHandler.Named.
+func (txh rejectUnknownMsgsTxHandler) Named() string {
+	return "reject_unknown_msgs"
+}
+
+// CheckTx implements tx.Handler.CheckTx.
+func (txh rejectUnknownMsgsTxHandler) CheckTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestCheckTx) (abci.ResponseCheckTx, error) {
+	sdkCtx := sdk.UnwrapSDKContext(ctx)
+	for i, msg := range sdkTx.GetMsgs() {
+		if !txh.isRoutable(sdkCtx, msg) {
+			return abci.ResponseCheckTx{}, sdkerrors.Wrapf(sdkerrors.ErrUnknownRequest, "unknown message type URL: %s; message index: %d", sdk.MsgTypeURL(msg), i)
+		}
+	}
+
+	return txh.next.CheckTx(ctx, sdkTx, req)
+}
+
+// DeliverTx implements tx.Handler.DeliverTx.
+func (txh rejectUnknownMsgsTxHandler) DeliverTx(ctx context.Context, sdkTx sdk.Tx, req abci.RequestDeliverTx) (abci.ResponseDeliverTx, error) {
+	return txh.next.DeliverTx(ctx, sdkTx, req)
+}
+
+// SimulateTx implements tx.Handler.SimulateTx.
+func (txh rejectUnknownMsgsTxHandler) SimulateTx(ctx context.Context, sdkTx sdk.Tx, req tx.RequestSimulateTx) (tx.ResponseSimulateTx, error) {
+	return txh.next.SimulateTx(ctx, sdkTx, req)
+}
@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/middleware"
+)
+
+func (s *MWTestSuite) TestRejectUnknownMsgs() {
+	ctx := s.SetupTest(true) // setup
+
+	msr := middleware.NewMsgServiceRouter(s.clientCtx.InterfaceRegistry)
+	testdata.RegisterMsgServer(msr, testdata.MsgServerImpl{})
+	legacyRouter := middleware.NewLegacyRouter()
+	legacyRouter.AddRoute(sdk.NewRoute((&testdata.TestMsg{}).Route(), func(ctx sdk.Context, msg sdk.Msg) (*sdk.Result, error) { return &sdk.Result{}, nil }))
+
+	_, _, addr1 := testdata.KeyTestPubAddr()
+	registeredMsg := &testdata.MsgCreateDog{Dog: &testdata.Dog{Name: "Spot"}}
+	legacyMsg := testdata.NewTestMsg(addr1)
+
+	testCases := []struct {
+		name         string
+		msgs         []sdk.Msg
+		legacyRouter sdk.Router
+		expErr       bool
+	}{
+		{"registered msg service route", []sdk.Msg{registeredMsg}, legacyRouter, false},
+		{"registered legacy route", []sdk.Msg{legacyMsg}, legacyRouter, false},
+		{"both registered", []sdk.Msg{registeredMsg, legacyMsg}, legacyRouter, false},
+		{"unregistered legacy route", []sdk.Msg{registeredMsg, legacyMsg}, middleware.NewLegacyRouter(), true},
+		{"no legacy router", []sdk.Msg{legacyMsg}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			txBuilder := s.clientCtx.TxConfig.NewTxBuilder()
+			s.Require().NoError(txBuilder.SetMsgs(tc.msgs...))
+			testTx := txBuilder.GetTx()
+
+			txHandler := middleware.ComposeMiddlewares(
+				noopTxHandler{},
+				middleware.NewRejectUnknownMsgsMiddleware(msr, tc.legacyRouter),
+			)
+
+			_, err := txHandler.CheckTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestCheckTx{})
+			if tc.expErr {
+				s.Require().True(errors.Is(err, sdkerrors.ErrUnknownRequest))
+			} else {
+				s.Require().NoError(err)
+			}
+
+			// DeliverTx and SimulateTx are left to the msg router
+			_, err = txHandler.DeliverTx(sdk.WrapSDKContext(ctx), testTx, abci.RequestDeliverTx{})
+			s.Require().NoError(err)
+			_, err = txHandler.SimulateTx(sdk.WrapSDKContext(ctx), testTx, tx.RequestSimulateTx{})
+			s.Require().NoError(err)
+		})
+	}
+}
@@ -31,6 +31,10 @@ type wrapper struct {
 	authInfoBz []byte
 
 	txBodyHasUnknownNonCriticals bool
+
+	// decompressedSize is the length of the decompressed encoding of the tx if
+	// it was decoded from a compressed tx envelope, zero otherwise
+	decompressedSize int
 }
 
 var (
@@ -39,6 +43,7 @@ var (
 	_ middleware.HasExtensionOptionsTx = &wrapper{}
 	_ ExtensionOptionsTxBuilder        = &wrapper{}
 	_ tx.TipTx                         = &wrapper{}
+	_ middleware.DecompressedTx        = &wrapper{}
 )
 
 // ExtensionOptionsTxBuilder defines a TxBuilder that can also set extensions.
@@ -169,6 +174,11 @@ func (w *wrapper) GetMemo() string {
 	return w.tx.Body.Memo
 }
 
+// GetDecompressedSize implements middleware.DecompressedTx.
+func (w *wrapper) GetDecompressedSize() int {
+	return w.decompressedSize
+}
+
 // GetTimeoutHeight returns the transaction's timeout height (if set).
 func (w *wrapper) GetTimeoutHeight() uint64 {
 	return w.tx.Body.TimeoutHeight
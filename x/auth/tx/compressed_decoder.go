@@ -0,0 +1,74 @@
+package tx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// CompressedTxPrefix is the magic prefix of the compressed tx envelopes, which
+// is the gzip header magic number. No protobuf TxRaw can start with it, as it
+// doesn't encode a valid field key.
+var CompressedTxPrefix = []byte{0x1f, 0x8b}
+
+// CompressTxBytes wraps the given encoded tx into a compressed tx envelope,
+// i.e. gzips it, to be decoded by CompressedTxDecoder.
+func CompressTxBytes(txBytes []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(txBytes); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// CompressedTxDecoder returns a TxDecoder decompressing the compressed tx
+// envelopes, detected by CompressedTxPrefix, before decoding them with the
+// given decoder. The other bytes are decoded as is, so that compression is
+// optional, e.g. for the relayers of large IBC client updates.
+//
+// The decompressed txs are limited to maxDecompressedSize bytes, to reject
+// compression bombs with ErrTxTooLarge without inflating them fully. The txs
+// decoded by this package record their decompressed size, which the tx size
+// limit and the tx size gas of the middlewares then use instead of the length
+// of the compressed bytes.
+func CompressedTxDecoder(decoder sdk.TxDecoder, maxDecompressedSize int) sdk.TxDecoder {
+	return func(txBytes []byte) (sdk.Tx, error) {
+		if !bytes.HasPrefix(txBytes, CompressedTxPrefix) {
+			return decoder(txBytes)
+		}
+
+		r, err := gzip.NewReader(bytes.NewReader(txBytes))
+		if err != nil {
+			return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, err.Error())
+		}
+		defer r.Close()
+
+		// read one more byte than allowed to detect oversized txs
+		decompressed, err := ioutil.ReadAll(io.LimitReader(r, int64(maxDecompressedSize)+1))
+		if err != nil {
+			return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, err.Error())
+		}
+		if len(decompressed) > maxDecompressedSize {
+			return nil, sdkerrors.Wrapf(sdkerrors.ErrTxTooLarge, "decompressed tx exceeds %d bytes", maxDecompressedSize)
+		}
+
+		sdkTx, err := decoder(decompressed)
+		if err != nil {
+			return nil, err
+		}
+		if w, ok := sdkTx.(*wrapper); ok {
+			w.decompressedSize = len(decompressed)
+		}
+
+		return sdkTx, nil
+	}
+}
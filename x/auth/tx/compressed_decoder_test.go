@@ -0,0 +1,57 @@
+package tx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+func TestCompressedTxDecoder(t *testing.T) {
+	registry := codectypes.NewInterfaceRegistry()
+	testdata.RegisterInterfaces(registry)
+	cdc := codec.NewProtoCodec(registry)
+	encoder := DefaultTxEncoder()
+	decoder := CompressedTxDecoder(DefaultTxDecoder(cdc), 1024)
+
+	_, _, addr := testdata.KeyTestPubAddr()
+	builder := newBuilder(nil)
+	require.NoError(t, builder.SetMsgs(testdata.NewTestMsg(addr)))
+	builder.SetMemo(string(bytes.Repeat([]byte("a"), 500)))
+
+	txBz, err := encoder(builder.GetTx())
+	require.NoError(t, err)
+
+	// a compressed tx decodes like the uncompressed one
+	compressed, err := CompressTxBytes(txBz)
+	require.NoError(t, err)
+	require.Less(t, len(compressed), len(txBz))
+
+	decoded, err := decoder(compressed)
+	require.NoError(t, err)
+	decodedBz, err := encoder(decoded)
+	require.NoError(t, err)
+	require.Equal(t, txBz, decodedBz)
+	require.Equal(t, len(txBz), decoded.(*wrapper).GetDecompressedSize())
+
+	// uncompressed txs are still accepted
+	decoded, err = decoder(txBz)
+	require.NoError(t, err)
+	require.Equal(t, builder.GetTx().GetMemo(), decoded.(*wrapper).GetMemo())
+	require.Zero(t, decoded.(*wrapper).GetDecompressedSize())
+
+	// a compression bomb is rejected before being inflated fully
+	bomb, err := CompressTxBytes(make([]byte, 1<<20))
+	require.NoError(t, err)
+	_, err = decoder(bomb)
+	require.ErrorIs(t, err, sdkerrors.ErrTxTooLarge)
+
+	// a corrupted envelope is not decoded
+	_, err = decoder(compressed[:len(compressed)/2])
+	require.ErrorIs(t, err, sdkerrors.ErrTxDecode)
+}
@@ -0,0 +1,91 @@
+package tx
+
+import (
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/x/auth/migrations/legacytx"
+)
+
+// AminoFallbackTxDecoder returns a TxDecoder decoding protobuf txs, which
+// falls back to decoding legacy Amino StdTxs for the bytes which can't be
+// decoded as protobuf. The StdTxs are converted into protobuf txs signed with
+// SIGN_MODE_LEGACY_AMINO_JSON, whose sign bytes are the StdTx sign bytes, so
+// that their signatures are verified like the ones of any protobuf tx. Bytes
+// decoding both as protobuf and Amino are decoded as protobuf.
+//
+// The Amino codec must have the StdTx and the msgs of the chain registered.
+func AminoFallbackTxDecoder(cdc codec.ProtoCodecMarshaler, legacyAmino *codec.LegacyAmino) sdk.TxDecoder {
+	protoDecoder := DefaultTxDecoder(cdc)
+	aminoDecoder := legacytx.StdTxConfig{Cdc: legacyAmino}.TxDecoder()
+
+	return func(txBytes []byte) (sdk.Tx, error) {
+		protoTx, protoErr := protoDecoder(txBytes)
+		if protoErr == nil {
+			return protoTx, nil
+		}
+
+		aminoTx, err := aminoDecoder(txBytes)
+		if err != nil {
+			// report the protobuf error, as protobuf is the default encoding
+			return nil, protoErr
+		}
+
+		stdTx, ok := aminoTx.(legacytx.StdTx)
+		if !ok {
+			return nil, protoErr
+		}
+
+		return stdTxToProtoTx(cdc, stdTx)
+	}
+}
+
+// stdTxToProtoTx converts a legacy StdTx into a protobuf tx, keeping its
+// SIGN_MODE_LEGACY_AMINO_JSON signatures.
+func stdTxToProtoTx(cdc codec.Codec, stdTx legacytx.StdTx) (sdk.Tx, error) {
+	w := newBuilder(cdc)
+	if err := w.SetMsgs(stdTx.GetMsgs()...); err != nil {
+		return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, err.Error())
+	}
+
+	w.SetMemo(stdTx.GetMemo())
+	w.SetFeeAmount(stdTx.GetFee())
+	w.SetGasLimit(stdTx.GetGas())
+	w.SetTimeoutHeight(stdTx.GetTimeoutHeight())
+	// the payer and granter are kept as is, as they are part of the sign bytes
+	w.tx.AuthInfo.Fee.Payer = stdTx.Fee.Payer
+	w.tx.AuthInfo.Fee.Granter = stdTx.Fee.Granter
+
+	sigs, err := stdTx.GetSignaturesV2()
+	if err != nil {
+		return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, err.Error())
+	}
+
+	signerInfos := make([]*tx.SignerInfo, len(sigs))
+	rawSigs := make([][]byte, len(sigs))
+	for i, sig := range sigs {
+		var modeInfo *tx.ModeInfo
+		modeInfo, rawSigs[i] = SignatureDataToModeInfoAndSig(sig.Data)
+
+		// the pubkey may be omitted if it is already set on the account
+		var pubKey *codectypes.Any
+		if sig.PubKey != nil {
+			pubKey, err = codectypes.NewAnyWithValue(sig.PubKey)
+			if err != nil {
+				return nil, sdkerrors.Wrap(sdkerrors.ErrTxDecode, err.Error())
+			}
+		}
+
+		signerInfos[i] = &tx.SignerInfo{
+			PublicKey: pubKey,
+			ModeInfo:  modeInfo,
+		}
+	}
+
+	w.setSignerInfos(signerInfos)
+	w.setSignatures(rawSigs)
+
+	return w, nil
+}
@@ -0,0 +1,91 @@
+package tx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	"github.com/cosmos/cosmos-sdk/testutil/testdata"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	signingtypes "github.com/cosmos/cosmos-sdk/types/tx/signing"
+	"github.com/cosmos/cosmos-sdk/x/auth/migrations/legacytx"
+	"github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+func TestAminoFallbackTxDecoder(t *testing.T) {
+	registry := codectypes.NewInterfaceRegistry()
+	testdata.RegisterInterfaces(registry)
+	cryptocodec.RegisterInterfaces(registry)
+	protoCdc := codec.NewProtoCodec(registry)
+
+	legacyAmino := codec.NewLegacyAmino()
+	sdk.RegisterLegacyAminoCodec(legacyAmino)
+	legacytx.RegisterLegacyAminoCodec(legacyAmino)
+	cryptocodec.RegisterCrypto(legacyAmino)
+	legacyAmino.RegisterConcrete(&testdata.TestMsg{}, "testdata.TestMsg", nil)
+
+	decoder := AminoFallbackTxDecoder(protoCdc, legacyAmino)
+
+	var (
+		chainID        = "test-chain"
+		accNum  uint64 = 1
+		seq     uint64 = 2
+	)
+
+	priv, pubKey, _ := testdata.KeyTestPubAddr()
+	fee := legacytx.NewStdFee(gas, coins)
+	signBz := legacytx.StdSignBytes(chainID, accNum, seq, timeout, fee, []sdk.Msg{msg}, memo, nil)
+	sigBz, err := priv.Sign(signBz)
+	require.NoError(t, err)
+
+	stdTx := legacytx.NewStdTx([]sdk.Msg{msg}, fee, []legacytx.StdSignature{legacytx.NewStdSignature(pubKey, sigBz)}, memo)
+	stdTx.TimeoutHeight = timeout
+	aminoBz, err := legacytx.DefaultTxEncoder(legacyAmino)(stdTx)
+	require.NoError(t, err)
+
+	// the amino tx is converted into a protobuf tx with the same content
+	decoded, err := decoder(aminoBz)
+	require.NoError(t, err)
+	protoTx, ok := decoded.(*wrapper)
+	require.True(t, ok)
+
+	require.Equal(t, []sdk.Msg{msg}, protoTx.GetMsgs())
+	require.Equal(t, memo, protoTx.GetMemo())
+	require.Equal(t, coins, protoTx.GetFee())
+	require.Equal(t, gas, protoTx.GetGas())
+	require.Equal(t, timeout, protoTx.GetTimeoutHeight())
+
+	// the signature is kept in SIGN_MODE_LEGACY_AMINO_JSON and still verifies
+	sigs, err := protoTx.GetSignaturesV2()
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+	require.True(t, pubKey.Equals(sigs[0].PubKey))
+	sigData, ok := sigs[0].Data.(*signingtypes.SingleSignatureData)
+	require.True(t, ok)
+	require.Equal(t, signingtypes.SignMode_SIGN_MODE_LEGACY_AMINO_JSON, sigData.SignMode)
+
+	handler := signModeLegacyAminoJSONHandler{}
+	convertedSignBz, err := handler.GetSignBytes(signingtypes.SignMode_SIGN_MODE_LEGACY_AMINO_JSON, signing.SignerData{
+		Address:       sdk.AccAddress(pubKey.Address()).String(),
+		ChainID:       chainID,
+		AccountNumber: accNum,
+		Sequence:      seq,
+	}, protoTx)
+	require.NoError(t, err)
+	require.Equal(t, signBz, convertedSignBz)
+	require.True(t, pubKey.VerifySignature(convertedSignBz, sigData.Signature))
+
+	// the converted tx can be re-encoded and decoded as a protobuf tx
+	protoBz, err := DefaultTxEncoder()(protoTx)
+	require.NoError(t, err)
+	reDecoded, err := decoder(protoBz)
+	require.NoError(t, err)
+	require.Equal(t, protoTx.GetMsgs(), reDecoded.GetMsgs())
+
+	// bytes which are neither protobuf nor amino still fail with the protobuf error
+	_, err = decoder([]byte("junk"))
+	require.Error(t, err)
+}
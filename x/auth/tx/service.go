@@ -18,8 +18,8 @@ import (
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 )
 
-// baseAppSimulateFn is the signature of the Baseapp#Simulate function.
-type baseAppSimulateFn func(txBytes []byte) (sdk.GasInfo, *sdk.Result, error)
+// baseAppSimulateFn is the signature of the Baseapp#SimulateAtHeight function.
+type baseAppSimulateFn func(txBytes []byte, height int64) (sdk.GasInfo, *sdk.Result, error)
 
 // txServer is the server for the protobuf Tx service.
 type txServer struct {
@@ -114,7 +114,11 @@ func (s txServer) Simulate(ctx context.Context, req *txtypes.SimulateRequest) (*
 		return nil, status.Errorf(codes.InvalidArgument, "empty txBytes is not allowed")
 	}
 
-	gasInfo, result, err := s.simulate(txBytes)
+	if req.Height < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "negative height %d is not allowed", req.Height)
+	}
+
+	gasInfo, result, err := s.simulate(txBytes, req.Height)
 	if err != nil {
 		return nil, err
 	}
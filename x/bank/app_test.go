@@ -9,7 +9,9 @@ import (
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/simapp"
+	"github.com/cosmos/cosmos-sdk/simapp/helpers"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/cosmos/cosmos-sdk/x/bank/testutil"
 	"github.com/cosmos/cosmos-sdk/x/bank/types"
@@ -337,3 +339,62 @@ func TestMsgMultiSendDependent(t *testing.T) {
 		}
 	}
 }
+
+func TestSimulateSendAtHeight(t *testing.T) {
+	acc := &authtypes.BaseAccount{
+		Address: addr1.String(),
+	}
+
+	genAccs := []authtypes.GenesisAccount{acc}
+	app := simapp.SetupWithGenesisAccounts(t, genAccs)
+	ctx := app.BaseApp.NewContext(false, tmproto.Header{})
+
+	require.NoError(t, testutil.FundAccount(app.BankKeeper, ctx, addr1, sdk.NewCoins(sdk.NewInt64Coin("foocoin", 67))))
+
+	app.Commit()
+	fundedHeight := app.LastBlockHeight()
+
+	// send 50foocoin, leaving 17foocoin to addr1
+	sendMsg := types.NewMsgSend(addr1, addr2, sdk.Coins{sdk.NewInt64Coin("foocoin", 50)})
+	header := tmproto.Header{Height: app.LastBlockHeight() + 1}
+	txGen := simapp.MakeTestEncodingConfig().TxConfig
+	_, _, err := simapp.SignCheckDeliver(t, txGen, app.BaseApp, header, []sdk.Msg{sendMsg}, "", []uint64{0}, []uint64{0}, true, true, priv1)
+	require.NoError(t, err)
+	sentHeight := app.LastBlockHeight()
+
+	genTxBytes := func(seq uint64) []byte {
+		tx, err := helpers.GenTx(
+			txGen,
+			[]sdk.Msg{sendMsg},
+			sdk.Coins{sdk.NewInt64Coin(sdk.DefaultBondDenom, 0)},
+			helpers.DefaultGenTxGas,
+			"",
+			[]uint64{0},
+			[]uint64{seq},
+			priv1,
+		)
+		require.NoError(t, err)
+		txBytes, err := txGen.TxEncoder()(tx)
+		require.NoError(t, err)
+
+		return txBytes
+	}
+
+	// addr1 holds enough funds before the first send
+	_, res, err := app.SimulateAtHeight(genTxBytes(0), fundedHeight)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	// but not anymore after it, including at the latest height
+	_, _, err = app.SimulateAtHeight(genTxBytes(1), sentHeight)
+	require.ErrorIs(t, err, sdkerrors.ErrInsufficientFunds)
+	_, _, err = app.SimulateAtHeight(genTxBytes(1), 0)
+	require.ErrorIs(t, err, sdkerrors.ErrInsufficientFunds)
+
+	// the state of future heights is not available
+	_, _, err = app.SimulateAtHeight(genTxBytes(1), sentHeight+1)
+	require.ErrorIs(t, err, sdkerrors.ErrInvalidRequest)
+
+	// simulations don't change the state
+	simapp.CheckBalance(t, app, addr1, sdk.Coins{sdk.NewInt64Coin("foocoin", 17)})
+}
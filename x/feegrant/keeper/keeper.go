@@ -2,6 +2,7 @@ package keeper
 
 import (
 	"fmt"
+	"time"
 
 	storetypes "github.com/cosmos/cosmos-sdk/store/types"
 	"github.com/tendermint/tendermint/libs/log"
@@ -21,7 +22,10 @@ type Keeper struct {
 	authKeeper feegrant.AccountKeeper
 }
 
-var _ middleware.FeegrantKeeper = &Keeper{}
+var (
+	_ middleware.FeegrantKeeper      = &Keeper{}
+	_ middleware.FeegrantGraceKeeper = &Keeper{}
+)
 
 // NewKeeper creates a fee grant Keeper
 func NewKeeper(cdc codec.BinaryCodec, storeKey storetypes.StoreKey, ak feegrant.AccountKeeper) Keeper {
@@ -47,6 +51,12 @@ func (k Keeper) GrantAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress,
 		k.authKeeper.SetAccount(ctx, granteeAcc)
 	}
 
+	return k.setAllowance(ctx, granter, grantee, feeAllowance)
+}
+
+// setAllowance stores the allowance between the granter and grantee, whose
+// account must exist.
+func (k Keeper) setAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress, feeAllowance feegrant.FeeAllowanceI) error {
 	store := ctx.KVStore(k.storeKey)
 	key := feegrant.FeeAllowanceKey(granter, grantee)
 	grant, err := feegrant.NewGrant(granter, grantee, feeAllowance)
@@ -79,6 +89,13 @@ func (k Keeper) revokeAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress
 		return err
 	}
 
+	k.deleteAllowance(ctx, granter, grantee)
+	return nil
+}
+
+// deleteAllowance removes the allowance between the granter and grantee,
+// which must exist.
+func (k Keeper) deleteAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress) {
 	store := ctx.KVStore(k.storeKey)
 	key := feegrant.FeeAllowanceKey(granter, grantee)
 	store.Delete(key)
@@ -90,7 +107,6 @@ func (k Keeper) revokeAllowance(ctx sdk.Context, granter, grantee sdk.AccAddress
 			sdk.NewAttribute(feegrant.AttributeKeyGrantee, grantee.String()),
 		),
 	)
-	return nil
 }
 
 // GetAllowance returns the allowance between the granter and grantee.
@@ -180,6 +196,68 @@ func (k Keeper) UseGrantedFees(ctx sdk.Context, granter, grantee sdk.AccAddress,
 	return k.GrantAllowance(ctx, granter, grantee, grant)
 }
 
+// UseGrantedFeesWithGrace implements
+// middleware.FeegrantGraceKeeper.UseGrantedFeesWithGrace. A grant which
+// expired less than grace ago is accepted as if it had no expiration, the rest
+// of the allowance, e.g. the period reset of a PeriodicAllowance, still being
+// evaluated at the block time. The grants which expired before are revoked
+// like in UseGrantedFees.
+func (k Keeper) UseGrantedFeesWithGrace(ctx sdk.Context, granter, grantee sdk.AccAddress, fee sdk.Coins, msgs []sdk.Msg, grace time.Duration) error {
+	f, err := k.getGrant(ctx, granter, grantee)
+	if err != nil {
+		return err
+	}
+
+	grant, err := f.GetGrant()
+	if err != nil {
+		return err
+	}
+
+	expiration, ok := swapExpiration(grant, nil)
+	if !ok || expiration == nil || !ctx.BlockTime().After(*expiration) || ctx.BlockTime().Sub(*expiration) >= grace {
+		return k.UseGrantedFees(ctx, granter, grantee, fee, msgs)
+	}
+
+	remove, err := grant.Accept(ctx, fee, msgs)
+	swapExpiration(grant, expiration)
+	if remove {
+		k.deleteAllowance(ctx, granter, grantee)
+	}
+	if err != nil {
+		return err
+	}
+
+	emitUseGrantEvent(ctx, granter.String(), grantee.String())
+
+	if remove {
+		return nil
+	}
+
+	// if fee allowance is accepted, store the updated state of the allowance
+	return k.setAllowance(ctx, granter, grantee, grant)
+}
+
+// swapExpiration sets the expiration of the allowance, and returns the
+// previous one. ok is false if the allowance has no expiration field.
+func swapExpiration(allowance feegrant.FeeAllowanceI, expiration *time.Time) (prev *time.Time, ok bool) {
+	switch a := allowance.(type) {
+	case *feegrant.BasicAllowance:
+		prev, a.Expiration = a.Expiration, expiration
+	case *feegrant.PeriodicAllowance:
+		prev, a.Basic.Expiration = a.Basic.Expiration, expiration
+	case *feegrant.AllowedMsgAllowance:
+		inner, err := a.GetAllowance()
+		if err != nil {
+			return nil, false
+		}
+		return swapExpiration(inner, expiration)
+	default:
+		return nil, false
+	}
+
+	return prev, true
+}
+
 func emitUseGrantEvent(ctx sdk.Context, granter, grantee string) {
 	ctx.EventManager().EmitEvent(
 		sdk.NewEvent(